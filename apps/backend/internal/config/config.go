@@ -3,6 +3,9 @@ package config
 import (
 	"fmt"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 )
 
 type Config struct {
@@ -18,6 +21,7 @@ type Config struct {
 
 	// Server configuration
 	ServerPort string
+	APIURL     string // Public URL of this API, where payment gateways send customers back
 
 	// Clerk configuration
 	ClerkSecretKey      string
@@ -28,6 +32,31 @@ type Config struct {
 	StripeSecretKey     string
 	StripeWebhookSecret string
 	FrontendURL         string
+
+	// eSewa configuration; the form and status URLs default to eSewa's test environment
+	EsewaProductCode string
+	EsewaSecretKey   string
+	EsewaFormURL     string
+	EsewaStatusURL   string
+
+	// Khalti configuration; the base URL defaults to Khalti's sandbox
+	KhaltiSecretKey string
+	KhaltiBaseURL   string
+
+	// Payment methods served by an in-memory fake gateway, for local testing
+	FakePaymentMethods []string
+
+	// Guest cart configuration
+	CartTokenSecret string
+
+	// Days a sale is held before the shop can be paid out, leaving time for refunds
+	PayoutHoldingDays int
+
+	// How long an order may wait for its payment before it is cancelled
+	UnpaidOrderWindow time.Duration
+
+	// Background job queue workers on this server; 0 leaves the queue to others
+	JobWorkers int
 }
 
 func (s *Config) LoadEnv() (*Config, error) {
@@ -41,11 +70,61 @@ func (s *Config) LoadEnv() (*Config, error) {
 		serverPort = "8080" // default port
 	}
 
+	apiURL := os.Getenv("API_URL")
+	if apiURL == "" {
+		apiURL = "http://localhost:" + serverPort
+	}
+
 	frontendURL := os.Getenv("FRONTEND_URL")
 	if frontendURL == "" {
 		frontendURL = "http://localhost:5173"
 	}
 
+	esewaProductCode := os.Getenv("ESEWA_PRODUCT_CODE")
+	if esewaProductCode == "" {
+		esewaProductCode = "EPAYTEST" // eSewa's test merchant
+	}
+
+	var fakePaymentMethods []string
+	for _, method := range strings.Split(os.Getenv("FAKE_PAYMENT_METHODS"), ",") {
+		if method = strings.TrimSpace(method); method != "" {
+			fakePaymentMethods = append(fakePaymentMethods, method)
+		}
+	}
+
+	payoutHoldingDays := 14
+	if days := os.Getenv("PAYOUT_HOLDING_DAYS"); days != "" {
+		parsed, err := strconv.Atoi(days)
+		if err != nil || parsed < 0 {
+			return nil, fmt.Errorf("invalid PAYOUT_HOLDING_DAYS %q", days)
+		}
+		payoutHoldingDays = parsed
+	}
+
+	unpaidOrderWindow := 24 * time.Hour
+	if window := os.Getenv("UNPAID_ORDER_WINDOW"); window != "" {
+		parsed, err := time.ParseDuration(window)
+		if err != nil || parsed <= 0 {
+			return nil, fmt.Errorf("invalid UNPAID_ORDER_WINDOW %q", window)
+		}
+		unpaidOrderWindow = parsed
+	}
+
+	jobWorkers := 2
+	if workers := os.Getenv("JOB_WORKERS"); workers != "" {
+		parsed, err := strconv.Atoi(workers)
+		if err != nil || parsed < 0 {
+			return nil, fmt.Errorf("invalid JOB_WORKERS %q", workers)
+		}
+		jobWorkers = parsed
+	}
+
+	// Guest cart tokens are signed with a key of their own, never an empty one
+	cartTokenSecret := os.Getenv("CART_TOKEN_SECRET")
+	if cartTokenSecret == "" {
+		return nil, fmt.Errorf("CART_TOKEN_SECRET is required")
+	}
+
 	return &Config{
 		Host:                os.Getenv("HOST"),
 		Username:            os.Getenv("USERNAME"),
@@ -56,12 +135,24 @@ func (s *Config) LoadEnv() (*Config, error) {
 		MaxOpenConn:         8,
 		MaxIdleConn:         5,
 		ServerPort:          serverPort,
+		APIURL:              apiURL,
 		ClerkSecretKey:      os.Getenv("CLERK_SECRET_KEY"),
 		ClerkPublishableKey: os.Getenv("CLERK_PUBLISHABLE_KEY"),
 		ClerkWebhookSecret:  os.Getenv("CLERK_WEBHOOK_SECRET"),
 		StripeSecretKey:     os.Getenv("STRIPE_SECRET_KEY"),
 		StripeWebhookSecret: os.Getenv("STRIPE_WEBHOOK_SECRET"),
 		FrontendURL:         frontendURL,
+		EsewaProductCode:    esewaProductCode,
+		EsewaSecretKey:      os.Getenv("ESEWA_SECRET_KEY"),
+		EsewaFormURL:        os.Getenv("ESEWA_FORM_URL"),
+		EsewaStatusURL:      os.Getenv("ESEWA_STATUS_URL"),
+		KhaltiSecretKey:     os.Getenv("KHALTI_SECRET_KEY"),
+		KhaltiBaseURL:       os.Getenv("KHALTI_BASE_URL"),
+		FakePaymentMethods:  fakePaymentMethods,
+		CartTokenSecret:     cartTokenSecret,
+		PayoutHoldingDays:   payoutHoldingDays,
+		UnpaidOrderWindow:   unpaidOrderWindow,
+		JobWorkers:          jobWorkers,
 	}, nil
 }
 
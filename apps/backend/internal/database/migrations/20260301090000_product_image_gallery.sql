@@ -0,0 +1,19 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Backfill galleries from the single image_url column
+INSERT INTO product_images (product_id, image_url, display_order, is_primary)
+SELECT p.id, p.image_url, 0, true
+FROM products p
+WHERE p.image_url IS NOT NULL
+  AND NOT EXISTS (SELECT 1 FROM product_images pi WHERE pi.product_id = p.id);
+
+-- A product can have at most one primary image
+CREATE UNIQUE INDEX IF NOT EXISTS idx_product_images_one_primary ON product_images (product_id) WHERE is_primary;
+CREATE INDEX IF NOT EXISTS idx_product_images_display_order ON product_images (product_id, display_order);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_product_images_display_order;
+DROP INDEX IF EXISTS idx_product_images_one_primary;
+-- +goose StatementEnd
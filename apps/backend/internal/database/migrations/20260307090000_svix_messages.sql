@@ -0,0 +1,17 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Svix message IDs of Clerk webhooks already received, used to reject replays.
+-- Rows older than the signature tolerance can be pruned at any time; a
+-- background job does so hourly.
+CREATE TABLE IF NOT EXISTS svix_messages (
+    message_id VARCHAR(255) PRIMARY KEY,
+    received_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_svix_messages_received_at ON svix_messages (received_at);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS svix_messages;
+-- +goose StatementEnd
@@ -0,0 +1,30 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Every Stripe webhook event received, with its processing outcome. The
+-- unique (provider, event_id) pair makes redelivered events a no-op.
+CREATE TABLE IF NOT EXISTS webhook_events (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    provider VARCHAR(20) NOT NULL DEFAULT 'stripe',
+    event_id VARCHAR(255) NOT NULL,
+    event_type VARCHAR(100) NOT NULL,
+    payload JSONB NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'processing' CHECK (status IN ('processing', 'processed', 'failed')),
+    attempts INT NOT NULL DEFAULT 1,
+    last_error TEXT,
+    received_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    processed_at TIMESTAMP WITH TIME ZONE,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(provider, event_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_webhook_events_status ON webhook_events (status, received_at DESC);
+
+CREATE TRIGGER update_webhook_events_updated_at BEFORE UPDATE ON webhook_events
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TRIGGER IF EXISTS update_webhook_events_updated_at ON webhook_events;
+DROP TABLE IF EXISTS webhook_events;
+-- +goose StatementEnd
@@ -0,0 +1,37 @@
+-- +goose Up
+-- +goose StatementBegin
+-- One fulfilment per shop per order. Each vendor advances only their own
+-- fulfilment; the order status is derived from all of them.
+CREATE TABLE IF NOT EXISTS order_fulfilments (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    order_id UUID NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+    shop_id UUID NOT NULL REFERENCES shops(id) ON DELETE RESTRICT,
+    status VARCHAR(50) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'confirmed', 'processing', 'shipped', 'delivered', 'cancelled', 'refunded')),
+    subtotal DECIMAL(10, 2) NOT NULL DEFAULT 0 CHECK (subtotal >= 0),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    confirmed_at TIMESTAMP WITH TIME ZONE,
+    shipped_at TIMESTAMP WITH TIME ZONE,
+    delivered_at TIMESTAMP WITH TIME ZONE,
+    UNIQUE (order_id, shop_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_order_fulfilments_shop_id ON order_fulfilments (shop_id, created_at DESC);
+
+CREATE TRIGGER update_order_fulfilments_updated_at BEFORE UPDATE ON order_fulfilments
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+-- Existing orders get one fulfilment per shop carrying the order's status
+INSERT INTO order_fulfilments (order_id, shop_id, status, subtotal, created_at, confirmed_at, shipped_at, delivered_at)
+SELECT o.id, oi.shop_id, o.status, SUM(oi.subtotal), o.created_at, o.confirmed_at, o.shipped_at, o.delivered_at
+FROM orders o
+INNER JOIN order_items oi ON oi.order_id = o.id
+GROUP BY o.id, oi.shop_id
+ON CONFLICT (order_id, shop_id) DO NOTHING;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TRIGGER IF EXISTS update_order_fulfilments_updated_at ON order_fulfilments;
+DROP TABLE IF EXISTS order_fulfilments;
+-- +goose StatementEnd
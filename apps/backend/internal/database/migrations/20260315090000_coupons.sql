@@ -0,0 +1,53 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Promotion codes. A coupon without shop_id is platform-wide; with shop_id it
+-- only discounts that shop's items.
+CREATE TABLE IF NOT EXISTS coupons (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    code VARCHAR(50) UNIQUE NOT NULL,
+    description TEXT,
+    discount_type VARCHAR(20) NOT NULL CHECK (discount_type IN ('percentage', 'fixed', 'free_shipping')),
+    value DECIMAL(10, 2) NOT NULL DEFAULT 0 CHECK (value >= 0),
+    max_discount DECIMAL(10, 2) CHECK (max_discount >= 0),
+    min_subtotal DECIMAL(10, 2) NOT NULL DEFAULT 0 CHECK (min_subtotal >= 0),
+    shop_id UUID REFERENCES shops(id) ON DELETE CASCADE,
+    usage_limit INT CHECK (usage_limit > 0),
+    per_user_limit INT CHECK (per_user_limit > 0),
+    used_count INT NOT NULL DEFAULT 0 CHECK (used_count >= 0),
+    starts_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    ends_at TIMESTAMP WITH TIME ZONE,
+    is_active BOOLEAN NOT NULL DEFAULT true,
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT percentage_at_most_100 CHECK (discount_type <> 'percentage' OR value <= 100)
+);
+
+CREATE INDEX IF NOT EXISTS idx_coupons_shop_id ON coupons (shop_id);
+
+CREATE TRIGGER update_coupons_updated_at BEFORE UPDATE ON coupons
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+-- One row per order that used a coupon; counts towards per-user limits
+CREATE TABLE IF NOT EXISTS coupon_redemptions (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    coupon_id UUID NOT NULL REFERENCES coupons(id) ON DELETE CASCADE,
+    order_id UUID NOT NULL UNIQUE REFERENCES orders(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    amount DECIMAL(10, 2) NOT NULL CHECK (amount >= 0),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_coupon_redemptions_coupon_user ON coupon_redemptions (coupon_id, user_id);
+
+-- The code applied to a cart, previewed until checkout redeems it
+ALTER TABLE carts ADD COLUMN IF NOT EXISTS coupon_code VARCHAR(50);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE carts DROP COLUMN IF EXISTS coupon_code;
+DROP TABLE IF EXISTS coupon_redemptions;
+DROP TRIGGER IF EXISTS update_coupons_updated_at ON coupons;
+DROP TABLE IF EXISTS coupons;
+-- +goose StatementEnd
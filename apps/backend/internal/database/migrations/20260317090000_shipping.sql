@@ -0,0 +1,59 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Destination areas shipping is priced for. Empty states/cities cover the
+-- whole country; the most specific matching zone wins.
+CREATE TABLE IF NOT EXISTS shipping_zones (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    name VARCHAR(100) NOT NULL,
+    country VARCHAR(100) NOT NULL,
+    states TEXT[] NOT NULL DEFAULT '{}',
+    cities TEXT[] NOT NULL DEFAULT '{}',
+    is_active BOOLEAN NOT NULL DEFAULT true,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TRIGGER update_shipping_zones_updated_at BEFORE UPDATE ON shipping_zones
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+-- How each shop ships to a zone: a flat rate, or a base rate plus a rate per kg
+CREATE TABLE IF NOT EXISTS shipping_methods (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    shop_id UUID NOT NULL REFERENCES shops(id) ON DELETE CASCADE,
+    zone_id UUID NOT NULL REFERENCES shipping_zones(id) ON DELETE CASCADE,
+    name VARCHAR(100) NOT NULL,
+    rate_type VARCHAR(20) NOT NULL CHECK (rate_type IN ('flat', 'weight')),
+    base_rate DECIMAL(10, 2) NOT NULL DEFAULT 0 CHECK (base_rate >= 0),
+    per_kg_rate DECIMAL(10, 2) NOT NULL DEFAULT 0 CHECK (per_kg_rate >= 0),
+    free_above DECIMAL(10, 2) CHECK (free_above >= 0),
+    min_days INT CHECK (min_days >= 0),
+    max_days INT CHECK (max_days >= 0),
+    is_active BOOLEAN NOT NULL DEFAULT true,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_shipping_methods_shop_id ON shipping_methods (shop_id);
+
+CREATE TRIGGER update_shipping_methods_updated_at BEFORE UPDATE ON shipping_methods
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+-- Each shop ships its part of an order with the method chosen at checkout.
+-- The name and cost are copied so later rate changes leave past orders alone.
+ALTER TABLE order_fulfilments
+    ADD COLUMN IF NOT EXISTS shipping_method_id UUID REFERENCES shipping_methods(id) ON DELETE SET NULL,
+    ADD COLUMN IF NOT EXISTS shipping_method_name VARCHAR(100),
+    ADD COLUMN IF NOT EXISTS shipping_cost DECIMAL(10, 2) NOT NULL DEFAULT 0;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE order_fulfilments
+    DROP COLUMN IF EXISTS shipping_cost,
+    DROP COLUMN IF EXISTS shipping_method_name,
+    DROP COLUMN IF EXISTS shipping_method_id;
+DROP TRIGGER IF EXISTS update_shipping_methods_updated_at ON shipping_methods;
+DROP TABLE IF EXISTS shipping_methods;
+DROP TRIGGER IF EXISTS update_shipping_zones_updated_at ON shipping_zones;
+DROP TABLE IF EXISTS shipping_zones;
+-- +goose StatementEnd
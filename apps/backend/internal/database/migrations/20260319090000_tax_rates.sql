@@ -0,0 +1,45 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Tax rules by region and, optionally, product category. The most specific
+-- matching rule taxes a line: a category rule beats a general one, a state
+-- rule beats a country-wide one. Inclusive rates are already part of prices.
+CREATE TABLE IF NOT EXISTS tax_rates (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    name VARCHAR(100) NOT NULL,
+    country VARCHAR(100) NOT NULL,
+    state VARCHAR(100),
+    category_id UUID REFERENCES categories(id) ON DELETE CASCADE,
+    rate DECIMAL(6, 3) NOT NULL CHECK (rate >= 0 AND rate <= 100),
+    inclusive BOOLEAN NOT NULL DEFAULT false,
+    is_active BOOLEAN NOT NULL DEFAULT true,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TRIGGER update_tax_rates_updated_at BEFORE UPDATE ON tax_rates
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+-- Nepal's VAT, included in listed prices
+INSERT INTO tax_rates (name, country, rate, inclusive) VALUES ('VAT', 'Nepal', 13, true);
+
+-- The tax charged on each line, kept with the order for invoices and refunds.
+-- taxable_amount is the line net of discount and of inclusive tax.
+ALTER TABLE order_items
+    ADD COLUMN IF NOT EXISTS tax_name VARCHAR(100),
+    ADD COLUMN IF NOT EXISTS tax_rate DECIMAL(6, 3) NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS taxable_amount DECIMAL(10, 2) NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS tax_amount DECIMAL(10, 2) NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS tax_inclusive BOOLEAN NOT NULL DEFAULT false;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE order_items
+    DROP COLUMN IF EXISTS tax_inclusive,
+    DROP COLUMN IF EXISTS tax_amount,
+    DROP COLUMN IF EXISTS taxable_amount,
+    DROP COLUMN IF EXISTS tax_rate,
+    DROP COLUMN IF EXISTS tax_name;
+DROP TRIGGER IF EXISTS update_tax_rates_updated_at ON tax_rates;
+DROP TABLE IF EXISTS tax_rates;
+-- +goose StatementEnd
@@ -0,0 +1,27 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Shops price their products, shipping and coupons in their own currency
+ALTER TABLE shops ADD COLUMN IF NOT EXISTS currency VARCHAR(3) NOT NULL DEFAULT 'NPR';
+
+-- How many units of each currency one NPR buys. NPR itself is implicitly 1.
+CREATE TABLE IF NOT EXISTS exchange_rates (
+    currency VARCHAR(3) PRIMARY KEY,
+    rate DECIMAL(18, 8) NOT NULL CHECK (rate > 0),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TRIGGER update_exchange_rates_updated_at BEFORE UPDATE ON exchange_rates
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+-- Every amount of an order is in the currency it was checked out in
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS currency VARCHAR(3) NOT NULL DEFAULT 'NPR';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE orders DROP COLUMN IF EXISTS currency;
+DROP TRIGGER IF EXISTS update_exchange_rates_updated_at ON exchange_rates;
+DROP TABLE IF EXISTS exchange_rates;
+ALTER TABLE shops DROP COLUMN IF EXISTS currency;
+-- +goose StatementEnd
@@ -0,0 +1,16 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Slugs a product used to have, so old links redirect to its current slug
+CREATE TABLE IF NOT EXISTS product_slug_redirects (
+    old_slug VARCHAR(255) PRIMARY KEY,
+    product_id UUID NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_product_slug_redirects_product_id ON product_slug_redirects(product_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS product_slug_redirects;
+-- +goose StatementEnd
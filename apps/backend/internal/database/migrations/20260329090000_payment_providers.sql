@@ -0,0 +1,22 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Payment columns are no longer Stripe-specific: the reference is the
+-- gateway's payment (a Stripe session, eSewa transaction UUID or Khalti pidx)
+-- and the transaction ID the completed payment refunds go against.
+ALTER TABLE orders RENAME COLUMN stripe_session_id TO payment_reference;
+ALTER TABLE orders RENAME COLUMN stripe_payment_intent_id TO payment_transaction_id;
+ALTER INDEX idx_orders_stripe_session_id RENAME TO idx_orders_payment_reference;
+ALTER INDEX idx_orders_stripe_payment_intent_id RENAME TO idx_orders_payment_transaction_id;
+
+ALTER TABLE refunds RENAME COLUMN stripe_refund_id TO provider_refund_id;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE refunds RENAME COLUMN provider_refund_id TO stripe_refund_id;
+
+ALTER INDEX idx_orders_payment_transaction_id RENAME TO idx_orders_stripe_payment_intent_id;
+ALTER INDEX idx_orders_payment_reference RENAME TO idx_orders_stripe_session_id;
+ALTER TABLE orders RENAME COLUMN payment_transaction_id TO stripe_payment_intent_id;
+ALTER TABLE orders RENAME COLUMN payment_reference TO stripe_session_id;
+-- +goose StatementEnd
@@ -0,0 +1,95 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Commission the platform keeps on each sale, as a percentage of the line net
+-- of discount and tax. A shop's override beats a rate for the product's
+-- category (or its nearest parent category), which beats the platform default:
+-- the single row with neither a shop nor a category.
+CREATE TABLE IF NOT EXISTS commission_rates (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    shop_id UUID UNIQUE REFERENCES shops(id) ON DELETE CASCADE,
+    category_id UUID UNIQUE REFERENCES categories(id) ON DELETE CASCADE,
+    rate DECIMAL(6, 3) NOT NULL CHECK (rate >= 0 AND rate <= 100),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    CHECK (shop_id IS NULL OR category_id IS NULL)
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_commission_rates_default ON commission_rates ((true))
+    WHERE shop_id IS NULL AND category_id IS NULL;
+
+CREATE TRIGGER update_commission_rates_updated_at BEFORE UPDATE ON commission_rates
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+INSERT INTO commission_rates (rate) VALUES (10);
+
+-- Payouts settle shops' available balances. A batch pays every shop owed
+-- money at the time; each payout is then marked paid or failed.
+CREATE TABLE IF NOT EXISTS payout_batches (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS payouts (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    batch_id UUID NOT NULL REFERENCES payout_batches(id) ON DELETE RESTRICT,
+    shop_id UUID NOT NULL REFERENCES shops(id) ON DELETE RESTRICT,
+    amount DECIMAL(12, 2) NOT NULL CHECK (amount > 0),
+    currency VARCHAR(3) NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'paid', 'failed')),
+    failure_reason TEXT,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    paid_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX IF NOT EXISTS idx_payouts_batch_id ON payouts (batch_id);
+CREATE INDEX IF NOT EXISTS idx_payouts_shop_id ON payouts (shop_id);
+
+CREATE TRIGGER update_payouts_updated_at BEFORE UPDATE ON payouts
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+-- Double-entry ledger of the money the platform holds for shops. The entries
+-- of one transaction sum to zero across the accounts: 'shop' (owed to a shop),
+-- 'platform' (commission earned) and 'gateway' (money held at the payment
+-- gateways and the bank). Amounts are credits, so a shop's balance is the sum
+-- of its entries. Entries count towards the available balance from
+-- available_at, which holds sales back for the holding period.
+CREATE TABLE IF NOT EXISTS ledger_entries (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    transaction_id UUID NOT NULL,
+    account VARCHAR(20) NOT NULL CHECK (account IN ('shop', 'platform', 'gateway')),
+    shop_id UUID REFERENCES shops(id) ON DELETE RESTRICT,
+    entry_type VARCHAR(20) NOT NULL CHECK (entry_type IN ('sale', 'commission', 'refund', 'payout')),
+    amount DECIMAL(12, 2) NOT NULL,
+    currency VARCHAR(3) NOT NULL,
+    order_id UUID REFERENCES orders(id) ON DELETE RESTRICT,
+    order_item_id UUID REFERENCES order_items(id) ON DELETE RESTRICT,
+    refund_id UUID REFERENCES refunds(id) ON DELETE RESTRICT,
+    payout_id UUID REFERENCES payouts(id) ON DELETE RESTRICT,
+    available_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    CHECK ((account = 'shop') = (shop_id IS NOT NULL))
+);
+
+CREATE INDEX IF NOT EXISTS idx_ledger_entries_shop ON ledger_entries (shop_id, currency, available_at) WHERE shop_id IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_ledger_entries_transaction_id ON ledger_entries (transaction_id);
+CREATE INDEX IF NOT EXISTS idx_ledger_entries_order_id ON ledger_entries (order_id) WHERE order_id IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_ledger_entries_refund_id ON ledger_entries (refund_id) WHERE refund_id IS NOT NULL;
+
+-- An order's sale and a refund are each posted once
+CREATE UNIQUE INDEX IF NOT EXISTS idx_ledger_entries_sale ON ledger_entries (order_id, shop_id)
+    WHERE entry_type = 'sale' AND account = 'shop';
+CREATE UNIQUE INDEX IF NOT EXISTS idx_ledger_entries_refund ON ledger_entries (refund_id, shop_id)
+    WHERE entry_type = 'refund' AND account = 'shop';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS ledger_entries;
+DROP TRIGGER IF EXISTS update_payouts_updated_at ON payouts;
+DROP TABLE IF EXISTS payouts;
+DROP TABLE IF EXISTS payout_batches;
+DROP TRIGGER IF EXISTS update_commission_rates_updated_at ON commission_rates;
+DROP TABLE IF EXISTS commission_rates;
+-- +goose StatementEnd
@@ -0,0 +1,15 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE orders ADD COLUMN cancelled_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE orders ADD COLUMN cancellation_reason TEXT;
+
+-- Unpaid orders are looked up by age to cancel the abandoned ones
+CREATE INDEX idx_orders_unpaid ON orders(created_at) WHERE status = 'pending';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_orders_unpaid;
+ALTER TABLE orders DROP COLUMN IF EXISTS cancellation_reason;
+ALTER TABLE orders DROP COLUMN IF EXISTS cancelled_at;
+-- +goose StatementEnd
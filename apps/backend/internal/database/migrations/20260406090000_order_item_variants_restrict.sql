@@ -0,0 +1,15 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Cancellations and refunds restock the variant an order line names, so a
+-- variant on an order is deactivated rather than deleted
+ALTER TABLE order_items DROP CONSTRAINT IF EXISTS order_items_variant_id_fkey;
+ALTER TABLE order_items ADD CONSTRAINT order_items_variant_id_fkey
+    FOREIGN KEY (variant_id) REFERENCES product_variants(id) ON DELETE RESTRICT;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE order_items DROP CONSTRAINT IF EXISTS order_items_variant_id_fkey;
+ALTER TABLE order_items ADD CONSTRAINT order_items_variant_id_fkey
+    FOREIGN KEY (variant_id) REFERENCES product_variants(id) ON DELETE SET NULL;
+-- +goose StatementEnd
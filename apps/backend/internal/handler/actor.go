@@ -0,0 +1,18 @@
+package handler
+
+import (
+	"errors"
+
+	"github.com/imbivek08/hamropasal/internal/model"
+	"github.com/imbivek08/hamropasal/internal/service"
+	"github.com/labstack/echo/v4"
+)
+
+// currentActor returns the policy actor for the user loaded by LoadUserMiddleware
+func currentActor(c echo.Context, userService *service.UserService) (*service.Actor, error) {
+	user, ok := c.Get("user").(*model.User)
+	if !ok || user == nil {
+		return nil, errors.New("user not found in context")
+	}
+	return userService.ActorFor(c.Request().Context(), user), nil
+}
@@ -1,6 +1,7 @@
 package handler
 
 import (
+	"errors"
 	"net/http"
 
 	"github.com/google/uuid"
@@ -10,6 +11,9 @@ import (
 	"github.com/labstack/echo/v4"
 )
 
+// cartTokenHeader carries the signed guest cart token for anonymous shoppers
+const cartTokenHeader = "X-Cart-Token"
+
 type CartHandler struct {
 	cartService *service.CartService
 	userService *service.UserService
@@ -24,21 +28,24 @@ func NewCartHandler(cartService *service.CartService, userService *service.UserS
 
 // GetCart retrieves user's cart with all items
 func (h *CartHandler) GetCart(c echo.Context) error {
-	// Get Clerk user ID from middleware
-	clerkUserID := middleware.GetClerkUserID(c)
-	if clerkUserID == "" {
-		return SendError(c, http.StatusUnauthorized, nil, "user not authenticated")
+	owner, err := h.cartOwner(c)
+	if err != nil {
+		return sendCartOwnerError(c, err)
 	}
 
-	// Get internal user
-	user, err := h.userService.GetUserByClerkID(c.Request().Context(), clerkUserID)
-	if err != nil {
-		return SendError(c, http.StatusNotFound, err, "user not found")
+	// Anonymous shoppers without a cart token have an empty cart
+	if owner == nil {
+		return SendSuccess(c, http.StatusOK, "cart retrieved successfully", &model.CartResponse{
+			Items: []model.CartItemWithProduct{},
+		})
 	}
 
-	// Get cart
-	cart, err := h.cartService.GetCart(c.Request().Context(), user.ID)
+	// Get cart, priced in the requested currency
+	cart, err := h.cartService.GetCart(c.Request().Context(), *owner, c.QueryParam("currency"))
 	if err != nil {
+		if errors.Is(err, model.ErrUnsupportedCurrency) {
+			return SendError(c, http.StatusBadRequest, err, "")
+		}
 		return SendError(c, http.StatusInternalServerError, err, "failed to get cart")
 	}
 
@@ -47,16 +54,9 @@ func (h *CartHandler) GetCart(c echo.Context) error {
 
 // AddToCart adds a product to cart
 func (h *CartHandler) AddToCart(c echo.Context) error {
-	// Get Clerk user ID from middleware
-	clerkUserID := middleware.GetClerkUserID(c)
-	if clerkUserID == "" {
-		return SendError(c, http.StatusUnauthorized, nil, "user not authenticated")
-	}
-
-	// Get internal user
-	user, err := h.userService.GetUserByClerkID(c.Request().Context(), clerkUserID)
+	owner, err := h.cartOwner(c)
 	if err != nil {
-		return SendError(c, http.StatusNotFound, err, "user not found")
+		return sendCartOwnerError(c, err)
 	}
 
 	// Parse request
@@ -70,8 +70,16 @@ func (h *CartHandler) AddToCart(c echo.Context) error {
 		return SendError(c, http.StatusBadRequest, err, "validation failed")
 	}
 
+	// Start a guest cart for anonymous shoppers; the client keeps the token
+	if owner == nil {
+		sessionID, token := h.cartService.NewGuestCartToken()
+		guest := model.GuestCartOwner(sessionID)
+		owner = &guest
+		c.Response().Header().Set(cartTokenHeader, token)
+	}
+
 	// Add to cart
-	item, err := h.cartService.AddToCart(c.Request().Context(), user.ID, &req)
+	item, err := h.cartService.AddToCart(c.Request().Context(), *owner, &req)
 	if err != nil {
 		return SendError(c, http.StatusBadRequest, err, err.Error())
 	}
@@ -81,16 +89,12 @@ func (h *CartHandler) AddToCart(c echo.Context) error {
 
 // UpdateCartItem updates cart item quantity
 func (h *CartHandler) UpdateCartItem(c echo.Context) error {
-	// Get Clerk user ID from middleware
-	clerkUserID := middleware.GetClerkUserID(c)
-	if clerkUserID == "" {
-		return SendError(c, http.StatusUnauthorized, nil, "user not authenticated")
-	}
-
-	// Get internal user
-	user, err := h.userService.GetUserByClerkID(c.Request().Context(), clerkUserID)
+	owner, err := h.cartOwner(c)
 	if err != nil {
-		return SendError(c, http.StatusNotFound, err, "user not found")
+		return sendCartOwnerError(c, err)
+	}
+	if owner == nil {
+		return SendError(c, http.StatusNotFound, nil, "cart item not found")
 	}
 
 	// Parse item ID
@@ -111,7 +115,7 @@ func (h *CartHandler) UpdateCartItem(c echo.Context) error {
 	}
 
 	// Update item quantity
-	if err := h.cartService.UpdateCartItemQuantity(c.Request().Context(), user.ID, itemID, req.Quantity); err != nil {
+	if err := h.cartService.UpdateCartItemQuantity(c.Request().Context(), *owner, itemID, req.Quantity); err != nil {
 		return SendError(c, http.StatusBadRequest, err, err.Error())
 	}
 
@@ -120,16 +124,12 @@ func (h *CartHandler) UpdateCartItem(c echo.Context) error {
 
 // RemoveCartItem removes an item from cart
 func (h *CartHandler) RemoveCartItem(c echo.Context) error {
-	// Get Clerk user ID from middleware
-	clerkUserID := middleware.GetClerkUserID(c)
-	if clerkUserID == "" {
-		return SendError(c, http.StatusUnauthorized, nil, "user not authenticated")
-	}
-
-	// Get internal user
-	user, err := h.userService.GetUserByClerkID(c.Request().Context(), clerkUserID)
+	owner, err := h.cartOwner(c)
 	if err != nil {
-		return SendError(c, http.StatusNotFound, err, "user not found")
+		return sendCartOwnerError(c, err)
+	}
+	if owner == nil {
+		return SendError(c, http.StatusNotFound, nil, "cart item not found")
 	}
 
 	// Parse item ID
@@ -139,7 +139,7 @@ func (h *CartHandler) RemoveCartItem(c echo.Context) error {
 	}
 
 	// Remove item
-	if err := h.cartService.RemoveCartItem(c.Request().Context(), user.ID, itemID); err != nil {
+	if err := h.cartService.RemoveCartItem(c.Request().Context(), *owner, itemID); err != nil {
 		return SendError(c, http.StatusBadRequest, err, err.Error())
 	}
 
@@ -148,20 +148,16 @@ func (h *CartHandler) RemoveCartItem(c echo.Context) error {
 
 // ClearCart removes all items from cart
 func (h *CartHandler) ClearCart(c echo.Context) error {
-	// Get Clerk user ID from middleware
-	clerkUserID := middleware.GetClerkUserID(c)
-	if clerkUserID == "" {
-		return SendError(c, http.StatusUnauthorized, nil, "user not authenticated")
-	}
-
-	// Get internal user
-	user, err := h.userService.GetUserByClerkID(c.Request().Context(), clerkUserID)
+	owner, err := h.cartOwner(c)
 	if err != nil {
-		return SendError(c, http.StatusNotFound, err, "user not found")
+		return sendCartOwnerError(c, err)
+	}
+	if owner == nil {
+		return SendError(c, http.StatusNotFound, nil, "cart not found")
 	}
 
 	// Clear cart
-	if err := h.cartService.ClearCart(c.Request().Context(), user.ID); err != nil {
+	if err := h.cartService.ClearCart(c.Request().Context(), *owner); err != nil {
 		return SendError(c, http.StatusInternalServerError, err, "failed to clear cart")
 	}
 
@@ -170,23 +166,165 @@ func (h *CartHandler) ClearCart(c echo.Context) error {
 
 // GetCartItemCount returns the total number of items in cart
 func (h *CartHandler) GetCartItemCount(c echo.Context) error {
-	// Get Clerk user ID from middleware
-	clerkUserID := middleware.GetClerkUserID(c)
-	if clerkUserID == "" {
-		return SendError(c, http.StatusUnauthorized, nil, "user not authenticated")
-	}
-
-	// Get internal user
-	user, err := h.userService.GetUserByClerkID(c.Request().Context(), clerkUserID)
+	owner, err := h.cartOwner(c)
 	if err != nil {
-		return SendError(c, http.StatusNotFound, err, "user not found")
+		return sendCartOwnerError(c, err)
+	}
+	if owner == nil {
+		return SendSuccess(c, http.StatusOK, "cart item count retrieved successfully", map[string]int{"count": 0})
 	}
 
 	// Get item count
-	count, err := h.cartService.GetCartItemCount(c.Request().Context(), user.ID)
+	count, err := h.cartService.GetCartItemCount(c.Request().Context(), *owner)
 	if err != nil {
 		return SendError(c, http.StatusInternalServerError, err, "failed to get cart item count")
 	}
 
 	return SendSuccess(c, http.StatusOK, "cart item count retrieved successfully", map[string]int{"count": count})
 }
+
+// ApplyCoupon applies a promotion code to the cart and returns the cart with
+// the discount previewed
+// POST /api/v1/cart/coupon
+func (h *CartHandler) ApplyCoupon(c echo.Context) error {
+	owner, err := h.cartOwner(c)
+	if err != nil {
+		return sendCartOwnerError(c, err)
+	}
+	if owner == nil {
+		return SendError(c, http.StatusNotFound, nil, "cart not found")
+	}
+
+	var req model.ApplyCouponRequest
+	if err := c.Bind(&req); err != nil {
+		return SendError(c, http.StatusBadRequest, err, "invalid request body")
+	}
+
+	if err := c.Validate(&req); err != nil {
+		return SendError(c, http.StatusBadRequest, err, "validation failed")
+	}
+
+	cart, err := h.cartService.ApplyCoupon(c.Request().Context(), *owner, req.Code)
+	if err != nil {
+		if err.Error() == "coupon not found" {
+			return SendError(c, http.StatusNotFound, err, err.Error())
+		}
+		return SendError(c, http.StatusBadRequest, err, err.Error())
+	}
+
+	return SendSuccess(c, http.StatusOK, "coupon applied successfully", cart)
+}
+
+// RemoveCoupon removes the promotion code from the cart
+// DELETE /api/v1/cart/coupon
+func (h *CartHandler) RemoveCoupon(c echo.Context) error {
+	owner, err := h.cartOwner(c)
+	if err != nil {
+		return sendCartOwnerError(c, err)
+	}
+	if owner == nil {
+		return SendError(c, http.StatusNotFound, nil, "cart not found")
+	}
+
+	cart, err := h.cartService.RemoveCoupon(c.Request().Context(), *owner)
+	if err != nil {
+		return SendError(c, http.StatusInternalServerError, err, "failed to remove coupon")
+	}
+
+	return SendSuccess(c, http.StatusOK, "coupon removed successfully", cart)
+}
+
+// QuoteShipping lists each shop's shipping options and costs for the cart to
+// a saved address or a city and country
+// POST /api/v1/cart/shipping-quote
+func (h *CartHandler) QuoteShipping(c echo.Context) error {
+	owner, err := h.cartOwner(c)
+	if err != nil {
+		return sendCartOwnerError(c, err)
+	}
+	if owner == nil {
+		return SendError(c, http.StatusNotFound, nil, "cart not found")
+	}
+
+	var req model.ShippingQuoteRequest
+	if err := c.Bind(&req); err != nil {
+		return SendError(c, http.StatusBadRequest, err, "invalid request body")
+	}
+
+	quote, err := h.cartService.QuoteShipping(c.Request().Context(), *owner, &req)
+	if err != nil {
+		if errors.Is(err, model.ErrUnsupportedCurrency) {
+			return SendError(c, http.StatusBadRequest, err, "")
+		}
+		switch err.Error() {
+		case "shipping address not found":
+			return SendError(c, http.StatusNotFound, err, err.Error())
+		case "provide shipping_address_id or city and country":
+			return SendError(c, http.StatusBadRequest, err, err.Error())
+		}
+		return SendError(c, http.StatusInternalServerError, err, "failed to quote shipping")
+	}
+
+	return SendSuccess(c, http.StatusOK, "shipping quote retrieved successfully", quote)
+}
+
+// MergeGuestCart folds the guest cart named by X-Cart-Token into the signed-in
+// user's cart. Clients call it right after sign-in and then drop the token.
+// POST /api/v1/cart/merge
+func (h *CartHandler) MergeGuestCart(c echo.Context) error {
+	user, ok := c.Get("user").(*model.User)
+	if !ok || user == nil {
+		return SendError(c, http.StatusUnauthorized, nil, "user not authenticated")
+	}
+
+	token := c.Request().Header.Get(cartTokenHeader)
+	if token == "" {
+		return SendError(c, http.StatusBadRequest, nil, "cart token required")
+	}
+
+	sessionID, err := h.cartService.VerifyGuestCartToken(token)
+	if err != nil {
+		return sendCartOwnerError(c, err)
+	}
+
+	cart, err := h.cartService.MergeGuestCart(c.Request().Context(), user.ID, sessionID)
+	if err != nil {
+		return SendError(c, http.StatusInternalServerError, err, "failed to merge cart")
+	}
+
+	return SendSuccess(c, http.StatusOK, "cart merged successfully", cart)
+}
+
+// cartOwner identifies the cart for the request: the signed-in user's cart or,
+// for anonymous requests, the guest cart named by the X-Cart-Token header.
+// It returns a nil owner for anonymous requests that have no cart yet.
+func (h *CartHandler) cartOwner(c echo.Context) (*model.CartOwner, error) {
+	if clerkUserID := middleware.GetClerkUserID(c); clerkUserID != "" {
+		user, err := h.userService.GetUserByClerkID(c.Request().Context(), clerkUserID)
+		if err != nil {
+			return nil, err
+		}
+		owner := model.UserCartOwner(user.ID)
+		return &owner, nil
+	}
+
+	token := c.Request().Header.Get(cartTokenHeader)
+	if token == "" {
+		return nil, nil
+	}
+
+	sessionID, err := h.cartService.VerifyGuestCartToken(token)
+	if err != nil {
+		return nil, err
+	}
+	owner := model.GuestCartOwner(sessionID)
+	return &owner, nil
+}
+
+// sendCartOwnerError maps cartOwner errors to the matching HTTP status
+func sendCartOwnerError(c echo.Context, err error) error {
+	if errors.Is(err, service.ErrInvalidCartToken) {
+		return SendError(c, http.StatusUnauthorized, err, service.ErrInvalidCartToken.Error())
+	}
+	return SendError(c, http.StatusNotFound, err, "user not found")
+}
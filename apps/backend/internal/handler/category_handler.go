@@ -0,0 +1,166 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+
+	"github.com/imbivek08/hamropasal/internal/model"
+	"github.com/imbivek08/hamropasal/internal/service"
+)
+
+type CategoryHandler struct {
+	categoryService *service.CategoryService
+}
+
+func NewCategoryHandler(categoryService *service.CategoryService) *CategoryHandler {
+	return &CategoryHandler{
+		categoryService: categoryService,
+	}
+}
+
+// ListCategories retrieves all categories as a flat list
+// GET /api/v1/categories
+func (h *CategoryHandler) ListCategories(c echo.Context) error {
+	// Inactive categories are only listed when explicitly asked for
+	activeOnly := c.QueryParam("include_inactive") != "true"
+
+	categories, err := h.categoryService.ListCategories(c.Request().Context(), activeOnly)
+	if err != nil {
+		return SendError(c, http.StatusInternalServerError, err, "failed to list categories")
+	}
+
+	responses := []*model.CategoryResponse{}
+	for _, category := range categories {
+		responses = append(responses, category.ToResponse())
+	}
+
+	return SendSuccess(c, http.StatusOK, "categories retrieved successfully", responses)
+}
+
+// GetCategoryTree retrieves active categories with nested children
+// GET /api/v1/categories/tree
+func (h *CategoryHandler) GetCategoryTree(c echo.Context) error {
+	tree, err := h.categoryService.GetCategoryTree(c.Request().Context())
+	if err != nil {
+		return SendError(c, http.StatusInternalServerError, err, "failed to get category tree")
+	}
+
+	return SendSuccess(c, http.StatusOK, "category tree retrieved successfully", tree)
+}
+
+// GetCategoryByID retrieves a category by ID
+// GET /api/v1/categories/:id
+func (h *CategoryHandler) GetCategoryByID(c echo.Context) error {
+	categoryID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return SendError(c, http.StatusBadRequest, err, "invalid category ID")
+	}
+
+	category, err := h.categoryService.GetCategoryByID(c.Request().Context(), categoryID)
+	if err != nil {
+		if err.Error() == "category not found" {
+			return SendError(c, http.StatusNotFound, err, "")
+		}
+		return SendError(c, http.StatusInternalServerError, err, "failed to get category")
+	}
+
+	return SendSuccess(c, http.StatusOK, "category retrieved successfully", category.ToResponse())
+}
+
+// GetCategoryBySlug retrieves a category by slug
+// GET /api/v1/categories/slug/:slug
+func (h *CategoryHandler) GetCategoryBySlug(c echo.Context) error {
+	slug := c.Param("slug")
+	if slug == "" {
+		return SendError(c, http.StatusBadRequest, nil, "slug is required")
+	}
+
+	category, err := h.categoryService.GetCategoryBySlug(c.Request().Context(), slug)
+	if err != nil {
+		if err.Error() == "category not found" {
+			return SendError(c, http.StatusNotFound, err, "")
+		}
+		return SendError(c, http.StatusInternalServerError, err, "failed to get category")
+	}
+
+	return SendSuccess(c, http.StatusOK, "category retrieved successfully", category.ToResponse())
+}
+
+// CreateCategory creates a new category (admin only)
+// POST /api/v1/categories
+func (h *CategoryHandler) CreateCategory(c echo.Context) error {
+	var req model.CreateCategoryRequest
+	if err := c.Bind(&req); err != nil {
+		return SendError(c, http.StatusBadRequest, err, "invalid request body")
+	}
+
+	if err := c.Validate(&req); err != nil {
+		return SendError(c, http.StatusBadRequest, err, "validation failed")
+	}
+
+	category, err := h.categoryService.CreateCategory(c.Request().Context(), &req)
+	if err != nil {
+		if err.Error() == "parent category not found" {
+			return SendError(c, http.StatusBadRequest, err, "")
+		}
+		return SendError(c, http.StatusInternalServerError, err, "failed to create category")
+	}
+
+	return SendSuccess(c, http.StatusCreated, "category created successfully", category.ToResponse())
+}
+
+// UpdateCategory updates a category (admin only)
+// PUT /api/v1/categories/:id
+func (h *CategoryHandler) UpdateCategory(c echo.Context) error {
+	categoryID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return SendError(c, http.StatusBadRequest, err, "invalid category ID")
+	}
+
+	var req model.UpdateCategoryRequest
+	if err := c.Bind(&req); err != nil {
+		return SendError(c, http.StatusBadRequest, err, "invalid request body")
+	}
+
+	if err := c.Validate(&req); err != nil {
+		return SendError(c, http.StatusBadRequest, err, "validation failed")
+	}
+
+	category, err := h.categoryService.UpdateCategory(c.Request().Context(), categoryID, &req)
+	if err != nil {
+		switch err.Error() {
+		case "category not found":
+			return SendError(c, http.StatusNotFound, err, "")
+		case "parent category not found",
+			"category cannot be its own parent",
+			"category cannot be moved under its own subcategory":
+			return SendError(c, http.StatusBadRequest, err, "")
+		}
+		return SendError(c, http.StatusInternalServerError, err, "failed to update category")
+	}
+
+	return SendSuccess(c, http.StatusOK, "category updated successfully", category.ToResponse())
+}
+
+// DeleteCategory deletes a category (admin only)
+// DELETE /api/v1/categories/:id
+func (h *CategoryHandler) DeleteCategory(c echo.Context) error {
+	categoryID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return SendError(c, http.StatusBadRequest, err, "invalid category ID")
+	}
+
+	if err := h.categoryService.DeleteCategory(c.Request().Context(), categoryID); err != nil {
+		switch err.Error() {
+		case "category not found":
+			return SendError(c, http.StatusNotFound, err, "")
+		case "category has subcategories":
+			return SendError(c, http.StatusConflict, err, "")
+		}
+		return SendError(c, http.StatusInternalServerError, err, "failed to delete category")
+	}
+
+	return SendSuccess(c, http.StatusOK, "category deleted successfully", nil)
+}
@@ -0,0 +1,130 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/imbivek08/hamropasal/internal/model"
+	"github.com/imbivek08/hamropasal/internal/service"
+	"github.com/labstack/echo/v4"
+)
+
+type CouponHandler struct {
+	couponService *service.CouponService
+	userService   *service.UserService
+}
+
+func NewCouponHandler(couponService *service.CouponService, userService *service.UserService) *CouponHandler {
+	return &CouponHandler{
+		couponService: couponService,
+		userService:   userService,
+	}
+}
+
+// CreateCoupon creates a coupon for the vendor's shop, or any coupon for admins
+// POST /api/v1/coupons
+func (h *CouponHandler) CreateCoupon(c echo.Context) error {
+	actor, err := currentActor(c, h.userService)
+	if err != nil {
+		return SendError(c, http.StatusUnauthorized, err, "user not authenticated")
+	}
+
+	var req model.CreateCouponRequest
+	if err := c.Bind(&req); err != nil {
+		return SendError(c, http.StatusBadRequest, err, "invalid request body")
+	}
+
+	if err := c.Validate(&req); err != nil {
+		return SendError(c, http.StatusBadRequest, err, "validation failed")
+	}
+
+	coupon, err := h.couponService.CreateCoupon(c.Request().Context(), actor, &req)
+	if err != nil {
+		return sendCouponError(c, err, "failed to create coupon")
+	}
+
+	return SendSuccess(c, http.StatusCreated, "coupon created successfully", coupon)
+}
+
+// ListCoupons lists the coupons the caller manages
+// GET /api/v1/coupons
+func (h *CouponHandler) ListCoupons(c echo.Context) error {
+	actor, err := currentActor(c, h.userService)
+	if err != nil {
+		return SendError(c, http.StatusUnauthorized, err, "user not authenticated")
+	}
+
+	coupons, err := h.couponService.ListCoupons(c.Request().Context(), actor)
+	if err != nil {
+		return SendError(c, http.StatusInternalServerError, err, "failed to get coupons")
+	}
+
+	return SendSuccess(c, http.StatusOK, "coupons retrieved successfully", coupons)
+}
+
+// UpdateCoupon changes a coupon's terms
+// PUT /api/v1/coupons/:id
+func (h *CouponHandler) UpdateCoupon(c echo.Context) error {
+	actor, err := currentActor(c, h.userService)
+	if err != nil {
+		return SendError(c, http.StatusUnauthorized, err, "user not authenticated")
+	}
+
+	couponID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return SendError(c, http.StatusBadRequest, err, "invalid coupon ID")
+	}
+
+	var req model.UpdateCouponRequest
+	if err := c.Bind(&req); err != nil {
+		return SendError(c, http.StatusBadRequest, err, "invalid request body")
+	}
+
+	if err := c.Validate(&req); err != nil {
+		return SendError(c, http.StatusBadRequest, err, "validation failed")
+	}
+
+	coupon, err := h.couponService.UpdateCoupon(c.Request().Context(), actor, couponID, &req)
+	if err != nil {
+		return sendCouponError(c, err, "failed to update coupon")
+	}
+
+	return SendSuccess(c, http.StatusOK, "coupon updated successfully", coupon)
+}
+
+// DeactivateCoupon stops a coupon from being applied
+// DELETE /api/v1/coupons/:id
+func (h *CouponHandler) DeactivateCoupon(c echo.Context) error {
+	actor, err := currentActor(c, h.userService)
+	if err != nil {
+		return SendError(c, http.StatusUnauthorized, err, "user not authenticated")
+	}
+
+	couponID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return SendError(c, http.StatusBadRequest, err, "invalid coupon ID")
+	}
+
+	if err := h.couponService.DeactivateCoupon(c.Request().Context(), actor, couponID); err != nil {
+		return sendCouponError(c, err, "failed to deactivate coupon")
+	}
+
+	return SendSuccess(c, http.StatusOK, "coupon deactivated successfully", nil)
+}
+
+// sendCouponError maps coupon service errors to HTTP status codes
+func sendCouponError(c echo.Context, err error, message string) error {
+	if errors.Is(err, service.ErrForbidden) {
+		return SendError(c, http.StatusForbidden, err, err.Error())
+	}
+	switch err.Error() {
+	case "coupon not found":
+		return SendError(c, http.StatusNotFound, err, err.Error())
+	case "coupon code already exists":
+		return SendError(c, http.StatusConflict, err, err.Error())
+	case "percentage coupons need a value between 0 and 100", "fixed coupons need a positive value", "ends_at must be after starts_at":
+		return SendError(c, http.StatusBadRequest, err, err.Error())
+	}
+	return SendError(c, http.StatusInternalServerError, err, message)
+}
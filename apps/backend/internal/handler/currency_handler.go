@@ -0,0 +1,94 @@
+package handler
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/imbivek08/hamropasal/internal/model"
+	"github.com/imbivek08/hamropasal/internal/service"
+	"github.com/labstack/echo/v4"
+)
+
+type CurrencyHandler struct {
+	currencyService *service.CurrencyService
+}
+
+func NewCurrencyHandler(currencyService *service.CurrencyService) *CurrencyHandler {
+	return &CurrencyHandler{currencyService: currencyService}
+}
+
+// ListRates lists the currencies prices can be shown and paid in
+// GET /api/v1/exchange-rates
+func (h *CurrencyHandler) ListRates(c echo.Context) error {
+	rates, err := h.currencyService.ListRates(c.Request().Context())
+	if err != nil {
+		return SendError(c, http.StatusInternalServerError, err, "failed to get exchange rates")
+	}
+
+	return SendSuccess(c, http.StatusOK, "exchange rates retrieved successfully", rates)
+}
+
+// SetRate adds or changes a currency's exchange rate (admin only)
+// PUT /api/v1/exchange-rates/:currency
+func (h *CurrencyHandler) SetRate(c echo.Context) error {
+	var req model.SetExchangeRateRequest
+	if err := c.Bind(&req); err != nil {
+		return SendError(c, http.StatusBadRequest, err, "invalid request body")
+	}
+
+	if err := c.Validate(&req); err != nil {
+		return SendError(c, http.StatusBadRequest, err, "validation failed")
+	}
+
+	rate, err := h.currencyService.SetRate(c.Request().Context(), c.Param("currency"), req.Rate)
+	if err != nil {
+		if strings.HasPrefix(err.Error(), "invalid currency") || strings.HasSuffix(err.Error(), "is the base currency") {
+			return SendError(c, http.StatusBadRequest, err, err.Error())
+		}
+		return SendError(c, http.StatusInternalServerError, err, "failed to save exchange rate")
+	}
+
+	return SendSuccess(c, http.StatusOK, "exchange rate saved successfully", rate)
+}
+
+// DeleteRate removes a currency's exchange rate (admin only)
+// DELETE /api/v1/exchange-rates/:currency
+func (h *CurrencyHandler) DeleteRate(c echo.Context) error {
+	if err := h.currencyService.DeleteRate(c.Request().Context(), c.Param("currency")); err != nil {
+		if err.Error() == "exchange rate not found" {
+			return SendError(c, http.StatusNotFound, err, err.Error())
+		}
+		if strings.HasPrefix(err.Error(), "invalid currency") || strings.HasSuffix(err.Error(), "is used by shops") {
+			return SendError(c, http.StatusBadRequest, err, err.Error())
+		}
+		return SendError(c, http.StatusInternalServerError, err, "failed to delete exchange rate")
+	}
+
+	return SendSuccess(c, http.StatusOK, "exchange rate deleted successfully", nil)
+}
+
+// ImportRates sets exchange rates from an uploaded CSV file of currency,rate
+// lines (admin only)
+// POST /api/v1/exchange-rates/import
+func (h *CurrencyHandler) ImportRates(c echo.Context) error {
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		return SendError(c, http.StatusBadRequest, err, "exchange rate file is required")
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		return SendError(c, http.StatusBadRequest, err, "failed to read exchange rate file")
+	}
+	defer file.Close()
+
+	result, err := h.currencyService.ImportRates(c.Request().Context(), file)
+	if err != nil {
+		if strings.HasPrefix(err.Error(), "failed to") {
+			return SendError(c, http.StatusInternalServerError, err, "failed to import exchange rates")
+		}
+		return SendError(c, http.StatusBadRequest, err, err.Error())
+	}
+
+	return SendSuccess(c, http.StatusOK, "exchange rates imported successfully", result)
+}
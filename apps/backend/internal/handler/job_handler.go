@@ -0,0 +1,86 @@
+package handler
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/imbivek08/hamropasal/internal/jobs"
+	"github.com/imbivek08/hamropasal/internal/model"
+	"github.com/labstack/echo/v4"
+)
+
+type JobHandler struct {
+	queue *jobs.Queue
+}
+
+func NewJobHandler(queue *jobs.Queue) *JobHandler {
+	return &JobHandler{queue: queue}
+}
+
+// ListJobs lists queued background jobs, newest first (admin only).
+// Filter with status (pending, running, succeeded or dead) and type.
+// GET /api/v1/admin/jobs
+func (h *JobHandler) ListJobs(c echo.Context) error {
+	page, pageSize := pagination(c)
+
+	status := c.QueryParam("status")
+	switch status {
+	case "",
+		string(model.JobStatusPending),
+		string(model.JobStatusRunning),
+		string(model.JobStatusSucceeded),
+		string(model.JobStatusDead):
+	default:
+		return SendError(c, http.StatusBadRequest, nil, "invalid status")
+	}
+
+	response, err := h.queue.List(c.Request().Context(), status, c.QueryParam("type"), page, pageSize)
+	if err != nil {
+		return SendError(c, http.StatusInternalServerError, err, "failed to list jobs")
+	}
+
+	return SendSuccess(c, http.StatusOK, "jobs retrieved successfully", response)
+}
+
+// GetJob retrieves a background job (admin only)
+// GET /api/v1/admin/jobs/:id
+func (h *JobHandler) GetJob(c echo.Context) error {
+	jobID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return SendError(c, http.StatusBadRequest, err, "invalid job ID")
+	}
+
+	job, err := h.queue.Get(c.Request().Context(), jobID)
+	if err != nil {
+		if err.Error() == "job not found" {
+			return SendError(c, http.StatusNotFound, err, err.Error())
+		}
+		return SendError(c, http.StatusInternalServerError, err, "failed to get job")
+	}
+
+	return SendSuccess(c, http.StatusOK, "job retrieved successfully", job)
+}
+
+// RetryJob runs a dead or pending job again now, with its attempts reset (admin only)
+// POST /api/v1/admin/jobs/:id/retry
+func (h *JobHandler) RetryJob(c echo.Context) error {
+	jobID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return SendError(c, http.StatusBadRequest, err, "invalid job ID")
+	}
+
+	job, err := h.queue.Retry(c.Request().Context(), jobID)
+	if err != nil {
+		switch {
+		case err.Error() == "job not found":
+			return SendError(c, http.StatusNotFound, err, err.Error())
+		case strings.HasPrefix(err.Error(), "job is "):
+			return SendError(c, http.StatusConflict, err, err.Error())
+		default:
+			return SendError(c, http.StatusInternalServerError, err, "failed to retry job")
+		}
+	}
+
+	return SendSuccess(c, http.StatusOK, "job queued for retry", job)
+}
@@ -0,0 +1,333 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/imbivek08/hamropasal/internal/model"
+	"github.com/imbivek08/hamropasal/internal/service"
+	"github.com/labstack/echo/v4"
+)
+
+type LedgerHandler struct {
+	ledgerService *service.LedgerService
+	userService   *service.UserService
+}
+
+func NewLedgerHandler(ledgerService *service.LedgerService, userService *service.UserService) *LedgerHandler {
+	return &LedgerHandler{
+		ledgerService: ledgerService,
+		userService:   userService,
+	}
+}
+
+// GetVendorBalance returns what the platform owes the vendor's shop
+// GET /api/v1/vendor/balance
+func (h *LedgerHandler) GetVendorBalance(c echo.Context) error {
+	shopID, err := h.vendorShop(c)
+	if err != nil {
+		return SendError(c, http.StatusNotFound, err, err.Error())
+	}
+
+	return h.sendBalance(c, shopID)
+}
+
+// ListVendorLedger lists the ledger entries of the vendor's shop
+// GET /api/v1/vendor/ledger
+func (h *LedgerHandler) ListVendorLedger(c echo.Context) error {
+	shopID, err := h.vendorShop(c)
+	if err != nil {
+		return SendError(c, http.StatusNotFound, err, err.Error())
+	}
+
+	return h.sendEntries(c, shopID)
+}
+
+// GetShopBalance returns what the platform owes any shop (admin only)
+// GET /api/v1/admin/shops/:id/balance
+func (h *LedgerHandler) GetShopBalance(c echo.Context) error {
+	shopID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return SendError(c, http.StatusBadRequest, err, "invalid shop ID")
+	}
+
+	return h.sendBalance(c, shopID)
+}
+
+// ListShopLedger lists any shop's ledger entries (admin only)
+// GET /api/v1/admin/shops/:id/ledger
+func (h *LedgerHandler) ListShopLedger(c echo.Context) error {
+	shopID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return SendError(c, http.StatusBadRequest, err, "invalid shop ID")
+	}
+
+	return h.sendEntries(c, shopID)
+}
+
+// ListCommissionRates lists the commission rates (admin only)
+// GET /api/v1/admin/commission-rates
+func (h *LedgerHandler) ListCommissionRates(c echo.Context) error {
+	rates, err := h.ledgerService.ListCommissionRates(c.Request().Context())
+	if err != nil {
+		return SendError(c, http.StatusInternalServerError, err, "failed to get commission rates")
+	}
+
+	return SendSuccess(c, http.StatusOK, "commission rates retrieved successfully", rates)
+}
+
+// SetDefaultCommission sets the platform's default commission rate (admin only)
+// PUT /api/v1/admin/commission-rates/default
+func (h *LedgerHandler) SetDefaultCommission(c echo.Context) error {
+	var req model.SetCommissionRateRequest
+	if err := c.Bind(&req); err != nil {
+		return SendError(c, http.StatusBadRequest, err, "invalid request body")
+	}
+
+	if err := c.Validate(&req); err != nil {
+		return SendError(c, http.StatusBadRequest, err, "validation failed")
+	}
+
+	rate, err := h.ledgerService.SetDefaultCommission(c.Request().Context(), req.Rate)
+	if err != nil {
+		return SendError(c, http.StatusInternalServerError, err, "failed to set commission rate")
+	}
+
+	return SendSuccess(c, http.StatusOK, "commission rate set successfully", rate)
+}
+
+// SetCategoryCommission sets a category's commission rate (admin only)
+// PUT /api/v1/admin/commission-rates/categories/:id
+func (h *LedgerHandler) SetCategoryCommission(c echo.Context) error {
+	categoryID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return SendError(c, http.StatusBadRequest, err, "invalid category ID")
+	}
+
+	var req model.SetCommissionRateRequest
+	if err := c.Bind(&req); err != nil {
+		return SendError(c, http.StatusBadRequest, err, "invalid request body")
+	}
+
+	if err := c.Validate(&req); err != nil {
+		return SendError(c, http.StatusBadRequest, err, "validation failed")
+	}
+
+	rate, err := h.ledgerService.SetCategoryCommission(c.Request().Context(), categoryID, req.Rate)
+	if err != nil {
+		if err.Error() == "category not found" {
+			return SendError(c, http.StatusNotFound, err, err.Error())
+		}
+		return SendError(c, http.StatusInternalServerError, err, "failed to set commission rate")
+	}
+
+	return SendSuccess(c, http.StatusOK, "commission rate set successfully", rate)
+}
+
+// DeleteCategoryCommission removes a category's commission rate (admin only)
+// DELETE /api/v1/admin/commission-rates/categories/:id
+func (h *LedgerHandler) DeleteCategoryCommission(c echo.Context) error {
+	categoryID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return SendError(c, http.StatusBadRequest, err, "invalid category ID")
+	}
+
+	if err := h.ledgerService.DeleteCategoryCommission(c.Request().Context(), categoryID); err != nil {
+		if err.Error() == "commission rate not found" {
+			return SendError(c, http.StatusNotFound, err, err.Error())
+		}
+		return SendError(c, http.StatusInternalServerError, err, "failed to delete commission rate")
+	}
+
+	return SendSuccess(c, http.StatusOK, "commission rate deleted successfully", nil)
+}
+
+// SetShopCommission overrides a shop's commission rate (admin only)
+// PUT /api/v1/admin/commission-rates/shops/:id
+func (h *LedgerHandler) SetShopCommission(c echo.Context) error {
+	shopID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return SendError(c, http.StatusBadRequest, err, "invalid shop ID")
+	}
+
+	var req model.SetCommissionRateRequest
+	if err := c.Bind(&req); err != nil {
+		return SendError(c, http.StatusBadRequest, err, "invalid request body")
+	}
+
+	if err := c.Validate(&req); err != nil {
+		return SendError(c, http.StatusBadRequest, err, "validation failed")
+	}
+
+	rate, err := h.ledgerService.SetShopCommission(c.Request().Context(), shopID, req.Rate)
+	if err != nil {
+		if err.Error() == "shop not found" {
+			return SendError(c, http.StatusNotFound, err, err.Error())
+		}
+		return SendError(c, http.StatusInternalServerError, err, "failed to set commission rate")
+	}
+
+	return SendSuccess(c, http.StatusOK, "commission rate set successfully", rate)
+}
+
+// DeleteShopCommission removes a shop's commission override (admin only)
+// DELETE /api/v1/admin/commission-rates/shops/:id
+func (h *LedgerHandler) DeleteShopCommission(c echo.Context) error {
+	shopID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return SendError(c, http.StatusBadRequest, err, "invalid shop ID")
+	}
+
+	if err := h.ledgerService.DeleteShopCommission(c.Request().Context(), shopID); err != nil {
+		if err.Error() == "commission rate not found" {
+			return SendError(c, http.StatusNotFound, err, err.Error())
+		}
+		return SendError(c, http.StatusInternalServerError, err, "failed to delete commission rate")
+	}
+
+	return SendSuccess(c, http.StatusOK, "commission rate deleted successfully", nil)
+}
+
+// CreatePayoutBatch pays out the shops' available balances (admin only)
+// POST /api/v1/admin/payout-batches
+func (h *LedgerHandler) CreatePayoutBatch(c echo.Context) error {
+	actor, err := currentActor(c, h.userService)
+	if err != nil {
+		return SendError(c, http.StatusUnauthorized, err, "user not found in context")
+	}
+
+	var req model.CreatePayoutBatchRequest
+	if err := c.Bind(&req); err != nil {
+		return SendError(c, http.StatusBadRequest, err, "invalid request body")
+	}
+
+	batch, err := h.ledgerService.CreatePayoutBatch(c.Request().Context(), actor.UserID, &req)
+	if err != nil {
+		if err.Error() == "no balance available for payout" {
+			return SendError(c, http.StatusConflict, err, err.Error())
+		}
+		return SendError(c, http.StatusInternalServerError, err, "failed to create payout batch")
+	}
+
+	return SendSuccess(c, http.StatusCreated, "payout batch created successfully", batch)
+}
+
+// ListPayoutBatches lists payout batches, newest first (admin only)
+// GET /api/v1/admin/payout-batches
+func (h *LedgerHandler) ListPayoutBatches(c echo.Context) error {
+	page, pageSize := pagination(c)
+
+	response, err := h.ledgerService.ListPayoutBatches(c.Request().Context(), page, pageSize)
+	if err != nil {
+		return SendError(c, http.StatusInternalServerError, err, "failed to list payout batches")
+	}
+
+	return SendSuccess(c, http.StatusOK, "payout batches retrieved successfully", response)
+}
+
+// GetPayoutBatch retrieves a payout batch with its payouts (admin only)
+// GET /api/v1/admin/payout-batches/:id
+func (h *LedgerHandler) GetPayoutBatch(c echo.Context) error {
+	batchID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return SendError(c, http.StatusBadRequest, err, "invalid payout batch ID")
+	}
+
+	batch, err := h.ledgerService.GetPayoutBatch(c.Request().Context(), batchID)
+	if err != nil {
+		if err.Error() == "payout batch not found" {
+			return SendError(c, http.StatusNotFound, err, err.Error())
+		}
+		return SendError(c, http.StatusInternalServerError, err, "failed to get payout batch")
+	}
+
+	return SendSuccess(c, http.StatusOK, "payout batch retrieved successfully", batch)
+}
+
+// UpdatePayoutStatus marks a pending payout paid or failed (admin only)
+// PATCH /api/v1/admin/payouts/:id/status
+func (h *LedgerHandler) UpdatePayoutStatus(c echo.Context) error {
+	payoutID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return SendError(c, http.StatusBadRequest, err, "invalid payout ID")
+	}
+
+	var req model.UpdatePayoutStatusRequest
+	if err := c.Bind(&req); err != nil {
+		return SendError(c, http.StatusBadRequest, err, "invalid request body")
+	}
+
+	if err := c.Validate(&req); err != nil {
+		return SendError(c, http.StatusBadRequest, err, "validation failed")
+	}
+
+	payout, err := h.ledgerService.UpdatePayoutStatus(c.Request().Context(), payoutID, &req)
+	if err != nil {
+		switch {
+		case err.Error() == "payout not found":
+			return SendError(c, http.StatusNotFound, err, err.Error())
+		case strings.HasPrefix(err.Error(), "payout is already"):
+			return SendError(c, http.StatusConflict, err, err.Error())
+		default:
+			return SendError(c, http.StatusInternalServerError, err, "failed to update payout")
+		}
+	}
+
+	return SendSuccess(c, http.StatusOK, "payout updated successfully", payout)
+}
+
+// vendorShop returns the shop of the vendor making the request
+func (h *LedgerHandler) vendorShop(c echo.Context) (uuid.UUID, error) {
+	actor, err := currentActor(c, h.userService)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	if actor.ShopID == nil {
+		return uuid.Nil, errors.New("no shop found for vendor")
+	}
+	return *actor.ShopID, nil
+}
+
+func (h *LedgerHandler) sendBalance(c echo.Context, shopID uuid.UUID) error {
+	balance, err := h.ledgerService.GetShopBalance(c.Request().Context(), shopID)
+	if err != nil {
+		if err.Error() == "shop not found" {
+			return SendError(c, http.StatusNotFound, err, err.Error())
+		}
+		return SendError(c, http.StatusInternalServerError, err, "failed to get balance")
+	}
+
+	return SendSuccess(c, http.StatusOK, "balance retrieved successfully", balance)
+}
+
+func (h *LedgerHandler) sendEntries(c echo.Context, shopID uuid.UUID) error {
+	page, pageSize := pagination(c)
+
+	response, err := h.ledgerService.ListShopEntries(c.Request().Context(), shopID, page, pageSize)
+	if err != nil {
+		if err.Error() == "shop not found" {
+			return SendError(c, http.StatusNotFound, err, err.Error())
+		}
+		return SendError(c, http.StatusInternalServerError, err, "failed to list ledger entries")
+	}
+
+	return SendSuccess(c, http.StatusOK, "ledger entries retrieved successfully", response)
+}
+
+// pagination reads the page and page_size query parameters
+func pagination(c echo.Context) (int, int) {
+	page, _ := strconv.Atoi(c.QueryParam("page"))
+	if page < 1 {
+		page = 1
+	}
+
+	pageSize, _ := strconv.Atoi(c.QueryParam("page_size"))
+	if pageSize < 1 {
+		pageSize = 20
+	}
+
+	return page, pageSize
+}
@@ -1,6 +1,7 @@
 package handler
 
 import (
+	"errors"
 	"net/http"
 
 	"github.com/google/uuid"
@@ -144,23 +145,13 @@ func (h *OrderHandler) GetVendorOrders(c echo.Context) error {
 	return SendSuccess(c, http.StatusOK, "vendor orders retrieved successfully", orders)
 }
 
-// UpdateOrderStatus updates order status (vendor only)
+// UpdateOrderStatus advances fulfilments of an order. Vendors move their own
+// shop's fulfilment; admins may move any (admin override).
 func (h *OrderHandler) UpdateOrderStatus(c echo.Context) error {
-	// Get Clerk user ID from middleware
-	clerkUserID := middleware.GetClerkUserID(c)
-	if clerkUserID == "" {
-		return SendError(c, http.StatusUnauthorized, nil, "user not authenticated")
-	}
-
-	// Get internal user
-	user, err := h.userService.GetUserByClerkID(c.Request().Context(), clerkUserID)
+	// Get the acting user; the service checks the order has their shop's items
+	actor, err := currentActor(c, h.userService)
 	if err != nil {
-		return SendError(c, http.StatusNotFound, err, "user not found")
-	}
-
-	// Check if user is vendor
-	if user.Role != model.RoleVendor {
-		return SendError(c, http.StatusForbidden, nil, "vendor access required")
+		return SendError(c, http.StatusUnauthorized, err, "user not authenticated")
 	}
 
 	// Parse order ID
@@ -180,8 +171,14 @@ func (h *OrderHandler) UpdateOrderStatus(c echo.Context) error {
 		return SendError(c, http.StatusBadRequest, err, "validation failed")
 	}
 
-	// Update order status
-	if err := h.orderService.UpdateOrderStatus(c.Request().Context(), orderID, req.Status); err != nil {
+	// Update the fulfilments; the order status follows
+	if err := h.orderService.UpdateFulfilmentStatus(c.Request().Context(), actor, orderID, &req); err != nil {
+		if errors.Is(err, service.ErrForbidden) {
+			return SendError(c, http.StatusForbidden, err, err.Error())
+		}
+		if errors.Is(err, service.ErrOrderNotFound) {
+			return SendError(c, http.StatusNotFound, err, service.ErrOrderNotFound.Error())
+		}
 		return SendError(c, http.StatusBadRequest, err, "failed to update order status")
 	}
 
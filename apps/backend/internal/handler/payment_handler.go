@@ -0,0 +1,287 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/google/uuid"
+
+	"github.com/imbivek08/hamropasal/internal/middleware"
+	"github.com/imbivek08/hamropasal/internal/model"
+	"github.com/imbivek08/hamropasal/internal/payment"
+	"github.com/imbivek08/hamropasal/internal/service"
+	"github.com/labstack/echo/v4"
+)
+
+type PaymentHandler struct {
+	paymentService *service.PaymentService
+	orderService   *service.OrderService
+	userService    *service.UserService
+}
+
+func NewPaymentHandler(
+	paymentService *service.PaymentService,
+	orderService *service.OrderService,
+	userService *service.UserService,
+) *PaymentHandler {
+	return &PaymentHandler{
+		paymentService: paymentService,
+		orderService:   orderService,
+		userService:    userService,
+	}
+}
+
+// ListPaymentMethods lists the payment methods customers can choose at checkout
+// GET /api/v1/payment-methods
+func (h *PaymentHandler) ListPaymentMethods(c echo.Context) error {
+	return SendSuccess(c, http.StatusOK, "payment methods retrieved successfully", h.paymentService.ListMethods())
+}
+
+// CreateCheckout creates an order and starts paying for it with the chosen
+// payment method. Online methods return the page the customer pays on.
+// POST /api/v1/orders/checkout
+func (h *PaymentHandler) CreateCheckout(c echo.Context) error {
+	return h.checkout(c, "")
+}
+
+// CreateCheckoutSession creates an order and returns a Stripe Checkout URL.
+// The frontend should redirect the user to this URL.
+func (h *PaymentHandler) CreateCheckoutSession(c echo.Context) error {
+	return h.checkout(c, payment.MethodStripe)
+}
+
+// checkout creates an order from the cart and starts its payment, forcing the
+// payment method when one is given
+func (h *PaymentHandler) checkout(c echo.Context, method string) error {
+	// Get Clerk user ID from middleware
+	clerkUserID := middleware.GetClerkUserID(c)
+	if clerkUserID == "" {
+		return SendError(c, http.StatusUnauthorized, nil, "user not authenticated")
+	}
+
+	// Get internal user
+	user, err := h.userService.GetUserByClerkID(c.Request().Context(), clerkUserID)
+	if err != nil {
+		return SendError(c, http.StatusNotFound, err, "user not found")
+	}
+
+	// Parse request — same shape as regular CreateOrderRequest
+	var req model.CreateOrderRequest
+	if err := c.Bind(&req); err != nil {
+		return SendError(c, http.StatusBadRequest, err, "invalid request body")
+	}
+
+	if method != "" {
+		req.PaymentMethod = method
+	}
+
+	if err := c.Validate(&req); err != nil {
+		return SendError(c, http.StatusBadRequest, err, "validation failed")
+	}
+
+	// Create the order (online payments leave it "pending")
+	orderResp, err := h.orderService.CreateOrderFromCart(c.Request().Context(), user.ID, &req)
+	if err != nil {
+		return SendError(c, http.StatusBadRequest, err, err.Error())
+	}
+
+	response, err := h.paymentService.StartCheckout(c.Request().Context(), orderResp)
+	if err != nil {
+		return SendError(c, http.StatusInternalServerError, err, "failed to create payment session")
+	}
+
+	return SendSuccess(c, http.StatusCreated, "checkout session created", response)
+}
+
+// PayOrder takes the customer back to paying for one of their unpaid orders,
+// reopening its payment or starting a new one. Passing a payment method
+// switches the order to it; offline methods confirm the order.
+// POST /api/v1/orders/:id/pay
+func (h *PaymentHandler) PayOrder(c echo.Context) error {
+	clerkUserID := middleware.GetClerkUserID(c)
+	if clerkUserID == "" {
+		return SendError(c, http.StatusUnauthorized, nil, "user not authenticated")
+	}
+
+	user, err := h.userService.GetUserByClerkID(c.Request().Context(), clerkUserID)
+	if err != nil {
+		return SendError(c, http.StatusNotFound, err, "user not found")
+	}
+
+	orderID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return SendError(c, http.StatusBadRequest, err, "invalid order ID")
+	}
+
+	var req model.PayOrderRequest
+	if err := c.Bind(&req); err != nil {
+		return SendError(c, http.StatusBadRequest, err, "invalid request body")
+	}
+
+	order, err := h.orderService.PreparePayment(c.Request().Context(), orderID, user.ID, req.PaymentMethod)
+	if err != nil {
+		switch {
+		case err.Error() == "order not found or unauthorized":
+			return SendError(c, http.StatusNotFound, err, "order not found")
+		case errors.Is(err, payment.ErrUnknownMethod):
+			return SendError(c, http.StatusBadRequest, err, err.Error())
+		case err.Error() == "order is not awaiting payment", err.Error() == "order's stock reservation has expired":
+			return SendError(c, http.StatusConflict, err, err.Error())
+		default:
+			return SendError(c, http.StatusInternalServerError, err, "failed to prepare payment")
+		}
+	}
+
+	response, err := h.paymentService.ResumeCheckout(c.Request().Context(), order)
+	if err != nil {
+		return SendError(c, http.StatusInternalServerError, err, "failed to create payment session")
+	}
+
+	return SendSuccess(c, http.StatusOK, "checkout session created", response)
+}
+
+// HandleStripeWebhook processes Stripe webhook events.
+// This endpoint must NOT have auth middleware — Stripe calls it directly.
+func (h *PaymentHandler) HandleStripeWebhook(c echo.Context) error {
+	// Read raw body from context (saved by saveRawBody middleware)
+	var body []byte
+	rawBody := c.Get("raw_body")
+	if rawBody != nil {
+		body = rawBody.([]byte)
+	} else {
+		var err error
+		body, err = io.ReadAll(c.Request().Body)
+		if err != nil {
+			return SendError(c, http.StatusBadRequest, err, "failed to read request body")
+		}
+	}
+
+	callback := &payment.Callback{
+		Header: c.Request().Header,
+		Query:  c.QueryParams(),
+		Body:   body,
+	}
+
+	// Verify, record and process the event; duplicates of processed events are skipped
+	event, err := h.paymentService.HandleCallback(c.Request().Context(), payment.MethodStripe, callback)
+	if err != nil {
+		switch {
+		case errors.Is(err, payment.ErrUnknownMethod):
+			return SendError(c, http.StatusNotFound, err, "payment method not available")
+		case errors.Is(err, payment.ErrInvalidCallback):
+			fmt.Printf("[Stripe Webhook] Signature verification FAILED: %v\n", err)
+			return SendError(c, http.StatusBadRequest, err, "invalid webhook signature")
+		}
+		return SendError(c, http.StatusInternalServerError, err, "failed to process webhook event")
+	}
+
+	fmt.Printf("[Stripe Webhook] Event received: %s\n", event.Type)
+
+	return c.JSON(http.StatusOK, map[string]string{"status": "ok"})
+}
+
+// HandlePaymentReturn receives customers coming back from a gateway's payment
+// page and sends them on to the shop's success or cancel page. It has no auth
+// middleware; the gateway's callback is authenticated instead.
+// GET /api/v1/payments/:method/return
+func (h *PaymentHandler) HandlePaymentReturn(c echo.Context) error {
+	callback := &payment.Callback{
+		Header: c.Request().Header,
+		Query:  c.QueryParams(),
+	}
+
+	return c.Redirect(http.StatusFound, h.paymentService.HandleReturn(c.Request().Context(), c.Param("method"), callback))
+}
+
+// ListWebhookEvents lists recorded payment webhook events and callbacks (admin only).
+// Defaults to failed events; pass status=all for every event.
+// GET /api/v1/admin/webhook-events
+func (h *PaymentHandler) ListWebhookEvents(c echo.Context) error {
+	page, _ := strconv.Atoi(c.QueryParam("page"))
+	if page < 1 {
+		page = 1
+	}
+
+	pageSize, _ := strconv.Atoi(c.QueryParam("page_size"))
+	if pageSize < 1 {
+		pageSize = 20
+	}
+
+	status := c.QueryParam("status")
+	switch status {
+	case "":
+		status = string(model.WebhookEventStatusFailed)
+	case "all":
+		status = ""
+	case string(model.WebhookEventStatusProcessing),
+		string(model.WebhookEventStatusProcessed),
+		string(model.WebhookEventStatusFailed):
+	default:
+		return SendError(c, http.StatusBadRequest, nil, "invalid status")
+	}
+
+	response, err := h.paymentService.ListWebhookEvents(c.Request().Context(), status, page, pageSize)
+	if err != nil {
+		return SendError(c, http.StatusInternalServerError, err, "failed to list webhook events")
+	}
+
+	return SendSuccess(c, http.StatusOK, "webhook events retrieved successfully", response)
+}
+
+// ReplayWebhookEvent processes a failed webhook event again (admin only)
+// POST /api/v1/admin/webhook-events/:id/replay
+func (h *PaymentHandler) ReplayWebhookEvent(c echo.Context) error {
+	eventID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return SendError(c, http.StatusBadRequest, err, "invalid webhook event ID")
+	}
+
+	event, err := h.paymentService.ReplayWebhookEvent(c.Request().Context(), eventID)
+	if err != nil {
+		switch err.Error() {
+		case "webhook event not found":
+			return SendError(c, http.StatusNotFound, err, "")
+		case "only failed webhook events can be replayed":
+			return SendError(c, http.StatusConflict, err, "")
+		}
+		return SendError(c, http.StatusInternalServerError, err, "failed to replay webhook event")
+	}
+
+	if event.Status == model.WebhookEventStatusFailed {
+		return SendSuccess(c, http.StatusOK, "webhook event replayed but failed again", event)
+	}
+
+	return SendSuccess(c, http.StatusOK, "webhook event replayed successfully", event)
+}
+
+// VerifySession lets the frontend check the payment status after redirect.
+// The order is settled here if the gateway's callback has not arrived yet.
+func (h *PaymentHandler) VerifySession(c echo.Context) error {
+	sessionID := c.QueryParam("session_id")
+	if sessionID == "" {
+		return SendError(c, http.StatusBadRequest, nil, "session_id is required")
+	}
+
+	clerkUserID := middleware.GetClerkUserID(c)
+	if clerkUserID == "" {
+		return SendError(c, http.StatusUnauthorized, nil, "user not authenticated")
+	}
+
+	user, err := h.userService.GetUserByClerkID(c.Request().Context(), clerkUserID)
+	if err != nil {
+		return SendError(c, http.StatusNotFound, err, "user not found")
+	}
+
+	status, err := h.paymentService.VerifyPayment(c.Request().Context(), sessionID, user.ID)
+	if err != nil {
+		if err.Error() == "order not found" {
+			return SendError(c, http.StatusNotFound, err, err.Error())
+		}
+		return SendError(c, http.StatusInternalServerError, err, "failed to verify session")
+	}
+
+	return SendSuccess(c, http.StatusOK, "session verified", status)
+}
@@ -1,7 +1,9 @@
 package handler
 
 import (
+	"errors"
 	"net/http"
+	"net/url"
 	"strconv"
 
 	"github.com/google/uuid"
@@ -12,18 +14,21 @@ import (
 )
 
 type ProductHandler struct {
-	productService *service.ProductService
-	userService    *service.UserService
+	productService  *service.ProductService
+	userService     *service.UserService
+	wishlistService *service.WishlistService
 }
 
-func NewProductHandler(productService *service.ProductService, userService *service.UserService) *ProductHandler {
+func NewProductHandler(productService *service.ProductService, userService *service.UserService, wishlistService *service.WishlistService) *ProductHandler {
 	return &ProductHandler{
-		productService: productService,
-		userService:    userService,
+		productService:  productService,
+		userService:     userService,
+		wishlistService: wishlistService,
 	}
 }
 
-// GetProducts retrieves all products (public)
+// GetProducts retrieves a page of products with facets (public)
+// GET /api/v1/products?search=&category=&shop=&min_price=&max_price=&min_rating=&in_stock=&verified=&sort_by=&page=&page_size=
 func (h *ProductHandler) GetProducts(c echo.Context) error {
 	// Build filters from query params
 	filters := make(map[string]interface{})
@@ -32,34 +37,73 @@ func (h *ProductHandler) GetProducts(c echo.Context) error {
 		filters["search"] = search
 	}
 
+	if category := c.QueryParam("category"); category != "" {
+		filters["category"] = category
+	}
+
 	if minPrice := c.QueryParam("min_price"); minPrice != "" {
-		if val, err := strconv.ParseFloat(minPrice, 64); err == nil {
+		if val, err := model.ParseMoney(minPrice); err == nil {
 			filters["min_price"] = val
 		}
 	}
 
 	if maxPrice := c.QueryParam("max_price"); maxPrice != "" {
-		if val, err := strconv.ParseFloat(maxPrice, 64); err == nil {
+		if val, err := model.ParseMoney(maxPrice); err == nil {
 			filters["max_price"] = val
 		}
 	}
 
+	if shop := c.QueryParam("shop"); shop != "" {
+		if shopID, err := uuid.Parse(shop); err == nil {
+			filters["shop_id"] = shopID
+		}
+	}
+
+	if minRating, err := strconv.Atoi(c.QueryParam("min_rating")); err == nil && minRating >= 1 && minRating <= 5 {
+		filters["min_rating"] = minRating
+	}
+
+	if c.QueryParam("in_stock") == "true" {
+		filters["in_stock"] = true
+	}
+
+	if c.QueryParam("verified") == "true" {
+		filters["verified"] = true
+	}
+
 	if sortBy := c.QueryParam("sort_by"); sortBy != "" {
 		filters["sort_by"] = sortBy
 	}
 
-	products, err := h.productService.GetAllProducts(c.Request().Context(), filters)
+	page, _ := strconv.Atoi(c.QueryParam("page"))
+	pageSize, _ := strconv.Atoi(c.QueryParam("page_size"))
+
+	// Prices are shown in the requested currency, e.g. ?currency=USD
+	response, err := h.productService.ListProducts(c.Request().Context(), filters, page, pageSize, c.QueryParam("currency"))
 	if err != nil {
+		if errors.Is(err, model.ErrUnsupportedCurrency) {
+			return SendError(c, http.StatusBadRequest, err, "")
+		}
 		return SendError(c, http.StatusInternalServerError, err, "failed to retrieve products")
 	}
 
-	// Convert to response format
-	var responses []*model.ProductResponse
-	for _, product := range products {
-		responses = append(responses, product.ToResponse())
+	return SendSuccess(c, http.StatusOK, "products retrieved successfully", response)
+}
+
+// SuggestProducts returns autocomplete matches for a partly typed search (public)
+// GET /api/v1/products/suggest?q=red%20sh
+func (h *ProductHandler) SuggestProducts(c echo.Context) error {
+	limit, _ := strconv.Atoi(c.QueryParam("limit"))
+	if limit < 1 || limit > 20 {
+		limit = 8
+	}
+
+	suggestions, err := h.productService.SuggestProducts(c.Request().Context(), c.QueryParam("q"), limit)
+	if err != nil {
+		return SendError(c, http.StatusInternalServerError, err, "failed to retrieve product suggestions")
 	}
 
-	return SendSuccess(c, http.StatusOK, "products retrieved successfully", responses)
+	return SendSuccess(c, http.StatusOK, "product suggestions retrieved successfully", suggestions)
 }
 
 // GetProductByID retrieves a single product by ID (public)
@@ -74,7 +118,47 @@ func (h *ProductHandler) GetProductByID(c echo.Context) error {
 		return SendError(c, http.StatusNotFound, err, "product not found")
 	}
 
-	return SendSuccess(c, http.StatusOK, "product retrieved successfully", product.ToResponse())
+	return SendSuccess(c, http.StatusOK, "product retrieved successfully", h.productResponse(c, product))
+}
+
+// GetProductBySlug retrieves a single product by slug (public). Old slugs
+// redirect to the product's current one.
+// GET /api/v1/products/slug/:slug
+func (h *ProductHandler) GetProductBySlug(c echo.Context) error {
+	slug := c.Param("slug")
+	if slug == "" {
+		return SendError(c, http.StatusBadRequest, nil, "slug is required")
+	}
+
+	product, err := h.productService.GetProductBySlug(c.Request().Context(), slug)
+	if err != nil {
+		if err.Error() == "product not found" {
+			return SendError(c, http.StatusNotFound, err, "")
+		}
+		return SendError(c, http.StatusInternalServerError, err, "failed to get product")
+	}
+
+	if product.Slug != slug {
+		return c.Redirect(http.StatusMovedPermanently, "/api/v1/products/slug/"+url.PathEscape(product.Slug))
+	}
+
+	return SendSuccess(c, http.StatusOK, "product retrieved successfully", h.productResponse(c, product))
+}
+
+// productResponse converts a product for a single-product view, flagging
+// whether signed-in users (set by the optional auth middleware) wishlisted it
+func (h *ProductHandler) productResponse(c echo.Context, product *model.Product) *model.ProductResponse {
+	response := product.ToResponse()
+
+	if clerkID := middleware.GetClerkUserID(c); clerkID != "" {
+		if user, err := h.userService.GetUserByClerkID(c.Request().Context(), clerkID); err == nil {
+			if inWishlist, err := h.wishlistService.IsInWishlist(c.Request().Context(), user.ID, product.ID); err == nil {
+				response.InWishlist = &inWishlist
+			}
+		}
+	}
+
+	return response
 }
 
 // CreateProduct creates a new product (vendor only)
@@ -111,30 +195,18 @@ func (h *ProductHandler) CreateProduct(c echo.Context) error {
 	// Create product
 	product, err := h.productService.CreateProduct(c.Request().Context(), shopID, &req)
 	if err != nil {
-		return SendError(c, http.StatusInternalServerError, err, "failed to create product")
+		return sendProductError(c, err, "failed to create product")
 	}
 
 	return SendSuccess(c, http.StatusCreated, "product created successfully", product.ToResponse())
 }
 
-// UpdateProduct updates a product (vendor only, own products)
+// UpdateProduct updates a product (own products, or admin)
 func (h *ProductHandler) UpdateProduct(c echo.Context) error {
-	// Get Clerk user ID from middleware
-	clerkID := middleware.GetClerkUserID(c)
-	if clerkID == "" {
-		return SendError(c, http.StatusUnauthorized, nil, "user not authenticated")
-	}
-
-	// Get user from database
-	user, err := h.userService.GetUserByClerkID(c.Request().Context(), clerkID)
+	// Get the acting user; the service checks they own the product
+	actor, err := currentActor(c, h.userService)
 	if err != nil {
-		return SendError(c, http.StatusNotFound, err, "user not found")
-	}
-
-	// Get shop ID for vendor
-	shopID, err := h.userService.GetShopIDByVendorID(c.Request().Context(), user.ID)
-	if err != nil {
-		return SendError(c, http.StatusNotFound, err, "no shop found for vendor")
+		return SendError(c, http.StatusUnauthorized, err, "user not authenticated")
 	}
 
 	// Parse product ID
@@ -150,32 +222,20 @@ func (h *ProductHandler) UpdateProduct(c echo.Context) error {
 	}
 
 	// Update product
-	product, err := h.productService.UpdateProduct(c.Request().Context(), productID, shopID, &req)
+	product, err := h.productService.UpdateProduct(c.Request().Context(), actor, productID, &req)
 	if err != nil {
-		return SendError(c, http.StatusInternalServerError, err, "failed to update product")
+		return sendProductError(c, err, "failed to update product")
 	}
 
 	return SendSuccess(c, http.StatusOK, "product updated successfully", product.ToResponse())
 }
 
-// DeleteProduct deletes a product (vendor only, own products)
+// DeleteProduct deletes a product (own products, or admin)
 func (h *ProductHandler) DeleteProduct(c echo.Context) error {
-	// Get Clerk user ID from middleware
-	clerkID := middleware.GetClerkUserID(c)
-	if clerkID == "" {
-		return SendError(c, http.StatusUnauthorized, nil, "user not authenticated")
-	}
-
-	// Get user from database
-	user, err := h.userService.GetUserByClerkID(c.Request().Context(), clerkID)
+	// Get the acting user; the service checks they own the product
+	actor, err := currentActor(c, h.userService)
 	if err != nil {
-		return SendError(c, http.StatusNotFound, err, "user not found")
-	}
-
-	// Get shop ID for vendor
-	shopID, err := h.userService.GetShopIDByVendorID(c.Request().Context(), user.ID)
-	if err != nil {
-		return SendError(c, http.StatusNotFound, err, "no shop found for vendor")
+		return SendError(c, http.StatusUnauthorized, err, "user not authenticated")
 	}
 
 	// Parse product ID
@@ -185,8 +245,8 @@ func (h *ProductHandler) DeleteProduct(c echo.Context) error {
 	}
 
 	// Delete product
-	if err := h.productService.DeleteProduct(c.Request().Context(), productID, shopID); err != nil {
-		return SendError(c, http.StatusInternalServerError, err, "failed to delete product")
+	if err := h.productService.DeleteProduct(c.Request().Context(), actor, productID); err != nil {
+		return sendProductError(c, err, "failed to delete product")
 	}
 
 	return SendSuccess(c, http.StatusOK, "product deleted successfully", nil)
@@ -226,3 +286,324 @@ func (h *ProductHandler) GetVendorProducts(c echo.Context) error {
 
 	return SendSuccess(c, http.StatusOK, "vendor products retrieved successfully", responses)
 }
+
+// GetProductImages retrieves the ordered image gallery for a product (public)
+func (h *ProductHandler) GetProductImages(c echo.Context) error {
+	productID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return SendError(c, http.StatusBadRequest, err, "invalid product ID")
+	}
+
+	images, err := h.productService.GetProductImages(c.Request().Context(), productID)
+	if err != nil {
+		if err.Error() == "product not found" {
+			return SendError(c, http.StatusNotFound, err, "")
+		}
+		return SendError(c, http.StatusInternalServerError, err, "failed to retrieve product images")
+	}
+
+	return SendSuccess(c, http.StatusOK, "product images retrieved successfully", images)
+}
+
+// AddProductImage adds an image to a product's gallery (own products, or admin)
+func (h *ProductHandler) AddProductImage(c echo.Context) error {
+	// Get the acting user; the service checks they own the product
+	actor, err := currentActor(c, h.userService)
+	if err != nil {
+		return SendError(c, http.StatusUnauthorized, err, "user not authenticated")
+	}
+
+	// Parse product ID
+	productID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return SendError(c, http.StatusBadRequest, err, "invalid product ID")
+	}
+
+	// Parse request body
+	var req model.AddProductImageRequest
+	if err := c.Bind(&req); err != nil {
+		return SendError(c, http.StatusBadRequest, err, "invalid request body")
+	}
+
+	if err := c.Validate(&req); err != nil {
+		return SendError(c, http.StatusBadRequest, err, "validation failed")
+	}
+
+	image, err := h.productService.AddProductImage(c.Request().Context(), actor, productID, &req)
+	if err != nil {
+		return sendProductError(c, err, "failed to add product image")
+	}
+
+	return SendSuccess(c, http.StatusCreated, "product image added successfully", image)
+}
+
+// ReorderProductImages changes the display order of a product's gallery (own products, or admin)
+func (h *ProductHandler) ReorderProductImages(c echo.Context) error {
+	// Get the acting user; the service checks they own the product
+	actor, err := currentActor(c, h.userService)
+	if err != nil {
+		return SendError(c, http.StatusUnauthorized, err, "user not authenticated")
+	}
+
+	// Parse product ID
+	productID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return SendError(c, http.StatusBadRequest, err, "invalid product ID")
+	}
+
+	// Parse request body
+	var req model.ReorderProductImagesRequest
+	if err := c.Bind(&req); err != nil {
+		return SendError(c, http.StatusBadRequest, err, "invalid request body")
+	}
+
+	if err := c.Validate(&req); err != nil {
+		return SendError(c, http.StatusBadRequest, err, "validation failed")
+	}
+
+	images, err := h.productService.ReorderProductImages(c.Request().Context(), actor, productID, req.ImageIDs)
+	if err != nil {
+		return sendProductError(c, err, "failed to reorder product images")
+	}
+
+	return SendSuccess(c, http.StatusOK, "product images reordered successfully", images)
+}
+
+// SetPrimaryProductImage makes an image the product's primary image (own products, or admin)
+func (h *ProductHandler) SetPrimaryProductImage(c echo.Context) error {
+	// Get the acting user; the service checks they own the product
+	actor, err := currentActor(c, h.userService)
+	if err != nil {
+		return SendError(c, http.StatusUnauthorized, err, "user not authenticated")
+	}
+
+	// Parse product and image IDs
+	productID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return SendError(c, http.StatusBadRequest, err, "invalid product ID")
+	}
+
+	imageID, err := uuid.Parse(c.Param("imageId"))
+	if err != nil {
+		return SendError(c, http.StatusBadRequest, err, "invalid image ID")
+	}
+
+	if err := h.productService.SetPrimaryProductImage(c.Request().Context(), actor, productID, imageID); err != nil {
+		return sendProductError(c, err, "failed to set primary image")
+	}
+
+	return SendSuccess(c, http.StatusOK, "primary image updated successfully", nil)
+}
+
+// DeleteProductImage removes an image from a product's gallery (own products, or admin)
+func (h *ProductHandler) DeleteProductImage(c echo.Context) error {
+	// Get the acting user; the service checks they own the product
+	actor, err := currentActor(c, h.userService)
+	if err != nil {
+		return SendError(c, http.StatusUnauthorized, err, "user not authenticated")
+	}
+
+	// Parse product and image IDs
+	productID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return SendError(c, http.StatusBadRequest, err, "invalid product ID")
+	}
+
+	imageID, err := uuid.Parse(c.Param("imageId"))
+	if err != nil {
+		return SendError(c, http.StatusBadRequest, err, "invalid image ID")
+	}
+
+	if err := h.productService.DeleteProductImage(c.Request().Context(), actor, productID, imageID); err != nil {
+		return sendProductError(c, err, "failed to delete product image")
+	}
+
+	return SendSuccess(c, http.StatusOK, "product image deleted successfully", nil)
+}
+
+// GetProductVariants retrieves a product's option types and variants
+func (h *ProductHandler) GetProductVariants(c echo.Context) error {
+	productID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return SendError(c, http.StatusBadRequest, err, "invalid product ID")
+	}
+
+	variants, err := h.productService.GetProductVariants(c.Request().Context(), productID)
+	if err != nil {
+		return sendProductError(c, err, "failed to retrieve product variants")
+	}
+
+	return SendSuccess(c, http.StatusOK, "product variants retrieved successfully", variants)
+}
+
+// AddProductOption adds an option type, such as Size, to a product (own products, or admin)
+func (h *ProductHandler) AddProductOption(c echo.Context) error {
+	// Get the acting user; the service checks they own the product
+	actor, err := currentActor(c, h.userService)
+	if err != nil {
+		return SendError(c, http.StatusUnauthorized, err, "user not authenticated")
+	}
+
+	// Parse product ID
+	productID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return SendError(c, http.StatusBadRequest, err, "invalid product ID")
+	}
+
+	// Parse request body
+	var req model.CreateProductOptionRequest
+	if err := c.Bind(&req); err != nil {
+		return SendError(c, http.StatusBadRequest, err, "invalid request body")
+	}
+
+	if err := c.Validate(&req); err != nil {
+		return SendError(c, http.StatusBadRequest, err, "validation failed")
+	}
+
+	option, err := h.productService.AddProductOption(c.Request().Context(), actor, productID, &req)
+	if err != nil {
+		return sendProductError(c, err, "failed to add product option")
+	}
+
+	return SendSuccess(c, http.StatusCreated, "product option added successfully", option)
+}
+
+// DeleteProductOption removes an option type from a product (own products, or admin)
+func (h *ProductHandler) DeleteProductOption(c echo.Context) error {
+	// Get the acting user; the service checks they own the product
+	actor, err := currentActor(c, h.userService)
+	if err != nil {
+		return SendError(c, http.StatusUnauthorized, err, "user not authenticated")
+	}
+
+	// Parse product and option IDs
+	productID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return SendError(c, http.StatusBadRequest, err, "invalid product ID")
+	}
+
+	optionID, err := uuid.Parse(c.Param("optionId"))
+	if err != nil {
+		return SendError(c, http.StatusBadRequest, err, "invalid option ID")
+	}
+
+	if err := h.productService.DeleteProductOption(c.Request().Context(), actor, productID, optionID); err != nil {
+		return sendProductError(c, err, "failed to delete product option")
+	}
+
+	return SendSuccess(c, http.StatusOK, "product option deleted successfully", nil)
+}
+
+// CreateProductVariant adds a variant to a product (own products, or admin)
+func (h *ProductHandler) CreateProductVariant(c echo.Context) error {
+	// Get the acting user; the service checks they own the product
+	actor, err := currentActor(c, h.userService)
+	if err != nil {
+		return SendError(c, http.StatusUnauthorized, err, "user not authenticated")
+	}
+
+	// Parse product ID
+	productID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return SendError(c, http.StatusBadRequest, err, "invalid product ID")
+	}
+
+	// Parse request body
+	var req model.CreateProductVariantRequest
+	if err := c.Bind(&req); err != nil {
+		return SendError(c, http.StatusBadRequest, err, "invalid request body")
+	}
+
+	if err := c.Validate(&req); err != nil {
+		return SendError(c, http.StatusBadRequest, err, "validation failed")
+	}
+
+	variant, err := h.productService.CreateProductVariant(c.Request().Context(), actor, productID, &req)
+	if err != nil {
+		return sendProductError(c, err, "failed to add product variant")
+	}
+
+	return SendSuccess(c, http.StatusCreated, "product variant added successfully", variant)
+}
+
+// UpdateProductVariant updates a product variant (own products, or admin)
+func (h *ProductHandler) UpdateProductVariant(c echo.Context) error {
+	// Get the acting user; the service checks they own the product
+	actor, err := currentActor(c, h.userService)
+	if err != nil {
+		return SendError(c, http.StatusUnauthorized, err, "user not authenticated")
+	}
+
+	// Parse product and variant IDs
+	productID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return SendError(c, http.StatusBadRequest, err, "invalid product ID")
+	}
+
+	variantID, err := uuid.Parse(c.Param("variantId"))
+	if err != nil {
+		return SendError(c, http.StatusBadRequest, err, "invalid variant ID")
+	}
+
+	// Parse request body
+	var req model.UpdateProductVariantRequest
+	if err := c.Bind(&req); err != nil {
+		return SendError(c, http.StatusBadRequest, err, "invalid request body")
+	}
+
+	if err := c.Validate(&req); err != nil {
+		return SendError(c, http.StatusBadRequest, err, "validation failed")
+	}
+
+	variant, err := h.productService.UpdateProductVariant(c.Request().Context(), actor, productID, variantID, &req)
+	if err != nil {
+		return sendProductError(c, err, "failed to update product variant")
+	}
+
+	return SendSuccess(c, http.StatusOK, "product variant updated successfully", variant)
+}
+
+// DeleteProductVariant removes a variant from a product (own products, or admin)
+func (h *ProductHandler) DeleteProductVariant(c echo.Context) error {
+	// Get the acting user; the service checks they own the product
+	actor, err := currentActor(c, h.userService)
+	if err != nil {
+		return SendError(c, http.StatusUnauthorized, err, "user not authenticated")
+	}
+
+	// Parse product and variant IDs
+	productID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return SendError(c, http.StatusBadRequest, err, "invalid product ID")
+	}
+
+	variantID, err := uuid.Parse(c.Param("variantId"))
+	if err != nil {
+		return SendError(c, http.StatusBadRequest, err, "invalid variant ID")
+	}
+
+	if err := h.productService.DeleteProductVariant(c.Request().Context(), actor, productID, variantID); err != nil {
+		return sendProductError(c, err, "failed to delete product variant")
+	}
+
+	return SendSuccess(c, http.StatusOK, "product variant deleted successfully", nil)
+}
+
+// sendProductError maps product, gallery and variant service errors to HTTP status codes
+func sendProductError(c echo.Context, err error, message string) error {
+	if errors.Is(err, service.ErrForbidden) {
+		return SendError(c, http.StatusForbidden, err, "")
+	}
+	if errors.Is(err, service.ErrInvalidVariantOptions) {
+		return SendError(c, http.StatusBadRequest, err, err.Error())
+	}
+	switch err.Error() {
+	case "product not found", "image not found", "option not found", "variant not found":
+		return SendError(c, http.StatusNotFound, err, "")
+	case "image_ids must list every product image exactly once", "delete the product's variants before changing its options", "sku cannot be empty":
+		return SendError(c, http.StatusBadRequest, err, "")
+	case "option already exists", "a variant with these options already exists", "sku already exists":
+		return SendError(c, http.StatusConflict, err, err.Error())
+	}
+	return SendError(c, http.StatusInternalServerError, err, message)
+}
@@ -0,0 +1,114 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/imbivek08/hamropasal/internal/model"
+	"github.com/imbivek08/hamropasal/internal/payment"
+	"github.com/imbivek08/hamropasal/internal/service"
+	"github.com/labstack/echo/v4"
+)
+
+type RefundHandler struct {
+	refundService *service.RefundService
+	userService   *service.UserService
+}
+
+func NewRefundHandler(refundService *service.RefundService, userService *service.UserService) *RefundHandler {
+	return &RefundHandler{
+		refundService: refundService,
+		userService:   userService,
+	}
+}
+
+// CreateRefund refunds a paid order, fully or per line item
+func (h *RefundHandler) CreateRefund(c echo.Context) error {
+	actor, err := currentActor(c, h.userService)
+	if err != nil {
+		return SendError(c, http.StatusUnauthorized, err, "user not found in context")
+	}
+
+	orderID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return SendError(c, http.StatusBadRequest, err, "invalid order ID")
+	}
+
+	var req model.CreateRefundRequest
+	if err := c.Bind(&req); err != nil {
+		return SendError(c, http.StatusBadRequest, err, "invalid request body")
+	}
+
+	if err := c.Validate(&req); err != nil {
+		return SendError(c, http.StatusBadRequest, err, "validation failed")
+	}
+
+	shopID, err := refundScope(actor)
+	if err != nil {
+		return SendError(c, http.StatusNotFound, err, err.Error())
+	}
+
+	refund, err := h.refundService.CreateRefund(c.Request().Context(), orderID, shopID, actor.UserID, &req)
+	if err != nil {
+		switch {
+		case err.Error() == "order not found":
+			return SendError(c, http.StatusNotFound, err, err.Error())
+		case err.Error() == "only paid orders can be refunded", err.Error() == "nothing left to refund":
+			return SendError(c, http.StatusConflict, err, err.Error())
+		case errors.Is(err, payment.ErrUnknownMethod):
+			return SendError(c, http.StatusConflict, err, "the order's payment method cannot be refunded")
+		case strings.HasPrefix(err.Error(), "order item"), strings.HasPrefix(err.Error(), "cannot refund"):
+			return SendError(c, http.StatusBadRequest, err, err.Error())
+		case errors.Is(err, payment.ErrRefundUnsupported):
+			return SendError(c, http.StatusConflict, err, "the payment method cannot refund automatically, refund the customer directly")
+		case strings.HasPrefix(err.Error(), "refund failed"):
+			return SendError(c, http.StatusBadGateway, err, "payment provider rejected the refund")
+		default:
+			return SendInternalError(c, err)
+		}
+	}
+
+	return SendSuccess(c, http.StatusCreated, "refund created successfully", refund)
+}
+
+// GetOrderRefunds lists the refunds of an order
+func (h *RefundHandler) GetOrderRefunds(c echo.Context) error {
+	actor, err := currentActor(c, h.userService)
+	if err != nil {
+		return SendError(c, http.StatusUnauthorized, err, "user not found in context")
+	}
+
+	orderID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return SendError(c, http.StatusBadRequest, err, "invalid order ID")
+	}
+
+	shopID, err := refundScope(actor)
+	if err != nil {
+		return SendError(c, http.StatusNotFound, err, err.Error())
+	}
+
+	refunds, err := h.refundService.GetOrderRefunds(c.Request().Context(), orderID, shopID)
+	if err != nil {
+		if err.Error() == "order not found" {
+			return SendError(c, http.StatusNotFound, err, err.Error())
+		}
+		return SendInternalError(c, err)
+	}
+
+	return SendSuccess(c, http.StatusOK, "refunds retrieved successfully", refunds)
+}
+
+// refundScope returns nil for admins, who may refund any order, and the
+// vendor's shop otherwise
+func refundScope(actor *service.Actor) (*uuid.UUID, error) {
+	if actor.IsAdmin() {
+		return nil, nil
+	}
+	if actor.ShopID == nil {
+		return nil, errors.New("no shop found for vendor")
+	}
+	return actor.ShopID, nil
+}
@@ -1,6 +1,7 @@
 package handler
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
 	"strconv"
@@ -122,16 +123,10 @@ func (h *ReviewHandler) GetProductRatingStats(c echo.Context) error {
 
 // UpdateReview updates an existing review
 func (h *ReviewHandler) UpdateReview(c echo.Context) error {
-	// Get Clerk user ID from middleware
-	clerkID := middleware.GetClerkUserID(c)
-	if clerkID == "" {
-		return SendError(c, http.StatusUnauthorized, nil, "user not authenticated")
-	}
-
-	// Get user from database
-	user, err := h.userService.GetUserByClerkID(c.Request().Context(), clerkID)
+	// Get the acting user; the service checks they wrote the review
+	actor, err := currentActor(c, h.userService)
 	if err != nil {
-		return SendError(c, http.StatusNotFound, err, "user not found")
+		return SendError(c, http.StatusUnauthorized, err, "user not authenticated")
 	}
 
 	reviewID, err := uuid.Parse(c.Param("id"))
@@ -151,9 +146,9 @@ func (h *ReviewHandler) UpdateReview(c echo.Context) error {
 	}
 
 	// Update review
-	err = h.reviewService.UpdateReview(c.Request().Context(), user.ID, reviewID, &req)
+	err = h.reviewService.UpdateReview(c.Request().Context(), actor, reviewID, &req)
 	if err != nil {
-		return SendError(c, http.StatusBadRequest, err, err.Error())
+		return sendReviewError(c, err)
 	}
 
 	return SendSuccess(c, http.StatusOK, "review updated successfully", nil)
@@ -161,16 +156,10 @@ func (h *ReviewHandler) UpdateReview(c echo.Context) error {
 
 // DeleteReview deletes a review
 func (h *ReviewHandler) DeleteReview(c echo.Context) error {
-	// Get Clerk user ID from middleware
-	clerkID := middleware.GetClerkUserID(c)
-	if clerkID == "" {
-		return SendError(c, http.StatusUnauthorized, nil, "user not authenticated")
-	}
-
-	// Get user from database
-	user, err := h.userService.GetUserByClerkID(c.Request().Context(), clerkID)
+	// Get the acting user; the service checks they wrote the review
+	actor, err := currentActor(c, h.userService)
 	if err != nil {
-		return SendError(c, http.StatusNotFound, err, "user not found")
+		return SendError(c, http.StatusUnauthorized, err, "user not authenticated")
 	}
 
 	reviewID, err := uuid.Parse(c.Param("id"))
@@ -179,9 +168,9 @@ func (h *ReviewHandler) DeleteReview(c echo.Context) error {
 	}
 
 	// Delete review
-	err = h.reviewService.DeleteReview(c.Request().Context(), user.ID, reviewID)
+	err = h.reviewService.DeleteReview(c.Request().Context(), actor, reviewID)
 	if err != nil {
-		return SendError(c, http.StatusBadRequest, err, err.Error())
+		return sendReviewError(c, err)
 	}
 
 	return SendSuccess(c, http.StatusOK, "review deleted successfully", nil)
@@ -228,3 +217,14 @@ func (h *ReviewHandler) CanUserReviewProduct(c echo.Context) error {
 
 	return SendSuccess(c, http.StatusOK, "check completed", response)
 }
+
+// sendReviewError maps review mutation errors to HTTP status codes
+func sendReviewError(c echo.Context, err error) error {
+	if errors.Is(err, service.ErrForbidden) {
+		return SendError(c, http.StatusForbidden, err, err.Error())
+	}
+	if err.Error() == "review not found" {
+		return SendError(c, http.StatusNotFound, err, err.Error())
+	}
+	return SendError(c, http.StatusBadRequest, err, err.Error())
+}
@@ -0,0 +1,199 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/imbivek08/hamropasal/internal/model"
+	"github.com/imbivek08/hamropasal/internal/service"
+	"github.com/labstack/echo/v4"
+)
+
+type ShippingHandler struct {
+	shippingService *service.ShippingService
+	userService     *service.UserService
+}
+
+func NewShippingHandler(shippingService *service.ShippingService, userService *service.UserService) *ShippingHandler {
+	return &ShippingHandler{
+		shippingService: shippingService,
+		userService:     userService,
+	}
+}
+
+// CreateZone creates a shipping zone (admin only)
+// POST /api/v1/shipping/zones
+func (h *ShippingHandler) CreateZone(c echo.Context) error {
+	var req model.CreateShippingZoneRequest
+	if err := c.Bind(&req); err != nil {
+		return SendError(c, http.StatusBadRequest, err, "invalid request body")
+	}
+
+	if err := c.Validate(&req); err != nil {
+		return SendError(c, http.StatusBadRequest, err, "validation failed")
+	}
+
+	zone, err := h.shippingService.CreateZone(c.Request().Context(), &req)
+	if err != nil {
+		return SendError(c, http.StatusInternalServerError, err, "failed to create shipping zone")
+	}
+
+	return SendSuccess(c, http.StatusCreated, "shipping zone created successfully", zone)
+}
+
+// ListZones lists the shipping zones shops can set up methods for
+// GET /api/v1/shipping/zones
+func (h *ShippingHandler) ListZones(c echo.Context) error {
+	zones, err := h.shippingService.ListZones(c.Request().Context())
+	if err != nil {
+		return SendError(c, http.StatusInternalServerError, err, "failed to get shipping zones")
+	}
+
+	return SendSuccess(c, http.StatusOK, "shipping zones retrieved successfully", zones)
+}
+
+// UpdateZone changes a shipping zone (admin only)
+// PUT /api/v1/shipping/zones/:id
+func (h *ShippingHandler) UpdateZone(c echo.Context) error {
+	zoneID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return SendError(c, http.StatusBadRequest, err, "invalid shipping zone ID")
+	}
+
+	var req model.UpdateShippingZoneRequest
+	if err := c.Bind(&req); err != nil {
+		return SendError(c, http.StatusBadRequest, err, "invalid request body")
+	}
+
+	if err := c.Validate(&req); err != nil {
+		return SendError(c, http.StatusBadRequest, err, "validation failed")
+	}
+
+	zone, err := h.shippingService.UpdateZone(c.Request().Context(), zoneID, &req)
+	if err != nil {
+		return sendShippingError(c, err, "failed to update shipping zone")
+	}
+
+	return SendSuccess(c, http.StatusOK, "shipping zone updated successfully", zone)
+}
+
+// DeleteZone deletes a shipping zone and the methods for it (admin only)
+// DELETE /api/v1/shipping/zones/:id
+func (h *ShippingHandler) DeleteZone(c echo.Context) error {
+	zoneID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return SendError(c, http.StatusBadRequest, err, "invalid shipping zone ID")
+	}
+
+	if err := h.shippingService.DeleteZone(c.Request().Context(), zoneID); err != nil {
+		return sendShippingError(c, err, "failed to delete shipping zone")
+	}
+
+	return SendSuccess(c, http.StatusOK, "shipping zone deleted successfully", nil)
+}
+
+// CreateMethod creates a shipping method for the vendor's shop, or any shop for admins
+// POST /api/v1/shipping/methods
+func (h *ShippingHandler) CreateMethod(c echo.Context) error {
+	actor, err := currentActor(c, h.userService)
+	if err != nil {
+		return SendError(c, http.StatusUnauthorized, err, "user not authenticated")
+	}
+
+	var req model.CreateShippingMethodRequest
+	if err := c.Bind(&req); err != nil {
+		return SendError(c, http.StatusBadRequest, err, "invalid request body")
+	}
+
+	if err := c.Validate(&req); err != nil {
+		return SendError(c, http.StatusBadRequest, err, "validation failed")
+	}
+
+	method, err := h.shippingService.CreateMethod(c.Request().Context(), actor, &req)
+	if err != nil {
+		return sendShippingError(c, err, "failed to create shipping method")
+	}
+
+	return SendSuccess(c, http.StatusCreated, "shipping method created successfully", method)
+}
+
+// ListMethods lists the shipping methods the caller manages
+// GET /api/v1/shipping/methods
+func (h *ShippingHandler) ListMethods(c echo.Context) error {
+	actor, err := currentActor(c, h.userService)
+	if err != nil {
+		return SendError(c, http.StatusUnauthorized, err, "user not authenticated")
+	}
+
+	methods, err := h.shippingService.ListMethods(c.Request().Context(), actor)
+	if err != nil {
+		return SendError(c, http.StatusInternalServerError, err, "failed to get shipping methods")
+	}
+
+	return SendSuccess(c, http.StatusOK, "shipping methods retrieved successfully", methods)
+}
+
+// UpdateMethod changes a shipping method's rates
+// PUT /api/v1/shipping/methods/:id
+func (h *ShippingHandler) UpdateMethod(c echo.Context) error {
+	actor, err := currentActor(c, h.userService)
+	if err != nil {
+		return SendError(c, http.StatusUnauthorized, err, "user not authenticated")
+	}
+
+	methodID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return SendError(c, http.StatusBadRequest, err, "invalid shipping method ID")
+	}
+
+	var req model.UpdateShippingMethodRequest
+	if err := c.Bind(&req); err != nil {
+		return SendError(c, http.StatusBadRequest, err, "invalid request body")
+	}
+
+	if err := c.Validate(&req); err != nil {
+		return SendError(c, http.StatusBadRequest, err, "validation failed")
+	}
+
+	method, err := h.shippingService.UpdateMethod(c.Request().Context(), actor, methodID, &req)
+	if err != nil {
+		return sendShippingError(c, err, "failed to update shipping method")
+	}
+
+	return SendSuccess(c, http.StatusOK, "shipping method updated successfully", method)
+}
+
+// DeleteMethod deletes a shipping method
+// DELETE /api/v1/shipping/methods/:id
+func (h *ShippingHandler) DeleteMethod(c echo.Context) error {
+	actor, err := currentActor(c, h.userService)
+	if err != nil {
+		return SendError(c, http.StatusUnauthorized, err, "user not authenticated")
+	}
+
+	methodID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return SendError(c, http.StatusBadRequest, err, "invalid shipping method ID")
+	}
+
+	if err := h.shippingService.DeleteMethod(c.Request().Context(), actor, methodID); err != nil {
+		return sendShippingError(c, err, "failed to delete shipping method")
+	}
+
+	return SendSuccess(c, http.StatusOK, "shipping method deleted successfully", nil)
+}
+
+// sendShippingError maps shipping service errors to HTTP status codes
+func sendShippingError(c echo.Context, err error, message string) error {
+	if errors.Is(err, service.ErrForbidden) {
+		return SendError(c, http.StatusForbidden, err, err.Error())
+	}
+	switch err.Error() {
+	case "shipping zone not found", "shipping method not found":
+		return SendError(c, http.StatusNotFound, err, err.Error())
+	case "shop_id is required", "weight methods need a base_rate or per_kg_rate", "min_days cannot exceed max_days":
+		return SendError(c, http.StatusBadRequest, err, err.Error())
+	}
+	return SendError(c, http.StatusInternalServerError, err, message)
+}
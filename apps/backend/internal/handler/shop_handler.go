@@ -1,6 +1,7 @@
 package handler
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
 
@@ -14,11 +15,13 @@ import (
 
 type ShopHandler struct {
 	shopService *service.ShopService
+	userService *service.UserService
 }
 
-func NewShopHandler(shopService *service.ShopService) *ShopHandler {
+func NewShopHandler(shopService *service.ShopService, userService *service.UserService) *ShopHandler {
 	return &ShopHandler{
 		shopService: shopService,
+		userService: userService,
 	}
 }
 
@@ -51,7 +54,7 @@ func (h *ShopHandler) CreateShop(c echo.Context) error {
 	// Create shop
 	shop, err := h.shopService.CreateShop(c.Request().Context(), user.ID, &req)
 	if err != nil {
-		if err.Error() == "vendor already has a shop" || err.Error() == "user is not a vendor" {
+		if err.Error() == "vendor already has a shop" || err.Error() == "user is not a vendor" || errors.Is(err, model.ErrUnsupportedCurrency) {
 			return SendError(c, http.StatusBadRequest, err, "")
 		}
 		return SendError(c, http.StatusInternalServerError, err, "failed to create shop")
@@ -161,9 +164,9 @@ func (h *ShopHandler) UpdateShop(c echo.Context) error {
 		return SendError(c, http.StatusBadRequest, err, "invalid shop ID")
 	}
 
-	// Get user from context
-	user, ok := c.Get("user").(*model.User)
-	if !ok || user == nil {
+	// Get the acting user; the service checks they own the shop
+	actor, err := currentActor(c, h.userService)
+	if err != nil {
 		return SendError(c, http.StatusUnauthorized, nil, "user not found in context")
 	}
 
@@ -179,14 +182,17 @@ func (h *ShopHandler) UpdateShop(c echo.Context) error {
 	}
 
 	// Update shop
-	shop, err := h.shopService.UpdateShop(c.Request().Context(), shopID, user.ID, &req)
+	shop, err := h.shopService.UpdateShop(c.Request().Context(), actor, shopID, &req)
 	if err != nil {
-		if err.Error() == "unauthorized: you don't own this shop" {
+		if errors.Is(err, service.ErrForbidden) {
 			return SendError(c, http.StatusForbidden, err, "")
 		}
 		if err.Error() == "shop not found" {
 			return SendError(c, http.StatusNotFound, err, "")
 		}
+		if errors.Is(err, model.ErrUnsupportedCurrency) {
+			return SendError(c, http.StatusBadRequest, err, "")
+		}
 		return SendError(c, http.StatusInternalServerError, err, "failed to update shop")
 	}
 
@@ -203,16 +209,16 @@ func (h *ShopHandler) ToggleShopStatus(c echo.Context) error {
 		return SendError(c, http.StatusBadRequest, err, "invalid shop ID")
 	}
 
-	// Get user from context
-	user, ok := c.Get("user").(*model.User)
-	if !ok || user == nil {
+	// Get the acting user; the service checks they own the shop
+	actor, err := currentActor(c, h.userService)
+	if err != nil {
 		return SendError(c, http.StatusUnauthorized, nil, "user not found in context")
 	}
 
 	// Toggle status
-	shop, err := h.shopService.ToggleShopStatus(c.Request().Context(), shopID, user.ID)
+	shop, err := h.shopService.ToggleShopStatus(c.Request().Context(), actor, shopID)
 	if err != nil {
-		if err.Error() == "unauthorized: you don't own this shop" {
+		if errors.Is(err, service.ErrForbidden) {
 			return SendError(c, http.StatusForbidden, err, "")
 		}
 		if err.Error() == "shop not found" {
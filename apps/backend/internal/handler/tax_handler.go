@@ -0,0 +1,98 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/imbivek08/hamropasal/internal/model"
+	"github.com/imbivek08/hamropasal/internal/service"
+	"github.com/labstack/echo/v4"
+)
+
+type TaxHandler struct {
+	taxService *service.TaxService
+}
+
+func NewTaxHandler(taxService *service.TaxService) *TaxHandler {
+	return &TaxHandler{taxService: taxService}
+}
+
+// CreateTaxRate creates a tax rate (admin only)
+// POST /api/v1/tax-rates
+func (h *TaxHandler) CreateTaxRate(c echo.Context) error {
+	var req model.CreateTaxRateRequest
+	if err := c.Bind(&req); err != nil {
+		return SendError(c, http.StatusBadRequest, err, "invalid request body")
+	}
+
+	if err := c.Validate(&req); err != nil {
+		return SendError(c, http.StatusBadRequest, err, "validation failed")
+	}
+
+	rate, err := h.taxService.CreateRate(c.Request().Context(), &req)
+	if err != nil {
+		if err.Error() == "category not found" {
+			return SendError(c, http.StatusBadRequest, err, err.Error())
+		}
+		return SendError(c, http.StatusInternalServerError, err, "failed to create tax rate")
+	}
+
+	return SendSuccess(c, http.StatusCreated, "tax rate created successfully", rate)
+}
+
+// ListTaxRates lists every tax rate (admin only)
+// GET /api/v1/tax-rates
+func (h *TaxHandler) ListTaxRates(c echo.Context) error {
+	rates, err := h.taxService.ListRates(c.Request().Context())
+	if err != nil {
+		return SendError(c, http.StatusInternalServerError, err, "failed to get tax rates")
+	}
+
+	return SendSuccess(c, http.StatusOK, "tax rates retrieved successfully", rates)
+}
+
+// UpdateTaxRate changes a tax rate (admin only)
+// PUT /api/v1/tax-rates/:id
+func (h *TaxHandler) UpdateTaxRate(c echo.Context) error {
+	rateID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return SendError(c, http.StatusBadRequest, err, "invalid tax rate ID")
+	}
+
+	var req model.UpdateTaxRateRequest
+	if err := c.Bind(&req); err != nil {
+		return SendError(c, http.StatusBadRequest, err, "invalid request body")
+	}
+
+	if err := c.Validate(&req); err != nil {
+		return SendError(c, http.StatusBadRequest, err, "validation failed")
+	}
+
+	rate, err := h.taxService.UpdateRate(c.Request().Context(), rateID, &req)
+	if err != nil {
+		if err.Error() == "tax rate not found" {
+			return SendError(c, http.StatusNotFound, err, err.Error())
+		}
+		return SendError(c, http.StatusInternalServerError, err, "failed to update tax rate")
+	}
+
+	return SendSuccess(c, http.StatusOK, "tax rate updated successfully", rate)
+}
+
+// DeleteTaxRate deletes a tax rate (admin only)
+// DELETE /api/v1/tax-rates/:id
+func (h *TaxHandler) DeleteTaxRate(c echo.Context) error {
+	rateID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return SendError(c, http.StatusBadRequest, err, "invalid tax rate ID")
+	}
+
+	if err := h.taxService.DeleteRate(c.Request().Context(), rateID); err != nil {
+		if err.Error() == "tax rate not found" {
+			return SendError(c, http.StatusNotFound, err, err.Error())
+		}
+		return SendError(c, http.StatusInternalServerError, err, "failed to delete tax rate")
+	}
+
+	return SendSuccess(c, http.StatusOK, "tax rate deleted successfully", nil)
+}
@@ -2,20 +2,27 @@ package handler
 
 import (
 	"encoding/json"
+	"errors"
+	"io"
 	"net/http"
 
 	"github.com/imbivek08/hamropasal/internal/model"
 	"github.com/imbivek08/hamropasal/internal/service"
+	"github.com/imbivek08/hamropasal/internal/svix"
 	"github.com/labstack/echo/v4"
 )
 
 type WebhookHandler struct {
 	userService *service.UserService
+	verifier    *svix.Verifier
 }
 
-func NewWebhookHandler(userService *service.UserService) *WebhookHandler {
+// NewWebhookHandler creates the Clerk webhook handler. A nil verifier (no
+// valid signing secret configured) makes every delivery fail closed.
+func NewWebhookHandler(userService *service.UserService, verifier *svix.Verifier) *WebhookHandler {
 	return &WebhookHandler{
 		userService: userService,
+		verifier:    verifier,
 	}
 }
 
@@ -42,24 +49,61 @@ type ClerkEmailAddress struct {
 	EmailAddress string `json:"email_address"`
 }
 
-// HandleClerkWebhook processes Clerk webhooks
+// HandleClerkWebhook processes Clerk webhooks.
+// Deliveries are authenticated with their Svix signature over the raw body
+// (saved by the saveRawBody middleware) and each svix-id is accepted once.
 func (h *WebhookHandler) HandleClerkWebhook(c echo.Context) error {
-	// Get the webhook signature from headers
-	svixID := c.Request().Header.Get("svix-id")
-	svixTimestamp := c.Request().Header.Get("svix-timestamp")
-	svixSignature := c.Request().Header.Get("svix-signature")
+	if h.verifier == nil {
+		return SendError(c, http.StatusInternalServerError, nil, "webhook verification is not configured")
+	}
+
+	// Read raw body from context (saved by saveRawBody middleware)
+	body, ok := c.Get("raw_body").([]byte)
+	if !ok {
+		var err error
+		body, err = io.ReadAll(c.Request().Body)
+		if err != nil {
+			return SendError(c, http.StatusBadRequest, err, "failed to read request body")
+		}
+	}
 
-	if svixID == "" || svixTimestamp == "" || svixSignature == "" {
-		return SendError(c, http.StatusBadRequest, nil, "missing webhook headers")
+	ctx := c.Request().Context()
+
+	// Verify signature, timestamp and replay
+	msgID, err := h.verifier.Verify(ctx, c.Request().Header, body)
+	if err != nil {
+		switch {
+		case errors.Is(err, svix.ErrReplayed):
+			return SendSuccess(c, http.StatusOK, "webhook already processed", nil)
+		case errors.Is(err, svix.ErrMissingHeaders):
+			return SendError(c, http.StatusBadRequest, err, "missing webhook headers")
+		case errors.Is(err, svix.ErrInvalidTimestamp),
+			errors.Is(err, svix.ErrTimestampExpired),
+			errors.Is(err, svix.ErrInvalidSignature):
+			return SendError(c, http.StatusUnauthorized, err, "invalid webhook signature")
+		}
+		return SendInternalError(c, err)
 	}
 
-	// Bind the webhook data
+	// Parse the webhook data
 	var event ClerkWebhookEvent
-	if err := c.Bind(&event); err != nil {
+	if err := json.Unmarshal(body, &event); err != nil {
+		h.forget(c, msgID)
 		return SendError(c, http.StatusBadRequest, err, "invalid webhook payload")
 	}
 
-	// Handle different event types
+	err = h.dispatch(c, &event)
+
+	// Let Svix retry deliveries that were not processed
+	if err != nil || c.Response().Status >= http.StatusBadRequest {
+		h.forget(c, msgID)
+	}
+
+	return err
+}
+
+// dispatch handles the different event types
+func (h *WebhookHandler) dispatch(c echo.Context, event *ClerkWebhookEvent) error {
 	switch event.Type {
 	case "user.created":
 		return h.handleUserCreated(c, event.Data)
@@ -73,6 +117,14 @@ func (h *WebhookHandler) HandleClerkWebhook(c echo.Context) error {
 	}
 }
 
+// forget drops a message from the replay store; failing to do so only means
+// Svix retries of that message are acknowledged without processing
+func (h *WebhookHandler) forget(c echo.Context, msgID string) {
+	if err := h.verifier.Forget(c.Request().Context(), msgID); err != nil {
+		c.Logger().Errorf("failed to forget webhook %s: %v", msgID, err)
+	}
+}
+
 func (h *WebhookHandler) handleUserCreated(c echo.Context, data json.RawMessage) error {
 	var userData ClerkUserData
 	if err := json.Unmarshal(data, &userData); err != nil {
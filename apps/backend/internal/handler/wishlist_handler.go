@@ -0,0 +1,146 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/imbivek08/hamropasal/internal/middleware"
+	"github.com/imbivek08/hamropasal/internal/model"
+	"github.com/imbivek08/hamropasal/internal/service"
+	"github.com/labstack/echo/v4"
+)
+
+type WishlistHandler struct {
+	wishlistService *service.WishlistService
+	userService     *service.UserService
+}
+
+func NewWishlistHandler(wishlistService *service.WishlistService, userService *service.UserService) *WishlistHandler {
+	return &WishlistHandler{
+		wishlistService: wishlistService,
+		userService:     userService,
+	}
+}
+
+// GetWishlist retrieves user's wishlist
+func (h *WishlistHandler) GetWishlist(c echo.Context) error {
+	// Get Clerk user ID from middleware
+	clerkUserID := middleware.GetClerkUserID(c)
+	if clerkUserID == "" {
+		return SendError(c, http.StatusUnauthorized, nil, "user not authenticated")
+	}
+
+	// Get internal user
+	user, err := h.userService.GetUserByClerkID(c.Request().Context(), clerkUserID)
+	if err != nil {
+		return SendError(c, http.StatusNotFound, err, "user not found")
+	}
+
+	wishlist, err := h.wishlistService.GetWishlist(c.Request().Context(), user.ID)
+	if err != nil {
+		return SendError(c, http.StatusInternalServerError, err, "failed to get wishlist")
+	}
+
+	return SendSuccess(c, http.StatusOK, "wishlist retrieved successfully", wishlist)
+}
+
+// AddToWishlist adds a product to wishlist
+func (h *WishlistHandler) AddToWishlist(c echo.Context) error {
+	// Get Clerk user ID from middleware
+	clerkUserID := middleware.GetClerkUserID(c)
+	if clerkUserID == "" {
+		return SendError(c, http.StatusUnauthorized, nil, "user not authenticated")
+	}
+
+	// Get internal user
+	user, err := h.userService.GetUserByClerkID(c.Request().Context(), clerkUserID)
+	if err != nil {
+		return SendError(c, http.StatusNotFound, err, "user not found")
+	}
+
+	// Parse request
+	var req model.AddToWishlistRequest
+	if err := c.Bind(&req); err != nil {
+		return SendError(c, http.StatusBadRequest, err, "invalid request body")
+	}
+
+	// Validate request
+	if err := c.Validate(&req); err != nil {
+		return SendError(c, http.StatusBadRequest, err, "validation failed")
+	}
+
+	item, err := h.wishlistService.AddToWishlist(c.Request().Context(), user.ID, &req)
+	if err != nil {
+		return SendError(c, http.StatusBadRequest, err, err.Error())
+	}
+
+	return SendSuccess(c, http.StatusCreated, "product added to wishlist", item)
+}
+
+// RemoveFromWishlist removes a product from wishlist
+func (h *WishlistHandler) RemoveFromWishlist(c echo.Context) error {
+	// Get Clerk user ID from middleware
+	clerkUserID := middleware.GetClerkUserID(c)
+	if clerkUserID == "" {
+		return SendError(c, http.StatusUnauthorized, nil, "user not authenticated")
+	}
+
+	// Get internal user
+	user, err := h.userService.GetUserByClerkID(c.Request().Context(), clerkUserID)
+	if err != nil {
+		return SendError(c, http.StatusNotFound, err, "user not found")
+	}
+
+	// Parse product ID
+	productID, err := uuid.Parse(c.Param("productId"))
+	if err != nil {
+		return SendError(c, http.StatusBadRequest, err, "invalid product ID")
+	}
+
+	if err := h.wishlistService.RemoveFromWishlist(c.Request().Context(), user.ID, productID); err != nil {
+		return SendError(c, http.StatusNotFound, err, err.Error())
+	}
+
+	return SendSuccess(c, http.StatusOK, "product removed from wishlist", nil)
+}
+
+// MoveToCart moves a wishlist product into the cart
+func (h *WishlistHandler) MoveToCart(c echo.Context) error {
+	// Get Clerk user ID from middleware
+	clerkUserID := middleware.GetClerkUserID(c)
+	if clerkUserID == "" {
+		return SendError(c, http.StatusUnauthorized, nil, "user not authenticated")
+	}
+
+	// Get internal user
+	user, err := h.userService.GetUserByClerkID(c.Request().Context(), clerkUserID)
+	if err != nil {
+		return SendError(c, http.StatusNotFound, err, "user not found")
+	}
+
+	// Parse product ID
+	productID, err := uuid.Parse(c.Param("productId"))
+	if err != nil {
+		return SendError(c, http.StatusBadRequest, err, "invalid product ID")
+	}
+
+	// Quantity is optional and defaults to 1
+	var req model.MoveToCartRequest
+	if err := c.Bind(&req); err != nil {
+		return SendError(c, http.StatusBadRequest, err, "invalid request body")
+	}
+
+	if err := c.Validate(&req); err != nil {
+		return SendError(c, http.StatusBadRequest, err, "validation failed")
+	}
+
+	item, err := h.wishlistService.MoveToCart(c.Request().Context(), user.ID, productID, req.VariantID, req.Quantity)
+	if err != nil {
+		if err.Error() == "wishlist item not found" {
+			return SendError(c, http.StatusNotFound, err, "")
+		}
+		return SendError(c, http.StatusBadRequest, err, err.Error())
+	}
+
+	return SendSuccess(c, http.StatusOK, "product moved to cart", item)
+}
@@ -0,0 +1,218 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/imbivek08/hamropasal/internal/model"
+	"github.com/imbivek08/hamropasal/internal/repository"
+	"github.com/jackc/pgx/v5"
+)
+
+const (
+	// defaultMaxAttempts is how often a job runs before it is dead
+	defaultMaxAttempts = 5
+	// jobTimeout bounds a single run; it stays under the time after which a
+	// running job counts as abandoned
+	jobTimeout = 5 * time.Minute
+	// retryBaseDelay and retryMaxDelay bound the exponential backoff between
+	// attempts
+	retryBaseDelay = 30 * time.Second
+	retryMaxDelay  = time.Hour
+)
+
+// ErrUnknownJobType is returned when queueing a job no handler is registered for
+var ErrUnknownJobType = errors.New("unknown job type")
+
+// Handler runs a job with its JSON payload
+type Handler func(ctx context.Context, payload json.RawMessage) error
+
+// Queue runs background work stored in the jobs table. Jobs are queued with
+// Enqueue, inside the transaction of the change they follow up on, and run
+// by workers calling Work. A failed job is retried with exponential backoff
+// until it runs out of attempts and is dead.
+type Queue struct {
+	jobRepo  *repository.JobRepository
+	handlers map[string]Handler
+}
+
+func NewQueue(jobRepo *repository.JobRepository) *Queue {
+	return &Queue{
+		jobRepo:  jobRepo,
+		handlers: make(map[string]Handler),
+	}
+}
+
+// Register sets the handler of a job type, replacing any registered before
+func (q *Queue) Register(jobType string, handler Handler) {
+	q.handlers[jobType] = handler
+}
+
+// Handle registers a handler taking the job's payload decoded into T
+func Handle[T any](q *Queue, jobType string, handle func(ctx context.Context, payload T) error) {
+	q.Register(jobType, func(ctx context.Context, raw json.RawMessage) error {
+		var payload T
+		if err := json.Unmarshal(raw, &payload); err != nil {
+			return fmt.Errorf("invalid %s payload: %w", jobType, err)
+		}
+		return handle(ctx, payload)
+	})
+}
+
+// Enqueue queues a job to run as soon as a worker is free
+func (q *Queue) Enqueue(ctx context.Context, jobType string, payload interface{}) (*model.Job, error) {
+	return q.EnqueueAt(ctx, jobType, payload, time.Now())
+}
+
+// EnqueueAt queues a job to run from runAt. Inside Database.WithTx the job is
+// only queued if the transaction commits.
+func (q *Queue) EnqueueAt(ctx context.Context, jobType string, payload interface{}, runAt time.Time) (*model.Job, error) {
+	if _, ok := q.handlers[jobType]; !ok {
+		return nil, fmt.Errorf("%w %q", ErrUnknownJobType, jobType)
+	}
+
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s payload: %w", jobType, err)
+	}
+
+	job := &model.Job{
+		ID:          uuid.New(),
+		Type:        jobType,
+		Payload:     data,
+		MaxAttempts: defaultMaxAttempts,
+		RunAt:       runAt,
+	}
+	if err := q.jobRepo.Create(ctx, job); err != nil {
+		return nil, fmt.Errorf("failed to queue %s job: %w", jobType, err)
+	}
+
+	return job, nil
+}
+
+// Work runs due jobs one at a time until none is left or ctx is cancelled.
+// Each worker is a scheduler job calling it; workers, on this server or
+// others, never claim the same job.
+func (q *Queue) Work(ctx context.Context) error {
+	for ctx.Err() == nil {
+		job, err := q.jobRepo.ClaimNext(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to claim job: %w", err)
+		}
+		if job == nil {
+			return nil
+		}
+
+		if err := q.run(ctx, job); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// run runs a claimed job and records the outcome
+func (q *Queue) run(ctx context.Context, job *model.Job) error {
+	var err error
+	handler, ok := q.handlers[job.Type]
+	if ok {
+		runCtx, cancel := context.WithTimeout(ctx, jobTimeout)
+		err = safeRun(runCtx, handler, job.Payload)
+		cancel()
+	}
+
+	// Record the outcome even when the server is shutting down mid-run
+	ctx = context.WithoutCancel(ctx)
+
+	switch {
+	case !ok:
+		err = q.jobRepo.MarkDead(ctx, job.ID, fmt.Sprintf("%s %q", ErrUnknownJobType, job.Type))
+	case err == nil:
+		err = q.jobRepo.MarkSucceeded(ctx, job.ID)
+	case job.Attempts >= job.MaxAttempts:
+		log.Printf("[Jobs] %s job %s is dead after %d attempt(s): %v", job.Type, job.ID, job.Attempts, err)
+		err = q.jobRepo.MarkDead(ctx, job.ID, err.Error())
+	default:
+		err = q.jobRepo.MarkForRetry(ctx, job.ID, time.Now().Add(backoff(job.Attempts)), err.Error())
+	}
+	if err != nil {
+		return fmt.Errorf("failed to record %s job %s: %w", job.Type, job.ID, err)
+	}
+	return nil
+}
+
+// safeRun runs a handler, turning a panic into the run's error
+func safeRun(ctx context.Context, handler Handler, payload json.RawMessage) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	return handler(ctx, payload)
+}
+
+// backoff is the delay after a failed attempt: it doubles with every attempt,
+// up to retryMaxDelay
+func backoff(attempt int) time.Duration {
+	delay := float64(retryBaseDelay) * math.Pow(2, float64(attempt-1))
+	if delay > float64(retryMaxDelay) {
+		return retryMaxDelay
+	}
+	return time.Duration(delay)
+}
+
+// List lists jobs, newest first, optionally by status and type (admin only)
+func (q *Queue) List(ctx context.Context, status, jobType string, page, pageSize int) (*model.JobListResponse, error) {
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	jobs, total, err := q.jobRepo.List(ctx, status, jobType, page, pageSize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list jobs: %w", err)
+	}
+
+	return &model.JobListResponse{
+		Jobs:       jobs,
+		Total:      total,
+		Page:       page,
+		PageSize:   pageSize,
+		TotalPages: int(math.Ceil(float64(total) / float64(pageSize))),
+	}, nil
+}
+
+// Get retrieves a job (admin only)
+func (q *Queue) Get(ctx context.Context, id uuid.UUID) (*model.Job, error) {
+	job, err := q.jobRepo.GetByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, errors.New("job not found")
+		}
+		return nil, fmt.Errorf("failed to get job: %w", err)
+	}
+	return job, nil
+}
+
+// Retry makes a dead or pending job due now, with its attempts reset (admin only)
+func (q *Queue) Retry(ctx context.Context, id uuid.UUID) (*model.Job, error) {
+	job, ok, err := q.jobRepo.Requeue(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retry job: %w", err)
+	}
+	if !ok {
+		current, err := q.Get(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		return nil, fmt.Errorf("job is %s", current.Status)
+	}
+	return job, nil
+}
@@ -0,0 +1,70 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBackoffDoublesUpToMax(t *testing.T) {
+	tests := []struct {
+		attempt int
+		want    time.Duration
+	}{
+		{1, 30 * time.Second},
+		{2, time.Minute},
+		{3, 2 * time.Minute},
+		{5, 8 * time.Minute},
+		{8, time.Hour},
+		{50, time.Hour},
+	}
+	for _, tt := range tests {
+		if got := backoff(tt.attempt); got != tt.want {
+			t.Errorf("backoff(%d) = %v, want %v", tt.attempt, got, tt.want)
+		}
+	}
+}
+
+func TestHandleDecodesPayload(t *testing.T) {
+	type payload struct {
+		Name string `json:"name"`
+	}
+
+	queue := NewQueue(nil)
+	var got payload
+	Handle(queue, "greet", func(ctx context.Context, p payload) error {
+		got = p
+		return nil
+	})
+
+	if err := queue.handlers["greet"](context.Background(), json.RawMessage(`{"name":"pasal"}`)); err != nil {
+		t.Fatalf("handler() = %v", err)
+	}
+	if got.Name != "pasal" {
+		t.Errorf("payload = %+v, want name pasal", got)
+	}
+
+	err := queue.handlers["greet"](context.Background(), json.RawMessage(`[]`))
+	if err == nil || !strings.Contains(err.Error(), "invalid greet payload") {
+		t.Errorf("handler(bad payload) = %v, want invalid payload error", err)
+	}
+}
+
+func TestEnqueueRejectsUnknownType(t *testing.T) {
+	_, err := NewQueue(nil).Enqueue(context.Background(), "missing", nil)
+	if !errors.Is(err, ErrUnknownJobType) {
+		t.Errorf("Enqueue() = %v, want ErrUnknownJobType", err)
+	}
+}
+
+func TestSafeRunRecoversPanics(t *testing.T) {
+	err := safeRun(context.Background(), func(ctx context.Context, payload json.RawMessage) error {
+		panic("boom")
+	}, nil)
+	if err == nil || err.Error() != "panic: boom" {
+		t.Errorf("safeRun() = %v, want panic: boom", err)
+	}
+}
@@ -0,0 +1,75 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestSchedulerRunsJobsUntilStopped(t *testing.T) {
+	var runs, panics atomic.Int32
+	scheduler := NewScheduler()
+	scheduler.Add("count", time.Millisecond, func(ctx context.Context) error {
+		runs.Add(1)
+		return errors.New("failed runs are retried")
+	})
+	scheduler.Add("panic", time.Millisecond, func(ctx context.Context) error {
+		panics.Add(1)
+		panic("recovered")
+	})
+
+	scheduler.Start()
+	deadline := time.Now().Add(time.Second)
+	for (runs.Load() < 3 || panics.Load() < 3) && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := scheduler.Stop(ctx); err != nil {
+		t.Fatalf("Stop() = %v", err)
+	}
+	if runs.Load() < 3 || panics.Load() < 3 {
+		t.Fatalf("runs = %d, panics = %d, want both at least 3", runs.Load(), panics.Load())
+	}
+
+	stopped := runs.Load()
+	time.Sleep(5 * time.Millisecond)
+	if runs.Load() != stopped {
+		t.Errorf("job ran after Stop")
+	}
+}
+
+func TestSchedulerStopWaitsForRunningJob(t *testing.T) {
+	started := make(chan struct{})
+	var finished atomic.Bool
+	scheduler := NewScheduler()
+	scheduler.Add("slow", time.Millisecond, func(ctx context.Context) error {
+		if finished.Load() {
+			return nil
+		}
+		close(started)
+		<-ctx.Done()
+		time.Sleep(10 * time.Millisecond)
+		finished.Store(true)
+		return ctx.Err()
+	})
+
+	scheduler.Start()
+	<-started
+
+	if err := scheduler.Stop(context.Background()); err != nil {
+		t.Fatalf("Stop() = %v", err)
+	}
+	if !finished.Load() {
+		t.Error("Stop returned before the running job finished")
+	}
+}
+
+func TestSchedulerStopWithoutStart(t *testing.T) {
+	if err := NewScheduler().Stop(context.Background()); err != nil {
+		t.Errorf("Stop() = %v, want nil", err)
+	}
+}
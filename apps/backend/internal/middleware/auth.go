@@ -29,35 +29,94 @@ func ClerkAuthMiddleware(cfg *config.Config) echo.MiddlewareFunc {
 				return echo.NewHTTPError(http.StatusUnauthorized, "missing authorization header")
 			}
 
-			parts := strings.Split(authHeader, " ")
-			if len(parts) != 2 || parts[0] != "Bearer" {
-				return echo.NewHTTPError(http.StatusUnauthorized, "invalid authorization header format")
+			userID, err := verifySessionToken(c, authHeader)
+			if err != nil {
+				return err
 			}
 
-			sessionToken := parts[1]
+			setClerkUserID(c, userID)
 
-			claims, err := jwt.Verify(c.Request().Context(), &jwt.VerifyParams{
-				Token: sessionToken,
-			})
-			if err != nil {
-				return echo.NewHTTPError(http.StatusUnauthorized, "invalid or expired token")
+			return next(c)
+		}
+	}
+}
+
+// OptionalClerkAuthMiddleware identifies the user when a valid session token is
+// sent, but lets anonymous requests (or invalid tokens) through untouched.
+// Use it on public routes whose response is enriched for signed-in users.
+func OptionalClerkAuthMiddleware(cfg *config.Config) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			authHeader := c.Request().Header.Get("Authorization")
+			if authHeader == "" {
+				return next(c)
 			}
 
-			userID := claims.Subject
-			if userID == "" {
-				return echo.NewHTTPError(http.StatusUnauthorized, "invalid token claims")
+			if userID, err := verifySessionToken(c, authHeader); err == nil {
+				setClerkUserID(c, userID)
 			}
 
-			ctx := context.WithValue(c.Request().Context(), ClerkUserIDKey, userID)
-			c.SetRequest(c.Request().WithContext(ctx))
+			return next(c)
+		}
+	}
+}
 
-			c.Set("clerk_user_id", userID)
+// GuestOrClerkAuthMiddleware identifies the user when an Authorization header
+// is sent and rejects the request when its token fails verification; only
+// requests without the header go through as guests. Use it on routes where
+// treating a signed-in user as a guest would act on the wrong data, such as
+// the cart.
+func GuestOrClerkAuthMiddleware(cfg *config.Config) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			authHeader := c.Request().Header.Get("Authorization")
+			if authHeader == "" {
+				return next(c)
+			}
+
+			userID, err := verifySessionToken(c, authHeader)
+			if err != nil {
+				return err
+			}
+
+			setClerkUserID(c, userID)
 
 			return next(c)
 		}
 	}
 }
 
+// verifySessionToken validates a "Bearer <token>" header and returns the Clerk user ID
+func verifySessionToken(c echo.Context, authHeader string) (string, error) {
+	parts := strings.Split(authHeader, " ")
+	if len(parts) != 2 || parts[0] != "Bearer" {
+		return "", echo.NewHTTPError(http.StatusUnauthorized, "invalid authorization header format")
+	}
+
+	sessionToken := parts[1]
+
+	claims, err := jwt.Verify(c.Request().Context(), &jwt.VerifyParams{
+		Token: sessionToken,
+	})
+	if err != nil {
+		return "", echo.NewHTTPError(http.StatusUnauthorized, "invalid or expired token")
+	}
+
+	userID := claims.Subject
+	if userID == "" {
+		return "", echo.NewHTTPError(http.StatusUnauthorized, "invalid token claims")
+	}
+
+	return userID, nil
+}
+
+func setClerkUserID(c echo.Context, userID string) {
+	ctx := context.WithValue(c.Request().Context(), ClerkUserIDKey, userID)
+	c.SetRequest(c.Request().WithContext(ctx))
+
+	c.Set("clerk_user_id", userID)
+}
+
 func GetClerkUserID(c echo.Context) string {
 	userID, _ := c.Get("clerk_user_id").(string)
 	return userID
@@ -121,16 +180,17 @@ func RequireAdmin() echo.MiddlewareFunc {
 	}
 }
 
-func RequireRole(allowedRoles ...string) echo.MiddlewareFunc {
+// RequireRole middleware ensures the user has one of the allowed roles
+func RequireRole(allowedRoles ...model.UserRole) echo.MiddlewareFunc {
 	return func(next echo.HandlerFunc) echo.HandlerFunc {
 		return func(c echo.Context) error {
-			userRole, ok := c.Get("user_role").(string)
-			if !ok {
-				return echo.NewHTTPError(http.StatusForbidden, "user role not found")
+			user, ok := c.Get("user").(*model.User)
+			if !ok || user == nil {
+				return echo.NewHTTPError(http.StatusUnauthorized, "user not found")
 			}
 
 			for _, role := range allowedRoles {
-				if userRole == role {
+				if user.Role == role {
 					return next(c)
 				}
 			}
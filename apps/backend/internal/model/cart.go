@@ -8,46 +8,80 @@ import (
 
 // Cart represents a shopping cart
 type Cart struct {
-	ID        uuid.UUID  `json:"id" db:"id"`
-	UserID    *uuid.UUID `json:"user_id,omitempty" db:"user_id"`
-	SessionID *string    `json:"session_id,omitempty" db:"session_id"`
-	CreatedAt time.Time  `json:"created_at" db:"created_at"`
-	UpdatedAt time.Time  `json:"updated_at" db:"updated_at"`
-	ExpiresAt *time.Time `json:"expires_at,omitempty" db:"expires_at"`
+	ID         uuid.UUID  `json:"id" db:"id"`
+	UserID     *uuid.UUID `json:"user_id,omitempty" db:"user_id"`
+	SessionID  *string    `json:"session_id,omitempty" db:"session_id"`
+	CouponCode *string    `json:"coupon_code,omitempty" db:"coupon_code"`
+	CreatedAt  time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at" db:"updated_at"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty" db:"expires_at"`
+}
+
+// CartOwner identifies whose cart is being used: a signed-in user or an
+// anonymous shopper holding a guest cart token. Exactly one field is set.
+type CartOwner struct {
+	UserID    *uuid.UUID
+	SessionID *string
+}
+
+// UserCartOwner returns the owner for a signed-in user's cart
+func UserCartOwner(userID uuid.UUID) CartOwner {
+	return CartOwner{UserID: &userID}
+}
+
+// GuestCartOwner returns the owner for an anonymous cart
+func GuestCartOwner(sessionID string) CartOwner {
+	return CartOwner{SessionID: &sessionID}
+}
+
+// IsGuest reports whether the cart belongs to an anonymous shopper
+func (o CartOwner) IsGuest() bool {
+	return o.UserID == nil
 }
 
 // CartItem represents an item in the cart
 type CartItem struct {
-	ID        uuid.UUID `json:"id" db:"id"`
-	CartID    uuid.UUID `json:"cart_id" db:"cart_id"`
-	ProductID uuid.UUID `json:"product_id" db:"product_id"`
-	Quantity  int       `json:"quantity" db:"quantity"`
-	CreatedAt time.Time `json:"created_at" db:"created_at"`
-	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+	ID        uuid.UUID  `json:"id" db:"id"`
+	CartID    uuid.UUID  `json:"cart_id" db:"cart_id"`
+	ProductID uuid.UUID  `json:"product_id" db:"product_id"`
+	VariantID *uuid.UUID `json:"variant_id,omitempty" db:"variant_id"`
+	Quantity  int        `json:"quantity" db:"quantity"`
+	CreatedAt time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at" db:"updated_at"`
 }
 
-// CartItemWithProduct represents a cart item with product details
+// CartItemWithProduct represents a cart item with product details. For a
+// variant, price, image and stock are the variant's.
 type CartItemWithProduct struct {
-	ID              uuid.UUID `json:"id" db:"id"`
-	CartID          uuid.UUID `json:"cart_id" db:"cart_id"`
-	ProductID       uuid.UUID `json:"product_id" db:"product_id"`
-	ProductName     string    `json:"product_name" db:"product_name"`
-	ProductPrice    float64   `json:"product_price" db:"product_price"`
-	ProductImageURL *string   `json:"product_image_url,omitempty" db:"product_image_url"`
-	StockQuantity   int       `json:"stock_quantity" db:"stock_quantity"`
-	IsActive        bool      `json:"is_active" db:"is_active"`
-	ShopID          uuid.UUID `json:"shop_id" db:"shop_id"`
-	ShopName        string    `json:"shop_name" db:"shop_name"`
-	Quantity        int       `json:"quantity" db:"quantity"`
-	Subtotal        float64   `json:"subtotal"`
-	CreatedAt       time.Time `json:"created_at" db:"created_at"`
-	UpdatedAt       time.Time `json:"updated_at" db:"updated_at"`
+	ID              uuid.UUID         `json:"id" db:"id"`
+	CartID          uuid.UUID         `json:"cart_id" db:"cart_id"`
+	ProductID       uuid.UUID         `json:"product_id" db:"product_id"`
+	VariantID       *uuid.UUID        `json:"variant_id,omitempty" db:"variant_id"`
+	VariantSKU      *string           `json:"variant_sku,omitempty" db:"variant_sku"`
+	VariantOptions  map[string]string `json:"variant_options,omitempty" db:"variant_options"`
+	ProductName     string            `json:"product_name" db:"product_name"`
+	ProductPrice    Money             `json:"product_price" db:"product_price"`
+	ProductImageURL *string           `json:"product_image_url,omitempty" db:"product_image_url"`
+	StockQuantity   int               `json:"stock_quantity" db:"stock_quantity"`
+	Weight          *float64          `json:"weight,omitempty" db:"weight"`
+	WeightUnit      string            `json:"weight_unit" db:"weight_unit"`
+	IsActive        bool              `json:"is_active" db:"is_active"`
+	CategoryID      *uuid.UUID        `json:"category_id,omitempty" db:"category_id"`
+	ShopID          uuid.UUID         `json:"shop_id" db:"shop_id"`
+	ShopName        string            `json:"shop_name" db:"shop_name"`
+	ShopCurrency    string            `json:"shop_currency" db:"shop_currency"` // Prices are converted from it
+	Quantity        int               `json:"quantity" db:"quantity"`
+	Subtotal        Money             `json:"subtotal"`
+	CreatedAt       time.Time         `json:"created_at" db:"created_at"`
+	UpdatedAt       time.Time         `json:"updated_at" db:"updated_at"`
 }
 
-// AddToCartRequest represents request to add item to cart
+// AddToCartRequest represents request to add item to cart. Products sold by
+// variant need the variant to add.
 type AddToCartRequest struct {
-	ProductID uuid.UUID `json:"product_id" validate:"required"`
-	Quantity  int       `json:"quantity" validate:"required,min=1"`
+	ProductID uuid.UUID  `json:"product_id" validate:"required"`
+	VariantID *uuid.UUID `json:"variant_id,omitempty"`
+	Quantity  int        `json:"quantity" validate:"required,min=1"`
 }
 
 // UpdateCartItemRequest represents request to update cart item quantity
@@ -55,44 +89,64 @@ type UpdateCartItemRequest struct {
 	Quantity int `json:"quantity" validate:"required,min=1"`
 }
 
-// CartResponse represents the cart with all items
+// CartResponse represents the cart with all items. When a coupon is applied,
+// Discount previews what it is worth; CouponError explains why it is not
+// (anymore) applicable to the cart.
 type CartResponse struct {
-	ID        uuid.UUID             `json:"id"`
-	UserID    *uuid.UUID            `json:"user_id,omitempty"`
-	Items     []CartItemWithProduct `json:"items"`
-	ItemCount int                   `json:"item_count"`
-	Subtotal  float64               `json:"subtotal"`
-	CreatedAt time.Time             `json:"created_at"`
-	UpdatedAt time.Time             `json:"updated_at"`
+	ID           uuid.UUID             `json:"id"`
+	UserID       *uuid.UUID            `json:"user_id,omitempty"`
+	Items        []CartItemWithProduct `json:"items"`
+	Currency     string                `json:"currency"`
+	ItemCount    int                   `json:"item_count"`
+	Subtotal     Money                 `json:"subtotal"`
+	CouponCode   *string               `json:"coupon_code,omitempty"`
+	CouponError  *string               `json:"coupon_error,omitempty"`
+	Discount     Money                 `json:"discount"`
+	FreeShipping bool                  `json:"free_shipping"`
+	Total        Money                 `json:"total"`
+	CreatedAt    time.Time             `json:"created_at"`
+	UpdatedAt    time.Time             `json:"updated_at"`
 }
 
 // CartItemResponse represents a single cart item response
 type CartItemResponse struct {
-	ID              uuid.UUID `json:"id"`
-	ProductID       uuid.UUID `json:"product_id"`
-	ProductName     string    `json:"product_name"`
-	ProductPrice    float64   `json:"product_price"`
-	ProductImageURL *string   `json:"product_image_url,omitempty"`
-	StockQuantity   int       `json:"stock_quantity"`
-	IsActive        bool      `json:"is_active"`
-	ShopID          uuid.UUID `json:"shop_id"`
-	ShopName        string    `json:"shop_name"`
-	Quantity        int       `json:"quantity"`
-	Subtotal        float64   `json:"subtotal"`
-	CreatedAt       time.Time `json:"created_at"`
-	UpdatedAt       time.Time `json:"updated_at"`
+	ID              uuid.UUID         `json:"id"`
+	ProductID       uuid.UUID         `json:"product_id"`
+	VariantID       *uuid.UUID        `json:"variant_id,omitempty"`
+	VariantSKU      *string           `json:"variant_sku,omitempty"`
+	VariantOptions  map[string]string `json:"variant_options,omitempty"`
+	ProductName     string            `json:"product_name"`
+	ProductPrice    Money             `json:"product_price"`
+	ProductImageURL *string           `json:"product_image_url,omitempty"`
+	StockQuantity   int               `json:"stock_quantity"`
+	IsActive        bool              `json:"is_active"`
+	ShopID          uuid.UUID         `json:"shop_id"`
+	ShopName        string            `json:"shop_name"`
+	Quantity        int               `json:"quantity"`
+	Subtotal        Money             `json:"subtotal"`
+	CreatedAt       time.Time         `json:"created_at"`
+	UpdatedAt       time.Time         `json:"updated_at"`
+}
+
+// DisplayName names the item's product, and its variant if it has one
+func (item *CartItemWithProduct) DisplayName() string {
+	if item.VariantID == nil {
+		return item.ProductName
+	}
+	return item.ProductName + " (" + VariantName(item.VariantOptions) + ")"
 }
 
 // CalculateSubtotal calculates subtotal for cart item
 func (item *CartItemWithProduct) CalculateSubtotal() {
-	item.Subtotal = item.ProductPrice * float64(item.Quantity)
+	item.Subtotal = item.ProductPrice.Mul(int64(item.Quantity))
 }
 
 // CalculateTotals calculates total items and subtotal for cart
 func (cart *CartResponse) CalculateTotals() {
 	cart.ItemCount = len(cart.Items)
-	cart.Subtotal = 0
+	cart.Subtotal = Money{}
 	for _, item := range cart.Items {
-		cart.Subtotal += item.Subtotal
+		cart.Subtotal = cart.Subtotal.Add(item.Subtotal)
 	}
+	cart.Total = cart.Subtotal
 }
@@ -0,0 +1,68 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Category represents a product category. Categories form a tree through ParentID.
+type Category struct {
+	ID           uuid.UUID  `json:"id" db:"id"`
+	Name         string     `json:"name" db:"name"`
+	Slug         string     `json:"slug" db:"slug"`
+	Description  *string    `json:"description,omitempty" db:"description"`
+	ParentID     *uuid.UUID `json:"parent_id,omitempty" db:"parent_id"`
+	ImageURL     *string    `json:"image_url,omitempty" db:"image_url"`
+	IsActive     bool       `json:"is_active" db:"is_active"`
+	DisplayOrder int        `json:"display_order" db:"display_order"`
+	CreatedAt    time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at" db:"updated_at"`
+}
+
+type CreateCategoryRequest struct {
+	Name         string     `json:"name" validate:"required,min=2,max=100"`
+	Description  *string    `json:"description,omitempty" validate:"omitempty,max=1000"`
+	ParentID     *uuid.UUID `json:"parent_id,omitempty"`
+	ImageURL     *string    `json:"image_url,omitempty" validate:"omitempty,url"`
+	DisplayOrder int        `json:"display_order" validate:"gte=0"`
+}
+
+type UpdateCategoryRequest struct {
+	Name         *string    `json:"name,omitempty" validate:"omitempty,min=2,max=100"`
+	Description  *string    `json:"description,omitempty" validate:"omitempty,max=1000"`
+	ParentID     *uuid.UUID `json:"parent_id,omitempty"`
+	RemoveParent bool       `json:"remove_parent"` // Move the category to the top level
+	ImageURL     *string    `json:"image_url,omitempty" validate:"omitempty,url"`
+	DisplayOrder *int       `json:"display_order,omitempty" validate:"omitempty,gte=0"`
+	IsActive     *bool      `json:"is_active,omitempty"`
+}
+
+type CategoryResponse struct {
+	ID           uuid.UUID           `json:"id"`
+	Name         string              `json:"name"`
+	Slug         string              `json:"slug"`
+	Description  *string             `json:"description,omitempty"`
+	ParentID     *uuid.UUID          `json:"parent_id,omitempty"`
+	ImageURL     *string             `json:"image_url,omitempty"`
+	IsActive     bool                `json:"is_active"`
+	DisplayOrder int                 `json:"display_order"`
+	Children     []*CategoryResponse `json:"children,omitempty"`
+	CreatedAt    time.Time           `json:"created_at"`
+	UpdatedAt    time.Time           `json:"updated_at"`
+}
+
+func (c *Category) ToResponse() *CategoryResponse {
+	return &CategoryResponse{
+		ID:           c.ID,
+		Name:         c.Name,
+		Slug:         c.Slug,
+		Description:  c.Description,
+		ParentID:     c.ParentID,
+		ImageURL:     c.ImageURL,
+		IsActive:     c.IsActive,
+		DisplayOrder: c.DisplayOrder,
+		CreatedAt:    c.CreatedAt,
+		UpdatedAt:    c.UpdatedAt,
+	}
+}
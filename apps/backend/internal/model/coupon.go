@@ -0,0 +1,88 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type CouponType string
+
+const (
+	CouponTypePercentage   CouponType = "percentage"
+	CouponTypeFixed        CouponType = "fixed"
+	CouponTypeFreeShipping CouponType = "free_shipping"
+)
+
+// Coupon is a promotion code. Without ShopID it applies to the whole cart;
+// with ShopID only that shop's items count towards it.
+type Coupon struct {
+	ID           uuid.UUID  `json:"id" db:"id"`
+	Code         string     `json:"code" db:"code"`
+	Description  *string    `json:"description,omitempty" db:"description"`
+	Type         CouponType `json:"discount_type" db:"discount_type"`
+	Value        float64    `json:"value" db:"value"`
+	MaxDiscount  *Money     `json:"max_discount,omitempty" db:"max_discount"`
+	MinSubtotal  Money      `json:"min_subtotal" db:"min_subtotal"`
+	ShopID       *uuid.UUID `json:"shop_id,omitempty" db:"shop_id"`
+	Currency     string     `json:"currency" db:"-"` // The shop's currency; the default currency for platform coupons
+	UsageLimit   *int       `json:"usage_limit,omitempty" db:"usage_limit"`
+	PerUserLimit *int       `json:"per_user_limit,omitempty" db:"per_user_limit"`
+	UsedCount    int        `json:"used_count" db:"used_count"`
+	StartsAt     time.Time  `json:"starts_at" db:"starts_at"`
+	EndsAt       *time.Time `json:"ends_at,omitempty" db:"ends_at"`
+	IsActive     bool       `json:"is_active" db:"is_active"`
+	CreatedBy    *uuid.UUID `json:"created_by,omitempty" db:"created_by"`
+	CreatedAt    time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at" db:"updated_at"`
+}
+
+// FixedAmount returns the value of a fixed coupon as money
+func (c *Coupon) FixedAmount() Money {
+	amount := MoneyFromFloat(c.Value)
+	amount.Currency = c.Currency
+	return amount
+}
+
+// CouponDiscount is what a coupon is worth for a given cart. Free shipping
+// covers the shipping of ShopID's items, or of every shop when ShopID is nil.
+type CouponDiscount struct {
+	CouponID     uuid.UUID  `json:"coupon_id"`
+	Code         string     `json:"code"`
+	ShopID       *uuid.UUID `json:"shop_id,omitempty"`
+	Amount       Money      `json:"amount"`
+	FreeShipping bool       `json:"free_shipping"`
+}
+
+// CreateCouponRequest creates a coupon. Vendors always create coupons for
+// their own shop; admins may leave ShopID empty for a platform-wide coupon.
+type CreateCouponRequest struct {
+	Code         string     `json:"code" validate:"required,min=3,max=50,alphanum"`
+	Description  *string    `json:"description,omitempty" validate:"omitempty,max=500"`
+	Type         CouponType `json:"discount_type" validate:"required,oneof=percentage fixed free_shipping"`
+	Value        float64    `json:"value" validate:"gte=0"`
+	MaxDiscount  *Money     `json:"max_discount,omitempty" validate:"omitempty,gte=0"`
+	MinSubtotal  Money      `json:"min_subtotal" validate:"gte=0"`
+	ShopID       *uuid.UUID `json:"shop_id,omitempty"`
+	UsageLimit   *int       `json:"usage_limit,omitempty" validate:"omitempty,min=1"`
+	PerUserLimit *int       `json:"per_user_limit,omitempty" validate:"omitempty,min=1"`
+	StartsAt     *time.Time `json:"starts_at,omitempty"`
+	EndsAt       *time.Time `json:"ends_at,omitempty"`
+}
+
+type UpdateCouponRequest struct {
+	Description  *string    `json:"description,omitempty" validate:"omitempty,max=500"`
+	Value        *float64   `json:"value,omitempty" validate:"omitempty,gte=0"`
+	MaxDiscount  *Money     `json:"max_discount,omitempty" validate:"omitempty,gte=0"`
+	MinSubtotal  *Money     `json:"min_subtotal,omitempty" validate:"omitempty,gte=0"`
+	UsageLimit   *int       `json:"usage_limit,omitempty" validate:"omitempty,min=1"`
+	PerUserLimit *int       `json:"per_user_limit,omitempty" validate:"omitempty,min=1"`
+	StartsAt     *time.Time `json:"starts_at,omitempty"`
+	EndsAt       *time.Time `json:"ends_at,omitempty"`
+	IsActive     *bool      `json:"is_active,omitempty"`
+}
+
+// ApplyCouponRequest applies a promotion code to the cart
+type ApplyCouponRequest struct {
+	Code string `json:"code" validate:"required,max=50"`
+}
@@ -0,0 +1,75 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrUnsupportedCurrency is returned for currencies without an exchange rate
+var ErrUnsupportedCurrency = errors.New("unsupported currency")
+
+// ExchangeRate is how many units of Currency one unit of DefaultCurrency buys
+type ExchangeRate struct {
+	Currency  string    `json:"currency" db:"currency"`
+	Rate      float64   `json:"rate" db:"rate"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// ExchangeRateList lists the currencies prices can be shown and paid in,
+// against Base
+type ExchangeRateList struct {
+	Base  string          `json:"base"`
+	Rates []*ExchangeRate `json:"rates"`
+}
+
+type SetExchangeRateRequest struct {
+	Rate float64 `json:"rate" validate:"gt=0"`
+}
+
+// ExchangeRateImportResult reports how many rates an import file set
+type ExchangeRateImportResult struct {
+	Imported int `json:"imported"`
+}
+
+// ExchangeRates maps currencies to their ExchangeRate. DefaultCurrency is
+// always 1.
+type ExchangeRates map[string]float64
+
+// Supports reports whether amounts can be converted to and from currency
+func (r ExchangeRates) Supports(currency string) bool {
+	_, ok := r.rate(currency)
+	return ok
+}
+
+// Convert converts an amount to another currency, rounding to the nearest
+// minor unit
+func (r ExchangeRates) Convert(amount Money, currency string) (Money, error) {
+	from := amount.CurrencyCode()
+	if from == currency {
+		amount.Currency = currency
+		return amount, nil
+	}
+
+	fromRate, ok := r.rate(from)
+	if !ok {
+		return Money{}, fmt.Errorf("%w %s", ErrUnsupportedCurrency, from)
+	}
+	toRate, ok := r.rate(currency)
+	if !ok {
+		return Money{}, fmt.Errorf("%w %s", ErrUnsupportedCurrency, currency)
+	}
+
+	converted := amount.MulRate(toRate / fromRate)
+	converted.Currency = currency
+	return converted, nil
+}
+
+func (r ExchangeRates) rate(currency string) (float64, bool) {
+	if currency == DefaultCurrency {
+		return 1, true
+	}
+	rate, ok := r[currency]
+	return rate, ok && rate > 0
+}
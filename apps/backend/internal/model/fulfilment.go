@@ -0,0 +1,38 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Fulfilment is one shop's portion of an order. It moves through the same
+// statuses as an order; the order's own status is derived from its fulfilments.
+type Fulfilment struct {
+	ID                 uuid.UUID   `json:"id" db:"id"`
+	OrderID            uuid.UUID   `json:"order_id" db:"order_id"`
+	ShopID             uuid.UUID   `json:"shop_id" db:"shop_id"`
+	Status             OrderStatus `json:"status" db:"status"`
+	Subtotal           Money       `json:"subtotal" db:"subtotal"`
+	ShippingMethodID   *uuid.UUID  `json:"shipping_method_id,omitempty" db:"shipping_method_id"`
+	ShippingMethodName *string     `json:"shipping_method_name,omitempty" db:"shipping_method_name"`
+	ShippingCost       Money       `json:"shipping_cost" db:"shipping_cost"`
+	CreatedAt          time.Time   `json:"created_at" db:"created_at"`
+	UpdatedAt          time.Time   `json:"updated_at" db:"updated_at"`
+	ConfirmedAt        *time.Time  `json:"confirmed_at,omitempty" db:"confirmed_at"`
+	ShippedAt          *time.Time  `json:"shipped_at,omitempty" db:"shipped_at"`
+	DeliveredAt        *time.Time  `json:"delivered_at,omitempty" db:"delivered_at"`
+}
+
+// VendorOrder is a vendor's view of an order: their fulfilment, the parent
+// order's reference and payment state, and only their own items
+type VendorOrder struct {
+	Fulfilment
+	OrderNumber       string                 `json:"order_number"`
+	OrderStatus       OrderStatus            `json:"order_status"`
+	PaymentMethod     *string                `json:"payment_method,omitempty"`
+	PaymentStatus     PaymentStatus          `json:"payment_status"`
+	ShippingAddressID *uuid.UUID             `json:"shipping_address_id,omitempty"`
+	ShippingAddress   *Address               `json:"shipping_address,omitempty"`
+	Items             []OrderItemWithDetails `json:"items"`
+}
@@ -0,0 +1,42 @@
+package model
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type JobStatus string
+
+const (
+	JobStatusPending   JobStatus = "pending"   // Waiting for RunAt, first run or retry
+	JobStatusRunning   JobStatus = "running"   // Claimed by a worker
+	JobStatusSucceeded JobStatus = "succeeded" // Done
+	JobStatusDead      JobStatus = "dead"      // Out of attempts, or of a type no worker handles
+)
+
+// Job is background work queued in the database. Type selects the handler
+// that runs it with Payload.
+type Job struct {
+	ID          uuid.UUID       `json:"id" db:"id"`
+	Type        string          `json:"type" db:"job_type"`
+	Payload     json.RawMessage `json:"payload" db:"payload"`
+	Status      JobStatus       `json:"status" db:"status"`
+	Attempts    int             `json:"attempts" db:"attempts"`
+	MaxAttempts int             `json:"max_attempts" db:"max_attempts"`
+	RunAt       time.Time       `json:"run_at" db:"run_at"`
+	LockedAt    *time.Time      `json:"locked_at,omitempty" db:"locked_at"`
+	LastError   *string         `json:"last_error,omitempty" db:"last_error"`
+	CreatedAt   time.Time       `json:"created_at" db:"created_at"`
+	UpdatedAt   time.Time       `json:"updated_at" db:"updated_at"`
+	CompletedAt *time.Time      `json:"completed_at,omitempty" db:"completed_at"`
+}
+
+type JobListResponse struct {
+	Jobs       []*Job `json:"jobs"`
+	Total      int    `json:"total"`
+	Page       int    `json:"page"`
+	PageSize   int    `json:"page_size"`
+	TotalPages int    `json:"total_pages"`
+}
@@ -0,0 +1,137 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// CommissionRate is the percentage of a sale the platform keeps. It applies
+// to one shop, to one product category (and its subcategories), or, with
+// neither set, to every sale as the platform default.
+type CommissionRate struct {
+	ID         uuid.UUID  `json:"id" db:"id"`
+	ShopID     *uuid.UUID `json:"shop_id,omitempty" db:"shop_id"`
+	CategoryID *uuid.UUID `json:"category_id,omitempty" db:"category_id"`
+	Rate       float64    `json:"rate" db:"rate"`
+	CreatedAt  time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at" db:"updated_at"`
+}
+
+type SetCommissionRateRequest struct {
+	Rate float64 `json:"rate" validate:"gte=0,lte=100"`
+}
+
+type LedgerAccount string
+type LedgerEntryType string
+
+const (
+	LedgerAccountShop     LedgerAccount = "shop"     // Owed to a shop
+	LedgerAccountPlatform LedgerAccount = "platform" // Commission earned
+	LedgerAccountGateway  LedgerAccount = "gateway"  // Held at the payment gateways and the bank
+)
+
+const (
+	LedgerEntrySale       LedgerEntryType = "sale"
+	LedgerEntryCommission LedgerEntryType = "commission"
+	LedgerEntryRefund     LedgerEntryType = "refund"
+	LedgerEntryPayout     LedgerEntryType = "payout"
+)
+
+// LedgerEntry is one leg of a double-entry transaction: the entries sharing a
+// TransactionID sum to zero. Amount is a credit to the account, so a shop's
+// balance is the sum of its entries. An entry counts towards the available
+// balance from AvailableAt.
+type LedgerEntry struct {
+	ID            uuid.UUID       `json:"id" db:"id"`
+	TransactionID uuid.UUID       `json:"transaction_id" db:"transaction_id"`
+	Account       LedgerAccount   `json:"account" db:"account"`
+	ShopID        *uuid.UUID      `json:"shop_id,omitempty" db:"shop_id"`
+	Type          LedgerEntryType `json:"type" db:"entry_type"`
+	Amount        Money           `json:"amount" db:"amount"`
+	Currency      string          `json:"currency" db:"currency"`
+	OrderID       *uuid.UUID      `json:"order_id,omitempty" db:"order_id"`
+	OrderItemID   *uuid.UUID      `json:"order_item_id,omitempty" db:"order_item_id"`
+	RefundID      *uuid.UUID      `json:"refund_id,omitempty" db:"refund_id"`
+	PayoutID      *uuid.UUID      `json:"payout_id,omitempty" db:"payout_id"`
+	AvailableAt   time.Time       `json:"available_at" db:"available_at"`
+	CreatedAt     time.Time       `json:"created_at" db:"created_at"`
+}
+
+type LedgerEntryListResponse struct {
+	Entries    []*LedgerEntry `json:"entries"`
+	Total      int            `json:"total"`
+	Page       int            `json:"page"`
+	PageSize   int            `json:"page_size"`
+	TotalPages int            `json:"total_pages"`
+}
+
+// ShopBalance is what the platform owes a shop in one currency. Available can
+// be paid out; Pending is still in its holding period. The totals are over
+// the shop's whole history.
+type ShopBalance struct {
+	Currency   string `json:"currency"`
+	Available  Money  `json:"available"`
+	Pending    Money  `json:"pending"`
+	Sales      Money  `json:"sales"`
+	Commission Money  `json:"commission"`
+	Refunds    Money  `json:"refunds"`
+	PaidOut    Money  `json:"paid_out"`
+}
+
+type ShopBalanceResponse struct {
+	ShopID      uuid.UUID     `json:"shop_id"`
+	HoldingDays int           `json:"holding_days"` // How long sales are held before they can be paid out
+	Balances    []ShopBalance `json:"balances"`
+}
+
+type PayoutStatus string
+
+const (
+	PayoutStatusPending PayoutStatus = "pending"
+	PayoutStatusPaid    PayoutStatus = "paid"
+	PayoutStatusFailed  PayoutStatus = "failed"
+)
+
+// PayoutBatch pays out the available balances of shops at one time
+type PayoutBatch struct {
+	ID        uuid.UUID  `json:"id" db:"id"`
+	CreatedBy *uuid.UUID `json:"created_by,omitempty" db:"created_by"`
+	Payouts   []*Payout  `json:"payouts" db:"-"`
+	CreatedAt time.Time  `json:"created_at" db:"created_at"`
+}
+
+// Payout is money sent to a shop. A failed payout returns the amount to the
+// shop's balance.
+type Payout struct {
+	ID            uuid.UUID    `json:"id" db:"id"`
+	BatchID       uuid.UUID    `json:"batch_id" db:"batch_id"`
+	ShopID        uuid.UUID    `json:"shop_id" db:"shop_id"`
+	ShopName      string       `json:"shop_name" db:"-"`
+	Amount        Money        `json:"amount" db:"amount"`
+	Currency      string       `json:"currency" db:"currency"`
+	Status        PayoutStatus `json:"status" db:"status"`
+	FailureReason *string      `json:"failure_reason,omitempty" db:"failure_reason"`
+	CreatedAt     time.Time    `json:"created_at" db:"created_at"`
+	UpdatedAt     time.Time    `json:"updated_at" db:"updated_at"`
+	PaidAt        *time.Time   `json:"paid_at,omitempty" db:"paid_at"`
+}
+
+type PayoutBatchListResponse struct {
+	Batches    []*PayoutBatch `json:"batches"`
+	Total      int            `json:"total"`
+	Page       int            `json:"page"`
+	PageSize   int            `json:"page_size"`
+	TotalPages int            `json:"total_pages"`
+}
+
+// CreatePayoutBatchRequest pays out the listed shops, or every shop with an
+// available balance when none are listed
+type CreatePayoutBatchRequest struct {
+	ShopIDs []uuid.UUID `json:"shop_ids,omitempty"`
+}
+
+type UpdatePayoutStatusRequest struct {
+	Status        PayoutStatus `json:"status" validate:"required,oneof=paid failed"`
+	FailureReason *string      `json:"failure_reason,omitempty" validate:"omitempty,max=500"`
+}
@@ -0,0 +1,315 @@
+package model
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"regexp"
+	"strconv"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// DefaultCurrency is the store's currency, used by amounts that do not name one
+const DefaultCurrency = "NPR"
+
+// minorUnitsPerMajor is how many minor units (paisa, cents) make one unit of
+// currency. Every currency the store takes has two decimal places.
+const minorUnitsPerMajor = 100
+
+// Money is an exact amount of money in minor units. An empty Currency means
+// DefaultCurrency.
+//
+// Money is stored in DECIMAL columns and encoded in JSON as a plain decimal
+// number (19.99), so neither the schema nor API clients see minor units.
+type Money struct {
+	Amount   int64  // Minor units, e.g. paisa
+	Currency string // ISO 4217 code
+}
+
+// NewMoney returns an amount of minor units in a currency
+func NewMoney(amount int64, currency string) Money {
+	return Money{Amount: amount, Currency: currency}
+}
+
+// maxMoneyLength caps the length of a decimal amount; any int64 of minor
+// units fits well within it
+const maxMoneyLength = 32
+
+// moneyPattern is a plain decimal amount, without exponents or fractions
+var moneyPattern = regexp.MustCompile(`^-?\d+(\.\d+)?$`)
+
+// ParseMoney parses a decimal amount such as "19.99" exactly. Amounts with
+// more decimal places than the currency has are rejected.
+func ParseMoney(s string) (Money, error) {
+	if len(s) > maxMoneyLength || !moneyPattern.MatchString(s) {
+		return Money{}, fmt.Errorf("invalid amount %q", s)
+	}
+
+	r, ok := new(big.Rat).SetString(s)
+	if !ok {
+		return Money{}, fmt.Errorf("invalid amount %q", s)
+	}
+	r.Mul(r, big.NewRat(minorUnitsPerMajor, 1))
+	if !r.IsInt() {
+		return Money{}, fmt.Errorf("amount %q has too many decimal places", s)
+	}
+	if !r.Num().IsInt64() {
+		return Money{}, fmt.Errorf("amount %q is out of range", s)
+	}
+	return Money{Amount: r.Num().Int64()}, nil
+}
+
+// MoneyFromFloat rounds a decimal amount held in a float to the nearest minor
+// unit. Prefer ParseMoney; this is for values that are floats by nature, such
+// as a coupon value that is either a percentage or an amount.
+func MoneyFromFloat(amount float64) Money {
+	return Money{Amount: int64(math.Round(amount * minorUnitsPerMajor))}
+}
+
+// CurrencyCode returns the money's currency, defaulting to DefaultCurrency
+func (m Money) CurrencyCode() string {
+	if m.Currency == "" {
+		return DefaultCurrency
+	}
+	return m.Currency
+}
+
+// IsZero reports whether the amount is zero
+func (m Money) IsZero() bool {
+	return m.Amount == 0
+}
+
+// IsPositive reports whether the amount is above zero
+func (m Money) IsPositive() bool {
+	return m.Amount > 0
+}
+
+// IsNegative reports whether the amount is below zero
+func (m Money) IsNegative() bool {
+	return m.Amount < 0
+}
+
+// Add returns m + o. Adding amounts in different currencies panics.
+func (m Money) Add(o Money) Money {
+	return Money{Amount: m.Amount + o.Amount, Currency: m.sameCurrency(o)}
+}
+
+// Sub returns m - o. Subtracting amounts in different currencies panics.
+func (m Money) Sub(o Money) Money {
+	return Money{Amount: m.Amount - o.Amount, Currency: m.sameCurrency(o)}
+}
+
+// Mul returns m times a whole quantity
+func (m Money) Mul(quantity int64) Money {
+	return Money{Amount: m.Amount * quantity, Currency: m.Currency}
+}
+
+// MulRate returns m times a factor, rounded half away from zero to the
+// nearest minor unit
+func (m Money) MulRate(factor float64) Money {
+	return Money{Amount: int64(math.Round(float64(m.Amount) * factor)), Currency: m.Currency}
+}
+
+// Percent returns rate percent of m, rounded to the nearest minor unit
+func (m Money) Percent(rate float64) Money {
+	return m.MulRate(rate / 100)
+}
+
+// Cmp compares m and o, returning -1, 0 or +1
+func (m Money) Cmp(o Money) int {
+	m.sameCurrency(o)
+	switch {
+	case m.Amount < o.Amount:
+		return -1
+	case m.Amount > o.Amount:
+		return 1
+	}
+	return 0
+}
+
+// Min returns the smaller of m and o
+func (m Money) Min(o Money) Money {
+	if o.Cmp(m) < 0 {
+		return o
+	}
+	return m
+}
+
+// Max returns the larger of m and o
+func (m Money) Max(o Money) Money {
+	if o.Cmp(m) > 0 {
+		return o
+	}
+	return m
+}
+
+// Allocate splits m across the weights in proportion to them, without losing
+// or creating a minor unit: the shares always add up to m. Minor units left
+// over after rounding down go to the largest remainders, earlier weights
+// first. Weights must not be negative; when they are all zero m is split
+// evenly.
+func (m Money) Allocate(weights []Money) []Money {
+	shares := make([]Money, len(weights))
+	if len(weights) == 0 {
+		return shares
+	}
+
+	w := make([]*big.Int, len(weights))
+	total := new(big.Int)
+	for i, weight := range weights {
+		w[i] = big.NewInt(weight.Amount)
+		total.Add(total, w[i])
+	}
+	if total.Sign() == 0 {
+		for i := range w {
+			w[i].SetInt64(1)
+		}
+		total.SetInt64(int64(len(w)))
+	}
+
+	amount := m.Amount
+	negative := amount < 0
+	if negative {
+		amount = -amount
+	}
+
+	remainders := make([]*big.Int, len(w))
+	allocated := int64(0)
+	for i := range w {
+		share, remainder := new(big.Int).QuoRem(new(big.Int).Mul(big.NewInt(amount), w[i]), total, new(big.Int))
+		shares[i] = Money{Amount: share.Int64(), Currency: m.Currency}
+		remainders[i] = remainder
+		allocated += share.Int64()
+	}
+
+	for left := amount - allocated; left > 0; left-- {
+		largest := 0
+		for i := range remainders {
+			if remainders[i].Cmp(remainders[largest]) > 0 {
+				largest = i
+			}
+		}
+		shares[largest].Amount++
+		remainders[largest].SetInt64(-1)
+	}
+
+	if negative {
+		for i := range shares {
+			shares[i].Amount = -shares[i].Amount
+		}
+	}
+	return shares
+}
+
+// SumMoney adds up amounts
+func SumMoney(amounts ...Money) Money {
+	var total Money
+	for _, amount := range amounts {
+		total = total.Add(amount)
+	}
+	return total
+}
+
+// Float64 returns the amount in major units. It is only meant for display
+// and for APIs that take floats; never compute with it.
+func (m Money) Float64() float64 {
+	return float64(m.Amount) / minorUnitsPerMajor
+}
+
+// String formats the amount as a decimal number, e.g. "19.99"
+func (m Money) String() string {
+	amount := m.Amount
+	sign := ""
+	if amount < 0 {
+		sign = "-"
+	}
+	major := amount / minorUnitsPerMajor
+	minor := amount % minorUnitsPerMajor
+	if major < 0 {
+		major = -major
+	}
+	if minor < 0 {
+		minor = -minor
+	}
+	return fmt.Sprintf("%s%d.%02d", sign, major, minor)
+}
+
+// MarshalJSON encodes the amount as a JSON number
+func (m Money) MarshalJSON() ([]byte, error) {
+	return []byte(m.String()), nil
+}
+
+// UnmarshalJSON decodes a JSON number, or a string holding one, exactly
+func (m *Money) UnmarshalJSON(data []byte) error {
+	if bytes.Equal(data, []byte("null")) {
+		return nil
+	}
+
+	s := string(data)
+	if len(data) > 0 && data[0] == '"' {
+		unquoted, err := strconv.Unquote(s)
+		if err != nil {
+			return fmt.Errorf("invalid amount %s", s)
+		}
+		s = unquoted
+	}
+
+	parsed, err := ParseMoney(s)
+	if err != nil {
+		return err
+	}
+	m.Amount = parsed.Amount
+	return nil
+}
+
+// ScanNumeric reads a DECIMAL column exactly. Values with more decimal
+// places than the currency has, such as averages, are rounded half away from
+// zero.
+func (m *Money) ScanNumeric(n pgtype.Numeric) error {
+	if !n.Valid {
+		return errors.New("cannot scan NULL into Money")
+	}
+	if n.NaN || n.InfinityModifier != pgtype.Finite {
+		return errors.New("cannot scan non-finite numeric into Money")
+	}
+
+	amount := new(big.Int)
+	if n.Int != nil {
+		amount.Set(n.Int)
+	}
+	exp := int64(n.Exp) + 2 // minorUnitsPerMajor is 10^2
+	if exp >= 0 {
+		amount.Mul(amount, new(big.Int).Exp(big.NewInt(10), big.NewInt(exp), nil))
+	} else {
+		divisor := new(big.Int).Exp(big.NewInt(10), big.NewInt(-exp), nil)
+		quotient, remainder := new(big.Int).QuoRem(amount, divisor, new(big.Int))
+		if new(big.Int).Mul(new(big.Int).Abs(remainder), big.NewInt(2)).Cmp(divisor) >= 0 {
+			quotient.Add(quotient, big.NewInt(int64(amount.Sign())))
+		}
+		amount = quotient
+	}
+
+	if !amount.IsInt64() {
+		return errors.New("numeric is out of range for Money")
+	}
+	m.Amount = amount.Int64()
+	return nil
+}
+
+// NumericValue writes the amount to a DECIMAL column
+func (m Money) NumericValue() (pgtype.Numeric, error) {
+	return pgtype.Numeric{Int: big.NewInt(m.Amount), Exp: -2, Valid: true}, nil
+}
+
+func (m Money) sameCurrency(o Money) string {
+	switch {
+	case m.Currency == "":
+		return o.Currency
+	case o.Currency == "" || o.Currency == m.Currency:
+		return m.Currency
+	}
+	panic(fmt.Sprintf("money: mixing %s and %s", m.Currency, o.Currency))
+}
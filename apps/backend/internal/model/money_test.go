@@ -0,0 +1,237 @@
+package model
+
+import (
+	"encoding/json"
+	"math/big"
+	"math/rand"
+	"testing"
+	"testing/quick"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// maxTestAmount keeps generated amounts (100 million rupees) far from int64
+// overflow when they are multiplied by quantities and summed
+const maxTestAmount = 10_000_000_000
+
+func randomAmount(r *rand.Rand) Money {
+	return Money{Amount: r.Int63n(2*maxTestAmount) - maxTestAmount}
+}
+
+func quickConfig() *quick.Config {
+	return &quick.Config{MaxCount: 2000, Rand: rand.New(rand.NewSource(1))}
+}
+
+func TestMoneyExactDecimals(t *testing.T) {
+	cases := map[string]int64{
+		"19.99": 1999,
+		"0.1":   10,
+		"0.01":  1,
+		"-5.5":  -550,
+		"100":   10000,
+	}
+	for s, want := range cases {
+		got, err := ParseMoney(s)
+		if err != nil {
+			t.Fatalf("ParseMoney(%q): %v", s, err)
+		}
+		if got.Amount != want {
+			t.Errorf("ParseMoney(%q) = %d, want %d", s, got.Amount, want)
+		}
+	}
+
+	for _, s := range []string{"19.999", "abc", "", "1e2", "1e30", "1e9999999", "1/2", "+5", ".5", "5.", "99999999999999999999999999999999.99"} {
+		if _, err := ParseMoney(s); err == nil {
+			t.Errorf("ParseMoney(%q) succeeded, want an error", s)
+		}
+	}
+
+	// 19.99 * 3 is 59.97 exactly, unlike with float64
+	if got := (Money{Amount: 1999}).Mul(3).String(); got != "59.97" {
+		t.Errorf("19.99 * 3 = %s, want 59.97", got)
+	}
+}
+
+func TestMoneyStringRoundTrip(t *testing.T) {
+	roundTrips := func(amount int64) bool {
+		m := Money{Amount: amount}
+		parsed, err := ParseMoney(m.String())
+		return err == nil && parsed.Amount == amount
+	}
+	if err := quick.Check(roundTrips, quickConfig()); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestMoneyJSONRoundTrip(t *testing.T) {
+	type priced struct {
+		Price Money  `json:"price"`
+		Max   *Money `json:"max,omitempty"`
+	}
+
+	roundTrips := func(amount int64) bool {
+		max := Money{Amount: amount / 2}
+		data, err := json.Marshal(priced{Price: Money{Amount: amount}, Max: &max})
+		if err != nil {
+			return false
+		}
+		var decoded priced
+		if err := json.Unmarshal(data, &decoded); err != nil {
+			return false
+		}
+		return decoded.Price.Amount == amount && decoded.Max != nil && decoded.Max.Amount == amount/2
+	}
+	if err := quick.Check(roundTrips, quickConfig()); err != nil {
+		t.Error(err)
+	}
+
+	var decoded priced
+	if err := json.Unmarshal([]byte(`{"price":"19.99"}`), &decoded); err != nil || decoded.Price.Amount != 1999 {
+		t.Errorf("decoding a quoted amount = %v, %v; want 1999", decoded.Price.Amount, err)
+	}
+	if err := json.Unmarshal([]byte(`{"price":19.999}`), &decoded); err == nil {
+		t.Error("decoding 19.999 succeeded, want an error")
+	}
+}
+
+func TestMoneyNumericRoundTrip(t *testing.T) {
+	roundTrips := func(amount int64) bool {
+		n, err := Money{Amount: amount}.NumericValue()
+		if err != nil {
+			return false
+		}
+		var scanned Money
+		return scanned.ScanNumeric(n) == nil && scanned.Amount == amount
+	}
+	if err := quick.Check(roundTrips, quickConfig()); err != nil {
+		t.Error(err)
+	}
+
+	cases := []struct {
+		n    pgtype.Numeric
+		want int64
+	}{
+		{pgtype.Numeric{Int: big.NewInt(1999), Exp: -2, Valid: true}, 1999},
+		{pgtype.Numeric{Int: big.NewInt(5), Exp: 1, Valid: true}, 5000},
+		{pgtype.Numeric{Int: big.NewInt(123456), Exp: -4, Valid: true}, 1235},
+		{pgtype.Numeric{Int: big.NewInt(-123450), Exp: -4, Valid: true}, -1235},
+		{pgtype.Numeric{Int: big.NewInt(0), Valid: true}, 0},
+	}
+	for _, c := range cases {
+		var m Money
+		if err := m.ScanNumeric(c.n); err != nil {
+			t.Fatalf("ScanNumeric(%v): %v", c.n, err)
+		}
+		if m.Amount != c.want {
+			t.Errorf("ScanNumeric(%v e%d) = %d, want %d", c.n.Int, c.n.Exp, m.Amount, c.want)
+		}
+	}
+
+	var m Money
+	if err := m.ScanNumeric(pgtype.Numeric{}); err == nil {
+		t.Error("scanning NULL succeeded, want an error")
+	}
+}
+
+func TestAllocateAddsUpToTotal(t *testing.T) {
+	check := func(seed int64) bool {
+		r := rand.New(rand.NewSource(seed))
+		total := randomAmount(r)
+		weights := make([]Money, 1+r.Intn(10))
+		var weightSum int64
+		for i := range weights {
+			weights[i] = Money{Amount: r.Int63n(maxTestAmount)}
+			weightSum += weights[i].Amount
+		}
+
+		shares := total.Allocate(weights)
+		if len(shares) != len(weights) || SumMoney(shares...).Amount != total.Amount {
+			return false
+		}
+
+		// Every share is within one minor unit of its exact proportion
+		for i, share := range shares {
+			exact := new(big.Rat).SetFrac(
+				new(big.Int).Mul(big.NewInt(total.Amount), big.NewInt(weights[i].Amount)),
+				big.NewInt(max(weightSum, 1)),
+			)
+			if weightSum == 0 {
+				exact = new(big.Rat).SetFrac64(total.Amount, int64(len(weights)))
+			}
+			diff := new(big.Rat).Sub(exact, new(big.Rat).SetInt64(share.Amount))
+			if diff.Abs(diff).Cmp(big.NewRat(1, 1)) >= 0 {
+				return false
+			}
+		}
+		return true
+	}
+	if err := quick.Check(check, quickConfig()); err != nil {
+		t.Error(err)
+	}
+
+	if shares := (Money{Amount: 100}).Allocate([]Money{{}, {}, {}}); SumMoney(shares...).Amount != 100 {
+		t.Errorf("allocating over zero weights = %v, want shares of 100", shares)
+	}
+}
+
+func TestCartTotalsMatchLineSums(t *testing.T) {
+	check := func(seed int64) bool {
+		r := rand.New(rand.NewSource(seed))
+		cart := CartResponse{Items: make([]CartItemWithProduct, r.Intn(20))}
+
+		var want int64
+		for i := range cart.Items {
+			item := &cart.Items[i]
+			item.ProductPrice = Money{Amount: 1 + r.Int63n(maxTestAmount)}
+			item.Quantity = 1 + r.Intn(50)
+			item.CalculateSubtotal()
+			if item.Subtotal.Amount != item.ProductPrice.Amount*int64(item.Quantity) {
+				return false
+			}
+			want += item.Subtotal.Amount
+		}
+
+		cart.CalculateTotals()
+		return cart.Subtotal.Amount == want && cart.Total.Amount == want && cart.ItemCount == len(cart.Items)
+	}
+	if err := quick.Check(check, quickConfig()); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestTaxBreakdownMatchesLineSums(t *testing.T) {
+	names := []string{"VAT", "Excise", "Luxury"}
+
+	check := func(seed int64) bool {
+		r := rand.New(rand.NewSource(seed))
+		items := make([]OrderItemWithDetails, r.Intn(20))
+
+		var wantTax, wantTaxable int64
+		for i := range items {
+			item := &items[i]
+			item.ID = uuid.New()
+			item.TaxableAmount = Money{Amount: r.Int63n(maxTestAmount)}
+			if r.Intn(4) == 0 {
+				continue // Untaxed line
+			}
+			name := names[r.Intn(len(names))]
+			item.TaxName = &name
+			item.TaxRate = float64(1 + r.Intn(3)*6)
+			item.TaxInclusive = r.Intn(2) == 0
+			item.TaxAmount = item.TaxableAmount.Percent(item.TaxRate)
+			wantTax += item.TaxAmount.Amount
+			wantTaxable += item.TaxableAmount.Amount
+		}
+
+		var gotTax, gotTaxable int64
+		for _, b := range TaxBreakdownOf(items) {
+			gotTax += b.Amount.Amount
+			gotTaxable += b.TaxableAmount.Amount
+		}
+		return gotTax == wantTax && gotTaxable == wantTaxable
+	}
+	if err := quick.Check(check, quickConfig()); err != nil {
+		t.Error(err)
+	}
+}
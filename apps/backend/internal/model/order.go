@@ -19,64 +19,91 @@ const (
 	OrderStatusRefunded   OrderStatus = "refunded"
 )
 
+// Why orders are cancelled
 const (
-	PaymentStatusPending  PaymentStatus = "pending"
-	PaymentStatusPaid     PaymentStatus = "paid"
-	PaymentStatusFailed   PaymentStatus = "failed"
-	PaymentStatusRefunded PaymentStatus = "refunded"
+	CancelReasonCustomer       = "cancelled by customer"
+	CancelReasonPaymentFailed  = "payment failed"
+	CancelReasonPaymentExpired = "payment session expired"
+	CancelReasonUnpaid         = "payment not received in time"
+)
+
+const (
+	PaymentStatusPending           PaymentStatus = "pending"
+	PaymentStatusPaid              PaymentStatus = "paid"
+	PaymentStatusFailed            PaymentStatus = "failed"
+	PaymentStatusPartiallyRefunded PaymentStatus = "partially_refunded"
+	PaymentStatusRefunded          PaymentStatus = "refunded"
 )
 
 // Order represents a customer order
 type Order struct {
-	ID                uuid.UUID     `json:"id" db:"id"`
-	UserID            uuid.UUID     `json:"user_id" db:"user_id"`
-	OrderNumber       string        `json:"order_number" db:"order_number"`
-	Status            OrderStatus   `json:"status" db:"status"`
-	ShippingAddressID *uuid.UUID    `json:"shipping_address_id,omitempty" db:"shipping_address_id"`
-	BillingAddressID  *uuid.UUID    `json:"billing_address_id,omitempty" db:"billing_address_id"`
-	Subtotal          float64       `json:"subtotal" db:"subtotal"`
-	ShippingCost      float64       `json:"shipping_cost" db:"shipping_cost"`
-	Tax               float64       `json:"tax" db:"tax"`
-	Discount          float64       `json:"discount" db:"discount"`
-	Total             float64       `json:"total" db:"total"`
-	PaymentMethod     *string       `json:"payment_method,omitempty" db:"payment_method"`
-	PaymentStatus     PaymentStatus `json:"payment_status" db:"payment_status"`
-	StripeSessionID   *string       `json:"stripe_session_id,omitempty" db:"stripe_session_id"`
-	Notes             *string       `json:"notes,omitempty" db:"notes"`
-	CreatedAt         time.Time     `json:"created_at" db:"created_at"`
-	UpdatedAt         time.Time     `json:"updated_at" db:"updated_at"`
-	ConfirmedAt       *time.Time    `json:"confirmed_at,omitempty" db:"confirmed_at"`
-	ShippedAt         *time.Time    `json:"shipped_at,omitempty" db:"shipped_at"`
-	DeliveredAt       *time.Time    `json:"delivered_at,omitempty" db:"delivered_at"`
+	ID                 uuid.UUID     `json:"id" db:"id"`
+	UserID             uuid.UUID     `json:"user_id" db:"user_id"`
+	OrderNumber        string        `json:"order_number" db:"order_number"`
+	Status             OrderStatus   `json:"status" db:"status"`
+	ShippingAddressID  *uuid.UUID    `json:"shipping_address_id,omitempty" db:"shipping_address_id"`
+	BillingAddressID   *uuid.UUID    `json:"billing_address_id,omitempty" db:"billing_address_id"`
+	Subtotal           Money         `json:"subtotal" db:"subtotal"`
+	ShippingCost       Money         `json:"shipping_cost" db:"shipping_cost"`
+	Tax                Money         `json:"tax" db:"tax"`
+	Discount           Money         `json:"discount" db:"discount"`
+	Total              Money         `json:"total" db:"total"`
+	Currency           string        `json:"currency" db:"currency"` // Every amount of the order is in it
+	PaymentMethod      *string       `json:"payment_method,omitempty" db:"payment_method"`
+	PaymentStatus      PaymentStatus `json:"payment_status" db:"payment_status"`
+	PaymentReference   *string       `json:"payment_reference,omitempty" db:"payment_reference"` // Identifies the payment at the gateway
+	Notes              *string       `json:"notes,omitempty" db:"notes"`
+	CreatedAt          time.Time     `json:"created_at" db:"created_at"`
+	UpdatedAt          time.Time     `json:"updated_at" db:"updated_at"`
+	ConfirmedAt        *time.Time    `json:"confirmed_at,omitempty" db:"confirmed_at"`
+	ShippedAt          *time.Time    `json:"shipped_at,omitempty" db:"shipped_at"`
+	DeliveredAt        *time.Time    `json:"delivered_at,omitempty" db:"delivered_at"`
+	CancelledAt        *time.Time    `json:"cancelled_at,omitempty" db:"cancelled_at"`
+	CancellationReason *string       `json:"cancellation_reason,omitempty" db:"cancellation_reason"`
 }
 
 // OrderItem represents an item in an order
 type OrderItem struct {
-	ID          uuid.UUID `json:"id" db:"id"`
-	OrderID     uuid.UUID `json:"order_id" db:"order_id"`
-	ProductID   uuid.UUID `json:"product_id" db:"product_id"`
-	ShopID      uuid.UUID `json:"shop_id" db:"shop_id"`
-	ProductName string    `json:"product_name" db:"product_name"`
-	ProductSKU  *string   `json:"product_sku,omitempty" db:"product_sku"`
-	Quantity    int       `json:"quantity" db:"quantity"`
-	UnitPrice   float64   `json:"unit_price" db:"unit_price"`
-	Subtotal    float64   `json:"subtotal" db:"subtotal"`
-	CreatedAt   time.Time `json:"created_at" db:"created_at"`
+	ID            uuid.UUID  `json:"id" db:"id"`
+	OrderID       uuid.UUID  `json:"order_id" db:"order_id"`
+	ProductID     uuid.UUID  `json:"product_id" db:"product_id"`
+	VariantID     *uuid.UUID `json:"variant_id,omitempty" db:"variant_id"`
+	ShopID        uuid.UUID  `json:"shop_id" db:"shop_id"`
+	ProductName   string     `json:"product_name" db:"product_name"`
+	VariantName   *string    `json:"variant_name,omitempty" db:"variant_name"` // e.g. "Colour: Red / Size: M"
+	ProductSKU    *string    `json:"product_sku,omitempty" db:"product_sku"`
+	Quantity      int        `json:"quantity" db:"quantity"`
+	UnitPrice     Money      `json:"unit_price" db:"unit_price"`
+	Subtotal      Money      `json:"subtotal" db:"subtotal"`
+	TaxName       *string    `json:"tax_name,omitempty" db:"tax_name"`
+	TaxRate       float64    `json:"tax_rate" db:"tax_rate"`
+	TaxableAmount Money      `json:"taxable_amount" db:"taxable_amount"`
+	TaxAmount     Money      `json:"tax_amount" db:"tax_amount"`
+	TaxInclusive  bool       `json:"tax_inclusive" db:"tax_inclusive"`
+	CreatedAt     time.Time  `json:"created_at" db:"created_at"`
 }
 
 // OrderItemWithDetails includes product and shop information
 type OrderItemWithDetails struct {
-	ID              uuid.UUID `json:"id" db:"id"`
-	OrderID         uuid.UUID `json:"order_id" db:"order_id"`
-	ProductID       uuid.UUID `json:"product_id" db:"product_id"`
-	ProductName     string    `json:"product_name" db:"product_name"`
-	ProductImageURL *string   `json:"product_image_url,omitempty" db:"product_image_url"`
-	ShopID          uuid.UUID `json:"shop_id" db:"shop_id"`
-	ShopName        string    `json:"shop_name" db:"shop_name"`
-	Quantity        int       `json:"quantity" db:"quantity"`
-	UnitPrice       float64   `json:"unit_price" db:"unit_price"`
-	Subtotal        float64   `json:"subtotal" db:"subtotal"`
-	CreatedAt       time.Time `json:"created_at" db:"created_at"`
+	ID              uuid.UUID  `json:"id" db:"id"`
+	OrderID         uuid.UUID  `json:"order_id" db:"order_id"`
+	ProductID       uuid.UUID  `json:"product_id" db:"product_id"`
+	VariantID       *uuid.UUID `json:"variant_id,omitempty" db:"variant_id"`
+	ProductName     string     `json:"product_name" db:"product_name"`
+	VariantName     *string    `json:"variant_name,omitempty" db:"variant_name"`
+	ProductSKU      *string    `json:"product_sku,omitempty" db:"product_sku"`
+	ProductImageURL *string    `json:"product_image_url,omitempty" db:"product_image_url"`
+	ShopID          uuid.UUID  `json:"shop_id" db:"shop_id"`
+	ShopName        string     `json:"shop_name" db:"shop_name"`
+	Quantity        int        `json:"quantity" db:"quantity"`
+	UnitPrice       Money      `json:"unit_price" db:"unit_price"`
+	Subtotal        Money      `json:"subtotal" db:"subtotal"`
+	TaxName         *string    `json:"tax_name,omitempty" db:"tax_name"`
+	TaxRate         float64    `json:"tax_rate" db:"tax_rate"`
+	TaxableAmount   Money      `json:"taxable_amount" db:"taxable_amount"`
+	TaxAmount       Money      `json:"tax_amount" db:"tax_amount"`
+	TaxInclusive    bool       `json:"tax_inclusive" db:"tax_inclusive"`
+	CreatedAt       time.Time  `json:"created_at" db:"created_at"`
 }
 
 // Address represents a shipping or billing address
@@ -103,10 +130,18 @@ type CreateOrderRequest struct {
 	ShippingAddress   *AddressInput `json:"shipping_address,omitempty"`    // Or provide new address
 	BillingAddress    *AddressInput `json:"billing_address,omitempty"`
 	PaymentMethod     string        `json:"payment_method" validate:"required"`
-	UseSameAddress    bool          `json:"use_same_address"` // Use shipping as billing
+	UseSameAddress    bool          `json:"use_same_address"`                                // Use shipping as billing
+	ShippingMethodIDs []uuid.UUID   `json:"shipping_method_ids,omitempty"`                   // One per shop; the cheapest option otherwise
+	Currency          string        `json:"currency,omitempty" validate:"omitempty,iso4217"` // Currency to pay in; the default currency otherwise
 	Notes             *string       `json:"notes,omitempty"`
 }
 
+// PayOrderRequest pays for an unpaid order again, optionally switching it to
+// another payment method
+type PayOrderRequest struct {
+	PaymentMethod string `json:"payment_method,omitempty"` // The order's current method otherwise
+}
+
 // AddressInput represents address input for order creation and address management
 type AddressInput struct {
 	FullName     string  `json:"full_name" validate:"required"`
@@ -120,33 +155,42 @@ type AddressInput struct {
 	IsDefault    bool    `json:"is_default"`
 }
 
-// UpdateOrderStatusRequest represents request to update order status
+// UpdateOrderStatusRequest represents request to update order status. Vendors
+// always update their own shop's fulfilment; admins may name a shop, or move
+// every fulfilment that can make the transition.
 type UpdateOrderStatusRequest struct {
 	Status OrderStatus `json:"status" validate:"required"`
+	ShopID *uuid.UUID  `json:"shop_id,omitempty"`
 }
 
 // OrderResponse represents order with items
 type OrderResponse struct {
-	ID              uuid.UUID              `json:"id"`
-	UserID          uuid.UUID              `json:"user_id"`
-	OrderNumber     string                 `json:"order_number"`
-	Status          OrderStatus            `json:"status"`
-	ShippingAddress *Address               `json:"shipping_address,omitempty"`
-	BillingAddress  *Address               `json:"billing_address,omitempty"`
-	Items           []OrderItemWithDetails `json:"items"`
-	Subtotal        float64                `json:"subtotal"`
-	ShippingCost    float64                `json:"shipping_cost"`
-	Tax             float64                `json:"tax"`
-	Discount        float64                `json:"discount"`
-	Total           float64                `json:"total"`
-	PaymentMethod   *string                `json:"payment_method,omitempty"`
-	PaymentStatus   PaymentStatus          `json:"payment_status"`
-	Notes           *string                `json:"notes,omitempty"`
-	CreatedAt       time.Time              `json:"created_at"`
-	UpdatedAt       time.Time              `json:"updated_at"`
-	ConfirmedAt     *time.Time             `json:"confirmed_at,omitempty"`
-	ShippedAt       *time.Time             `json:"shipped_at,omitempty"`
-	DeliveredAt     *time.Time             `json:"delivered_at,omitempty"`
+	ID                 uuid.UUID              `json:"id"`
+	UserID             uuid.UUID              `json:"user_id"`
+	OrderNumber        string                 `json:"order_number"`
+	Status             OrderStatus            `json:"status"`
+	ShippingAddress    *Address               `json:"shipping_address,omitempty"`
+	BillingAddress     *Address               `json:"billing_address,omitempty"`
+	Items              []OrderItemWithDetails `json:"items"`
+	Fulfilments        []Fulfilment           `json:"fulfilments"`
+	Subtotal           Money                  `json:"subtotal"`
+	ShippingCost       Money                  `json:"shipping_cost"`
+	Tax                Money                  `json:"tax"`          // Added on top of prices
+	TaxIncluded        Money                  `json:"tax_included"` // Already part of prices
+	TaxBreakdown       []TaxBreakdown         `json:"tax_breakdown"`
+	Discount           Money                  `json:"discount"`
+	Total              Money                  `json:"total"`
+	Currency           string                 `json:"currency"`
+	PaymentMethod      *string                `json:"payment_method,omitempty"`
+	PaymentStatus      PaymentStatus          `json:"payment_status"`
+	Notes              *string                `json:"notes,omitempty"`
+	CreatedAt          time.Time              `json:"created_at"`
+	UpdatedAt          time.Time              `json:"updated_at"`
+	ConfirmedAt        *time.Time             `json:"confirmed_at,omitempty"`
+	ShippedAt          *time.Time             `json:"shipped_at,omitempty"`
+	DeliveredAt        *time.Time             `json:"delivered_at,omitempty"`
+	CancelledAt        *time.Time             `json:"cancelled_at,omitempty"`
+	CancellationReason *string                `json:"cancellation_reason,omitempty"`
 }
 
 // OrderSummary represents a simplified order for lists
@@ -155,22 +199,35 @@ type OrderSummary struct {
 	OrderNumber string      `json:"order_number"`
 	Status      OrderStatus `json:"status"`
 	ItemCount   int         `json:"item_count"`
-	Total       float64     `json:"total"`
+	Total       Money       `json:"total"`
 	CreatedAt   time.Time   `json:"created_at"`
 }
 
-// StripeSessionStatus represents status info from a Stripe checkout session
-type StripeSessionStatus struct {
-	SessionID     string    `json:"session_id"`
-	PaymentStatus string    `json:"payment_status"`
-	OrderID       uuid.UUID `json:"order_id"`
-	OrderNumber   string    `json:"order_number"`
+// PaymentMethodInfo is a payment method customers can choose at checkout.
+// Online methods are paid through a payment page before the order is confirmed.
+type PaymentMethodInfo struct {
+	Name   string `json:"name"`
+	Online bool   `json:"online"`
+}
+
+// PaymentSessionStatus is the state of an order's payment as its gateway
+// reports it. SessionID is the payment reference, the Stripe session ID for
+// Stripe payments.
+type PaymentSessionStatus struct {
+	SessionID     string        `json:"session_id"`
+	PaymentMethod string        `json:"payment_method"`
+	PaymentStatus PaymentStatus `json:"payment_status"`
+	OrderID       uuid.UUID     `json:"order_id"`
+	OrderNumber   string        `json:"order_number"`
 }
 
-// CreateCheckoutResponse is returned when a Stripe checkout order is created
+// CreateCheckoutResponse is returned when a checkout order is created. Online
+// payments continue at CheckoutURL, by redirect or, when CheckoutForm is set,
+// by POSTing its fields there.
 type CreateCheckoutResponse struct {
-	Order       *OrderResponse `json:"order"`
-	CheckoutURL string         `json:"checkout_url,omitempty"`
+	Order        *OrderResponse    `json:"order"`
+	CheckoutURL  string            `json:"checkout_url,omitempty"`
+	CheckoutForm map[string]string `json:"checkout_form,omitempty"`
 }
 
 // GenerateOrderNumber generates a unique order number
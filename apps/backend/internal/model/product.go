@@ -11,59 +11,171 @@ type Product struct {
 	ShopID        uuid.UUID  `json:"shop_id" db:"shop_id"`
 	CategoryID    *uuid.UUID `json:"category_id,omitempty" db:"category_id"`
 	Name          string     `json:"name" db:"name"`
+	Slug          string     `json:"slug" db:"slug"`
+	SKU           *string    `json:"sku,omitempty" db:"sku"`
 	Description   *string    `json:"description,omitempty" db:"description"`
-	Price         float64    `json:"price" db:"price"`
+	Price         Money      `json:"price" db:"price"`
 	StockQuantity int        `json:"stock_quantity" db:"stock_quantity"`
+	Weight        *float64   `json:"weight,omitempty" db:"weight"`
+	WeightUnit    string     `json:"weight_unit" db:"weight_unit"`
 	ImageURL      *string    `json:"image_url,omitempty" db:"image_url"`
 	IsActive      bool       `json:"is_active" db:"is_active"`
 	CreatedAt     time.Time  `json:"created_at" db:"created_at"`
 	UpdatedAt     time.Time  `json:"updated_at" db:"updated_at"`
+
+	// Images is the ordered gallery, loaded separately from product_images
+	Images []ProductImage `json:"images,omitempty" db:"-"`
+
+	// Options and Variants are loaded separately for single-product views
+	Options  []ProductOption  `json:"options,omitempty" db:"-"`
+	Variants []ProductVariant `json:"variants,omitempty" db:"-"`
+}
+
+// ProductImage represents one image in a product's gallery
+type ProductImage struct {
+	ID           uuid.UUID `json:"id" db:"id"`
+	ProductID    uuid.UUID `json:"product_id" db:"product_id"`
+	ImageURL     string    `json:"image_url" db:"image_url"`
+	AltText      *string   `json:"alt_text,omitempty" db:"alt_text"`
+	DisplayOrder int       `json:"display_order" db:"display_order"`
+	IsPrimary    bool      `json:"is_primary" db:"is_primary"`
+	CreatedAt    time.Time `json:"created_at" db:"created_at"`
 }
 
 type CreateProductRequest struct {
 	Name          string     `json:"name" validate:"required"`
+	SKU           *string    `json:"sku,omitempty" validate:"omitempty,max=100"` // Generated when not given
 	Description   *string    `json:"description,omitempty"`
-	Price         float64    `json:"price" validate:"required,gt=0"`
+	Price         Money      `json:"price" validate:"required,gt=0"`
 	StockQuantity int        `json:"stock_quantity" validate:"gte=0"`
+	Weight        *float64   `json:"weight,omitempty" validate:"omitempty,gt=0"`
+	WeightUnit    string     `json:"weight_unit,omitempty" validate:"omitempty,oneof=kg g lb oz"`
 	CategoryID    *uuid.UUID `json:"category_id,omitempty"`
 	ImageURL      *string    `json:"image_url,omitempty"`
 }
 
 type UpdateProductRequest struct {
 	Name          *string    `json:"name,omitempty"`
+	SKU           *string    `json:"sku,omitempty" validate:"omitempty,max=100"`
 	Description   *string    `json:"description,omitempty"`
-	Price         *float64   `json:"price,omitempty" validate:"omitempty,gt=0"`
+	Price         *Money     `json:"price,omitempty" validate:"omitempty,gt=0"`
 	StockQuantity *int       `json:"stock_quantity,omitempty" validate:"omitempty,gte=0"`
+	Weight        *float64   `json:"weight,omitempty" validate:"omitempty,gt=0"`
+	WeightUnit    *string    `json:"weight_unit,omitempty" validate:"omitempty,oneof=kg g lb oz"`
 	CategoryID    *uuid.UUID `json:"category_id,omitempty"`
 	ImageURL      *string    `json:"image_url,omitempty"`
 	IsActive      *bool      `json:"is_active,omitempty"`
 }
 
+type AddProductImageRequest struct {
+	ImageURL  string  `json:"image_url" validate:"required,url"`
+	AltText   *string `json:"alt_text,omitempty" validate:"omitempty,max=255"`
+	IsPrimary bool    `json:"is_primary"`
+}
+
+// ReorderProductImagesRequest lists every image ID of the product in the desired order
+type ReorderProductImagesRequest struct {
+	ImageIDs []uuid.UUID `json:"image_ids" validate:"required,min=1"`
+}
+
+// ProductListResponse is a page of products with facets counting the
+// matches by each filter
+type ProductListResponse struct {
+	Products   []*ProductResponse `json:"products"`
+	Total      int                `json:"total"`
+	Page       int                `json:"page"`
+	PageSize   int                `json:"page_size"`
+	TotalPages int                `json:"total_pages"`
+	Facets     *ProductFacets     `json:"facets"`
+}
+
+// ProductFacets counts the products matching a listing's filters by the
+// values of each filter, ignoring that filter's own selection
+type ProductFacets struct {
+	Categories    []FacetCount      `json:"categories"`
+	Shops         []FacetCount      `json:"shops"`
+	PriceRanges   []PriceRangeFacet `json:"price_ranges"`
+	Ratings       []RatingFacet     `json:"ratings"`
+	InStock       int               `json:"in_stock"`
+	VerifiedShops int               `json:"verified_shops"`
+}
+
+// FacetCount is how many products have a facet value, such as a category ID
+type FacetCount struct {
+	Value string `json:"value"`
+	Label string `json:"label"`
+	Count int    `json:"count"`
+}
+
+// PriceRangeFacet is how many products cost at least Min and less than Max.
+// The last range has no Max.
+type PriceRangeFacet struct {
+	Min   Money  `json:"min"`
+	Max   *Money `json:"max,omitempty"`
+	Count int    `json:"count"`
+}
+
+// RatingFacet is how many products average at least MinRating stars
+type RatingFacet struct {
+	MinRating int `json:"min_rating"`
+	Count     int `json:"count"`
+}
+
+// ProductSuggestion is an autocomplete match for a partly typed search
+type ProductSuggestion struct {
+	ID       uuid.UUID `json:"id"`
+	Name     string    `json:"name"`
+	Slug     string    `json:"slug"`
+	ImageURL *string   `json:"image_url,omitempty"`
+}
+
 type ProductResponse struct {
-	ID            uuid.UUID  `json:"id"`
-	ShopID        uuid.UUID  `json:"shop_id"`
-	CategoryID    *uuid.UUID `json:"category_id,omitempty"`
-	Name          string     `json:"name"`
-	Description   *string    `json:"description,omitempty"`
-	Price         float64    `json:"price"`
-	StockQuantity int        `json:"stock_quantity"`
-	ImageURL      *string    `json:"image_url,omitempty"`
-	IsActive      bool       `json:"is_active"`
-	CreatedAt     time.Time  `json:"created_at"`
-	UpdatedAt     time.Time  `json:"updated_at"`
+	ID            uuid.UUID        `json:"id"`
+	ShopID        uuid.UUID        `json:"shop_id"`
+	CategoryID    *uuid.UUID       `json:"category_id,omitempty"`
+	Name          string           `json:"name"`
+	Slug          string           `json:"slug"`
+	SKU           *string          `json:"sku,omitempty"`
+	Description   *string          `json:"description,omitempty"`
+	Price         Money            `json:"price"`
+	Currency      string           `json:"currency"`
+	StockQuantity int              `json:"stock_quantity"`
+	Weight        *float64         `json:"weight,omitempty"`
+	WeightUnit    string           `json:"weight_unit"`
+	ImageURL      *string          `json:"image_url,omitempty"`
+	IsActive      bool             `json:"is_active"`
+	Images        []ProductImage   `json:"images"`
+	Options       []ProductOption  `json:"options,omitempty"`
+	Variants      []ProductVariant `json:"variants,omitempty"`
+	InWishlist    *bool            `json:"in_wishlist,omitempty"` // Only set for authenticated requests
+	CreatedAt     time.Time        `json:"created_at"`
+	UpdatedAt     time.Time        `json:"updated_at"`
 }
 
 func (p *Product) ToResponse() *ProductResponse {
+	images := p.Images
+	if images == nil {
+		images = []ProductImage{}
+	}
+
 	return &ProductResponse{
 		ID:            p.ID,
 		ShopID:        p.ShopID,
 		CategoryID:    p.CategoryID,
 		Name:          p.Name,
+		Slug:          p.Slug,
+		SKU:           p.SKU,
 		Description:   p.Description,
 		Price:         p.Price,
+		Currency:      p.Price.CurrencyCode(),
 		StockQuantity: p.StockQuantity,
+		Weight:        p.Weight,
+		WeightUnit:    p.WeightUnit,
 		ImageURL:      p.ImageURL,
 		IsActive:      p.IsActive,
+		Images:        images,
+		Options:       p.Options,
+		Variants:      p.Variants,
 		CreatedAt:     p.CreatedAt,
 		UpdatedAt:     p.UpdatedAt,
 	}
@@ -0,0 +1,53 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type RefundStatus string
+
+const (
+	RefundStatusPending   RefundStatus = "pending"
+	RefundStatusSucceeded RefundStatus = "succeeded"
+	RefundStatusFailed    RefundStatus = "failed"
+)
+
+// Refund is money returned to the customer for (part of) an order
+type Refund struct {
+	ID               uuid.UUID    `json:"id" db:"id"`
+	OrderID          uuid.UUID    `json:"order_id" db:"order_id"`
+	ProviderRefundID *string      `json:"provider_refund_id,omitempty" db:"provider_refund_id"` // The payment gateway's refund ID
+	Amount           Money        `json:"amount" db:"amount"`
+	Reason           *string      `json:"reason,omitempty" db:"reason"`
+	Status           RefundStatus `json:"status" db:"status"`
+	FailureReason    *string      `json:"failure_reason,omitempty" db:"failure_reason"`
+	Restock          bool         `json:"restock" db:"restock"`
+	CreatedBy        *uuid.UUID   `json:"created_by,omitempty" db:"created_by"`
+	Items            []RefundItem `json:"items" db:"-"`
+	CreatedAt        time.Time    `json:"created_at" db:"created_at"`
+	UpdatedAt        time.Time    `json:"updated_at" db:"updated_at"`
+}
+
+// RefundItem is the quantity of one order line covered by a refund
+type RefundItem struct {
+	ID          uuid.UUID `json:"id" db:"id"`
+	RefundID    uuid.UUID `json:"refund_id" db:"refund_id"`
+	OrderItemID uuid.UUID `json:"order_item_id" db:"order_item_id"`
+	Quantity    int       `json:"quantity" db:"quantity"`
+	Amount      Money     `json:"amount" db:"amount"`
+}
+
+// CreateRefundRequest refunds the listed order lines, or everything still
+// refundable when no items are given
+type CreateRefundRequest struct {
+	Items   []RefundItemInput `json:"items,omitempty" validate:"omitempty,dive"`
+	Restock bool              `json:"restock"` // Put refunded quantities back in stock
+	Reason  *string           `json:"reason,omitempty" validate:"omitempty,max=500"`
+}
+
+type RefundItemInput struct {
+	OrderItemID uuid.UUID `json:"order_item_id" validate:"required"`
+	Quantity    int       `json:"quantity" validate:"required,min=1"`
+}
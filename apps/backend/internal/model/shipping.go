@@ -0,0 +1,198 @@
+package model
+
+import (
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type ShippingRateType string
+
+const (
+	ShippingRateFlat   ShippingRateType = "flat"
+	ShippingRateWeight ShippingRateType = "weight"
+)
+
+const (
+	WeightUnitKg = "kg"
+	WeightUnitG  = "g"
+	WeightUnitLb = "lb"
+	WeightUnitOz = "oz"
+)
+
+// ShippingZone is a destination area shops price their shipping for. A zone
+// covers a country, optionally narrowed to some states and/or cities, e.g.
+// "Kathmandu Valley" (Nepal; Kathmandu, Lalitpur, Bhaktapur) or "Nepal".
+type ShippingZone struct {
+	ID        uuid.UUID `json:"id" db:"id"`
+	Name      string    `json:"name" db:"name"`
+	Country   string    `json:"country" db:"country"`
+	States    []string  `json:"states" db:"states"`
+	Cities    []string  `json:"cities" db:"cities"`
+	IsActive  bool      `json:"is_active" db:"is_active"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// Matches reports whether the destination lies in the zone
+func (z *ShippingZone) Matches(dest ShippingDestination) bool {
+	if !strings.EqualFold(strings.TrimSpace(z.Country), strings.TrimSpace(dest.Country)) {
+		return false
+	}
+	if len(z.States) > 0 && (dest.State == nil || !containsFold(z.States, *dest.State)) {
+		return false
+	}
+	if len(z.Cities) > 0 && !containsFold(z.Cities, dest.City) {
+		return false
+	}
+	return true
+}
+
+// Specificity ranks zones so a city zone wins over a state zone, which wins
+// over a whole country
+func (z *ShippingZone) Specificity() int {
+	switch {
+	case len(z.Cities) > 0:
+		return 2
+	case len(z.States) > 0:
+		return 1
+	default:
+		return 0
+	}
+}
+
+func containsFold(values []string, value string) bool {
+	for _, v := range values {
+		if strings.EqualFold(strings.TrimSpace(v), strings.TrimSpace(value)) {
+			return true
+		}
+	}
+	return false
+}
+
+// ShippingMethod is how a shop ships to a zone. Flat methods charge BaseRate;
+// weight methods charge BaseRate plus PerKgRate for every started kilogram.
+// Shipments whose subtotal reaches FreeAbove ship free.
+type ShippingMethod struct {
+	ID        uuid.UUID        `json:"id" db:"id"`
+	ShopID    uuid.UUID        `json:"shop_id" db:"shop_id"`
+	ZoneID    uuid.UUID        `json:"zone_id" db:"zone_id"`
+	Name      string           `json:"name" db:"name"`
+	RateType  ShippingRateType `json:"rate_type" db:"rate_type"`
+	BaseRate  Money            `json:"base_rate" db:"base_rate"`
+	PerKgRate Money            `json:"per_kg_rate" db:"per_kg_rate"`
+	FreeAbove *Money           `json:"free_above,omitempty" db:"free_above"`
+	MinDays   *int             `json:"min_days,omitempty" db:"min_days"`
+	MaxDays   *int             `json:"max_days,omitempty" db:"max_days"`
+	IsActive  bool             `json:"is_active" db:"is_active"`
+	CreatedAt time.Time        `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time        `json:"updated_at" db:"updated_at"`
+}
+
+// ShippingDestination is the part of an address shipping is priced on
+type ShippingDestination struct {
+	City    string  `json:"city"`
+	State   *string `json:"state,omitempty"`
+	Country string  `json:"country"`
+}
+
+// DestinationOf returns the shipping destination of an address
+func DestinationOf(address *Address) ShippingDestination {
+	return ShippingDestination{City: address.City, State: address.State, Country: address.Country}
+}
+
+// ShippingOption is one way a shop can ship a cart's items. MethodID is nil
+// for shops that have not set up shipping, which ship for free.
+type ShippingOption struct {
+	MethodID *uuid.UUID `json:"method_id,omitempty"`
+	Name     string     `json:"name"`
+	ZoneName *string    `json:"zone_name,omitempty"`
+	Cost     Money      `json:"cost"`
+	MinDays  *int       `json:"min_days,omitempty"`
+	MaxDays  *int       `json:"max_days,omitempty"`
+}
+
+// ShopShippingQuote lists the shipping options of one shop's items, cheapest
+// first. A shop without options does not ship to the destination.
+type ShopShippingQuote struct {
+	ShopID   uuid.UUID        `json:"shop_id"`
+	ShopName string           `json:"shop_name"`
+	Subtotal Money            `json:"subtotal"`
+	WeightKg float64          `json:"weight_kg"`
+	Options  []ShippingOption `json:"options"`
+}
+
+// ShippingQuote is the shipping of a cart to a destination, per shop.
+// ShippingCost is the total when every shop ships with its cheapest option.
+type ShippingQuote struct {
+	Destination  ShippingDestination `json:"destination"`
+	Shops        []ShopShippingQuote `json:"shops"`
+	ShippingCost Money               `json:"shipping_cost"`
+	Currency     string              `json:"currency"`
+	Deliverable  bool                `json:"deliverable"`
+}
+
+// ShippingQuoteRequest names the destination of a quote: a saved address or
+// a city and country typed in at checkout. Costs are quoted in Currency, the
+// default currency when empty.
+type ShippingQuoteRequest struct {
+	ShippingAddressID *uuid.UUID `json:"shipping_address_id,omitempty"`
+	City              string     `json:"city,omitempty"`
+	State             *string    `json:"state,omitempty"`
+	Country           string     `json:"country,omitempty"`
+	Currency          string     `json:"currency,omitempty"`
+}
+
+type CreateShippingZoneRequest struct {
+	Name    string   `json:"name" validate:"required,max=100"`
+	Country string   `json:"country" validate:"required,max=100"`
+	States  []string `json:"states,omitempty"`
+	Cities  []string `json:"cities,omitempty"`
+}
+
+type UpdateShippingZoneRequest struct {
+	Name     *string  `json:"name,omitempty" validate:"omitempty,max=100"`
+	Country  *string  `json:"country,omitempty" validate:"omitempty,max=100"`
+	States   []string `json:"states,omitempty"`
+	Cities   []string `json:"cities,omitempty"`
+	IsActive *bool    `json:"is_active,omitempty"`
+}
+
+// CreateShippingMethodRequest creates a shipping method. Vendors always create
+// methods for their own shop; admins must name the shop.
+type CreateShippingMethodRequest struct {
+	ShopID    *uuid.UUID       `json:"shop_id,omitempty"`
+	ZoneID    uuid.UUID        `json:"zone_id" validate:"required"`
+	Name      string           `json:"name" validate:"required,max=100"`
+	RateType  ShippingRateType `json:"rate_type" validate:"required,oneof=flat weight"`
+	BaseRate  Money            `json:"base_rate" validate:"gte=0"`
+	PerKgRate Money            `json:"per_kg_rate" validate:"gte=0"`
+	FreeAbove *Money           `json:"free_above,omitempty" validate:"omitempty,gte=0"`
+	MinDays   *int             `json:"min_days,omitempty" validate:"omitempty,gte=0"`
+	MaxDays   *int             `json:"max_days,omitempty" validate:"omitempty,gte=0"`
+}
+
+type UpdateShippingMethodRequest struct {
+	Name      *string `json:"name,omitempty" validate:"omitempty,max=100"`
+	BaseRate  *Money  `json:"base_rate,omitempty" validate:"omitempty,gte=0"`
+	PerKgRate *Money  `json:"per_kg_rate,omitempty" validate:"omitempty,gte=0"`
+	FreeAbove *Money  `json:"free_above,omitempty" validate:"omitempty,gte=0"`
+	MinDays   *int    `json:"min_days,omitempty" validate:"omitempty,gte=0"`
+	MaxDays   *int    `json:"max_days,omitempty" validate:"omitempty,gte=0"`
+	IsActive  *bool   `json:"is_active,omitempty"`
+}
+
+// WeightInKg converts a product weight to kilograms. Unknown units are taken as kg.
+func WeightInKg(weight float64, unit string) float64 {
+	switch unit {
+	case WeightUnitG:
+		return weight / 1000
+	case WeightUnitLb:
+		return weight * 0.45359237
+	case WeightUnitOz:
+		return weight * 0.028349523125
+	default:
+		return weight
+	}
+}
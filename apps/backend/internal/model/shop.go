@@ -21,6 +21,7 @@ type Shop struct {
 	PostalCode  *string   `json:"postal_code"`
 	Phone       *string   `json:"phone"`
 	Email       *string   `json:"email"`
+	Currency    string    `json:"currency"` // Products, shipping and coupons are priced in it
 	IsActive    bool      `json:"is_active"`
 	IsVerified  bool      `json:"is_verified"`
 	CreatedAt   time.Time `json:"created_at"`
@@ -37,6 +38,7 @@ type CreateShopRequest struct {
 	PostalCode  *string `json:"postal_code" validate:"omitempty,max=20"`
 	Phone       *string `json:"phone" validate:"omitempty,max=20"`
 	Email       *string `json:"email" validate:"omitempty,email"`
+	Currency    string  `json:"currency" validate:"omitempty,iso4217"`
 }
 
 type UpdateShopRequest struct {
@@ -51,6 +53,7 @@ type UpdateShopRequest struct {
 	PostalCode  *string `json:"postal_code" validate:"omitempty,max=20"`
 	Phone       *string `json:"phone" validate:"omitempty,max=20"`
 	Email       *string `json:"email" validate:"omitempty,email"`
+	Currency    *string `json:"currency" validate:"omitempty,iso4217"`
 }
 
 type ShopResponse struct {
@@ -68,6 +71,7 @@ type ShopResponse struct {
 	PostalCode  *string   `json:"postal_code"`
 	Phone       *string   `json:"phone"`
 	Email       *string   `json:"email"`
+	Currency    string    `json:"currency"`
 	IsActive    bool      `json:"is_active"`
 	IsVerified  bool      `json:"is_verified"`
 	CreatedAt   time.Time `json:"created_at"`
@@ -78,7 +82,7 @@ type ShopWithStats struct {
 	ShopResponse
 	TotalProducts int     `json:"total_products"`
 	TotalOrders   int     `json:"total_orders"`
-	TotalRevenue  float64 `json:"total_revenue"`
+	TotalRevenue  Money   `json:"total_revenue"`
 	AverageRating float64 `json:"average_rating"`
 }
 
@@ -107,6 +111,7 @@ func (s *Shop) ToResponse() ShopResponse {
 		PostalCode:  s.PostalCode,
 		Phone:       s.Phone,
 		Email:       s.Email,
+		Currency:    s.Currency,
 		IsActive:    s.IsActive,
 		IsVerified:  s.IsVerified,
 		CreatedAt:   s.CreatedAt,
@@ -0,0 +1,40 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type ReservationStatus string
+
+const (
+	ReservationStatusActive    ReservationStatus = "active"
+	ReservationStatusCommitted ReservationStatus = "committed"
+	ReservationStatusReleased  ReservationStatus = "released"
+)
+
+const (
+	// StockReservationTTL is how long a pending online order holds its stock.
+	// It doubles as the payment lifetime. Stripe wants a session to live at
+	// least 30 minutes from when it creates it, so the extra minute covers
+	// the time until the session is created.
+	StockReservationTTL = 31 * time.Minute
+
+	// StockReservationGracePeriod keeps a reservation alive past the session
+	// expiry so the checkout.session.* webhook settles it before the sweeper does
+	StockReservationGracePeriod = 15 * time.Minute
+)
+
+// StockReservation is stock held for an order that has not been paid yet
+type StockReservation struct {
+	ID        uuid.UUID         `json:"id" db:"id"`
+	OrderID   uuid.UUID         `json:"order_id" db:"order_id"`
+	ProductID uuid.UUID         `json:"product_id" db:"product_id"`
+	VariantID *uuid.UUID        `json:"variant_id,omitempty" db:"variant_id"`
+	Quantity  int               `json:"quantity" db:"quantity"`
+	Status    ReservationStatus `json:"status" db:"status"`
+	ExpiresAt time.Time         `json:"expires_at" db:"expires_at"`
+	CreatedAt time.Time         `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time         `json:"updated_at" db:"updated_at"`
+}
@@ -0,0 +1,90 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// TaxRate is a tax rule for a country, optionally narrowed to a state and/or
+// a product category (including its subcategories). Rate is a percentage.
+// Inclusive rates are part of listed prices; exclusive ones are added on top.
+type TaxRate struct {
+	ID         uuid.UUID  `json:"id" db:"id"`
+	Name       string     `json:"name" db:"name"`
+	Country    string     `json:"country" db:"country"`
+	State      *string    `json:"state,omitempty" db:"state"`
+	CategoryID *uuid.UUID `json:"category_id,omitempty" db:"category_id"`
+	Rate       float64    `json:"rate" db:"rate"`
+	Inclusive  bool       `json:"inclusive" db:"inclusive"`
+	IsActive   bool       `json:"is_active" db:"is_active"`
+	CreatedAt  time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at" db:"updated_at"`
+}
+
+// LineTax is the tax charged on one order line. TaxableAmount is the line net
+// of discount and, for inclusive rates, of the tax itself.
+type LineTax struct {
+	Name          *string `json:"name,omitempty"`
+	Rate          float64 `json:"rate"`
+	TaxableAmount Money   `json:"taxable_amount"`
+	Amount        Money   `json:"amount"`
+	Inclusive     bool    `json:"inclusive"`
+}
+
+// TaxBreakdown sums an order's tax per rate for invoices
+type TaxBreakdown struct {
+	Name          string  `json:"name"`
+	Rate          float64 `json:"rate"`
+	Inclusive     bool    `json:"inclusive"`
+	TaxableAmount Money   `json:"taxable_amount"`
+	Amount        Money   `json:"amount"`
+}
+
+type CreateTaxRateRequest struct {
+	Name       string     `json:"name" validate:"required,max=100"`
+	Country    string     `json:"country" validate:"required,max=100"`
+	State      *string    `json:"state,omitempty" validate:"omitempty,max=100"`
+	CategoryID *uuid.UUID `json:"category_id,omitempty"`
+	Rate       float64    `json:"rate" validate:"gte=0,lte=100"`
+	Inclusive  bool       `json:"inclusive"`
+}
+
+type UpdateTaxRateRequest struct {
+	Name      *string  `json:"name,omitempty" validate:"omitempty,max=100"`
+	Rate      *float64 `json:"rate,omitempty" validate:"omitempty,gte=0,lte=100"`
+	Inclusive *bool    `json:"inclusive,omitempty"`
+	IsActive  *bool    `json:"is_active,omitempty"`
+}
+
+// TaxBreakdownOf groups the tax of order items by rate
+func TaxBreakdownOf(items []OrderItemWithDetails) []TaxBreakdown {
+	breakdown := []TaxBreakdown{}
+	for _, item := range items {
+		if item.TaxName == nil {
+			continue
+		}
+
+		taxable := item.TaxableAmount
+		found := false
+		for i := range breakdown {
+			b := &breakdown[i]
+			if b.Name == *item.TaxName && b.Rate == item.TaxRate && b.Inclusive == item.TaxInclusive {
+				b.TaxableAmount = b.TaxableAmount.Add(taxable)
+				b.Amount = b.Amount.Add(item.TaxAmount)
+				found = true
+				break
+			}
+		}
+		if !found {
+			breakdown = append(breakdown, TaxBreakdown{
+				Name:          *item.TaxName,
+				Rate:          item.TaxRate,
+				Inclusive:     item.TaxInclusive,
+				TaxableAmount: taxable,
+				Amount:        item.TaxAmount,
+			})
+		}
+	}
+	return breakdown
+}
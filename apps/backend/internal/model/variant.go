@@ -0,0 +1,95 @@
+package model
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ProductOption is an option type a product's variants differ by, such as
+// Size, with the values it takes
+type ProductOption struct {
+	ID           uuid.UUID `json:"id" db:"id"`
+	ProductID    uuid.UUID `json:"product_id" db:"product_id"`
+	Name         string    `json:"name" db:"name"`
+	Values       []string  `json:"values" db:"option_values"`
+	DisplayOrder int       `json:"display_order" db:"display_order"`
+	CreatedAt    time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// ProductVariant is one purchasable combination of a product's option values
+// with its own SKU and stock. A nil Price or ImageURL uses the product's.
+type ProductVariant struct {
+	ID            uuid.UUID         `json:"id" db:"id"`
+	ProductID     uuid.UUID         `json:"product_id" db:"product_id"`
+	SKU           string            `json:"sku" db:"sku"`
+	Options       map[string]string `json:"options" db:"options"` // Option name to value
+	Price         *Money            `json:"price,omitempty" db:"price"`
+	StockQuantity int               `json:"stock_quantity" db:"stock_quantity"`
+	ImageURL      *string           `json:"image_url,omitempty" db:"image_url"`
+	IsActive      bool              `json:"is_active" db:"is_active"`
+	CreatedAt     time.Time         `json:"created_at" db:"created_at"`
+	UpdatedAt     time.Time         `json:"updated_at" db:"updated_at"`
+}
+
+// PriceOf returns what the variant sells for given its product's price
+func (v *ProductVariant) PriceOf(productPrice Money) Money {
+	if v.Price == nil {
+		return productPrice
+	}
+	price := *v.Price
+	price.Currency = productPrice.Currency
+	return price
+}
+
+// Name describes the variant by its option values, e.g. "Colour: Red / Size: M"
+func (v *ProductVariant) Name() string {
+	return VariantName(v.Options)
+}
+
+// VariantName describes a combination of option values, ordered by option name
+func VariantName(options map[string]string) string {
+	names := make([]string, 0, len(options))
+	for name := range options {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	parts := make([]string, len(names))
+	for i, name := range names {
+		parts[i] = name + ": " + options[name]
+	}
+	return strings.Join(parts, " / ")
+}
+
+// ProductVariantList is a product's option types and the variants built from them
+type ProductVariantList struct {
+	Options  []ProductOption  `json:"options"`
+	Variants []ProductVariant `json:"variants"`
+}
+
+type CreateProductOptionRequest struct {
+	Name   string   `json:"name" validate:"required,max=50"`
+	Values []string `json:"values" validate:"required,min=1,dive,required,max=100"`
+}
+
+// CreateProductVariantRequest adds a variant. Options must give one value for
+// each of the product's option types.
+type CreateProductVariantRequest struct {
+	SKU           string            `json:"sku" validate:"required,max=100"`
+	Options       map[string]string `json:"options" validate:"required,min=1"`
+	Price         *Money            `json:"price,omitempty" validate:"omitempty,gte=0"`
+	StockQuantity int               `json:"stock_quantity" validate:"gte=0"`
+	ImageURL      *string           `json:"image_url,omitempty" validate:"omitempty,url"`
+}
+
+type UpdateProductVariantRequest struct {
+	SKU           *string `json:"sku,omitempty" validate:"omitempty,max=100"`
+	Price         *Money  `json:"price,omitempty" validate:"omitempty,gte=0"`
+	StockQuantity *int    `json:"stock_quantity,omitempty" validate:"omitempty,gte=0"`
+	ImageURL      *string `json:"image_url,omitempty" validate:"omitempty,url"`
+	IsActive      *bool   `json:"is_active,omitempty"`
+}
@@ -0,0 +1,40 @@
+package model
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type WebhookEventStatus string
+
+const (
+	WebhookEventStatusProcessing WebhookEventStatus = "processing"
+	WebhookEventStatusProcessed  WebhookEventStatus = "processed"
+	WebhookEventStatusFailed     WebhookEventStatus = "failed"
+)
+
+// WebhookEvent is a received webhook event or payment callback and the
+// outcome of processing it. Provider is the payment method that sent it.
+type WebhookEvent struct {
+	ID          uuid.UUID          `json:"id" db:"id"`
+	Provider    string             `json:"provider" db:"provider"`
+	EventID     string             `json:"event_id" db:"event_id"`
+	EventType   string             `json:"event_type" db:"event_type"`
+	Payload     json.RawMessage    `json:"payload" db:"payload"`
+	Status      WebhookEventStatus `json:"status" db:"status"`
+	Attempts    int                `json:"attempts" db:"attempts"`
+	LastError   *string            `json:"last_error,omitempty" db:"last_error"`
+	ReceivedAt  time.Time          `json:"received_at" db:"received_at"`
+	ProcessedAt *time.Time         `json:"processed_at,omitempty" db:"processed_at"`
+	UpdatedAt   time.Time          `json:"updated_at" db:"updated_at"`
+}
+
+type WebhookEventListResponse struct {
+	Events     []*WebhookEvent `json:"events"`
+	Total      int             `json:"total"`
+	Page       int             `json:"page"`
+	PageSize   int             `json:"page_size"`
+	TotalPages int             `json:"total_pages"`
+}
@@ -0,0 +1,48 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// WishlistItem represents a product saved to a user's wishlist
+type WishlistItem struct {
+	ID        uuid.UUID `json:"id" db:"id"`
+	UserID    uuid.UUID `json:"user_id" db:"user_id"`
+	ProductID uuid.UUID `json:"product_id" db:"product_id"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
+
+// WishlistItemWithProduct represents a wishlist item with product details
+type WishlistItemWithProduct struct {
+	ID              uuid.UUID `json:"id" db:"id"`
+	ProductID       uuid.UUID `json:"product_id" db:"product_id"`
+	ProductName     string    `json:"product_name" db:"product_name"`
+	ProductPrice    Money     `json:"product_price" db:"product_price"`
+	Currency        string    `json:"currency" db:"currency"`
+	ProductImageURL *string   `json:"product_image_url,omitempty" db:"product_image_url"`
+	StockQuantity   int       `json:"stock_quantity" db:"stock_quantity"`
+	IsActive        bool      `json:"is_active" db:"is_active"`
+	ShopID          uuid.UUID `json:"shop_id" db:"shop_id"`
+	ShopName        string    `json:"shop_name" db:"shop_name"`
+	CreatedAt       time.Time `json:"created_at" db:"created_at"`
+}
+
+// AddToWishlistRequest represents request to add a product to the wishlist
+type AddToWishlistRequest struct {
+	ProductID uuid.UUID `json:"product_id" validate:"required"`
+}
+
+// MoveToCartRequest represents request to move a wishlist item into the cart.
+// Products sold by variant need the variant to move.
+type MoveToCartRequest struct {
+	VariantID *uuid.UUID `json:"variant_id,omitempty"`
+	Quantity  int        `json:"quantity" validate:"omitempty,min=1"`
+}
+
+// WishlistResponse represents the user's wishlist
+type WishlistResponse struct {
+	Items     []WishlistItemWithProduct `json:"items"`
+	ItemCount int                       `json:"item_count"`
+}
@@ -0,0 +1,46 @@
+package payment
+
+import (
+	"context"
+
+	"github.com/imbivek08/hamropasal/internal/model"
+)
+
+// COD is cash on delivery: orders are confirmed at checkout and paid to the
+// courier, so there is no gateway to talk to
+type COD struct{}
+
+func NewCOD() *COD { return &COD{} }
+
+func (p *COD) Name() string { return MethodCOD }
+
+func (p *COD) Online() bool { return false }
+
+// Initiate has nothing to start; the customer pays on delivery
+func (p *COD) Initiate(ctx context.Context, checkout *Checkout) (*Session, error) {
+	return &Session{}, nil
+}
+
+// Verify reports the payment as recorded on the order, which is marked paid
+// once it is delivered
+func (p *COD) Verify(ctx context.Context, order *model.Order) (*Payment, error) {
+	payment := &Payment{OrderID: order.ID, Status: StatusPending}
+	if order.PaymentStatus != model.PaymentStatusPending && order.PaymentStatus != model.PaymentStatusFailed {
+		payment.Status = StatusPaid
+		payment.Amount = order.Total
+	}
+	return payment, nil
+}
+
+func (p *COD) HandleCallback(ctx context.Context, callback *Callback) (*Event, error) {
+	return nil, ErrNoCallbacks
+}
+
+// Refund succeeds straight away: the cash is handed back outside the shop
+func (p *COD) Refund(ctx context.Context, req *RefundRequest) (*RefundUpdate, error) {
+	return &RefundUpdate{
+		RefundID: req.RefundID,
+		Amount:   req.Amount,
+		Status:   model.RefundStatusSucceeded,
+	}, nil
+}
@@ -0,0 +1,293 @@
+package payment
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/imbivek08/hamropasal/internal/model"
+)
+
+// eSewa's test environment
+const (
+	EsewaTestFormURL   = "https://rc-epay.esewa.com.np/api/epay/main/v2/form"
+	EsewaTestStatusURL = "https://rc.esewa.com.np/api/epay/transaction/status/"
+)
+
+// esewaSignedFields are the form fields eSewa checks the signature of
+const esewaSignedFields = "total_amount,transaction_uuid,product_code"
+
+// esewaResponseFields are the fields eSewa signs in the data it returns.
+// Callbacks signed over any other list are rejected: the payment form's own
+// signature would otherwise pass for a completed payment.
+const esewaResponseFields = "transaction_code,status,total_amount,transaction_uuid,product_code,signed_field_names"
+
+// Esewa takes payments through eSewa ePay v2. The customer is sent to eSewa
+// with a signed form and comes back with the signed outcome. See
+// https://developer.esewa.com.np/pages/Epay
+type Esewa struct {
+	productCode string
+	secretKey   string
+	formURL     string
+	statusURL   string
+	client      *http.Client
+}
+
+// NewEsewa creates an eSewa provider, using the test environment for URLs
+// left empty
+func NewEsewa(productCode, secretKey, formURL, statusURL string) *Esewa {
+	if formURL == "" {
+		formURL = EsewaTestFormURL
+	}
+	if statusURL == "" {
+		statusURL = EsewaTestStatusURL
+	}
+
+	return &Esewa{
+		productCode: productCode,
+		secretKey:   secretKey,
+		formURL:     formURL,
+		statusURL:   statusURL,
+		client:      &http.Client{Timeout: 15 * time.Second},
+	}
+}
+
+func (p *Esewa) Name() string { return MethodEsewa }
+
+func (p *Esewa) Online() bool { return true }
+
+// Initiate signs the payment form the customer posts to eSewa. eSewa sends
+// the customer to ReturnURL when paid and to CancelURL otherwise.
+func (p *Esewa) Initiate(ctx context.Context, checkout *Checkout) (*Session, error) {
+	if err := nprOnly(p.Name(), checkout.Order); err != nil {
+		return nil, err
+	}
+
+	fields := map[string]string{
+		"amount":                  rupees(checkout.Order.Total),
+		"tax_amount":              "0",
+		"product_service_charge":  "0",
+		"product_delivery_charge": "0",
+		"total_amount":            rupees(checkout.Order.Total),
+		"transaction_uuid":        uuid.New().String(),
+		"product_code":            p.productCode,
+		"success_url":             checkout.ReturnURL,
+		"failure_url":             checkout.CancelURL,
+		"signed_field_names":      esewaSignedFields,
+	}
+	fields["signature"] = esewaSign(p.secretKey, fields)
+
+	return &Session{
+		Reference:   fields["transaction_uuid"],
+		RedirectURL: p.formURL,
+		FormFields:  fields,
+	}, nil
+}
+
+// Verify asks eSewa's transaction status API about the order's payment
+func (p *Esewa) Verify(ctx context.Context, order *model.Order) (*Payment, error) {
+	if order.PaymentReference == nil {
+		return nil, errors.New("order has no esewa payment")
+	}
+
+	status, err := p.lookup(ctx, *order.PaymentReference, rupees(order.Total))
+	if err != nil {
+		return nil, err
+	}
+
+	payment := &Payment{
+		OrderID:   order.ID,
+		Reference: status.TransactionUUID,
+		Status:    esewaStatus(status.Status),
+	}
+	if status.RefID != nil {
+		payment.TransactionID = *status.RefID
+	}
+	if payment.Amount, err = parseRupees(status.TotalAmount.String()); err != nil {
+		return nil, err
+	}
+
+	return payment, nil
+}
+
+// esewaTransaction is a payment as reported by eSewa's transaction status API
+type esewaTransaction struct {
+	TransactionUUID string      `json:"transaction_uuid"`
+	TotalAmount     json.Number `json:"total_amount"`
+	Status          string      `json:"status"`
+	RefID           *string     `json:"ref_id"`
+}
+
+// lookup asks eSewa's transaction status API about a payment
+func (p *Esewa) lookup(ctx context.Context, transactionUUID, totalAmount string) (*esewaTransaction, error) {
+	query := url.Values{
+		"product_code":     {p.productCode},
+		"total_amount":     {totalAmount},
+		"transaction_uuid": {transactionUUID},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.statusURL+"?"+query.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var status esewaTransaction
+	if err := doJSON(p.client, req, &status); err != nil {
+		return nil, fmt.Errorf("failed to get esewa payment status: %w", err)
+	}
+	return &status, nil
+}
+
+// HandleCallback checks the signature of the base64 data eSewa appends to
+// ReturnURL. A completed payment is only reported as paid once the status
+// API confirms it.
+func (p *Esewa) HandleCallback(ctx context.Context, callback *Callback) (*Event, error) {
+	payload := callback.Body
+	if !callback.Verified {
+		decoded, err := base64.StdEncoding.DecodeString(callback.Query.Get("data"))
+		if err != nil || len(decoded) == 0 {
+			return nil, fmt.Errorf("%w: missing data", ErrInvalidCallback)
+		}
+		payload = decoded
+	}
+
+	fields, err := decodeFields(payload)
+	if err != nil {
+		return nil, err
+	}
+	if !callback.Verified {
+		if err := esewaVerify(p.secretKey, fields); err != nil {
+			return nil, err
+		}
+	}
+	if fields["product_code"] != p.productCode {
+		return nil, fmt.Errorf("%w: unexpected product code", ErrInvalidCallback)
+	}
+
+	if esewaStatus(fields["status"]) == StatusPaid {
+		status, err := p.lookup(ctx, fields["transaction_uuid"], fields["total_amount"])
+		if err != nil {
+			return nil, err
+		}
+		// The status API is authoritative; a payment it has not completed
+		// stays pending or fails as it reports
+		fields["status"] = status.Status
+		if status.RefID != nil {
+			fields["transaction_code"] = *status.RefID
+		}
+	}
+
+	return esewaEvent(fields, payload)
+}
+
+// Refund is not offered by eSewa's API; refunds are made in the merchant portal
+func (p *Esewa) Refund(ctx context.Context, req *RefundRequest) (*RefundUpdate, error) {
+	return nil, ErrRefundUnsupported
+}
+
+// esewaEvent reports the payment described by authenticated callback fields
+func esewaEvent(fields map[string]string, payload []byte) (*Event, error) {
+	amount, err := parseRupees(fields["total_amount"])
+	if err != nil {
+		return nil, err
+	}
+
+	return &Event{
+		ID:      fields["transaction_uuid"] + ":" + fields["status"],
+		Type:    "payment." + strings.ToLower(fields["status"]),
+		Payload: payload,
+		Payment: &Payment{
+			Reference:     fields["transaction_uuid"],
+			TransactionID: fields["transaction_code"],
+			Status:        esewaStatus(fields["status"]),
+			Amount:        amount,
+		},
+	}, nil
+}
+
+func esewaStatus(status string) Status {
+	switch status {
+	case "COMPLETE", "FULL_REFUND", "PARTIAL_REFUND":
+		return StatusPaid
+	case "CANCELED", "NOT_FOUND":
+		return StatusFailed
+	default:
+		return StatusPending
+	}
+}
+
+// esewaSign signs the fields named by signed_field_names: base64 of the
+// HMAC-SHA256 of "name=value" pairs joined by commas
+func esewaSign(secretKey string, fields map[string]string) string {
+	names := strings.Split(fields["signed_field_names"], ",")
+	pairs := make([]string, len(names))
+	for i, name := range names {
+		pairs[i] = name + "=" + fields[name]
+	}
+
+	mac := hmac.New(sha256.New, []byte(secretKey))
+	mac.Write([]byte(strings.Join(pairs, ",")))
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func esewaVerify(secretKey string, fields map[string]string) error {
+	if fields["signature"] == "" {
+		return fmt.Errorf("%w: missing signature", ErrInvalidCallback)
+	}
+	if fields["signed_field_names"] != esewaResponseFields {
+		return fmt.Errorf("%w: unexpected signed fields", ErrInvalidCallback)
+	}
+	expected := esewaSign(secretKey, fields)
+	if !hmac.Equal([]byte(expected), []byte(fields["signature"])) {
+		return fmt.Errorf("%w: signature mismatch", ErrInvalidCallback)
+	}
+	return nil
+}
+
+// decodeFields reads a JSON object of callback fields as the strings they
+// were signed as, keeping numbers exactly as written
+func decodeFields(payload []byte) (map[string]string, error) {
+	decoder := json.NewDecoder(bytes.NewReader(payload))
+	decoder.UseNumber()
+
+	var raw map[string]interface{}
+	if err := decoder.Decode(&raw); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidCallback, err)
+	}
+
+	fields := make(map[string]string, len(raw))
+	for name, value := range raw {
+		if value != nil {
+			fields[name] = fmt.Sprint(value)
+		}
+	}
+	return fields, nil
+}
+
+// rupees formats an amount in paisa as the rupee amount eSewa expects
+func rupees(amount model.Money) string {
+	if amount.Amount%100 == 0 {
+		return strconv.FormatInt(amount.Amount/100, 10)
+	}
+	return fmt.Sprintf("%d.%02d", amount.Amount/100, amount.Amount%100)
+}
+
+// parseRupees reads a rupee amount such as "1,000.0" into NPR
+func parseRupees(value string) (model.Money, error) {
+	amount, err := strconv.ParseFloat(strings.ReplaceAll(value, ",", ""), 64)
+	if err != nil {
+		return model.Money{}, fmt.Errorf("%w: invalid amount %q", ErrInvalidCallback, value)
+	}
+	return model.NewMoney(int64(math.Round(amount*100)), "NPR"), nil
+}
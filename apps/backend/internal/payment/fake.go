@@ -0,0 +1,312 @@
+package payment
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/google/uuid"
+	"github.com/imbivek08/hamropasal/internal/model"
+	"github.com/stripe/stripe-go/v84"
+	"github.com/stripe/stripe-go/v84/webhook"
+)
+
+// fakeProductCode is the eSewa product code of fake payments
+const fakeProductCode = "FAKE"
+
+// Fake is an in-memory gateway for local development and tests. It stands in
+// for a payment method, approves payments as soon as they are initiated and
+// reports them with callbacks authenticated the way that method's gateway does
+// it: Stripe-Signature webhooks for stripe, a pidx to look up for khalti, and
+// eSewa's signed return data for esewa and any other method.
+type Fake struct {
+	method string
+	secret string
+
+	mu       sync.Mutex
+	payments map[string]*fakePayment // By reference
+}
+
+type fakePayment struct {
+	orderID       uuid.UUID
+	transactionID string
+	amount        model.Money
+	status        Status
+}
+
+func NewFake(method, secret string) *Fake {
+	return &Fake{
+		method:   method,
+		secret:   secret,
+		payments: make(map[string]*fakePayment),
+	}
+}
+
+func (p *Fake) Name() string { return p.method }
+
+func (p *Fake) Online() bool { return true }
+
+// Initiate records the payment as made and sends the customer straight back
+// with the gateway's callback. Stripe sends its outcome by webhook, so fake
+// Stripe customers go to the success page instead; see Callback.
+func (p *Fake) Initiate(ctx context.Context, checkout *Checkout) (*Session, error) {
+	reference := "fake_" + uuid.New().String()
+
+	p.mu.Lock()
+	p.payments[reference] = &fakePayment{
+		orderID:       checkout.Order.ID,
+		transactionID: "fake_txn_" + uuid.New().String(),
+		amount:        checkout.Order.Total,
+		status:        StatusPaid,
+	}
+	p.mu.Unlock()
+
+	if p.method == MethodStripe {
+		return &Session{Reference: reference, RedirectURL: checkout.SuccessURL + "?session_id=" + reference}, nil
+	}
+
+	callback, err := p.Callback(reference, StatusPaid)
+	if err != nil {
+		return nil, err
+	}
+	return &Session{Reference: reference, RedirectURL: checkout.ReturnURL + "?" + callback.Query.Encode()}, nil
+}
+
+// Verify reports the payment as the fake gateway last settled it
+func (p *Fake) Verify(ctx context.Context, order *model.Order) (*Payment, error) {
+	if order.PaymentReference == nil {
+		return nil, errors.New("order has no fake payment")
+	}
+	return p.payment(*order.PaymentReference)
+}
+
+// Callback settles a payment and builds the callback the gateway would send
+// about it
+func (p *Fake) Callback(reference string, status Status) (*Callback, error) {
+	if err := p.setStatus(reference, status); err != nil {
+		return nil, err
+	}
+	payment, err := p.payment(reference)
+	if err != nil {
+		return nil, err
+	}
+
+	switch p.method {
+	case MethodStripe:
+		return p.stripeCallback(payment)
+	case MethodKhalti:
+		return &Callback{Query: url.Values{
+			"pidx":              {reference},
+			"status":            {p.khaltiLookup(payment).Status},
+			"transaction_id":    {payment.TransactionID},
+			"total_amount":      {fmt.Sprint(payment.Amount.Amount)},
+			"purchase_order_id": {payment.OrderID.String()},
+		}}, nil
+	default:
+		return p.esewaCallback(payment)
+	}
+}
+
+func (p *Fake) HandleCallback(ctx context.Context, callback *Callback) (*Event, error) {
+	switch p.method {
+	case MethodStripe:
+		return p.handleStripeCallback(callback)
+	case MethodKhalti:
+		return p.handleKhaltiCallback(callback)
+	default:
+		return p.handleEsewaCallback(callback)
+	}
+}
+
+// Refund always succeeds
+func (p *Fake) Refund(ctx context.Context, req *RefundRequest) (*RefundUpdate, error) {
+	return &RefundUpdate{
+		ID:       "fake_re_" + uuid.New().String(),
+		RefundID: req.RefundID,
+		Amount:   req.Amount,
+		Status:   model.RefundStatusSucceeded,
+	}, nil
+}
+
+func (p *Fake) setStatus(reference string, status Status) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	payment, ok := p.payments[reference]
+	if !ok {
+		return fmt.Errorf("fake payment %s not found", reference)
+	}
+	payment.status = status
+	return nil
+}
+
+func (p *Fake) payment(reference string) (*Payment, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	payment, ok := p.payments[reference]
+	if !ok {
+		return nil, fmt.Errorf("fake payment %s not found", reference)
+	}
+	return &Payment{
+		OrderID:       payment.orderID,
+		Reference:     reference,
+		TransactionID: payment.transactionID,
+		Status:        payment.status,
+		Amount:        payment.amount,
+	}, nil
+}
+
+// stripeCallback is a checkout.session.completed or expired webhook
+func (p *Fake) stripeCallback(payment *Payment) (*Callback, error) {
+	eventType, sessionStatus, paymentStatus := "checkout.session.completed", "complete", "paid"
+	switch payment.Status {
+	case StatusFailed:
+		eventType, sessionStatus, paymentStatus = "checkout.session.expired", "expired", "unpaid"
+	case StatusPending:
+		return nil, errors.New("stripe sends no webhook for pending payments")
+	}
+
+	body, err := json.Marshal(map[string]interface{}{
+		"id":          "evt_fake_" + uuid.New().String(),
+		"object":      "event",
+		"api_version": stripe.APIVersion,
+		"type":        eventType,
+		"data": map[string]interface{}{
+			"object": map[string]interface{}{
+				"id":             payment.Reference,
+				"object":         "checkout.session",
+				"status":         sessionStatus,
+				"payment_status": paymentStatus,
+				"payment_intent": payment.TransactionID,
+				"amount_total":   payment.Amount.Amount,
+				"currency":       strings.ToLower(payment.Amount.CurrencyCode()),
+				"metadata":       map[string]string{"order_id": payment.OrderID.String()},
+			},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	signed := webhook.GenerateTestSignedPayload(&webhook.UnsignedPayload{Payload: body, Secret: p.secret})
+	callback := &Callback{Body: body, Header: make(map[string][]string)}
+	callback.Header.Set("Stripe-Signature", signed.Header)
+	return callback, nil
+}
+
+func (p *Fake) handleStripeCallback(callback *Callback) (*Event, error) {
+	var stripeEvent stripe.Event
+	if callback.Verified {
+		if err := json.Unmarshal(callback.Body, &stripeEvent); err != nil {
+			return nil, fmt.Errorf("invalid stored payload: %w", err)
+		}
+	} else {
+		var err error
+		stripeEvent, err = webhook.ConstructEvent(callback.Body, callback.Header.Get("Stripe-Signature"), p.secret)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidCallback, err)
+		}
+	}
+
+	var sess stripe.CheckoutSession
+	if err := json.Unmarshal(stripeEvent.Data.Raw, &sess); err != nil {
+		return nil, fmt.Errorf("invalid session data: %w", err)
+	}
+
+	return &Event{
+		ID:      stripeEvent.ID,
+		Type:    string(stripeEvent.Type),
+		Payload: callback.Body,
+		Payment: sessionPayment(&sess),
+	}, nil
+}
+
+// khaltiLookup answers like Khalti's lookup API
+func (p *Fake) khaltiLookup(payment *Payment) *khaltiLookup {
+	lookup := &khaltiLookup{
+		Pidx:          payment.Reference,
+		TotalAmount:   payment.Amount.Amount,
+		Status:        "Pending",
+		TransactionID: &payment.TransactionID,
+	}
+	switch payment.Status {
+	case StatusPaid:
+		lookup.Status = "Completed"
+	case StatusFailed:
+		lookup.Status = "User canceled"
+	}
+	return lookup
+}
+
+func (p *Fake) handleKhaltiCallback(callback *Callback) (*Event, error) {
+	var lookup *khaltiLookup
+	if callback.Verified {
+		if err := json.Unmarshal(callback.Body, &lookup); err != nil {
+			return nil, fmt.Errorf("invalid stored payload: %w", err)
+		}
+	} else {
+		payment, err := p.payment(callback.Query.Get("pidx"))
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidCallback, err)
+		}
+		lookup = p.khaltiLookup(payment)
+	}
+
+	return khaltiEvent(lookup)
+}
+
+// esewaCallback is eSewa's signed return data
+func (p *Fake) esewaCallback(payment *Payment) (*Callback, error) {
+	status := "PENDING"
+	switch payment.Status {
+	case StatusPaid:
+		status = "COMPLETE"
+	case StatusFailed:
+		status = "CANCELED"
+	}
+
+	fields := map[string]string{
+		"transaction_code":   payment.TransactionID,
+		"status":             status,
+		"total_amount":       rupees(payment.Amount),
+		"transaction_uuid":   payment.Reference,
+		"product_code":       fakeProductCode,
+		"signed_field_names": esewaResponseFields,
+	}
+	fields["signature"] = esewaSign(p.secret, fields)
+
+	data, err := json.Marshal(fields)
+	if err != nil {
+		return nil, err
+	}
+	return &Callback{Query: url.Values{"data": {base64.StdEncoding.EncodeToString(data)}}}, nil
+}
+
+func (p *Fake) handleEsewaCallback(callback *Callback) (*Event, error) {
+	payload := callback.Body
+	if !callback.Verified {
+		decoded, err := base64.StdEncoding.DecodeString(callback.Query.Get("data"))
+		if err != nil || len(decoded) == 0 {
+			return nil, fmt.Errorf("%w: missing data", ErrInvalidCallback)
+		}
+		payload = decoded
+	}
+
+	fields, err := decodeFields(payload)
+	if err != nil {
+		return nil, err
+	}
+	if !callback.Verified {
+		if err := esewaVerify(p.secret, fields); err != nil {
+			return nil, err
+		}
+	}
+
+	return esewaEvent(fields, payload)
+}
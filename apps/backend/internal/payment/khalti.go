@@ -0,0 +1,207 @@
+package payment
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/imbivek08/hamropasal/internal/model"
+)
+
+// KhaltiTestBaseURL is Khalti's sandbox
+const KhaltiTestBaseURL = "https://dev.khalti.com/api"
+
+// Khalti takes payments through Khalti ePayment v2. Khalti does not sign the
+// customer's return, so every callback is confirmed by looking the payment up.
+// See https://docs.khalti.com/khalti-epayment/
+type Khalti struct {
+	secretKey string
+	baseURL   string
+	client    *http.Client
+}
+
+// NewKhalti creates a Khalti provider, using the sandbox when baseURL is empty
+func NewKhalti(secretKey, baseURL string) *Khalti {
+	if baseURL == "" {
+		baseURL = KhaltiTestBaseURL
+	}
+
+	return &Khalti{
+		secretKey: secretKey,
+		baseURL:   strings.TrimSuffix(baseURL, "/"),
+		client:    &http.Client{Timeout: 15 * time.Second},
+	}
+}
+
+func (p *Khalti) Name() string { return MethodKhalti }
+
+func (p *Khalti) Online() bool { return true }
+
+// khaltiLookup is Khalti's report of a payment, identified by its pidx
+type khaltiLookup struct {
+	Pidx          string  `json:"pidx"`
+	TotalAmount   int64   `json:"total_amount"` // In paisa
+	Status        string  `json:"status"`
+	TransactionID *string `json:"transaction_id"`
+	Refunded      bool    `json:"refunded"`
+}
+
+// Initiate registers the payment with Khalti, which returns the page the
+// customer pays on. Khalti sends the customer to ReturnURL either way.
+func (p *Khalti) Initiate(ctx context.Context, checkout *Checkout) (*Session, error) {
+	if err := nprOnly(p.Name(), checkout.Order); err != nil {
+		return nil, err
+	}
+
+	body := map[string]interface{}{
+		"return_url":          checkout.ReturnURL,
+		"website_url":         checkout.WebsiteURL,
+		"amount":              checkout.Order.Total.Amount,
+		"purchase_order_id":   checkout.Order.ID.String(),
+		"purchase_order_name": checkout.Order.OrderNumber,
+	}
+
+	var initiated struct {
+		Pidx       string    `json:"pidx"`
+		PaymentURL string    `json:"payment_url"`
+		ExpiresAt  time.Time `json:"expires_at"`
+	}
+	if err := p.post(ctx, "/v2/epayment/initiate/", body, &initiated); err != nil {
+		return nil, fmt.Errorf("failed to initiate khalti payment: %w", err)
+	}
+
+	session := &Session{
+		Reference:   initiated.Pidx,
+		RedirectURL: initiated.PaymentURL,
+	}
+	if !initiated.ExpiresAt.IsZero() {
+		session.ExpiresAt = &initiated.ExpiresAt
+	}
+	return session, nil
+}
+
+// Verify looks the order's payment up
+func (p *Khalti) Verify(ctx context.Context, order *model.Order) (*Payment, error) {
+	if order.PaymentReference == nil {
+		return nil, errors.New("order has no khalti payment")
+	}
+
+	lookup, err := p.lookup(ctx, *order.PaymentReference)
+	if err != nil {
+		return nil, err
+	}
+
+	payment := khaltiPayment(lookup)
+	payment.OrderID = order.ID
+	return payment, nil
+}
+
+// HandleCallback looks up the pidx Khalti appends to ReturnURL; the other
+// query parameters are not trusted
+func (p *Khalti) HandleCallback(ctx context.Context, callback *Callback) (*Event, error) {
+	var lookup *khaltiLookup
+	if callback.Verified {
+		if err := json.Unmarshal(callback.Body, &lookup); err != nil {
+			return nil, fmt.Errorf("invalid stored payload: %w", err)
+		}
+	} else {
+		pidx := callback.Query.Get("pidx")
+		if pidx == "" {
+			return nil, fmt.Errorf("%w: missing pidx", ErrInvalidCallback)
+		}
+		var err error
+		if lookup, err = p.lookup(ctx, pidx); err != nil {
+			return nil, err
+		}
+	}
+
+	return khaltiEvent(lookup)
+}
+
+// Refund refunds the payment's transaction, in part when the amount is less
+// than was paid
+func (p *Khalti) Refund(ctx context.Context, req *RefundRequest) (*RefundUpdate, error) {
+	if req.TransactionID == "" {
+		return nil, errors.New("order has no khalti transaction")
+	}
+
+	var refunded struct {
+		Detail string `json:"detail"`
+		Idx    string `json:"idx"`
+	}
+	body := map[string]interface{}{"amount": req.Amount.Amount}
+	if err := p.post(ctx, "/merchant-transaction/"+req.TransactionID+"/refund/", body, &refunded); err != nil {
+		return nil, err
+	}
+
+	return &RefundUpdate{
+		ID:       refunded.Idx,
+		RefundID: req.RefundID,
+		Amount:   req.Amount,
+		Status:   model.RefundStatusSucceeded,
+	}, nil
+}
+
+func (p *Khalti) lookup(ctx context.Context, pidx string) (*khaltiLookup, error) {
+	var lookup khaltiLookup
+	if err := p.post(ctx, "/v2/epayment/lookup/", map[string]string{"pidx": pidx}, &lookup); err != nil {
+		return nil, fmt.Errorf("failed to look up khalti payment: %w", err)
+	}
+	return &lookup, nil
+}
+
+func (p *Khalti) post(ctx context.Context, path string, body, out interface{}) error {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.baseURL+path, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Key "+p.secretKey)
+	req.Header.Set("Content-Type", "application/json")
+
+	return doJSON(p.client, req, out)
+}
+
+// khaltiEvent reports a looked up payment, keeping the lookup as payload
+func khaltiEvent(lookup *khaltiLookup) (*Event, error) {
+	payload, err := json.Marshal(lookup)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Event{
+		ID:      lookup.Pidx + ":" + lookup.Status,
+		Type:    "payment." + strings.ToLower(strings.ReplaceAll(lookup.Status, " ", "_")),
+		Payload: payload,
+		Payment: khaltiPayment(lookup),
+	}, nil
+}
+
+func khaltiPayment(lookup *khaltiLookup) *Payment {
+	payment := &Payment{
+		Reference: lookup.Pidx,
+		Status:    StatusPending,
+		Amount:    model.NewMoney(lookup.TotalAmount, "NPR"),
+	}
+	if lookup.TransactionID != nil {
+		payment.TransactionID = *lookup.TransactionID
+	}
+
+	switch lookup.Status {
+	case "Completed", "Refunded", "Partially Refunded":
+		payment.Status = StatusPaid
+	case "User canceled", "Expired", "Failed":
+		payment.Status = StatusFailed
+	}
+
+	return payment
+}
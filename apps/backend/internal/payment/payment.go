@@ -0,0 +1,237 @@
+// Package payment defines the gateways orders are paid through and adapters
+// for Stripe, cash on delivery, eSewa and Khalti. Each gateway is a Provider,
+// looked up in a Registry by the payment method a customer picks at checkout.
+package payment
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/imbivek08/hamropasal/internal/model"
+)
+
+// Payment methods with a built-in provider
+const (
+	MethodStripe = "stripe"
+	MethodCOD    = "COD"
+	MethodEsewa  = "esewa"
+	MethodKhalti = "khalti"
+)
+
+var (
+	ErrUnknownMethod     = errors.New("unsupported payment method")
+	ErrInvalidCallback   = errors.New("invalid payment callback")
+	ErrNoCallbacks       = errors.New("payment method has no callbacks")
+	ErrRefundUnsupported = errors.New("payment method cannot refund automatically")
+)
+
+// Status is the state of a payment at the gateway
+type Status string
+
+const (
+	StatusPending Status = "pending"
+	StatusPaid    Status = "paid"
+	StatusFailed  Status = "failed"
+)
+
+// Provider is a payment gateway
+type Provider interface {
+	// Name is the payment method the provider is registered under
+	Name() string
+	// Online reports whether the customer pays through the gateway at
+	// checkout. Offline orders are confirmed straight away.
+	Online() bool
+	// Initiate starts paying for an order and tells the customer where to pay
+	Initiate(ctx context.Context, checkout *Checkout) (*Session, error)
+	// Verify asks the gateway for the state of an order's payment
+	Verify(ctx context.Context, order *model.Order) (*Payment, error)
+	// HandleCallback authenticates a webhook or returning customer and
+	// reports what happened. A Verified callback carries the Payload of an
+	// earlier Event and is not authenticated again.
+	HandleCallback(ctx context.Context, callback *Callback) (*Event, error)
+	// Refund returns (part of) a payment to the customer
+	Refund(ctx context.Context, req *RefundRequest) (*RefundUpdate, error)
+}
+
+// Resumer is a Provider whose payments stay open at the gateway until they
+// expire, so a customer who left the payment page can go back to it
+type Resumer interface {
+	// Resume returns the order's payment while the customer can still
+	// complete it, or nil once the gateway has closed it
+	Resume(ctx context.Context, order *model.Order) (*Session, error)
+	// Close stops the order's open payment from being completed
+	Close(ctx context.Context, order *model.Order) error
+}
+
+// Checkout is an order to pay for
+type Checkout struct {
+	Order *model.Order
+	Items []model.OrderItemWithDetails
+	// ReturnURL receives the customer back from gateways that report the
+	// payment to the customer's browser
+	ReturnURL string
+	// SuccessURL and CancelURL are the shop's pages for gateways that report
+	// the payment by webhook
+	SuccessURL string
+	CancelURL  string
+	// WebsiteURL is the shop's home page
+	WebsiteURL string
+	// ExpiresAt is when the order stops holding its stock
+	ExpiresAt time.Time
+}
+
+// Session is a started payment
+type Session struct {
+	// Reference identifies the payment at the gateway
+	Reference string
+	// RedirectURL is where the customer pays
+	RedirectURL string
+	// FormFields, when set, are POSTed to RedirectURL instead of redirecting
+	FormFields map[string]string
+	// ExpiresAt is when the gateway gives up on the payment, if it does
+	ExpiresAt *time.Time
+}
+
+// Payment is the state of a payment as the gateway reports it
+type Payment struct {
+	// OrderID is the order paid for, when the gateway echoes it back
+	OrderID   uuid.UUID
+	Reference string
+	// TransactionID identifies the completed payment; refunds go against it
+	TransactionID string
+	Status        Status
+	// Amount is what the gateway took, zero when it does not say
+	Amount model.Money
+}
+
+// Callback is a request a gateway sent, as a webhook or through the
+// customer's browser
+type Callback struct {
+	Header   http.Header
+	Query    url.Values
+	Body     []byte
+	Verified bool
+}
+
+// Event is what a callback reports
+type Event struct {
+	// ID is unique per gateway event, so redeliveries can be recognised
+	ID   string
+	Type string
+	// Payload is the authenticated content of the callback, kept so the
+	// event can be replayed
+	Payload json.RawMessage
+	// Payment is set when the event settles a payment
+	Payment *Payment
+	// Refunds are set when the event reports refunds of TransactionID
+	TransactionID string
+	Refunds       []RefundUpdate
+}
+
+// RefundRequest returns money from a paid order
+type RefundRequest struct {
+	// RefundID is the shop's refund ID, also used as idempotency key
+	RefundID uuid.UUID
+	Order    *model.Order
+	// TransactionID is the completed payment, empty when it was never stored
+	TransactionID string
+	Amount        model.Money
+}
+
+// RefundUpdate is the state of a refund as the gateway reports it
+type RefundUpdate struct {
+	// ID is the gateway's refund ID, empty for refunds it does not track
+	ID string
+	// RefundID is the shop's refund ID, when the gateway echoes it back
+	RefundID      uuid.UUID
+	Amount        model.Money
+	Status        model.RefundStatus
+	FailureReason string
+}
+
+// Registry holds the providers of the payment methods the shop accepts
+type Registry struct {
+	providers map[string]Provider
+}
+
+func NewRegistry(providers ...Provider) *Registry {
+	registry := &Registry{providers: make(map[string]Provider)}
+	for _, provider := range providers {
+		registry.Register(provider)
+	}
+	return registry
+}
+
+// Register adds a provider, replacing any registered under the same name
+func (r *Registry) Register(provider Provider) {
+	r.providers[strings.ToLower(provider.Name())] = provider
+}
+
+// Get returns the provider for a payment method, ignoring case
+func (r *Registry) Get(method string) (Provider, error) {
+	provider, ok := r.providers[strings.ToLower(strings.TrimSpace(method))]
+	if !ok {
+		return nil, fmt.Errorf("%w %q", ErrUnknownMethod, method)
+	}
+	return provider, nil
+}
+
+// Methods lists the accepted payment methods in name order
+func (r *Registry) Methods() []string {
+	methods := make([]string, 0, len(r.providers))
+	for _, provider := range r.providers {
+		methods = append(methods, provider.Name())
+	}
+	sort.Strings(methods)
+	return methods
+}
+
+// nprOnly rejects orders not paid in Nepalese rupees, the only currency the
+// Nepali gateways take
+func nprOnly(method string, order *model.Order) error {
+	if order.Total.CurrencyCode() != "NPR" {
+		return fmt.Errorf("%s only accepts NPR payments", method)
+	}
+	return nil
+}
+
+// doJSON sends a request and decodes a successful JSON response into out.
+// Failed responses become errors carrying the gateway's message.
+func doJSON(client *http.Client, req *http.Request, out interface{}) error {
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		var body struct {
+			Detail       string `json:"detail"`
+			ErrorMessage string `json:"error_message"`
+		}
+		_ = json.NewDecoder(resp.Body).Decode(&body)
+		message := body.Detail
+		if message == "" {
+			message = body.ErrorMessage
+		}
+		if message == "" {
+			message = resp.Status
+		}
+		return errors.New(message)
+	}
+
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
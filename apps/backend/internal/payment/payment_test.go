@@ -0,0 +1,250 @@
+package payment
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/imbivek08/hamropasal/internal/model"
+)
+
+// esewaTestSecret is eSewa's published secret key for the EPAYTEST merchant
+const esewaTestSecret = "8gBm/:&EnhH.1/q"
+
+func testOrder(total int64) *model.Order {
+	return &model.Order{
+		ID:          uuid.New(),
+		OrderNumber: "ORD-TEST",
+		Total:       model.NewMoney(total, "NPR"),
+	}
+}
+
+func TestEsewaSignMatchesDocumentedExample(t *testing.T) {
+	fields := map[string]string{
+		"total_amount":       "110",
+		"transaction_uuid":   "241028",
+		"product_code":       "EPAYTEST",
+		"signed_field_names": esewaSignedFields,
+	}
+	want := "i94zsd3oXF6ZsSr/kGqT4sSzYQzjj1W/waxjWyRwaME="
+	if got := esewaSign(esewaTestSecret, fields); got != want {
+		t.Errorf("esewaSign = %q, want %q", got, want)
+	}
+}
+
+func esewaReturn(t *testing.T, fields map[string]string) *Callback {
+	t.Helper()
+	data, err := json.Marshal(fields)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &Callback{Query: url.Values{"data": {base64.StdEncoding.EncodeToString(data)}}}
+}
+
+// esewaStatusServer is an eSewa transaction status API reporting status for
+// every payment
+func esewaStatusServer(t *testing.T, status string) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		if query.Get("product_code") != "EPAYTEST" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"product_code":     "EPAYTEST",
+			"transaction_uuid": query.Get("transaction_uuid"),
+			"total_amount":     json.Number(strings.ReplaceAll(query.Get("total_amount"), ",", "")),
+			"status":           status,
+			"ref_id":           "000AWEO",
+		})
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestEsewaCallback(t *testing.T) {
+	provider := NewEsewa("EPAYTEST", esewaTestSecret, "", esewaStatusServer(t, "COMPLETE").URL)
+	fields := map[string]string{
+		"transaction_code":   "000AWEO",
+		"status":             "COMPLETE",
+		"total_amount":       "1,000.0",
+		"transaction_uuid":   "250610-162413",
+		"product_code":       "EPAYTEST",
+		"signed_field_names": esewaResponseFields,
+	}
+	fields["signature"] = esewaSign(esewaTestSecret, fields)
+
+	event, err := provider.HandleCallback(context.Background(), esewaReturn(t, fields))
+	if err != nil {
+		t.Fatalf("HandleCallback: %v", err)
+	}
+	if event.Payment.Status != StatusPaid || event.Payment.Amount.Amount != 100000 || event.Payment.Reference != "250610-162413" {
+		t.Errorf("unexpected payment %+v", event.Payment)
+	}
+
+	// Verified callbacks are replayed from the stored payload without a signature check
+	replayed, err := provider.HandleCallback(context.Background(), &Callback{Body: event.Payload, Verified: true})
+	if err != nil || replayed.ID != event.ID {
+		t.Errorf("replay = %+v, %v; want event %s", replayed, err, event.ID)
+	}
+
+	fields["total_amount"] = "1.0"
+	if _, err := provider.HandleCallback(context.Background(), esewaReturn(t, fields)); !errors.Is(err, ErrInvalidCallback) {
+		t.Errorf("tampered callback: got %v, want ErrInvalidCallback", err)
+	}
+}
+
+func TestEsewaCallbackRejectsFormSignature(t *testing.T) {
+	provider := NewEsewa("EPAYTEST", esewaTestSecret, "", esewaStatusServer(t, "COMPLETE").URL)
+	session, err := provider.Initiate(context.Background(), &Checkout{Order: testOrder(1000)})
+	if err != nil {
+		t.Fatalf("Initiate: %v", err)
+	}
+
+	// The payment form's signature stays valid with status fields added
+	fields := map[string]string{"status": "COMPLETE", "transaction_code": "FORGED"}
+	for _, name := range []string{"total_amount", "transaction_uuid", "product_code", "signed_field_names", "signature"} {
+		fields[name] = session.FormFields[name]
+	}
+	if _, err := provider.HandleCallback(context.Background(), esewaReturn(t, fields)); !errors.Is(err, ErrInvalidCallback) {
+		t.Errorf("callback signed as the form: got %v, want ErrInvalidCallback", err)
+	}
+}
+
+func TestEsewaCallbackConfirmsWithStatusAPI(t *testing.T) {
+	provider := NewEsewa("EPAYTEST", esewaTestSecret, "", esewaStatusServer(t, "PENDING").URL)
+	fields := map[string]string{
+		"transaction_code":   "000AWEO",
+		"status":             "COMPLETE",
+		"total_amount":       "10.0",
+		"transaction_uuid":   "250610-162414",
+		"product_code":       "EPAYTEST",
+		"signed_field_names": esewaResponseFields,
+	}
+	fields["signature"] = esewaSign(esewaTestSecret, fields)
+
+	event, err := provider.HandleCallback(context.Background(), esewaReturn(t, fields))
+	if err != nil {
+		t.Fatalf("HandleCallback: %v", err)
+	}
+	if event.Payment.Status != StatusPending {
+		t.Errorf("payment status = %s, want pending until eSewa confirms it", event.Payment.Status)
+	}
+}
+
+func TestEsewaInitiateRejectsOtherCurrencies(t *testing.T) {
+	provider := NewEsewa("EPAYTEST", esewaTestSecret, "", "")
+	order := testOrder(1000)
+	order.Total = model.NewMoney(1000, "USD")
+	if _, err := provider.Initiate(context.Background(), &Checkout{Order: order}); err == nil {
+		t.Error("Initiate accepted a USD order")
+	}
+}
+
+func TestRupees(t *testing.T) {
+	cases := map[int64]string{10000: "100", 12345: "123.45", 5: "0.05"}
+	for paisa, want := range cases {
+		if got := rupees(model.NewMoney(paisa, "NPR")); got != want {
+			t.Errorf("rupees(%d) = %q, want %q", paisa, got, want)
+		}
+	}
+}
+
+func TestKhaltiCallbackLooksUpPayment(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Key test-key" {
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"detail":"Invalid token."}`))
+			return
+		}
+		var body map[string]string
+		json.NewDecoder(r.Body).Decode(&body)
+		if r.URL.Path != "/v2/epayment/lookup/" || body["pidx"] != "HT6o6PEZRWFJ5ygavzHWd5" {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"detail":"Not found."}`))
+			return
+		}
+		w.Write([]byte(`{"pidx":"HT6o6PEZRWFJ5ygavzHWd5","total_amount":1000,"status":"Completed","transaction_id":"GFq9PFS7b2iYvL8Lir9oXe","refunded":false}`))
+	}))
+	defer server.Close()
+
+	provider := NewKhalti("test-key", server.URL)
+	// The query claims a different status; only the lookup is trusted
+	callback := &Callback{Query: url.Values{"pidx": {"HT6o6PEZRWFJ5ygavzHWd5"}, "status": {"User canceled"}}}
+	event, err := provider.HandleCallback(context.Background(), callback)
+	if err != nil {
+		t.Fatalf("HandleCallback: %v", err)
+	}
+	if event.Payment.Status != StatusPaid || event.Payment.TransactionID != "GFq9PFS7b2iYvL8Lir9oXe" || event.Payment.Amount.Amount != 1000 {
+		t.Errorf("unexpected payment %+v", event.Payment)
+	}
+
+	bad := NewKhalti("wrong-key", server.URL)
+	if _, err := bad.HandleCallback(context.Background(), callback); err == nil || !strings.Contains(err.Error(), "Invalid token") {
+		t.Errorf("HandleCallback with a wrong key: got %v, want the gateway's error", err)
+	}
+}
+
+func TestFakeCallbacksRoundTrip(t *testing.T) {
+	for _, method := range []string{MethodStripe, MethodKhalti, MethodEsewa, "wallet"} {
+		t.Run(method, func(t *testing.T) {
+			provider := NewFake(method, "fake-secret")
+			order := testOrder(2500)
+			session, err := provider.Initiate(context.Background(), &Checkout{
+				Order:      order,
+				ReturnURL:  "http://api.test/return",
+				SuccessURL: "http://shop.test/success",
+			})
+			if err != nil {
+				t.Fatalf("Initiate: %v", err)
+			}
+
+			for _, status := range []Status{StatusPaid, StatusFailed} {
+				callback, err := provider.Callback(session.Reference, status)
+				if err != nil {
+					t.Fatalf("Callback(%s): %v", status, err)
+				}
+				event, err := provider.HandleCallback(context.Background(), callback)
+				if err != nil {
+					t.Fatalf("HandleCallback(%s): %v", status, err)
+				}
+				if event.Payment.Reference != session.Reference || event.Payment.Status != status || event.Payment.Amount.Amount != 2500 {
+					t.Errorf("%s callback reported %+v", status, event.Payment)
+				}
+			}
+
+			forged := NewFake(method, "other-secret")
+			forged.payments = provider.payments
+			callback, _ := forged.Callback(session.Reference, StatusPaid)
+			if method == MethodKhalti {
+				return // Khalti callbacks are looked up, not signed
+			}
+			if _, err := provider.HandleCallback(context.Background(), callback); !errors.Is(err, ErrInvalidCallback) {
+				t.Errorf("forged callback: got %v, want ErrInvalidCallback", err)
+			}
+		})
+	}
+}
+
+func TestRegistry(t *testing.T) {
+	registry := NewRegistry(NewCOD(), NewFake(MethodKhalti, "secret"))
+
+	provider, err := registry.Get("cod")
+	if err != nil || provider.Name() != MethodCOD {
+		t.Errorf("Get(cod) = %v, %v; want the COD provider", provider, err)
+	}
+	if _, err := registry.Get(MethodStripe); !errors.Is(err, ErrUnknownMethod) {
+		t.Errorf("Get(stripe) = %v, want ErrUnknownMethod", err)
+	}
+	if got := strings.Join(registry.Methods(), ","); got != "COD,khalti" {
+		t.Errorf("Methods() = %s", got)
+	}
+}
@@ -0,0 +1,321 @@
+package payment
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/imbivek08/hamropasal/internal/model"
+	"github.com/stripe/stripe-go/v84"
+	"github.com/stripe/stripe-go/v84/webhook"
+)
+
+// Stripe takes card payments through Stripe Checkout and learns their outcome
+// from Stripe webhooks
+type Stripe struct {
+	client        *stripe.Client
+	webhookSecret string
+}
+
+func NewStripe(apiKey, webhookSecret string) *Stripe {
+	return &Stripe{
+		client:        stripe.NewClient(apiKey),
+		webhookSecret: webhookSecret,
+	}
+}
+
+func (p *Stripe) Name() string { return MethodStripe }
+
+func (p *Stripe) Online() bool { return true }
+
+// Initiate creates a Checkout Session for the order, which lives as long as
+// the order holds its stock
+func (p *Stripe) Initiate(ctx context.Context, checkout *Checkout) (*Session, error) {
+	order := checkout.Order
+	currency := stripeCurrency(order)
+
+	var lineItems []*stripe.CheckoutSessionCreateLineItemParams
+	for _, item := range checkout.Items {
+		productData := &stripe.CheckoutSessionCreateLineItemPriceDataProductDataParams{
+			Name: stripe.String(item.ProductName),
+		}
+		if item.TaxInclusive && item.TaxName != nil {
+			productData.Description = stripe.String(fmt.Sprintf("Includes %s %s", *item.TaxName, item.TaxAmount))
+		}
+
+		lineItems = append(lineItems, &stripe.CheckoutSessionCreateLineItemParams{
+			PriceData: &stripe.CheckoutSessionCreateLineItemPriceDataParams{
+				Currency:    stripe.String(currency),
+				ProductData: productData,
+				// Stripe takes amounts in minor units, as Money holds them
+				UnitAmount: stripe.Int64(item.UnitPrice.Amount),
+			},
+			Quantity: stripe.Int64(int64(item.Quantity)),
+		})
+	}
+
+	// Exclusive tax is charged as one line per rate
+	for _, tax := range model.TaxBreakdownOf(checkout.Items) {
+		if tax.Inclusive || tax.Amount.IsZero() {
+			continue
+		}
+		lineItems = append(lineItems, &stripe.CheckoutSessionCreateLineItemParams{
+			PriceData: &stripe.CheckoutSessionCreateLineItemPriceDataParams{
+				Currency: stripe.String(currency),
+				ProductData: &stripe.CheckoutSessionCreateLineItemPriceDataProductDataParams{
+					Name: stripe.String(fmt.Sprintf("%s (%g%%)", tax.Name, tax.Rate)),
+				},
+				UnitAmount: stripe.Int64(tax.Amount.Amount),
+			},
+			Quantity: stripe.Int64(1),
+		})
+	}
+
+	params := &stripe.CheckoutSessionCreateParams{
+		PaymentMethodTypes: stripe.StringSlice([]string{"card"}),
+		LineItems:          lineItems,
+		Mode:               stripe.String(string(stripe.CheckoutSessionModePayment)),
+		SuccessURL:         stripe.String(checkout.SuccessURL + "?session_id={CHECKOUT_SESSION_ID}"),
+		CancelURL:          stripe.String(checkout.CancelURL),
+		ExpiresAt:          stripe.Int64(checkout.ExpiresAt.Unix()),
+		Metadata: map[string]string{
+			"order_id":     order.ID.String(),
+			"order_number": order.OrderNumber,
+		},
+	}
+
+	// Charge the order's shipping as one fixed rate covering every shop
+	if order.ShippingCost.IsPositive() {
+		params.ShippingOptions = []*stripe.CheckoutSessionCreateShippingOptionParams{{
+			ShippingRateData: &stripe.CheckoutSessionCreateShippingOptionShippingRateDataParams{
+				Type:        stripe.String("fixed_amount"),
+				DisplayName: stripe.String("Shipping"),
+				FixedAmount: &stripe.CheckoutSessionCreateShippingOptionShippingRateDataFixedAmountParams{
+					Amount:   stripe.Int64(order.ShippingCost.Amount),
+					Currency: stripe.String(currency),
+				},
+			},
+		}}
+	}
+
+	// Stripe has no negative line items, so the order's discount becomes a
+	// single-use Stripe coupon
+	if order.Discount.IsPositive() {
+		c, err := p.client.V1Coupons.Create(ctx, &stripe.CouponCreateParams{
+			AmountOff:      stripe.Int64(order.Discount.Amount),
+			Currency:       stripe.String(currency),
+			Duration:       stripe.String(string(stripe.CouponDurationOnce)),
+			MaxRedemptions: stripe.Int64(1),
+			Name:           stripe.String(fmt.Sprintf("Discount %s", order.OrderNumber)),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to create stripe discount: %w", err)
+		}
+		params.Discounts = []*stripe.CheckoutSessionCreateDiscountParams{{Coupon: stripe.String(c.ID)}}
+	}
+
+	sess, err := p.client.V1CheckoutSessions.Create(ctx, params)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create stripe checkout session: %w", err)
+	}
+
+	expiresAt := checkout.ExpiresAt
+	return &Session{
+		Reference:   sess.ID,
+		RedirectURL: sess.URL,
+		ExpiresAt:   &expiresAt,
+	}, nil
+}
+
+// Verify retrieves the order's Checkout Session
+func (p *Stripe) Verify(ctx context.Context, order *model.Order) (*Payment, error) {
+	if order.PaymentReference == nil {
+		return nil, errors.New("order has no stripe payment")
+	}
+
+	sess, err := p.client.V1CheckoutSessions.Retrieve(ctx, *order.PaymentReference, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve stripe session: %w", err)
+	}
+
+	return sessionPayment(sess), nil
+}
+
+// Resume returns the order's Checkout Session while it is still open
+func (p *Stripe) Resume(ctx context.Context, order *model.Order) (*Session, error) {
+	if order.PaymentReference == nil {
+		return nil, nil
+	}
+
+	sess, err := p.client.V1CheckoutSessions.Retrieve(ctx, *order.PaymentReference, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve stripe session: %w", err)
+	}
+	if sess.Status != stripe.CheckoutSessionStatusOpen {
+		return nil, nil
+	}
+
+	expiresAt := time.Unix(sess.ExpiresAt, 0)
+	return &Session{
+		Reference:   sess.ID,
+		RedirectURL: sess.URL,
+		ExpiresAt:   &expiresAt,
+	}, nil
+}
+
+// Close expires the order's Checkout Session if it is still open
+func (p *Stripe) Close(ctx context.Context, order *model.Order) error {
+	session, err := p.Resume(ctx, order)
+	if err != nil || session == nil {
+		return err
+	}
+
+	if _, err := p.client.V1CheckoutSessions.Expire(ctx, session.Reference, nil); err != nil {
+		return fmt.Errorf("failed to expire stripe session: %w", err)
+	}
+	return nil
+}
+
+// HandleCallback verifies a webhook's Stripe-Signature and turns completed
+// and expired Checkout Sessions and refunded charges into events
+func (p *Stripe) HandleCallback(ctx context.Context, callback *Callback) (*Event, error) {
+	var stripeEvent stripe.Event
+	if callback.Verified {
+		if err := json.Unmarshal(callback.Body, &stripeEvent); err != nil {
+			return nil, fmt.Errorf("invalid stored payload: %w", err)
+		}
+	} else {
+		var err error
+		stripeEvent, err = webhook.ConstructEvent(callback.Body, callback.Header.Get("Stripe-Signature"), p.webhookSecret)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidCallback, err)
+		}
+	}
+
+	event := &Event{
+		ID:      stripeEvent.ID,
+		Type:    string(stripeEvent.Type),
+		Payload: callback.Body,
+	}
+
+	switch stripeEvent.Type {
+	case "checkout.session.completed", "checkout.session.expired":
+		var sess stripe.CheckoutSession
+		if err := json.Unmarshal(stripeEvent.Data.Raw, &sess); err != nil {
+			return nil, fmt.Errorf("invalid session data: %w", err)
+		}
+		event.Payment = sessionPayment(&sess)
+
+	case "charge.refunded":
+		var charge stripe.Charge
+		if err := json.Unmarshal(stripeEvent.Data.Raw, &charge); err != nil {
+			return nil, fmt.Errorf("invalid charge data: %w", err)
+		}
+		if charge.PaymentIntent == nil {
+			break
+		}
+
+		// The charge only lists its latest refunds, so ask for all of them
+		event.TransactionID = charge.PaymentIntent.ID
+		params := &stripe.RefundListParams{PaymentIntent: stripe.String(charge.PaymentIntent.ID)}
+		for stripeRefund, err := range p.client.V1Refunds.List(ctx, params) {
+			if err != nil {
+				return nil, fmt.Errorf("failed to list stripe refunds: %w", err)
+			}
+			event.Refunds = append(event.Refunds, stripeRefundUpdate(stripeRefund))
+		}
+	}
+
+	return event, nil
+}
+
+// Refund refunds the order's PaymentIntent. The shop's refund ID doubles as
+// idempotency key.
+func (p *Stripe) Refund(ctx context.Context, req *RefundRequest) (*RefundUpdate, error) {
+	paymentIntentID := req.TransactionID
+	if paymentIntentID == "" {
+		// Orders paid before the PaymentIntent was stored: find it through the session
+		payment, err := p.Verify(ctx, req.Order)
+		if err != nil {
+			return nil, err
+		}
+		if payment.TransactionID == "" {
+			return nil, errors.New("order has no stripe payment")
+		}
+		paymentIntentID = payment.TransactionID
+	}
+
+	params := &stripe.RefundCreateParams{
+		PaymentIntent: stripe.String(paymentIntentID),
+		Amount:        stripe.Int64(req.Amount.Amount),
+		Metadata: map[string]string{
+			"refund_id": req.RefundID.String(),
+			"order_id":  req.Order.ID.String(),
+		},
+	}
+	params.SetIdempotencyKey(req.RefundID.String())
+
+	stripeRefund, err := p.client.V1Refunds.Create(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+
+	update := stripeRefundUpdate(stripeRefund)
+	return &update, nil
+}
+
+func sessionPayment(sess *stripe.CheckoutSession) *Payment {
+	payment := &Payment{
+		Reference: sess.ID,
+		Status:    StatusPending,
+		Amount:    model.NewMoney(sess.AmountTotal, strings.ToUpper(string(sess.Currency))),
+	}
+	payment.OrderID, _ = uuid.Parse(sess.Metadata["order_id"])
+	if sess.PaymentIntent != nil {
+		payment.TransactionID = sess.PaymentIntent.ID
+	}
+
+	switch {
+	case sess.PaymentStatus == stripe.CheckoutSessionPaymentStatusPaid:
+		payment.Status = StatusPaid
+	case sess.Status == stripe.CheckoutSessionStatusExpired:
+		payment.Status = StatusFailed
+	}
+
+	return payment
+}
+
+func stripeRefundUpdate(stripeRefund *stripe.Refund) RefundUpdate {
+	update := RefundUpdate{
+		ID:     stripeRefund.ID,
+		Amount: model.NewMoney(stripeRefund.Amount, strings.ToUpper(string(stripeRefund.Currency))),
+		Status: model.RefundStatusPending,
+	}
+	update.RefundID, _ = uuid.Parse(stripeRefund.Metadata["refund_id"])
+
+	switch stripeRefund.Status {
+	case stripe.RefundStatusSucceeded:
+		update.Status = model.RefundStatusSucceeded
+	case stripe.RefundStatusFailed, stripe.RefundStatusCanceled:
+		update.Status = model.RefundStatusFailed
+		update.FailureReason = string(stripeRefund.FailureReason)
+		if update.FailureReason == "" {
+			update.FailureReason = string(stripeRefund.Status)
+		}
+	}
+
+	return update
+}
+
+// stripeCurrency is the lowercase code of the currency an order is paid in,
+// as Stripe expects it
+func stripeCurrency(order *model.Order) string {
+	if order.Currency == "" {
+		return strings.ToLower(model.DefaultCurrency)
+	}
+	return strings.ToLower(order.Currency)
+}
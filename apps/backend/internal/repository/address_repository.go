@@ -30,7 +30,7 @@ func (r *AddressRepository) GetByUserID(ctx context.Context, userID uuid.UUID) (
 		ORDER BY is_default DESC, created_at DESC
 	`
 
-	rows, err := r.db.Pool.Query(ctx, query, userID)
+	rows, err := r.db.Conn(ctx).Query(ctx, query, userID)
 	if err != nil {
 		return nil, err
 	}
@@ -74,7 +74,7 @@ func (r *AddressRepository) GetByID(ctx context.Context, id uuid.UUID) (*model.A
 		WHERE id = $1
 	`
 
-	err := r.db.Pool.QueryRow(ctx, query, id).Scan(
+	err := r.db.Conn(ctx).QueryRow(ctx, query, id).Scan(
 		&addr.ID,
 		&addr.UserID,
 		&addr.FullName,
@@ -108,7 +108,7 @@ func (r *AddressRepository) GetDefaultByUserID(ctx context.Context, userID uuid.
 		LIMIT 1
 	`
 
-	err := r.db.Pool.QueryRow(ctx, query, userID).Scan(
+	err := r.db.Conn(ctx).QueryRow(ctx, query, userID).Scan(
 		&addr.ID,
 		&addr.UserID,
 		&addr.FullName,
@@ -140,7 +140,7 @@ func (r *AddressRepository) Create(ctx context.Context, addr *model.Address) err
 		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
 	`
 
-	_, err := r.db.Pool.Exec(ctx, query,
+	_, err := r.db.Conn(ctx).Exec(ctx, query,
 		addr.ID,
 		addr.UserID,
 		addr.FullName,
@@ -169,7 +169,7 @@ func (r *AddressRepository) Update(ctx context.Context, addr *model.Address) err
 		WHERE id = $11 AND user_id = $12
 	`
 
-	result, err := r.db.Pool.Exec(ctx, query,
+	result, err := r.db.Conn(ctx).Exec(ctx, query,
 		addr.FullName,
 		addr.Phone,
 		addr.AddressLine1,
@@ -196,7 +196,7 @@ func (r *AddressRepository) Update(ctx context.Context, addr *model.Address) err
 // Delete deletes an address
 func (r *AddressRepository) Delete(ctx context.Context, id uuid.UUID, userID uuid.UUID) error {
 	query := `DELETE FROM addresses WHERE id = $1 AND user_id = $2`
-	result, err := r.db.Pool.Exec(ctx, query, id, userID)
+	result, err := r.db.Conn(ctx).Exec(ctx, query, id, userID)
 	if err != nil {
 		return err
 	}
@@ -210,7 +210,7 @@ func (r *AddressRepository) Delete(ctx context.Context, id uuid.UUID, userID uui
 // UnsetDefaultForUser unsets any default address for a user
 func (r *AddressRepository) UnsetDefaultForUser(ctx context.Context, userID uuid.UUID) error {
 	query := `UPDATE addresses SET is_default = false, updated_at = $1 WHERE user_id = $2 AND is_default = true`
-	_, err := r.db.Pool.Exec(ctx, query, time.Now(), userID)
+	_, err := r.db.Conn(ctx).Exec(ctx, query, time.Now(), userID)
 	return err
 }
 
@@ -223,7 +223,7 @@ func (r *AddressRepository) SetDefault(ctx context.Context, id uuid.UUID, userID
 
 	// Set the new default
 	query := `UPDATE addresses SET is_default = true, updated_at = $1 WHERE id = $2 AND user_id = $3`
-	result, err := r.db.Pool.Exec(ctx, query, time.Now(), id, userID)
+	result, err := r.db.Conn(ctx).Exec(ctx, query, time.Now(), id, userID)
 	if err != nil {
 		return err
 	}
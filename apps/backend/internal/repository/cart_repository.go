@@ -3,6 +3,7 @@ package repository
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/imbivek08/hamropasal/internal/database"
@@ -17,30 +18,42 @@ func NewCartRepository(db *database.Database) *CartRepository {
 	return &CartRepository{db: db}
 }
 
-// GetOrCreateCart gets existing cart or creates new one for user
-func (r *CartRepository) GetOrCreateCart(ctx context.Context, userID uuid.UUID) (*model.Cart, error) {
+// guestCartTTL is how long an anonymous cart is kept before it expires
+const guestCartTTL = 30 * 24 * time.Hour
+
+// GetOrCreateCart gets existing cart or creates new one for a user or guest session
+func (r *CartRepository) GetOrCreateCart(ctx context.Context, owner model.CartOwner) (*model.Cart, error) {
 	// Try to get existing cart
-	cart, err := r.GetCartByUserID(ctx, userID)
+	cart, err := r.GetCart(ctx, owner)
 	if err == nil {
 		return cart, nil
 	}
 
 	// Create new cart if not found
 	cart = &model.Cart{
-		ID:     uuid.New(),
-		UserID: &userID,
+		ID:        uuid.New(),
+		UserID:    owner.UserID,
+		SessionID: owner.SessionID,
+	}
+
+	// Guest carts expire, user carts live as long as the user
+	var expiresAt *time.Time
+	if owner.IsGuest() {
+		expiry := time.Now().Add(guestCartTTL)
+		expiresAt = &expiry
 	}
 
 	query := `
-		INSERT INTO carts (id, user_id, created_at, updated_at)
-		VALUES ($1, $2, NOW(), NOW())
-		RETURNING id, user_id, session_id, created_at, updated_at, expires_at
+		INSERT INTO carts (id, user_id, session_id, created_at, updated_at, expires_at)
+		VALUES ($1, $2, $3, NOW(), NOW(), $4)
+		RETURNING id, user_id, session_id, coupon_code, created_at, updated_at, expires_at
 	`
 
-	err = r.db.Pool.QueryRow(ctx, query, cart.ID, cart.UserID).Scan(
+	err = r.db.Conn(ctx).QueryRow(ctx, query, cart.ID, cart.UserID, cart.SessionID, expiresAt).Scan(
 		&cart.ID,
 		&cart.UserID,
 		&cart.SessionID,
+		&cart.CouponCode,
 		&cart.CreatedAt,
 		&cart.UpdatedAt,
 		&cart.ExpiresAt,
@@ -49,20 +62,29 @@ func (r *CartRepository) GetOrCreateCart(ctx context.Context, userID uuid.UUID)
 	return cart, err
 }
 
+// GetCart retrieves the cart of a user or guest session
+func (r *CartRepository) GetCart(ctx context.Context, owner model.CartOwner) (*model.Cart, error) {
+	if owner.IsGuest() {
+		return r.GetCartBySessionID(ctx, *owner.SessionID)
+	}
+	return r.GetCartByUserID(ctx, *owner.UserID)
+}
+
 // GetCartByUserID retrieves cart by user ID
 func (r *CartRepository) GetCartByUserID(ctx context.Context, userID uuid.UUID) (*model.Cart, error) {
 	var cart model.Cart
 	query := `
-		SELECT id, user_id, session_id, created_at, updated_at, expires_at
+		SELECT id, user_id, session_id, coupon_code, created_at, updated_at, expires_at
 		FROM carts
 		WHERE user_id = $1
 		LIMIT 1
 	`
 
-	err := r.db.Pool.QueryRow(ctx, query, userID).Scan(
+	err := r.db.Conn(ctx).QueryRow(ctx, query, userID).Scan(
 		&cart.ID,
 		&cart.UserID,
 		&cart.SessionID,
+		&cart.CouponCode,
 		&cart.CreatedAt,
 		&cart.UpdatedAt,
 		&cart.ExpiresAt,
@@ -71,24 +93,50 @@ func (r *CartRepository) GetCartByUserID(ctx context.Context, userID uuid.UUID)
 	return &cart, err
 }
 
-// AddItem adds or updates item in cart
-func (r *CartRepository) AddItem(ctx context.Context, cartID, productID uuid.UUID, quantity int) (*model.CartItem, error) {
+// GetCartBySessionID retrieves an unexpired guest cart by session ID
+func (r *CartRepository) GetCartBySessionID(ctx context.Context, sessionID string) (*model.Cart, error) {
+	var cart model.Cart
+	query := `
+		SELECT id, user_id, session_id, coupon_code, created_at, updated_at, expires_at
+		FROM carts
+		WHERE session_id = $1 AND user_id IS NULL
+		  AND (expires_at IS NULL OR expires_at > NOW())
+		LIMIT 1
+	`
+
+	err := r.db.Conn(ctx).QueryRow(ctx, query, sessionID).Scan(
+		&cart.ID,
+		&cart.UserID,
+		&cart.SessionID,
+		&cart.CouponCode,
+		&cart.CreatedAt,
+		&cart.UpdatedAt,
+		&cart.ExpiresAt,
+	)
+
+	return &cart, err
+}
+
+// AddItem adds or updates item in cart. variantID is nil for products without
+// variants.
+func (r *CartRepository) AddItem(ctx context.Context, cartID, productID uuid.UUID, variantID *uuid.UUID, quantity int) (*model.CartItem, error) {
 	var item model.CartItem
 
 	query := `
-		INSERT INTO cart_items (id, cart_id, product_id, quantity, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, NOW(), NOW())
-		ON CONFLICT (cart_id, product_id) 
+		INSERT INTO cart_items (id, cart_id, product_id, variant_id, quantity, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, NOW(), NOW())
+		ON CONFLICT (cart_id, product_id, variant_id) 
 		DO UPDATE SET 
 			quantity = cart_items.quantity + EXCLUDED.quantity,
 			updated_at = NOW()
-		RETURNING id, cart_id, product_id, quantity, created_at, updated_at
+		RETURNING id, cart_id, product_id, variant_id, quantity, created_at, updated_at
 	`
 
-	err := r.db.Pool.QueryRow(ctx, query, uuid.New(), cartID, productID, quantity).Scan(
+	err := r.db.Conn(ctx).QueryRow(ctx, query, uuid.New(), cartID, productID, variantID, quantity).Scan(
 		&item.ID,
 		&item.CartID,
 		&item.ProductID,
+		&item.VariantID,
 		&item.Quantity,
 		&item.CreatedAt,
 		&item.UpdatedAt,
@@ -105,7 +153,7 @@ func (r *CartRepository) UpdateItemQuantity(ctx context.Context, itemID uuid.UUI
 		WHERE id = $2
 	`
 
-	result, err := r.db.Pool.Exec(ctx, query, quantity, itemID)
+	result, err := r.db.Conn(ctx).Exec(ctx, query, quantity, itemID)
 	if err != nil {
 		return err
 	}
@@ -121,7 +169,7 @@ func (r *CartRepository) UpdateItemQuantity(ctx context.Context, itemID uuid.UUI
 func (r *CartRepository) RemoveItem(ctx context.Context, itemID uuid.UUID) error {
 	query := `DELETE FROM cart_items WHERE id = $1`
 
-	result, err := r.db.Pool.Exec(ctx, query, itemID)
+	result, err := r.db.Conn(ctx).Exec(ctx, query, itemID)
 	if err != nil {
 		return err
 	}
@@ -140,24 +188,32 @@ func (r *CartRepository) GetCartWithItems(ctx context.Context, cartID uuid.UUID)
 			ci.id,
 			ci.cart_id,
 			ci.product_id,
+			ci.variant_id,
+			v.sku,
+			v.options,
 			p.name as product_name,
-			p.price as product_price,
-			p.image_url as product_image_url,
-			p.stock_quantity,
-			p.is_active,
+			COALESCE(v.price, p.price) as product_price,
+			COALESCE(v.image_url, p.image_url) as product_image_url,
+			COALESCE(v.stock_quantity, p.stock_quantity),
+			p.weight,
+			COALESCE(p.weight_unit, 'kg'),
+			p.is_active AND COALESCE(v.is_active, true),
+			p.category_id,
 			p.shop_id,
 			s.shop_name,
+			s.currency,
 			ci.quantity,
 			ci.created_at,
 			ci.updated_at
 		FROM cart_items ci
 		INNER JOIN products p ON ci.product_id = p.id
 		INNER JOIN shops s ON p.shop_id = s.id
+		LEFT JOIN product_variants v ON ci.variant_id = v.id
 		WHERE ci.cart_id = $1
 		ORDER BY ci.created_at DESC
 	`
 
-	rows, err := r.db.Pool.Query(ctx, query, cartID)
+	rows, err := r.db.Conn(ctx).Query(ctx, query, cartID)
 	if err != nil {
 		return nil, err
 	}
@@ -170,13 +226,20 @@ func (r *CartRepository) GetCartWithItems(ctx context.Context, cartID uuid.UUID)
 			&item.ID,
 			&item.CartID,
 			&item.ProductID,
+			&item.VariantID,
+			&item.VariantSKU,
+			&item.VariantOptions,
 			&item.ProductName,
 			&item.ProductPrice,
 			&item.ProductImageURL,
 			&item.StockQuantity,
+			&item.Weight,
+			&item.WeightUnit,
 			&item.IsActive,
+			&item.CategoryID,
 			&item.ShopID,
 			&item.ShopName,
+			&item.ShopCurrency,
 			&item.Quantity,
 			&item.CreatedAt,
 			&item.UpdatedAt,
@@ -185,7 +248,8 @@ func (r *CartRepository) GetCartWithItems(ctx context.Context, cartID uuid.UUID)
 			return nil, err
 		}
 
-		// Calculate subtotal
+		// Calculate subtotal, in the shop's currency
+		item.ProductPrice.Currency = item.ShopCurrency
 		item.CalculateSubtotal()
 		items = append(items, item)
 	}
@@ -196,7 +260,7 @@ func (r *CartRepository) GetCartWithItems(ctx context.Context, cartID uuid.UUID)
 // ClearCart removes all items from cart
 func (r *CartRepository) ClearCart(ctx context.Context, cartID uuid.UUID) error {
 	query := `DELETE FROM cart_items WHERE cart_id = $1`
-	_, err := r.db.Pool.Exec(ctx, query, cartID)
+	_, err := r.db.Conn(ctx).Exec(ctx, query, cartID)
 	return err
 }
 
@@ -204,7 +268,7 @@ func (r *CartRepository) ClearCart(ctx context.Context, cartID uuid.UUID) error
 func (r *CartRepository) GetCartItemCount(ctx context.Context, cartID uuid.UUID) (int, error) {
 	var count int
 	query := `SELECT COALESCE(SUM(quantity), 0) FROM cart_items WHERE cart_id = $1`
-	err := r.db.Pool.QueryRow(ctx, query, cartID).Scan(&count)
+	err := r.db.Conn(ctx).QueryRow(ctx, query, cartID).Scan(&count)
 	return count, err
 }
 
@@ -212,15 +276,16 @@ func (r *CartRepository) GetCartItemCount(ctx context.Context, cartID uuid.UUID)
 func (r *CartRepository) GetCartItemByID(ctx context.Context, itemID uuid.UUID) (*model.CartItem, error) {
 	var item model.CartItem
 	query := `
-		SELECT id, cart_id, product_id, quantity, created_at, updated_at
+		SELECT id, cart_id, product_id, variant_id, quantity, created_at, updated_at
 		FROM cart_items
 		WHERE id = $1
 	`
 
-	err := r.db.Pool.QueryRow(ctx, query, itemID).Scan(
+	err := r.db.Conn(ctx).QueryRow(ctx, query, itemID).Scan(
 		&item.ID,
 		&item.CartID,
 		&item.ProductID,
+		&item.VariantID,
 		&item.Quantity,
 		&item.CreatedAt,
 		&item.UpdatedAt,
@@ -229,10 +294,73 @@ func (r *CartRepository) GetCartItemByID(ctx context.Context, itemID uuid.UUID)
 	return &item, err
 }
 
-// VerifyCartOwnership checks if cart belongs to user
-func (r *CartRepository) VerifyCartOwnership(ctx context.Context, cartID, userID uuid.UUID) (bool, error) {
+// SetCouponCode applies a coupon code to the cart, or removes it when code is nil
+func (r *CartRepository) SetCouponCode(ctx context.Context, cartID uuid.UUID, code *string) error {
+	query := `UPDATE carts SET coupon_code = $1, updated_at = NOW() WHERE id = $2`
+	_, err := r.db.Conn(ctx).Exec(ctx, query, code, cartID)
+	return err
+}
+
+// VerifyCartOwnership checks if cart belongs to the user or guest session
+func (r *CartRepository) VerifyCartOwnership(ctx context.Context, cartID uuid.UUID, owner model.CartOwner) (bool, error) {
 	var exists bool
-	query := `SELECT EXISTS(SELECT 1 FROM carts WHERE id = $1 AND user_id = $2)`
-	err := r.db.Pool.QueryRow(ctx, query, cartID, userID).Scan(&exists)
+	query := `
+		SELECT EXISTS(
+			SELECT 1 FROM carts
+			WHERE id = $1
+			  AND (($2::uuid IS NOT NULL AND user_id = $2)
+			    OR ($3::text IS NOT NULL AND session_id = $3 AND user_id IS NULL))
+		)
+	`
+	err := r.db.Conn(ctx).QueryRow(ctx, query, cartID, owner.UserID, owner.SessionID).Scan(&exists)
 	return exists, err
 }
+
+// MergeCarts moves every item of the guest cart into the user cart and deletes
+// the guest cart. Quantities of products (or variants) present in both carts
+// are summed; all quantities are capped at the current stock and items that
+// are out of stock or inactive are dropped.
+func (r *CartRepository) MergeCarts(ctx context.Context, guestCartID, userCartID uuid.UUID) error {
+	return r.db.WithTx(ctx, func(ctx context.Context) error {
+		query := `
+			INSERT INTO cart_items (id, cart_id, product_id, variant_id, quantity, created_at, updated_at)
+			SELECT uuid_generate_v4(), $2, ci.product_id, ci.variant_id,
+				LEAST(ci.quantity, COALESCE(v.stock_quantity, p.stock_quantity)), NOW(), NOW()
+			FROM cart_items ci
+			INNER JOIN products p ON ci.product_id = p.id
+			LEFT JOIN product_variants v ON ci.variant_id = v.id
+			WHERE ci.cart_id = $1 AND p.is_active = true AND COALESCE(v.is_active, true)
+			  AND COALESCE(v.stock_quantity, p.stock_quantity) > 0
+			ON CONFLICT (cart_id, product_id, variant_id)
+			DO UPDATE SET
+				quantity = LEAST(
+					cart_items.quantity + EXCLUDED.quantity,
+					COALESCE(
+						(SELECT stock_quantity FROM product_variants WHERE id = EXCLUDED.variant_id),
+						(SELECT stock_quantity FROM products WHERE id = EXCLUDED.product_id)
+					)
+				),
+				updated_at = NOW()
+		`
+		if _, err := r.db.Conn(ctx).Exec(ctx, query, guestCartID, userCartID); err != nil {
+			return err
+		}
+
+		// Keep the guest's coupon unless the user already applied one
+		query = `
+			UPDATE carts
+			SET coupon_code = (SELECT coupon_code FROM carts WHERE id = $1), updated_at = NOW()
+			WHERE id = $2 AND coupon_code IS NULL
+		`
+		if _, err := r.db.Conn(ctx).Exec(ctx, query, guestCartID, userCartID); err != nil {
+			return err
+		}
+
+		// Guest items are removed along with the cart (ON DELETE CASCADE)
+		if _, err := r.db.Conn(ctx).Exec(ctx, `DELETE FROM carts WHERE id = $1`, guestCartID); err != nil {
+			return err
+		}
+
+		return nil
+	})
+}
@@ -0,0 +1,210 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/imbivek08/hamropasal/internal/database"
+	"github.com/imbivek08/hamropasal/internal/model"
+)
+
+type CategoryRepository struct {
+	db *database.Database
+}
+
+func NewCategoryRepository(db *database.Database) *CategoryRepository {
+	return &CategoryRepository{db: db}
+}
+
+// Create creates a new category
+func (r *CategoryRepository) Create(ctx context.Context, category *model.Category) error {
+	query := `
+		INSERT INTO categories (id, name, slug, description, parent_id, image_url, is_active, display_order, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+	`
+	_, err := r.db.Conn(ctx).Exec(ctx, query,
+		category.ID,
+		category.Name,
+		category.Slug,
+		category.Description,
+		category.ParentID,
+		category.ImageURL,
+		category.IsActive,
+		category.DisplayOrder,
+		category.CreatedAt,
+		category.UpdatedAt,
+	)
+	return err
+}
+
+// GetByID retrieves a category by ID
+func (r *CategoryRepository) GetByID(ctx context.Context, id uuid.UUID) (*model.Category, error) {
+	var category model.Category
+	query := `
+		SELECT id, name, slug, description, parent_id, image_url, is_active, display_order, created_at, updated_at
+		FROM categories
+		WHERE id = $1
+	`
+	err := r.db.Conn(ctx).QueryRow(ctx, query, id).Scan(
+		&category.ID,
+		&category.Name,
+		&category.Slug,
+		&category.Description,
+		&category.ParentID,
+		&category.ImageURL,
+		&category.IsActive,
+		&category.DisplayOrder,
+		&category.CreatedAt,
+		&category.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &category, nil
+}
+
+// GetBySlug retrieves a category by slug
+func (r *CategoryRepository) GetBySlug(ctx context.Context, slug string) (*model.Category, error) {
+	var category model.Category
+	query := `
+		SELECT id, name, slug, description, parent_id, image_url, is_active, display_order, created_at, updated_at
+		FROM categories
+		WHERE slug = $1
+	`
+	err := r.db.Conn(ctx).QueryRow(ctx, query, slug).Scan(
+		&category.ID,
+		&category.Name,
+		&category.Slug,
+		&category.Description,
+		&category.ParentID,
+		&category.ImageURL,
+		&category.IsActive,
+		&category.DisplayOrder,
+		&category.CreatedAt,
+		&category.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &category, nil
+}
+
+// GetAll retrieves all categories ordered by display order
+func (r *CategoryRepository) GetAll(ctx context.Context, activeOnly bool) ([]*model.Category, error) {
+	query := `
+		SELECT id, name, slug, description, parent_id, image_url, is_active, display_order, created_at, updated_at
+		FROM categories
+		WHERE ($1 = false OR is_active = true)
+		ORDER BY display_order ASC, name ASC
+	`
+	rows, err := r.db.Conn(ctx).Query(ctx, query, activeOnly)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var categories []*model.Category
+	for rows.Next() {
+		var category model.Category
+		err := rows.Scan(
+			&category.ID,
+			&category.Name,
+			&category.Slug,
+			&category.Description,
+			&category.ParentID,
+			&category.ImageURL,
+			&category.IsActive,
+			&category.DisplayOrder,
+			&category.CreatedAt,
+			&category.UpdatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		categories = append(categories, &category)
+	}
+
+	return categories, rows.Err()
+}
+
+// GetDescendantIDs returns the IDs of every category below the given one
+func (r *CategoryRepository) GetDescendantIDs(ctx context.Context, id uuid.UUID) ([]uuid.UUID, error) {
+	query := `
+		WITH RECURSIVE category_tree AS (
+			SELECT id FROM categories WHERE parent_id = $1
+			UNION
+			SELECT c.id FROM categories c
+			INNER JOIN category_tree ct ON c.parent_id = ct.id
+		)
+		SELECT id FROM category_tree
+	`
+	rows, err := r.db.Conn(ctx).Query(ctx, query, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []uuid.UUID
+	for rows.Next() {
+		var descendantID uuid.UUID
+		if err := rows.Scan(&descendantID); err != nil {
+			return nil, err
+		}
+		ids = append(ids, descendantID)
+	}
+
+	return ids, rows.Err()
+}
+
+// HasChildren checks if any category uses the given category as its parent
+func (r *CategoryRepository) HasChildren(ctx context.Context, id uuid.UUID) (bool, error) {
+	var exists bool
+	query := `SELECT EXISTS(SELECT 1 FROM categories WHERE parent_id = $1)`
+	err := r.db.Conn(ctx).QueryRow(ctx, query, id).Scan(&exists)
+	return exists, err
+}
+
+// Update updates a category
+func (r *CategoryRepository) Update(ctx context.Context, category *model.Category) error {
+	query := `
+		UPDATE categories
+		SET name = $1, slug = $2, description = $3, parent_id = $4, image_url = $5,
+		    is_active = $6, display_order = $7, updated_at = NOW()
+		WHERE id = $8
+		RETURNING updated_at
+	`
+	return r.db.Conn(ctx).QueryRow(ctx, query,
+		category.Name,
+		category.Slug,
+		category.Description,
+		category.ParentID,
+		category.ImageURL,
+		category.IsActive,
+		category.DisplayOrder,
+		category.ID,
+	).Scan(&category.UpdatedAt)
+}
+
+// Delete deletes a category. Products in it keep existing with no category.
+func (r *CategoryRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	query := `DELETE FROM categories WHERE id = $1`
+	result, err := r.db.Conn(ctx).Exec(ctx, query, id)
+	if err != nil {
+		return err
+	}
+
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("category not found")
+	}
+
+	return nil
+}
+
+// SlugExists checks if a slug already exists
+func (r *CategoryRepository) SlugExists(ctx context.Context, slug string, excludeID *uuid.UUID) (bool, error) {
+	query := `SELECT EXISTS(SELECT 1 FROM categories WHERE slug = $1 AND ($2::uuid IS NULL OR id != $2))`
+	var exists bool
+	err := r.db.Conn(ctx).QueryRow(ctx, query, slug, excludeID).Scan(&exists)
+	return exists, err
+}
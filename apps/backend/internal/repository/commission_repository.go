@@ -0,0 +1,122 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/imbivek08/hamropasal/internal/database"
+	"github.com/imbivek08/hamropasal/internal/model"
+	"github.com/jackc/pgx/v5"
+)
+
+type CommissionRepository struct {
+	db *database.Database
+}
+
+func NewCommissionRepository(db *database.Database) *CommissionRepository {
+	return &CommissionRepository{db: db}
+}
+
+const commissionRateColumns = `id, shop_id, category_id, rate, created_at, updated_at`
+
+// List retrieves every commission rate, the platform default first
+func (r *CommissionRepository) List(ctx context.Context) ([]*model.CommissionRate, error) {
+	query := `
+		SELECT ` + commissionRateColumns + `
+		FROM commission_rates
+		ORDER BY shop_id NULLS FIRST, category_id NULLS FIRST, created_at
+	`
+
+	rows, err := r.db.Conn(ctx).Query(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	rates := []*model.CommissionRate{}
+	for rows.Next() {
+		rate, err := scanCommissionRate(rows)
+		if err != nil {
+			return nil, err
+		}
+		rates = append(rates, rate)
+	}
+
+	return rates, rows.Err()
+}
+
+// SetDefault sets the platform's default rate
+func (r *CommissionRepository) SetDefault(ctx context.Context, rate float64) (*model.CommissionRate, error) {
+	query := `
+		INSERT INTO commission_rates (rate) VALUES ($1)
+		ON CONFLICT ((true)) WHERE shop_id IS NULL AND category_id IS NULL
+		DO UPDATE SET rate = EXCLUDED.rate, updated_at = NOW()
+		RETURNING ` + commissionRateColumns
+
+	return scanCommissionRate(r.db.Conn(ctx).QueryRow(ctx, query, rate))
+}
+
+// SetForCategory sets the rate of a category's sales
+func (r *CommissionRepository) SetForCategory(ctx context.Context, categoryID uuid.UUID, rate float64) (*model.CommissionRate, error) {
+	query := `
+		INSERT INTO commission_rates (category_id, rate) VALUES ($1, $2)
+		ON CONFLICT (category_id) DO UPDATE SET rate = EXCLUDED.rate, updated_at = NOW()
+		RETURNING ` + commissionRateColumns
+
+	return scanCommissionRate(r.db.Conn(ctx).QueryRow(ctx, query, categoryID, rate))
+}
+
+// SetForShop sets a shop's override rate
+func (r *CommissionRepository) SetForShop(ctx context.Context, shopID uuid.UUID, rate float64) (*model.CommissionRate, error) {
+	query := `
+		INSERT INTO commission_rates (shop_id, rate) VALUES ($1, $2)
+		ON CONFLICT (shop_id) DO UPDATE SET rate = EXCLUDED.rate, updated_at = NOW()
+		RETURNING ` + commissionRateColumns
+
+	return scanCommissionRate(r.db.Conn(ctx).QueryRow(ctx, query, shopID, rate))
+}
+
+// DeleteForCategory removes a category's rate
+func (r *CommissionRepository) DeleteForCategory(ctx context.Context, categoryID uuid.UUID) error {
+	result, err := r.db.Conn(ctx).Exec(ctx, `DELETE FROM commission_rates WHERE category_id = $1`, categoryID)
+	if err != nil {
+		return err
+	}
+
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("commission rate not found")
+	}
+
+	return nil
+}
+
+// DeleteForShop removes a shop's override rate
+func (r *CommissionRepository) DeleteForShop(ctx context.Context, shopID uuid.UUID) error {
+	result, err := r.db.Conn(ctx).Exec(ctx, `DELETE FROM commission_rates WHERE shop_id = $1`, shopID)
+	if err != nil {
+		return err
+	}
+
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("commission rate not found")
+	}
+
+	return nil
+}
+
+func scanCommissionRate(row pgx.Row) (*model.CommissionRate, error) {
+	var rate model.CommissionRate
+	err := row.Scan(
+		&rate.ID,
+		&rate.ShopID,
+		&rate.CategoryID,
+		&rate.Rate,
+		&rate.CreatedAt,
+		&rate.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &rate, nil
+}
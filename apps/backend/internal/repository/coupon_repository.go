@@ -0,0 +1,224 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/imbivek08/hamropasal/internal/database"
+	"github.com/imbivek08/hamropasal/internal/model"
+	"github.com/jackc/pgx/v5"
+)
+
+type CouponRepository struct {
+	db *database.Database
+}
+
+func NewCouponRepository(db *database.Database) *CouponRepository {
+	return &CouponRepository{db: db}
+}
+
+const couponColumns = `id, code, description, discount_type, value, max_discount, min_subtotal, shop_id,
+	usage_limit, per_user_limit, used_count, starts_at, ends_at, is_active, created_by, created_at, updated_at`
+
+// couponCurrency is the currency of a coupon's amounts: its shop's currency, or
+// NULL for platform coupons, which are in the default currency
+const couponCurrency = `(SELECT currency FROM shops WHERE shops.id = coupons.shop_id)`
+
+// Create inserts a new coupon
+func (r *CouponRepository) Create(ctx context.Context, coupon *model.Coupon) error {
+	query := `
+		INSERT INTO coupons (` + couponColumns + `)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17)
+		RETURNING ` + couponCurrency + `
+	`
+
+	var currency *string
+	err := r.db.Conn(ctx).QueryRow(ctx, query,
+		coupon.ID,
+		coupon.Code,
+		coupon.Description,
+		coupon.Type,
+		coupon.Value,
+		coupon.MaxDiscount,
+		coupon.MinSubtotal,
+		coupon.ShopID,
+		coupon.UsageLimit,
+		coupon.PerUserLimit,
+		coupon.UsedCount,
+		coupon.StartsAt,
+		coupon.EndsAt,
+		coupon.IsActive,
+		coupon.CreatedBy,
+		coupon.CreatedAt,
+		coupon.UpdatedAt,
+	).Scan(&currency)
+	if err != nil {
+		return err
+	}
+
+	setCouponCurrency(coupon, currency)
+	return nil
+}
+
+// GetByID retrieves a coupon by ID
+func (r *CouponRepository) GetByID(ctx context.Context, id uuid.UUID) (*model.Coupon, error) {
+	query := `SELECT ` + couponColumns + `, ` + couponCurrency + ` FROM coupons WHERE id = $1`
+	return scanCoupon(r.db.Conn(ctx).QueryRow(ctx, query, id))
+}
+
+// GetByCode retrieves a coupon by its code, ignoring case
+func (r *CouponRepository) GetByCode(ctx context.Context, code string) (*model.Coupon, error) {
+	query := `SELECT ` + couponColumns + `, ` + couponCurrency + ` FROM coupons WHERE code = $1`
+	return scanCoupon(r.db.Conn(ctx).QueryRow(ctx, query, strings.ToUpper(code)))
+}
+
+// LockByCode retrieves a coupon by its code and locks it until the
+// surrounding transaction ends, so usage limits hold under concurrent checkouts
+func (r *CouponRepository) LockByCode(ctx context.Context, code string) (*model.Coupon, error) {
+	query := `SELECT ` + couponColumns + `, ` + couponCurrency + ` FROM coupons WHERE code = $1 FOR UPDATE`
+	return scanCoupon(r.db.Conn(ctx).QueryRow(ctx, query, strings.ToUpper(code)))
+}
+
+// List retrieves coupons, newest first. A nil shopID lists every coupon.
+func (r *CouponRepository) List(ctx context.Context, shopID *uuid.UUID) ([]*model.Coupon, error) {
+	query := `
+		SELECT ` + couponColumns + `, ` + couponCurrency + `
+		FROM coupons
+		WHERE $1::uuid IS NULL OR shop_id = $1
+		ORDER BY created_at DESC
+	`
+
+	rows, err := r.db.Conn(ctx).Query(ctx, query, shopID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	coupons := []*model.Coupon{}
+	for rows.Next() {
+		coupon, err := scanCoupon(rows)
+		if err != nil {
+			return nil, err
+		}
+		coupons = append(coupons, coupon)
+	}
+
+	return coupons, rows.Err()
+}
+
+// Update saves a coupon's editable fields
+func (r *CouponRepository) Update(ctx context.Context, coupon *model.Coupon) error {
+	query := `
+		UPDATE coupons
+		SET description = $1, value = $2, max_discount = $3, min_subtotal = $4, usage_limit = $5,
+		    per_user_limit = $6, starts_at = $7, ends_at = $8, is_active = $9, updated_at = NOW()
+		WHERE id = $10
+	`
+
+	result, err := r.db.Conn(ctx).Exec(ctx, query,
+		coupon.Description,
+		coupon.Value,
+		coupon.MaxDiscount,
+		coupon.MinSubtotal,
+		coupon.UsageLimit,
+		coupon.PerUserLimit,
+		coupon.StartsAt,
+		coupon.EndsAt,
+		coupon.IsActive,
+		coupon.ID,
+	)
+	if err != nil {
+		return err
+	}
+
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("coupon not found")
+	}
+
+	return nil
+}
+
+// CountUserRedemptions returns how many orders of a user used the coupon
+func (r *CouponRepository) CountUserRedemptions(ctx context.Context, couponID, userID uuid.UUID) (int, error) {
+	var count int
+	query := `SELECT COUNT(*) FROM coupon_redemptions WHERE coupon_id = $1 AND user_id = $2`
+	err := r.db.Conn(ctx).QueryRow(ctx, query, couponID, userID).Scan(&count)
+	return count, err
+}
+
+// Redeem records that an order used a coupon and counts the use
+func (r *CouponRepository) Redeem(ctx context.Context, couponID, orderID, userID uuid.UUID, amount model.Money) error {
+	return r.db.WithTx(ctx, func(ctx context.Context) error {
+		query := `
+			INSERT INTO coupon_redemptions (id, coupon_id, order_id, user_id, amount, created_at)
+			VALUES ($1, $2, $3, $4, $5, NOW())
+		`
+		if _, err := r.db.Conn(ctx).Exec(ctx, query, uuid.New(), couponID, orderID, userID, amount); err != nil {
+			return err
+		}
+
+		_, err := r.db.Conn(ctx).Exec(ctx, `UPDATE coupons SET used_count = used_count + 1 WHERE id = $1`, couponID)
+		return err
+	})
+}
+
+// ReleaseForOrder gives back the coupon use of a cancelled order. Orders
+// without a redemption are left alone, which makes releasing idempotent.
+func (r *CouponRepository) ReleaseForOrder(ctx context.Context, orderID uuid.UUID) error {
+	query := `
+		WITH released AS (
+			DELETE FROM coupon_redemptions WHERE order_id = $1 RETURNING coupon_id
+		)
+		UPDATE coupons
+		SET used_count = GREATEST(used_count - 1, 0)
+		WHERE id IN (SELECT coupon_id FROM released)
+	`
+	_, err := r.db.Conn(ctx).Exec(ctx, query, orderID)
+	return err
+}
+
+func scanCoupon(row pgx.Row) (*model.Coupon, error) {
+	var coupon model.Coupon
+	var currency *string
+	err := row.Scan(
+		&coupon.ID,
+		&coupon.Code,
+		&coupon.Description,
+		&coupon.Type,
+		&coupon.Value,
+		&coupon.MaxDiscount,
+		&coupon.MinSubtotal,
+		&coupon.ShopID,
+		&coupon.UsageLimit,
+		&coupon.PerUserLimit,
+		&coupon.UsedCount,
+		&coupon.StartsAt,
+		&coupon.EndsAt,
+		&coupon.IsActive,
+		&coupon.CreatedBy,
+		&coupon.CreatedAt,
+		&coupon.UpdatedAt,
+		&currency,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	setCouponCurrency(&coupon, currency)
+	return &coupon, nil
+}
+
+// setCouponCurrency tags a coupon's amounts with its currency
+func setCouponCurrency(coupon *model.Coupon, currency *string) {
+	coupon.Currency = model.DefaultCurrency
+	if currency != nil {
+		coupon.Currency = *currency
+	}
+
+	coupon.MinSubtotal.Currency = coupon.Currency
+	if coupon.MaxDiscount != nil {
+		coupon.MaxDiscount.Currency = coupon.Currency
+	}
+}
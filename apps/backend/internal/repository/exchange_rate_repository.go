@@ -0,0 +1,72 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/imbivek08/hamropasal/internal/database"
+	"github.com/imbivek08/hamropasal/internal/model"
+)
+
+type ExchangeRateRepository struct {
+	db *database.Database
+}
+
+func NewExchangeRateRepository(db *database.Database) *ExchangeRateRepository {
+	return &ExchangeRateRepository{db: db}
+}
+
+// List retrieves every exchange rate by currency
+func (r *ExchangeRateRepository) List(ctx context.Context) ([]*model.ExchangeRate, error) {
+	query := `SELECT currency, rate, created_at, updated_at FROM exchange_rates ORDER BY currency`
+
+	rows, err := r.db.Conn(ctx).Query(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	rates := []*model.ExchangeRate{}
+	for rows.Next() {
+		var rate model.ExchangeRate
+		if err := rows.Scan(&rate.Currency, &rate.Rate, &rate.CreatedAt, &rate.UpdatedAt); err != nil {
+			return nil, err
+		}
+		rates = append(rates, &rate)
+	}
+
+	return rates, rows.Err()
+}
+
+// Upsert sets the rate of a currency, adding it if it is new
+func (r *ExchangeRateRepository) Upsert(ctx context.Context, rate *model.ExchangeRate) error {
+	query := `
+		INSERT INTO exchange_rates (currency, rate)
+		VALUES ($1, $2)
+		ON CONFLICT (currency) DO UPDATE SET rate = EXCLUDED.rate, updated_at = NOW()
+		RETURNING created_at, updated_at
+	`
+	return r.db.Conn(ctx).QueryRow(ctx, query, rate.Currency, rate.Rate).Scan(&rate.CreatedAt, &rate.UpdatedAt)
+}
+
+// Delete removes a currency's rate
+func (r *ExchangeRateRepository) Delete(ctx context.Context, currency string) error {
+	result, err := r.db.Conn(ctx).Exec(ctx, `DELETE FROM exchange_rates WHERE currency = $1`, currency)
+	if err != nil {
+		return err
+	}
+
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("exchange rate not found")
+	}
+
+	return nil
+}
+
+// InUse reports whether any shop prices in the currency
+func (r *ExchangeRateRepository) InUse(ctx context.Context, currency string) (bool, error) {
+	var inUse bool
+	query := `SELECT EXISTS(SELECT 1 FROM shops WHERE currency = $1)`
+	err := r.db.Conn(ctx).QueryRow(ctx, query, currency).Scan(&inUse)
+	return inUse, err
+}
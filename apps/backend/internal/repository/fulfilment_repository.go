@@ -0,0 +1,197 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/imbivek08/hamropasal/internal/database"
+	"github.com/imbivek08/hamropasal/internal/model"
+	"github.com/jackc/pgx/v5"
+)
+
+type FulfilmentRepository struct {
+	db *database.Database
+}
+
+func NewFulfilmentRepository(db *database.Database) *FulfilmentRepository {
+	return &FulfilmentRepository{db: db}
+}
+
+const fulfilmentColumns = `id, order_id, shop_id, status, subtotal, shipping_method_id, shipping_method_name, shipping_cost,
+	created_at, updated_at, confirmed_at, shipped_at, delivered_at`
+
+// Create inserts the fulfilments of a new order
+func (r *FulfilmentRepository) Create(ctx context.Context, fulfilments []model.Fulfilment) error {
+	query := `
+		INSERT INTO order_fulfilments (` + fulfilmentColumns + `)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+	`
+
+	for _, f := range fulfilments {
+		_, err := r.db.Conn(ctx).Exec(ctx, query,
+			f.ID,
+			f.OrderID,
+			f.ShopID,
+			f.Status,
+			f.Subtotal,
+			f.ShippingMethodID,
+			f.ShippingMethodName,
+			f.ShippingCost,
+			f.CreatedAt,
+			f.UpdatedAt,
+			f.ConfirmedAt,
+			f.ShippedAt,
+			f.DeliveredAt,
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// GetByOrderID retrieves all fulfilments of an order
+func (r *FulfilmentRepository) GetByOrderID(ctx context.Context, orderID uuid.UUID) ([]model.Fulfilment, error) {
+	query := `SELECT ` + fulfilmentColumns + ` FROM order_fulfilments WHERE order_id = $1 ORDER BY created_at, shop_id`
+
+	rows, err := r.db.Conn(ctx).Query(ctx, query, orderID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var fulfilments []model.Fulfilment
+	for rows.Next() {
+		f, err := scanFulfilment(rows)
+		if err != nil {
+			return nil, err
+		}
+		fulfilments = append(fulfilments, *f)
+	}
+
+	return fulfilments, rows.Err()
+}
+
+// LockByOrderAndShop retrieves a shop's fulfilment of an order and locks it
+// until the surrounding transaction ends
+func (r *FulfilmentRepository) LockByOrderAndShop(ctx context.Context, orderID, shopID uuid.UUID) (*model.Fulfilment, error) {
+	query := `SELECT ` + fulfilmentColumns + ` FROM order_fulfilments WHERE order_id = $1 AND shop_id = $2 FOR UPDATE`
+	return scanFulfilment(r.db.Conn(ctx).QueryRow(ctx, query, orderID, shopID))
+}
+
+// GetVendorOrders retrieves a shop's fulfilments together with their parent
+// order's reference and payment state. Items and address are left to the caller.
+func (r *FulfilmentRepository) GetVendorOrders(ctx context.Context, shopID uuid.UUID) ([]*model.VendorOrder, error) {
+	query := `
+		SELECT f.id, f.order_id, f.shop_id, f.status, f.subtotal, f.shipping_method_id, f.shipping_method_name,
+		       f.shipping_cost, f.created_at, f.updated_at,
+		       f.confirmed_at, f.shipped_at, f.delivered_at,
+		       o.order_number, o.status, o.payment_method, o.payment_status, o.shipping_address_id
+		FROM order_fulfilments f
+		INNER JOIN orders o ON o.id = f.order_id
+		WHERE f.shop_id = $1
+		ORDER BY f.created_at DESC
+	`
+
+	rows, err := r.db.Conn(ctx).Query(ctx, query, shopID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var orders []*model.VendorOrder
+	for rows.Next() {
+		var order model.VendorOrder
+		err := rows.Scan(
+			&order.ID,
+			&order.OrderID,
+			&order.ShopID,
+			&order.Status,
+			&order.Subtotal,
+			&order.ShippingMethodID,
+			&order.ShippingMethodName,
+			&order.ShippingCost,
+			&order.CreatedAt,
+			&order.UpdatedAt,
+			&order.ConfirmedAt,
+			&order.ShippedAt,
+			&order.DeliveredAt,
+			&order.OrderNumber,
+			&order.OrderStatus,
+			&order.PaymentMethod,
+			&order.PaymentStatus,
+			&order.ShippingAddressID,
+		)
+		if err != nil {
+			return nil, err
+		}
+		orders = append(orders, &order)
+	}
+
+	return orders, rows.Err()
+}
+
+// UpdateStatus moves a fulfilment to a new status and stamps the matching timestamp
+func (r *FulfilmentRepository) UpdateStatus(ctx context.Context, fulfilmentID uuid.UUID, status model.OrderStatus) error {
+	query := `UPDATE order_fulfilments SET ` + fulfilmentStatusAssignments + ` WHERE id = $2`
+
+	result, err := r.db.Conn(ctx).Exec(ctx, query, status, fulfilmentID)
+	if err != nil {
+		return err
+	}
+
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("fulfilment not found")
+	}
+
+	return nil
+}
+
+// TransitionForOrder moves every fulfilment of an order that is in one of the
+// from statuses to status. Order-wide events (payment, cancellation, full
+// refund) use it to carry the order's fulfilments along.
+func (r *FulfilmentRepository) TransitionForOrder(ctx context.Context, orderID uuid.UUID, status model.OrderStatus, from ...model.OrderStatus) error {
+	fromStatuses := make([]string, len(from))
+	for i, s := range from {
+		fromStatuses[i] = string(s)
+	}
+
+	query := `UPDATE order_fulfilments SET ` + fulfilmentStatusAssignments + ` WHERE order_id = $2 AND status = ANY($3)`
+
+	_, err := r.db.Conn(ctx).Exec(ctx, query, status, orderID, fromStatuses)
+	return err
+}
+
+// fulfilmentStatusAssignments sets status ($1) and the timestamp of the stage it reaches
+const fulfilmentStatusAssignments = `
+	status = $1::varchar,
+	confirmed_at = CASE WHEN $1::varchar = 'confirmed' THEN NOW() ELSE confirmed_at END,
+	shipped_at = CASE WHEN $1::varchar = 'shipped' THEN NOW() ELSE shipped_at END,
+	delivered_at = CASE WHEN $1::varchar = 'delivered' THEN NOW() ELSE delivered_at END,
+	updated_at = NOW()
+`
+
+func scanFulfilment(row pgx.Row) (*model.Fulfilment, error) {
+	var f model.Fulfilment
+	err := row.Scan(
+		&f.ID,
+		&f.OrderID,
+		&f.ShopID,
+		&f.Status,
+		&f.Subtotal,
+		&f.ShippingMethodID,
+		&f.ShippingMethodName,
+		&f.ShippingCost,
+		&f.CreatedAt,
+		&f.UpdatedAt,
+		&f.ConfirmedAt,
+		&f.ShippedAt,
+		&f.DeliveredAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &f, nil
+}
@@ -0,0 +1,204 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/imbivek08/hamropasal/internal/database"
+	"github.com/imbivek08/hamropasal/internal/model"
+	"github.com/jackc/pgx/v5"
+)
+
+const jobColumns = `id, job_type, payload, status, attempts, max_attempts, run_at, locked_at, last_error, created_at, updated_at, completed_at`
+
+// jobStaleAfter is how long a job may stay running before it is assumed
+// abandoned (e.g. the process died) and may be claimed again
+const jobStaleAfter = "10 minutes"
+
+type JobRepository struct {
+	db *database.Database
+}
+
+func NewJobRepository(db *database.Database) *JobRepository {
+	return &JobRepository{db: db}
+}
+
+// Create queues a job. Inside Database.WithTx it is only queued if the
+// transaction commits.
+func (r *JobRepository) Create(ctx context.Context, job *model.Job) error {
+	query := `
+		INSERT INTO jobs (id, job_type, payload, status, max_attempts, run_at)
+		VALUES ($1, $2, $3, 'pending', $4, $5)
+		RETURNING ` + jobColumns
+
+	created, err := scanJob(r.db.Conn(ctx).QueryRow(ctx, query,
+		job.ID,
+		job.Type,
+		job.Payload,
+		job.MaxAttempts,
+		job.RunAt,
+	))
+	if err != nil {
+		return err
+	}
+
+	*job = *created
+	return nil
+}
+
+// ClaimNext marks the next due job, or an abandoned running one, as running
+// and counts the attempt. Abandoned jobs without attempts left are dead
+// instead. Concurrent workers skip each other's jobs. It returns nil when no
+// job is due.
+func (r *JobRepository) ClaimNext(ctx context.Context) (*model.Job, error) {
+	query := `
+		WITH exhausted AS (
+			UPDATE jobs
+			SET status = 'dead', locked_at = NULL, completed_at = NOW(),
+				last_error = 'abandoned while running after ' || attempts::text || ' attempt(s)'
+			WHERE status = 'running' AND locked_at < NOW() - $1::interval AND attempts >= max_attempts
+		)
+		UPDATE jobs
+		SET status = 'running', attempts = attempts + 1, locked_at = NOW()
+		WHERE id = (
+			SELECT id
+			FROM jobs
+			WHERE (status = 'pending' AND run_at <= NOW())
+			   OR (status = 'running' AND locked_at < NOW() - $1::interval AND attempts < max_attempts)
+			ORDER BY run_at ASC
+			LIMIT 1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING ` + jobColumns
+
+	job, err := scanJob(r.db.Conn(ctx).QueryRow(ctx, query, jobStaleAfter))
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+	return job, err
+}
+
+// MarkSucceeded records a successful run
+func (r *JobRepository) MarkSucceeded(ctx context.Context, id uuid.UUID) error {
+	query := `
+		UPDATE jobs
+		SET status = 'succeeded', locked_at = NULL, last_error = NULL, completed_at = NOW()
+		WHERE id = $1
+	`
+	return r.exec(ctx, query, id)
+}
+
+// MarkForRetry records a failed run and schedules the next attempt
+func (r *JobRepository) MarkForRetry(ctx context.Context, id uuid.UUID, runAt time.Time, lastError string) error {
+	query := `
+		UPDATE jobs
+		SET status = 'pending', locked_at = NULL, run_at = $2, last_error = $3
+		WHERE id = $1
+	`
+	return r.exec(ctx, query, id, runAt, lastError)
+}
+
+// MarkDead records a failed run after which the job is not tried again
+func (r *JobRepository) MarkDead(ctx context.Context, id uuid.UUID, lastError string) error {
+	query := `
+		UPDATE jobs
+		SET status = 'dead', locked_at = NULL, last_error = $2, completed_at = NOW()
+		WHERE id = $1
+	`
+	return r.exec(ctx, query, id, lastError)
+}
+
+// Requeue makes a dead or pending job due now with a fresh set of attempts.
+// It reports false when the job is running or has succeeded.
+func (r *JobRepository) Requeue(ctx context.Context, id uuid.UUID) (*model.Job, bool, error) {
+	query := `
+		UPDATE jobs
+		SET status = 'pending', attempts = 0, run_at = NOW(), last_error = NULL, completed_at = NULL
+		WHERE id = $1 AND status IN ('pending', 'dead')
+		RETURNING ` + jobColumns
+
+	job, err := scanJob(r.db.Conn(ctx).QueryRow(ctx, query, id))
+	if err == pgx.ErrNoRows {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return job, true, nil
+}
+
+// GetByID retrieves a job by ID
+func (r *JobRepository) GetByID(ctx context.Context, id uuid.UUID) (*model.Job, error) {
+	query := `SELECT ` + jobColumns + ` FROM jobs WHERE id = $1`
+	return scanJob(r.db.Conn(ctx).QueryRow(ctx, query, id))
+}
+
+// List retrieves jobs, newest first, optionally filtered by status and type
+func (r *JobRepository) List(ctx context.Context, status, jobType string, page, pageSize int) ([]*model.Job, int, error) {
+	offset := (page - 1) * pageSize
+
+	var total int
+	countQuery := `SELECT COUNT(*) FROM jobs WHERE ($1 = '' OR status = $1) AND ($2 = '' OR job_type = $2)`
+	if err := r.db.Conn(ctx).QueryRow(ctx, countQuery, status, jobType).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	query := `
+		SELECT ` + jobColumns + `
+		FROM jobs
+		WHERE ($1 = '' OR status = $1) AND ($2 = '' OR job_type = $2)
+		ORDER BY created_at DESC
+		LIMIT $3 OFFSET $4
+	`
+	rows, err := r.db.Conn(ctx).Query(ctx, query, status, jobType, pageSize, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	jobs := []*model.Job{}
+	for rows.Next() {
+		job, err := scanJob(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		jobs = append(jobs, job)
+	}
+
+	return jobs, total, rows.Err()
+}
+
+func (r *JobRepository) exec(ctx context.Context, query string, args ...interface{}) error {
+	result, err := r.db.Conn(ctx).Exec(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("job not found")
+	}
+	return nil
+}
+
+func scanJob(row pgx.Row) (*model.Job, error) {
+	var job model.Job
+	err := row.Scan(
+		&job.ID,
+		&job.Type,
+		&job.Payload,
+		&job.Status,
+		&job.Attempts,
+		&job.MaxAttempts,
+		&job.RunAt,
+		&job.LockedAt,
+		&job.LastError,
+		&job.CreatedAt,
+		&job.UpdatedAt,
+		&job.CompletedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
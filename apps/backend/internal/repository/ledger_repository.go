@@ -0,0 +1,432 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/imbivek08/hamropasal/internal/database"
+	"github.com/imbivek08/hamropasal/internal/model"
+	"github.com/jackc/pgx/v5"
+)
+
+const ledgerEntryColumns = `id, transaction_id, account, shop_id, entry_type, amount, currency, order_id, order_item_id, refund_id, payout_id, available_at, created_at`
+
+const payoutColumns = `p.id, p.batch_id, p.shop_id, s.shop_name, p.amount, p.currency, p.status, p.failure_reason, p.created_at, p.updated_at, p.paid_at`
+
+// LedgerRepository stores the vendor ledger and the payouts settling it
+type LedgerRepository struct {
+	db *database.Database
+}
+
+func NewLedgerRepository(db *database.Database) *LedgerRepository {
+	return &LedgerRepository{db: db}
+}
+
+// CreateEntries inserts the entries of one or more transactions
+func (r *LedgerRepository) CreateEntries(ctx context.Context, entries []model.LedgerEntry) error {
+	query := `
+		INSERT INTO ledger_entries (` + ledgerEntryColumns + `)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+	`
+
+	return r.db.WithTx(ctx, func(ctx context.Context) error {
+		for _, entry := range entries {
+			_, err := r.db.Conn(ctx).Exec(ctx, query,
+				entry.ID,
+				entry.TransactionID,
+				entry.Account,
+				entry.ShopID,
+				entry.Type,
+				entry.Amount,
+				entry.Currency,
+				entry.OrderID,
+				entry.OrderItemID,
+				entry.RefundID,
+				entry.PayoutID,
+				entry.AvailableAt,
+				entry.CreatedAt,
+			)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// HasSale reports whether an order's sale has been posted
+func (r *LedgerRepository) HasSale(ctx context.Context, orderID uuid.UUID) (bool, error) {
+	query := `SELECT EXISTS(SELECT 1 FROM ledger_entries WHERE order_id = $1 AND entry_type = 'sale')`
+	var exists bool
+	err := r.db.Conn(ctx).QueryRow(ctx, query, orderID).Scan(&exists)
+	return exists, err
+}
+
+// HasRefund reports whether a refund has been posted
+func (r *LedgerRepository) HasRefund(ctx context.Context, refundID uuid.UUID) (bool, error) {
+	query := `SELECT EXISTS(SELECT 1 FROM ledger_entries WHERE refund_id = $1)`
+	var exists bool
+	err := r.db.Conn(ctx).QueryRow(ctx, query, refundID).Scan(&exists)
+	return exists, err
+}
+
+// GetOrderSales returns what each shop was credited for an order's sale
+func (r *LedgerRepository) GetOrderSales(ctx context.Context, orderID uuid.UUID) (map[uuid.UUID]model.Money, error) {
+	query := `
+		SELECT shop_id, SUM(amount)
+		FROM ledger_entries
+		WHERE order_id = $1 AND account = 'shop' AND entry_type = 'sale'
+		GROUP BY shop_id
+	`
+	rows, err := r.db.Conn(ctx).Query(ctx, query, orderID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	sales := make(map[uuid.UUID]model.Money)
+	for rows.Next() {
+		var shopID uuid.UUID
+		var amount model.Money
+		if err := rows.Scan(&shopID, &amount); err != nil {
+			return nil, err
+		}
+		sales[shopID] = amount
+	}
+
+	return sales, rows.Err()
+}
+
+// GetOrderCommissions returns, per order line, the commission charged on its
+// sale and what of it has not been returned by refunds
+func (r *LedgerRepository) GetOrderCommissions(ctx context.Context, orderID uuid.UUID) (charged, remaining map[uuid.UUID]model.Money, err error) {
+	query := `
+		SELECT order_item_id,
+		       -COALESCE(SUM(amount) FILTER (WHERE refund_id IS NULL), 0),
+		       -SUM(amount)
+		FROM ledger_entries
+		WHERE order_id = $1 AND account = 'shop' AND entry_type = 'commission' AND order_item_id IS NOT NULL
+		GROUP BY order_item_id
+	`
+	rows, err := r.db.Conn(ctx).Query(ctx, query, orderID)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	charged = make(map[uuid.UUID]model.Money)
+	remaining = make(map[uuid.UUID]model.Money)
+	for rows.Next() {
+		var itemID uuid.UUID
+		var c, rem model.Money
+		if err := rows.Scan(&itemID, &c, &rem); err != nil {
+			return nil, nil, err
+		}
+		charged[itemID] = c
+		remaining[itemID] = rem
+	}
+
+	return charged, remaining, rows.Err()
+}
+
+// GetBalances sums a shop's entries per currency
+func (r *LedgerRepository) GetBalances(ctx context.Context, shopID uuid.UUID) ([]model.ShopBalance, error) {
+	query := `
+		SELECT currency,
+		       COALESCE(SUM(amount) FILTER (WHERE available_at <= NOW()), 0),
+		       COALESCE(SUM(amount) FILTER (WHERE available_at > NOW()), 0),
+		       COALESCE(SUM(amount) FILTER (WHERE entry_type = 'sale'), 0),
+		       -COALESCE(SUM(amount) FILTER (WHERE entry_type = 'commission'), 0),
+		       -COALESCE(SUM(amount) FILTER (WHERE entry_type = 'refund'), 0),
+		       -COALESCE(SUM(amount) FILTER (WHERE entry_type = 'payout'), 0)
+		FROM ledger_entries
+		WHERE account = 'shop' AND shop_id = $1
+		GROUP BY currency
+		ORDER BY currency
+	`
+	rows, err := r.db.Conn(ctx).Query(ctx, query, shopID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	balances := []model.ShopBalance{}
+	for rows.Next() {
+		var b model.ShopBalance
+		if err := rows.Scan(&b.Currency, &b.Available, &b.Pending, &b.Sales, &b.Commission, &b.Refunds, &b.PaidOut); err != nil {
+			return nil, err
+		}
+		balances = append(balances, b)
+	}
+
+	return balances, rows.Err()
+}
+
+// ListEntries lists a shop's entries, newest first
+func (r *LedgerRepository) ListEntries(ctx context.Context, shopID uuid.UUID, page, pageSize int) ([]*model.LedgerEntry, int, error) {
+	offset := (page - 1) * pageSize
+
+	var total int
+	countQuery := `SELECT COUNT(*) FROM ledger_entries WHERE account = 'shop' AND shop_id = $1`
+	if err := r.db.Conn(ctx).QueryRow(ctx, countQuery, shopID).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	query := `
+		SELECT ` + ledgerEntryColumns + `
+		FROM ledger_entries
+		WHERE account = 'shop' AND shop_id = $1
+		ORDER BY created_at DESC, id
+		LIMIT $2 OFFSET $3
+	`
+	rows, err := r.db.Conn(ctx).Query(ctx, query, shopID, pageSize, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	entries := []*model.LedgerEntry{}
+	for rows.Next() {
+		entry, err := scanLedgerEntry(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		entries = append(entries, entry)
+	}
+
+	return entries, total, rows.Err()
+}
+
+// GetAvailableBalances returns, per shop and currency, the positive available
+// balance as unsaved payouts. With shopIDs set only those shops are included.
+func (r *LedgerRepository) GetAvailableBalances(ctx context.Context, shopIDs []uuid.UUID) ([]*model.Payout, error) {
+	query := `
+		SELECT e.shop_id, s.shop_name, e.currency, SUM(e.amount)
+		FROM ledger_entries e
+		INNER JOIN shops s ON e.shop_id = s.id
+		WHERE e.account = 'shop' AND e.available_at <= NOW()
+		  AND (cardinality($1::uuid[]) = 0 OR e.shop_id = ANY($1))
+		GROUP BY e.shop_id, s.shop_name, e.currency
+		HAVING SUM(e.amount) > 0
+		ORDER BY s.shop_name, e.currency
+	`
+	if shopIDs == nil {
+		shopIDs = []uuid.UUID{}
+	}
+	rows, err := r.db.Conn(ctx).Query(ctx, query, shopIDs)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var payouts []*model.Payout
+	for rows.Next() {
+		var payout model.Payout
+		if err := rows.Scan(&payout.ShopID, &payout.ShopName, &payout.Currency, &payout.Amount); err != nil {
+			return nil, err
+		}
+		payouts = append(payouts, &payout)
+	}
+
+	return payouts, rows.Err()
+}
+
+// LockPayouts serialises payout batches until the surrounding transaction
+// ends, so no balance is paid out twice. It must be called inside
+// Database.WithTx.
+func (r *LedgerRepository) LockPayouts(ctx context.Context) error {
+	_, err := r.db.Conn(ctx).Exec(ctx, `SELECT pg_advisory_xact_lock(hashtext('payouts'))`)
+	return err
+}
+
+// CreateBatch inserts a payout batch together with its payouts
+func (r *LedgerRepository) CreateBatch(ctx context.Context, batch *model.PayoutBatch) error {
+	return r.db.WithTx(ctx, func(ctx context.Context) error {
+		query := `INSERT INTO payout_batches (id, created_by, created_at) VALUES ($1, $2, $3)`
+		if _, err := r.db.Conn(ctx).Exec(ctx, query, batch.ID, batch.CreatedBy, batch.CreatedAt); err != nil {
+			return err
+		}
+
+		query = `
+			INSERT INTO payouts (id, batch_id, shop_id, amount, currency, status, created_at, updated_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		`
+		for _, payout := range batch.Payouts {
+			_, err := r.db.Conn(ctx).Exec(ctx, query,
+				payout.ID,
+				payout.BatchID,
+				payout.ShopID,
+				payout.Amount,
+				payout.Currency,
+				payout.Status,
+				payout.CreatedAt,
+				payout.UpdatedAt,
+			)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// GetBatch retrieves a payout batch with its payouts
+func (r *LedgerRepository) GetBatch(ctx context.Context, id uuid.UUID) (*model.PayoutBatch, error) {
+	var batch model.PayoutBatch
+	query := `SELECT id, created_by, created_at FROM payout_batches WHERE id = $1`
+	if err := r.db.Conn(ctx).QueryRow(ctx, query, id).Scan(&batch.ID, &batch.CreatedBy, &batch.CreatedAt); err != nil {
+		return nil, err
+	}
+
+	if err := r.attachPayouts(ctx, &batch); err != nil {
+		return nil, err
+	}
+
+	return &batch, nil
+}
+
+// ListBatches lists payout batches with their payouts, newest first
+func (r *LedgerRepository) ListBatches(ctx context.Context, page, pageSize int) ([]*model.PayoutBatch, int, error) {
+	offset := (page - 1) * pageSize
+
+	var total int
+	if err := r.db.Conn(ctx).QueryRow(ctx, `SELECT COUNT(*) FROM payout_batches`).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	query := `
+		SELECT id, created_by, created_at
+		FROM payout_batches
+		ORDER BY created_at DESC
+		LIMIT $1 OFFSET $2
+	`
+	rows, err := r.db.Conn(ctx).Query(ctx, query, pageSize, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	batches := []*model.PayoutBatch{}
+	for rows.Next() {
+		var batch model.PayoutBatch
+		if err := rows.Scan(&batch.ID, &batch.CreatedBy, &batch.CreatedAt); err != nil {
+			rows.Close()
+			return nil, 0, err
+		}
+		batches = append(batches, &batch)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	for _, batch := range batches {
+		if err := r.attachPayouts(ctx, batch); err != nil {
+			return nil, 0, err
+		}
+	}
+
+	return batches, total, nil
+}
+
+// GetPayout retrieves a payout by ID
+func (r *LedgerRepository) GetPayout(ctx context.Context, id uuid.UUID) (*model.Payout, error) {
+	query := `
+		SELECT ` + payoutColumns + `
+		FROM payouts p
+		INNER JOIN shops s ON p.shop_id = s.id
+		WHERE p.id = $1
+	`
+	return scanPayout(r.db.Conn(ctx).QueryRow(ctx, query, id))
+}
+
+// MarkPayoutPaid moves a pending payout to paid, reporting whether it did
+func (r *LedgerRepository) MarkPayoutPaid(ctx context.Context, id uuid.UUID) (bool, error) {
+	query := `UPDATE payouts SET status = 'paid', paid_at = NOW() WHERE id = $1 AND status = 'pending'`
+	result, err := r.db.Conn(ctx).Exec(ctx, query, id)
+	if err != nil {
+		return false, err
+	}
+	return result.RowsAffected() == 1, nil
+}
+
+// MarkPayoutFailed moves a pending payout to failed with the reason,
+// reporting whether it did
+func (r *LedgerRepository) MarkPayoutFailed(ctx context.Context, id uuid.UUID, reason *string) (bool, error) {
+	query := `UPDATE payouts SET status = 'failed', failure_reason = $1 WHERE id = $2 AND status = 'pending'`
+	result, err := r.db.Conn(ctx).Exec(ctx, query, reason, id)
+	if err != nil {
+		return false, err
+	}
+	return result.RowsAffected() == 1, nil
+}
+
+func (r *LedgerRepository) attachPayouts(ctx context.Context, batch *model.PayoutBatch) error {
+	query := `
+		SELECT ` + payoutColumns + `
+		FROM payouts p
+		INNER JOIN shops s ON p.shop_id = s.id
+		WHERE p.batch_id = $1
+		ORDER BY s.shop_name, p.currency
+	`
+	rows, err := r.db.Conn(ctx).Query(ctx, query, batch.ID)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	batch.Payouts = []*model.Payout{}
+	for rows.Next() {
+		payout, err := scanPayout(rows)
+		if err != nil {
+			return err
+		}
+		batch.Payouts = append(batch.Payouts, payout)
+	}
+
+	return rows.Err()
+}
+
+func scanLedgerEntry(row pgx.Row) (*model.LedgerEntry, error) {
+	var entry model.LedgerEntry
+	err := row.Scan(
+		&entry.ID,
+		&entry.TransactionID,
+		&entry.Account,
+		&entry.ShopID,
+		&entry.Type,
+		&entry.Amount,
+		&entry.Currency,
+		&entry.OrderID,
+		&entry.OrderItemID,
+		&entry.RefundID,
+		&entry.PayoutID,
+		&entry.AvailableAt,
+		&entry.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+func scanPayout(row pgx.Row) (*model.Payout, error) {
+	var payout model.Payout
+	err := row.Scan(
+		&payout.ID,
+		&payout.BatchID,
+		&payout.ShopID,
+		&payout.ShopName,
+		&payout.Amount,
+		&payout.Currency,
+		&payout.Status,
+		&payout.FailureReason,
+		&payout.CreatedAt,
+		&payout.UpdatedAt,
+		&payout.PaidAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &payout, nil
+}
@@ -3,6 +3,7 @@ package repository
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/imbivek08/hamropasal/internal/database"
@@ -22,12 +23,12 @@ func (r *OrderRepository) Create(ctx context.Context, order *model.Order) error
 	query := `
 		INSERT INTO orders (
 			id, user_id, order_number, status, shipping_address_id, billing_address_id,
-			subtotal, shipping_cost, tax, discount, total, payment_method, payment_status,
+			subtotal, shipping_cost, tax, discount, total, currency, payment_method, payment_status,
 			notes, created_at, updated_at
-		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16)
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17)
 	`
 
-	_, err := r.db.Pool.Exec(ctx, query,
+	_, err := r.db.Conn(ctx).Exec(ctx, query,
 		order.ID,
 		order.UserID,
 		order.OrderNumber,
@@ -39,6 +40,7 @@ func (r *OrderRepository) Create(ctx context.Context, order *model.Order) error
 		order.Tax,
 		order.Discount,
 		order.Total,
+		order.Currency,
 		order.PaymentMethod,
 		order.PaymentStatus,
 		order.Notes,
@@ -53,22 +55,30 @@ func (r *OrderRepository) Create(ctx context.Context, order *model.Order) error
 func (r *OrderRepository) CreateOrderItems(ctx context.Context, items []model.OrderItem) error {
 	query := `
 		INSERT INTO order_items (
-			id, order_id, product_id, shop_id, product_name, product_sku,
-			quantity, unit_price, subtotal, created_at
-		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+			id, order_id, product_id, variant_id, shop_id, product_name, variant_name, product_sku,
+			quantity, unit_price, subtotal, tax_name, tax_rate, taxable_amount,
+			tax_amount, tax_inclusive, created_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17)
 	`
 
 	for _, item := range items {
-		_, err := r.db.Pool.Exec(ctx, query,
+		_, err := r.db.Conn(ctx).Exec(ctx, query,
 			item.ID,
 			item.OrderID,
 			item.ProductID,
+			item.VariantID,
 			item.ShopID,
 			item.ProductName,
+			item.VariantName,
 			item.ProductSKU,
 			item.Quantity,
 			item.UnitPrice,
 			item.Subtotal,
+			item.TaxName,
+			item.TaxRate,
+			item.TaxableAmount,
+			item.TaxAmount,
+			item.TaxInclusive,
 			item.CreatedAt,
 		)
 		if err != nil {
@@ -84,13 +94,14 @@ func (r *OrderRepository) GetByID(ctx context.Context, orderID uuid.UUID) (*mode
 	var order model.Order
 	query := `
 		SELECT id, user_id, order_number, status, shipping_address_id, billing_address_id,
-		       subtotal, shipping_cost, tax, discount, total, payment_method, payment_status,
-		       notes, created_at, updated_at, confirmed_at, shipped_at, delivered_at
+		       subtotal, shipping_cost, tax, discount, total, currency, payment_method, payment_status,
+		       payment_reference, notes, created_at, updated_at, confirmed_at, shipped_at, delivered_at,
+		       cancelled_at, cancellation_reason
 		FROM orders
 		WHERE id = $1
 	`
 
-	err := r.db.Pool.QueryRow(ctx, query, orderID).Scan(
+	err := r.db.Conn(ctx).QueryRow(ctx, query, orderID).Scan(
 		&order.ID,
 		&order.UserID,
 		&order.OrderNumber,
@@ -102,19 +113,31 @@ func (r *OrderRepository) GetByID(ctx context.Context, orderID uuid.UUID) (*mode
 		&order.Tax,
 		&order.Discount,
 		&order.Total,
+		&order.Currency,
 		&order.PaymentMethod,
 		&order.PaymentStatus,
+		&order.PaymentReference,
 		&order.Notes,
 		&order.CreatedAt,
 		&order.UpdatedAt,
 		&order.ConfirmedAt,
 		&order.ShippedAt,
 		&order.DeliveredAt,
+		&order.CancelledAt,
+		&order.CancellationReason,
 	)
 
 	return &order, err
 }
 
+// LockByID locks the order row until the surrounding transaction ends.
+// It must be called inside Database.WithTx.
+func (r *OrderRepository) LockByID(ctx context.Context, orderID uuid.UUID) error {
+	var id uuid.UUID
+	query := `SELECT id FROM orders WHERE id = $1 FOR UPDATE`
+	return r.db.Conn(ctx).QueryRow(ctx, query, orderID).Scan(&id)
+}
+
 // GetOrderItems retrieves all items for an order
 func (r *OrderRepository) GetOrderItems(ctx context.Context, orderID uuid.UUID) ([]model.OrderItemWithDetails, error) {
 	query := `
@@ -122,22 +145,31 @@ func (r *OrderRepository) GetOrderItems(ctx context.Context, orderID uuid.UUID)
 			oi.id,
 			oi.order_id,
 			oi.product_id,
+			oi.variant_id,
 			oi.product_name,
-			p.image_url as product_image_url,
+			oi.variant_name,
+			oi.product_sku,
+			COALESCE(v.image_url, p.image_url) as product_image_url,
 			oi.shop_id,
 			s.shop_name,
 			oi.quantity,
 			oi.unit_price,
 			oi.subtotal,
+			oi.tax_name,
+			oi.tax_rate,
+			oi.taxable_amount,
+			oi.tax_amount,
+			oi.tax_inclusive,
 			oi.created_at
 		FROM order_items oi
 		LEFT JOIN products p ON oi.product_id = p.id
+		LEFT JOIN product_variants v ON oi.variant_id = v.id
 		LEFT JOIN shops s ON oi.shop_id = s.id
 		WHERE oi.order_id = $1
 		ORDER BY oi.created_at ASC
 	`
 
-	rows, err := r.db.Pool.Query(ctx, query, orderID)
+	rows, err := r.db.Conn(ctx).Query(ctx, query, orderID)
 	if err != nil {
 		return nil, err
 	}
@@ -150,13 +182,21 @@ func (r *OrderRepository) GetOrderItems(ctx context.Context, orderID uuid.UUID)
 			&item.ID,
 			&item.OrderID,
 			&item.ProductID,
+			&item.VariantID,
 			&item.ProductName,
+			&item.VariantName,
+			&item.ProductSKU,
 			&item.ProductImageURL,
 			&item.ShopID,
 			&item.ShopName,
 			&item.Quantity,
 			&item.UnitPrice,
 			&item.Subtotal,
+			&item.TaxName,
+			&item.TaxRate,
+			&item.TaxableAmount,
+			&item.TaxAmount,
+			&item.TaxInclusive,
 			&item.CreatedAt,
 		)
 		if err != nil {
@@ -168,69 +208,46 @@ func (r *OrderRepository) GetOrderItems(ctx context.Context, orderID uuid.UUID)
 	return items, rows.Err()
 }
 
-// GetByUserID retrieves all orders for a user
-func (r *OrderRepository) GetByUserID(ctx context.Context, userID uuid.UUID) ([]*model.Order, error) {
+// GetItemCategories returns the category of each order line's product, for
+// lines whose product is still categorised
+func (r *OrderRepository) GetItemCategories(ctx context.Context, orderID uuid.UUID) (map[uuid.UUID]uuid.UUID, error) {
 	query := `
-		SELECT id, user_id, order_number, status, shipping_address_id, billing_address_id,
-		       subtotal, shipping_cost, tax, discount, total, payment_method, payment_status,
-		       notes, created_at, updated_at, confirmed_at, shipped_at, delivered_at
-		FROM orders
-		WHERE user_id = $1
-		ORDER BY created_at DESC
+		SELECT oi.id, p.category_id
+		FROM order_items oi
+		INNER JOIN products p ON oi.product_id = p.id
+		WHERE oi.order_id = $1 AND p.category_id IS NOT NULL
 	`
-
-	rows, err := r.db.Pool.Query(ctx, query, userID)
+	rows, err := r.db.Conn(ctx).Query(ctx, query, orderID)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	var orders []*model.Order
+	categories := make(map[uuid.UUID]uuid.UUID)
 	for rows.Next() {
-		var order model.Order
-		err := rows.Scan(
-			&order.ID,
-			&order.UserID,
-			&order.OrderNumber,
-			&order.Status,
-			&order.ShippingAddressID,
-			&order.BillingAddressID,
-			&order.Subtotal,
-			&order.ShippingCost,
-			&order.Tax,
-			&order.Discount,
-			&order.Total,
-			&order.PaymentMethod,
-			&order.PaymentStatus,
-			&order.Notes,
-			&order.CreatedAt,
-			&order.UpdatedAt,
-			&order.ConfirmedAt,
-			&order.ShippedAt,
-			&order.DeliveredAt,
-		)
-		if err != nil {
+		var itemID, categoryID uuid.UUID
+		if err := rows.Scan(&itemID, &categoryID); err != nil {
 			return nil, err
 		}
-		orders = append(orders, &order)
+		categories[itemID] = categoryID
 	}
 
-	return orders, rows.Err()
+	return categories, rows.Err()
 }
 
-// GetByShopID retrieves orders containing products from a specific shop
-func (r *OrderRepository) GetByShopID(ctx context.Context, shopID uuid.UUID) ([]*model.Order, error) {
+// GetByUserID retrieves all orders for a user
+func (r *OrderRepository) GetByUserID(ctx context.Context, userID uuid.UUID) ([]*model.Order, error) {
 	query := `
-		SELECT DISTINCT o.id, o.user_id, o.order_number, o.status, o.shipping_address_id, o.billing_address_id,
-		       o.subtotal, o.shipping_cost, o.tax, o.discount, o.total, o.payment_method, o.payment_status,
-		       o.notes, o.created_at, o.updated_at, o.confirmed_at, o.shipped_at, o.delivered_at
-		FROM orders o
-		INNER JOIN order_items oi ON o.id = oi.order_id
-		WHERE oi.shop_id = $1
-		ORDER BY o.created_at DESC
+		SELECT id, user_id, order_number, status, shipping_address_id, billing_address_id,
+		       subtotal, shipping_cost, tax, discount, total, currency, payment_method, payment_status,
+		       notes, created_at, updated_at, confirmed_at, shipped_at, delivered_at,
+		       cancelled_at, cancellation_reason
+		FROM orders
+		WHERE user_id = $1
+		ORDER BY created_at DESC
 	`
 
-	rows, err := r.db.Pool.Query(ctx, query, shopID)
+	rows, err := r.db.Conn(ctx).Query(ctx, query, userID)
 	if err != nil {
 		return nil, err
 	}
@@ -251,6 +268,7 @@ func (r *OrderRepository) GetByShopID(ctx context.Context, shopID uuid.UUID) ([]
 			&order.Tax,
 			&order.Discount,
 			&order.Total,
+			&order.Currency,
 			&order.PaymentMethod,
 			&order.PaymentStatus,
 			&order.Notes,
@@ -259,6 +277,8 @@ func (r *OrderRepository) GetByShopID(ctx context.Context, shopID uuid.UUID) ([]
 			&order.ConfirmedAt,
 			&order.ShippedAt,
 			&order.DeliveredAt,
+			&order.CancelledAt,
+			&order.CancellationReason,
 		)
 		if err != nil {
 			return nil, err
@@ -277,7 +297,7 @@ func (r *OrderRepository) UpdateStatus(ctx context.Context, orderID uuid.UUID, s
 		WHERE id = $2
 	`
 
-	result, err := r.db.Pool.Exec(ctx, query, status, orderID)
+	result, err := r.db.Conn(ctx).Exec(ctx, query, status, orderID)
 	if err != nil {
 		return err
 	}
@@ -304,7 +324,7 @@ func (r *OrderRepository) UpdateStatusWithTimestamp(ctx context.Context, orderID
 		query = `UPDATE orders SET status = $1, updated_at = NOW() WHERE id = $2`
 	}
 
-	result, err := r.db.Pool.Exec(ctx, query, status, orderID)
+	result, err := r.db.Conn(ctx).Exec(ctx, query, status, orderID)
 	if err != nil {
 		return err
 	}
@@ -316,11 +336,58 @@ func (r *OrderRepository) UpdateStatusWithTimestamp(ctx context.Context, orderID
 	return nil
 }
 
+// Cancel cancels an order, recording why
+func (r *OrderRepository) Cancel(ctx context.Context, orderID uuid.UUID, reason string) error {
+	query := `
+		UPDATE orders
+		SET status = 'cancelled', cancelled_at = NOW(), cancellation_reason = $1, updated_at = NOW()
+		WHERE id = $2
+	`
+
+	result, err := r.db.Conn(ctx).Exec(ctx, query, reason, orderID)
+	if err != nil {
+		return err
+	}
+
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("order not found")
+	}
+
+	return nil
+}
+
+// GetUnpaidBefore returns pending orders placed before cutoff, oldest first
+func (r *OrderRepository) GetUnpaidBefore(ctx context.Context, cutoff time.Time, limit int) ([]uuid.UUID, error) {
+	query := `
+		SELECT id
+		FROM orders
+		WHERE status = 'pending' AND payment_status IN ('pending', 'failed') AND created_at < $1
+		ORDER BY created_at ASC
+		LIMIT $2
+	`
+	rows, err := r.db.Conn(ctx).Query(ctx, query, cutoff, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var orderIDs []uuid.UUID
+	for rows.Next() {
+		var orderID uuid.UUID
+		if err := rows.Scan(&orderID); err != nil {
+			return nil, err
+		}
+		orderIDs = append(orderIDs, orderID)
+	}
+
+	return orderIDs, rows.Err()
+}
+
 // UpdatePaymentStatus updates the payment status of an order
 func (r *OrderRepository) UpdatePaymentStatus(ctx context.Context, orderID uuid.UUID, status model.PaymentStatus) error {
 	query := `UPDATE orders SET payment_status = $1, updated_at = NOW() WHERE id = $2`
 
-	result, err := r.db.Pool.Exec(ctx, query, status, orderID)
+	result, err := r.db.Conn(ctx).Exec(ctx, query, status, orderID)
 	if err != nil {
 		return err
 	}
@@ -342,7 +409,7 @@ func (r *OrderRepository) CreateAddress(ctx context.Context, address *model.Addr
 		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
 	`
 
-	_, err := r.db.Pool.Exec(ctx, query,
+	_, err := r.db.Conn(ctx).Exec(ctx, query,
 		address.ID,
 		address.UserID,
 		address.FullName,
@@ -373,7 +440,7 @@ func (r *OrderRepository) GetAddressByID(ctx context.Context, addressID uuid.UUI
 		WHERE id = $1
 	`
 
-	err := r.db.Pool.QueryRow(ctx, query, addressID).Scan(
+	err := r.db.Conn(ctx).QueryRow(ctx, query, addressID).Scan(
 		&address.ID,
 		&address.UserID,
 		&address.FullName,
@@ -393,10 +460,23 @@ func (r *OrderRepository) GetAddressByID(ctx context.Context, addressID uuid.UUI
 	return &address, err
 }
 
-// UpdateStripeSessionID saves the Stripe checkout session ID on the order
-func (r *OrderRepository) UpdateStripeSessionID(ctx context.Context, orderID uuid.UUID, sessionID string) error {
-	query := `UPDATE orders SET stripe_session_id = $1, updated_at = NOW() WHERE id = $2`
-	result, err := r.db.Pool.Exec(ctx, query, sessionID, orderID)
+// UpdatePaymentReference saves the gateway's reference for the order's payment
+func (r *OrderRepository) UpdatePaymentReference(ctx context.Context, orderID uuid.UUID, reference string) error {
+	query := `UPDATE orders SET payment_reference = $1, updated_at = NOW() WHERE id = $2`
+	result, err := r.db.Conn(ctx).Exec(ctx, query, reference, orderID)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("order not found")
+	}
+	return nil
+}
+
+// UpdatePaymentMethod switches the payment method of an order
+func (r *OrderRepository) UpdatePaymentMethod(ctx context.Context, orderID uuid.UUID, method string) error {
+	query := `UPDATE orders SET payment_method = $1, updated_at = NOW() WHERE id = $2`
+	result, err := r.db.Conn(ctx).Exec(ctx, query, method, orderID)
 	if err != nil {
 		return err
 	}
@@ -406,18 +486,19 @@ func (r *OrderRepository) UpdateStripeSessionID(ctx context.Context, orderID uui
 	return nil
 }
 
-// GetByStripeSessionID retrieves order by Stripe session ID
-func (r *OrderRepository) GetByStripeSessionID(ctx context.Context, sessionID string) (*model.Order, error) {
+// GetByPaymentReference retrieves order by its gateway payment reference
+func (r *OrderRepository) GetByPaymentReference(ctx context.Context, reference string) (*model.Order, error) {
 	var order model.Order
 	query := `
 		SELECT id, user_id, order_number, status, shipping_address_id, billing_address_id,
-		       subtotal, shipping_cost, tax, discount, total, payment_method, payment_status,
-		       stripe_session_id, notes, created_at, updated_at, confirmed_at, shipped_at, delivered_at
+		       subtotal, shipping_cost, tax, discount, total, currency, payment_method, payment_status,
+		       payment_reference, notes, created_at, updated_at, confirmed_at, shipped_at, delivered_at,
+		       cancelled_at, cancellation_reason
 		FROM orders
-		WHERE stripe_session_id = $1
+		WHERE payment_reference = $1
 	`
 
-	err := r.db.Pool.QueryRow(ctx, query, sessionID).Scan(
+	err := r.db.Conn(ctx).QueryRow(ctx, query, reference).Scan(
 		&order.ID,
 		&order.UserID,
 		&order.OrderNumber,
@@ -429,24 +510,58 @@ func (r *OrderRepository) GetByStripeSessionID(ctx context.Context, sessionID st
 		&order.Tax,
 		&order.Discount,
 		&order.Total,
+		&order.Currency,
 		&order.PaymentMethod,
 		&order.PaymentStatus,
-		&order.StripeSessionID,
+		&order.PaymentReference,
 		&order.Notes,
 		&order.CreatedAt,
 		&order.UpdatedAt,
 		&order.ConfirmedAt,
 		&order.ShippedAt,
 		&order.DeliveredAt,
+		&order.CancelledAt,
+		&order.CancellationReason,
 	)
 
 	return &order, err
 }
 
+// UpdatePaymentTransactionID saves the gateway transaction that paid for the order
+func (r *OrderRepository) UpdatePaymentTransactionID(ctx context.Context, orderID uuid.UUID, transactionID string) error {
+	query := `UPDATE orders SET payment_transaction_id = $1, updated_at = NOW() WHERE id = $2`
+	_, err := r.db.Conn(ctx).Exec(ctx, query, transactionID, orderID)
+	return err
+}
+
+// GetPaymentTransactionID returns the gateway transaction that paid for the order, if known
+func (r *OrderRepository) GetPaymentTransactionID(ctx context.Context, orderID uuid.UUID) (*string, error) {
+	var transactionID *string
+	query := `SELECT payment_transaction_id FROM orders WHERE id = $1`
+	err := r.db.Conn(ctx).QueryRow(ctx, query, orderID).Scan(&transactionID)
+	return transactionID, err
+}
+
+// GetIDByPaymentTransactionID finds the order paid by a payment method's transaction
+func (r *OrderRepository) GetIDByPaymentTransactionID(ctx context.Context, method, transactionID string) (uuid.UUID, error) {
+	var orderID uuid.UUID
+	query := `SELECT id FROM orders WHERE payment_method = $1 AND payment_transaction_id = $2`
+	err := r.db.Conn(ctx).QueryRow(ctx, query, method, transactionID).Scan(&orderID)
+	return orderID, err
+}
+
+// HasShopItems checks whether an order contains items sold by a shop
+func (r *OrderRepository) HasShopItems(ctx context.Context, orderID, shopID uuid.UUID) (bool, error) {
+	var exists bool
+	query := `SELECT EXISTS(SELECT 1 FROM order_items WHERE order_id = $1 AND shop_id = $2)`
+	err := r.db.Conn(ctx).QueryRow(ctx, query, orderID, shopID).Scan(&exists)
+	return exists, err
+}
+
 // VerifyOrderOwnership checks if order belongs to user
 func (r *OrderRepository) VerifyOrderOwnership(ctx context.Context, orderID, userID uuid.UUID) (bool, error) {
 	var exists bool
 	query := `SELECT EXISTS(SELECT 1 FROM orders WHERE id = $1 AND user_id = $2)`
-	err := r.db.Pool.QueryRow(ctx, query, orderID, userID).Scan(&exists)
+	err := r.db.Conn(ctx).QueryRow(ctx, query, orderID, userID).Scan(&exists)
 	return exists, err
 }
@@ -0,0 +1,232 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/imbivek08/hamropasal/internal/database"
+	"github.com/imbivek08/hamropasal/internal/model"
+	"github.com/jackc/pgx/v5"
+)
+
+type ProductImageRepository struct {
+	db *database.Database
+}
+
+func NewProductImageRepository(db *database.Database) *ProductImageRepository {
+	return &ProductImageRepository{db: db}
+}
+
+// Create adds an image to the end of a product's gallery. If the image is
+// primary it replaces the current primary image and products.image_url.
+func (r *ProductImageRepository) Create(ctx context.Context, image *model.ProductImage) error {
+	return r.db.WithTx(ctx, func(ctx context.Context) error {
+		query := `
+			SELECT COALESCE(MAX(display_order) + 1, 0)
+			FROM product_images
+			WHERE product_id = $1
+		`
+		if err := r.db.Conn(ctx).QueryRow(ctx, query, image.ProductID).Scan(&image.DisplayOrder); err != nil {
+			return err
+		}
+
+		// The first image of a gallery is always the primary one
+		if image.DisplayOrder == 0 {
+			image.IsPrimary = true
+		}
+
+		if image.IsPrimary {
+			if _, err := r.db.Conn(ctx).Exec(ctx, `UPDATE product_images SET is_primary = false WHERE product_id = $1`, image.ProductID); err != nil {
+				return err
+			}
+		}
+
+		query = `
+			INSERT INTO product_images (id, product_id, image_url, alt_text, display_order, is_primary, created_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7)
+		`
+		_, err := r.db.Conn(ctx).Exec(ctx, query,
+			image.ID,
+			image.ProductID,
+			image.ImageURL,
+			image.AltText,
+			image.DisplayOrder,
+			image.IsPrimary,
+			image.CreatedAt,
+		)
+		if err != nil {
+			return err
+		}
+
+		if image.IsPrimary {
+			if err := r.syncPrimaryImageURL(ctx, image.ProductID); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// GetByID retrieves a product image by ID
+func (r *ProductImageRepository) GetByID(ctx context.Context, id uuid.UUID) (*model.ProductImage, error) {
+	var image model.ProductImage
+	query := `
+		SELECT id, product_id, image_url, alt_text, display_order, is_primary, created_at
+		FROM product_images
+		WHERE id = $1
+	`
+	err := r.db.Conn(ctx).QueryRow(ctx, query, id).Scan(
+		&image.ID,
+		&image.ProductID,
+		&image.ImageURL,
+		&image.AltText,
+		&image.DisplayOrder,
+		&image.IsPrimary,
+		&image.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &image, nil
+}
+
+// GetByProductID retrieves the ordered gallery for a product
+func (r *ProductImageRepository) GetByProductID(ctx context.Context, productID uuid.UUID) ([]model.ProductImage, error) {
+	galleries, err := r.GetByProductIDs(ctx, []uuid.UUID{productID})
+	if err != nil {
+		return nil, err
+	}
+	return galleries[productID], nil
+}
+
+// GetByProductIDs retrieves the ordered galleries for several products at once
+func (r *ProductImageRepository) GetByProductIDs(ctx context.Context, productIDs []uuid.UUID) (map[uuid.UUID][]model.ProductImage, error) {
+	galleries := make(map[uuid.UUID][]model.ProductImage)
+	if len(productIDs) == 0 {
+		return galleries, nil
+	}
+
+	query := `
+		SELECT id, product_id, image_url, alt_text, display_order, is_primary, created_at
+		FROM product_images
+		WHERE product_id = ANY($1)
+		ORDER BY product_id, display_order ASC, created_at ASC
+	`
+	rows, err := r.db.Conn(ctx).Query(ctx, query, productIDs)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var image model.ProductImage
+		err := rows.Scan(
+			&image.ID,
+			&image.ProductID,
+			&image.ImageURL,
+			&image.AltText,
+			&image.DisplayOrder,
+			&image.IsPrimary,
+			&image.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		galleries[image.ProductID] = append(galleries[image.ProductID], image)
+	}
+
+	return galleries, rows.Err()
+}
+
+// SetPrimary marks an image as the primary one and mirrors it into products.image_url
+func (r *ProductImageRepository) SetPrimary(ctx context.Context, productID, imageID uuid.UUID) error {
+	return r.db.WithTx(ctx, func(ctx context.Context) error {
+		// Unset first so the one-primary-per-product index is never violated
+		if _, err := r.db.Conn(ctx).Exec(ctx, `UPDATE product_images SET is_primary = false WHERE product_id = $1`, productID); err != nil {
+			return err
+		}
+
+		query := `UPDATE product_images SET is_primary = true WHERE id = $1 AND product_id = $2`
+		result, err := r.db.Conn(ctx).Exec(ctx, query, imageID, productID)
+		if err != nil {
+			return err
+		}
+		if result.RowsAffected() == 0 {
+			return fmt.Errorf("image not found")
+		}
+
+		return r.syncPrimaryImageURL(ctx, productID)
+	})
+}
+
+// Reorder sets display_order following the position of each ID in imageIDs
+func (r *ProductImageRepository) Reorder(ctx context.Context, productID uuid.UUID, imageIDs []uuid.UUID) error {
+	return r.db.WithTx(ctx, func(ctx context.Context) error {
+		query := `UPDATE product_images SET display_order = $1 WHERE id = $2 AND product_id = $3`
+		for i, imageID := range imageIDs {
+			result, err := r.db.Conn(ctx).Exec(ctx, query, i, imageID, productID)
+			if err != nil {
+				return err
+			}
+			if result.RowsAffected() == 0 {
+				return fmt.Errorf("image not found")
+			}
+		}
+
+		return nil
+	})
+}
+
+// Delete removes an image. If it was the primary image, the next image in the
+// gallery is promoted and products.image_url is updated accordingly.
+func (r *ProductImageRepository) Delete(ctx context.Context, productID, imageID uuid.UUID) error {
+	return r.db.WithTx(ctx, func(ctx context.Context) error {
+		var wasPrimary bool
+		query := `DELETE FROM product_images WHERE id = $1 AND product_id = $2 RETURNING is_primary`
+		if err := r.db.Conn(ctx).QueryRow(ctx, query, imageID, productID).Scan(&wasPrimary); err != nil {
+			if err == pgx.ErrNoRows {
+				return fmt.Errorf("image not found")
+			}
+			return err
+		}
+
+		if wasPrimary {
+			query = `
+				UPDATE product_images SET is_primary = true
+				WHERE id = (
+					SELECT id FROM product_images
+					WHERE product_id = $1
+					ORDER BY display_order ASC, created_at ASC
+					LIMIT 1
+				)
+			`
+			if _, err := r.db.Conn(ctx).Exec(ctx, query, productID); err != nil {
+				return err
+			}
+
+			if err := r.syncPrimaryImageURL(ctx, productID); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// syncPrimaryImageURL copies the primary gallery image into products.image_url,
+// clearing it when the gallery is empty
+func (r *ProductImageRepository) syncPrimaryImageURL(ctx context.Context, productID uuid.UUID) error {
+	query := `
+		UPDATE products
+		SET image_url = (
+			SELECT image_url FROM product_images
+			WHERE product_id = $1 AND is_primary = true
+			LIMIT 1
+		), updated_at = NOW()
+		WHERE id = $1
+	`
+	_, err := r.db.Conn(ctx).Exec(ctx, query, productID)
+	return err
+}
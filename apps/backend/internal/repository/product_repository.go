@@ -3,10 +3,13 @@ package repository
 import (
 	"context"
 	"fmt"
+	"strings"
+	"unicode"
 
 	"github.com/google/uuid"
 	"github.com/imbivek08/hamropasal/internal/database"
 	"github.com/imbivek08/hamropasal/internal/model"
+	"github.com/jackc/pgx/v5"
 )
 
 type ProductRepository struct {
@@ -17,44 +20,72 @@ func NewProductRepository(db *database.Database) *ProductRepository {
 	return &ProductRepository{db: db}
 }
 
+// productCurrency is the currency of a product's price: its shop's
+const productCurrency = `(SELECT currency FROM shops WHERE shops.id = products.shop_id)`
+
+// productBasePrice is a product's price in the default currency, so prices of
+// shops with different currencies can be filtered and sorted together
+const productBasePrice = `(price / COALESCE((SELECT rate FROM exchange_rates WHERE exchange_rates.currency = ` + productCurrency + `), 1))`
+
+// productAverageRating is a product's average approved review rating, NULL
+// when it has none
+const productAverageRating = `(SELECT AVG(rating) FROM reviews WHERE reviews.product_id = products.id AND reviews.is_approved = true)`
+
+// productInStock holds for products, or any of their variants, with stock left
+const productInStock = `(stock_quantity > 0 OR EXISTS (
+	SELECT 1 FROM product_variants v WHERE v.product_id = products.id AND v.is_active = true AND v.stock_quantity > 0
+))`
+
+// productShopVerified holds for products of verified shops
+const productShopVerified = `(SELECT is_verified FROM shops WHERE shops.id = products.shop_id)`
+
 // Create creates a new product
 func (r *ProductRepository) Create(ctx context.Context, product *model.Product) error {
 	query := `
-		INSERT INTO products (id, shop_id, category_id, name, description, price, stock_quantity, image_url, is_active, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+		INSERT INTO products (id, shop_id, category_id, name, slug, sku, description, price, stock_quantity, weight, weight_unit, image_url, is_active, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15)
+		RETURNING ` + productCurrency + `
 	`
-	_, err := r.db.Pool.Exec(ctx, query,
+	return r.db.Conn(ctx).QueryRow(ctx, query,
 		product.ID,
 		product.ShopID,
 		product.CategoryID,
 		product.Name,
+		product.Slug,
+		product.SKU,
 		product.Description,
 		product.Price,
 		product.StockQuantity,
+		product.Weight,
+		product.WeightUnit,
 		product.ImageURL,
 		product.IsActive,
 		product.CreatedAt,
 		product.UpdatedAt,
-	)
-	return err
+	).Scan(&product.Price.Currency)
 }
 
 // GetByID retrieves a product by ID
 func (r *ProductRepository) GetByID(ctx context.Context, id uuid.UUID) (*model.Product, error) {
 	var product model.Product
 	query := `
-		SELECT id, shop_id, category_id, name, description, price, stock_quantity, image_url, is_active, created_at, updated_at
+		SELECT id, shop_id, category_id, name, slug, sku, description, price, ` + productCurrency + `, stock_quantity, weight, COALESCE(weight_unit, 'kg'), image_url, is_active, created_at, updated_at
 		FROM products
 		WHERE id = $1
 	`
-	err := r.db.Pool.QueryRow(ctx, query, id).Scan(
+	err := r.db.Conn(ctx).QueryRow(ctx, query, id).Scan(
 		&product.ID,
 		&product.ShopID,
 		&product.CategoryID,
 		&product.Name,
+		&product.Slug,
+		&product.SKU,
 		&product.Description,
 		&product.Price,
+		&product.Price.Currency,
 		&product.StockQuantity,
+		&product.Weight,
+		&product.WeightUnit,
 		&product.ImageURL,
 		&product.IsActive,
 		&product.CreatedAt,
@@ -77,7 +108,7 @@ func (r *ProductRepository) GetShopByProductID(ctx context.Context, productID uu
 		INNER JOIN products p ON s.id = p.shop_id
 		WHERE p.id = $1
 	`
-	err := r.db.Pool.QueryRow(ctx, query, productID).Scan(
+	err := r.db.Conn(ctx).QueryRow(ctx, query, productID).Scan(
 		&shop.ID,
 		&shop.VendorID,
 		&shop.Name,
@@ -103,58 +134,60 @@ func (r *ProductRepository) GetShopByProductID(ctx context.Context, productID uu
 	return &shop, nil
 }
 
-// GetAll retrieves all products with optional filters
-func (r *ProductRepository) GetAll(ctx context.Context, filters map[string]interface{}) ([]*model.Product, error) {
-	var products []*model.Product
-	query := `
-		SELECT id, shop_id, category_id, name, description, price, stock_quantity, image_url, is_active, created_at, updated_at
-		FROM products
-		WHERE is_active = true
-	`
-	args := []interface{}{}
-	argIndex := 1
-
-	// Search filter (matches name or description)
-	if search, ok := filters["search"].(string); ok && search != "" {
-		query += fmt.Sprintf(" AND (name ILIKE $%d OR description ILIKE $%d)", argIndex, argIndex+1)
-		searchPattern := "%" + search + "%"
-		args = append(args, searchPattern, searchPattern)
-		argIndex += 2
-	}
+// List retrieves a page of active products matching the filters, with the
+// total number of matches
+func (r *ProductRepository) List(ctx context.Context, filters map[string]interface{}, page, pageSize int) ([]*model.Product, int, error) {
+	conditions, args := productFilterConditions(filters, "")
+	whereClause := "WHERE " + strings.Join(conditions, " AND ")
 
-	// Min price filter
-	if minPrice, ok := filters["min_price"].(float64); ok {
-		query += fmt.Sprintf(" AND price >= $%d", argIndex)
-		args = append(args, minPrice)
-		argIndex++
-	}
-
-	// Max price filter
-	if maxPrice, ok := filters["max_price"].(float64); ok {
-		query += fmt.Sprintf(" AND price <= $%d", argIndex)
-		args = append(args, maxPrice)
-		argIndex++
+	// Count total
+	var total int
+	countQuery := "SELECT COUNT(*) FROM products " + whereClause
+	if err := r.db.Conn(ctx).QueryRow(ctx, countQuery, args...).Scan(&total); err != nil {
+		return nil, 0, err
 	}
 
 	// Sort
+	var orderBy string
 	sortBy, _ := filters["sort_by"].(string)
 	switch sortBy {
 	case "price_asc":
-		query += " ORDER BY price ASC"
+		orderBy = productBasePrice + " ASC"
 	case "price_desc":
-		query += " ORDER BY price DESC"
+		orderBy = productBasePrice + " DESC"
 	case "name_asc":
-		query += " ORDER BY name ASC"
+		orderBy = "name ASC"
+	case "rating":
+		orderBy = productAverageRating + " DESC NULLS LAST, created_at DESC"
+	case "relevance":
+		orderBy = "created_at DESC"
+		if search, ok := filters["search"].(string); ok {
+			if tsQuery := prefixTSQuery(search); tsQuery != "" {
+				args = append(args, tsQuery)
+				orderBy = fmt.Sprintf("ts_rank(search_vector, to_tsquery('simple', $%d)) DESC, created_at DESC", len(args))
+			}
+		}
 	default:
-		query += " ORDER BY created_at DESC"
+		orderBy = "created_at DESC"
 	}
 
-	rows, err := r.db.Pool.Query(ctx, query, args...)
+	// Get products
+	args = append(args, pageSize, (page-1)*pageSize)
+	query := fmt.Sprintf(`
+		SELECT id, shop_id, category_id, name, slug, sku, description, price, `+productCurrency+`, stock_quantity, weight, COALESCE(weight_unit, 'kg'), image_url, is_active, created_at, updated_at
+		FROM products
+		%s
+		ORDER BY %s, id
+		LIMIT $%d OFFSET $%d
+	`, whereClause, orderBy, len(args)-1, len(args))
+
+	rows, err := r.db.Conn(ctx).Query(ctx, query, args...)
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
 	defer rows.Close()
 
+	products := []*model.Product{}
 	for rows.Next() {
 		var product model.Product
 		err := rows.Scan(
@@ -162,33 +195,285 @@ func (r *ProductRepository) GetAll(ctx context.Context, filters map[string]inter
 			&product.ShopID,
 			&product.CategoryID,
 			&product.Name,
+			&product.Slug,
+			&product.SKU,
 			&product.Description,
 			&product.Price,
+			&product.Price.Currency,
 			&product.StockQuantity,
+			&product.Weight,
+			&product.WeightUnit,
 			&product.ImageURL,
 			&product.IsActive,
 			&product.CreatedAt,
 			&product.UpdatedAt,
 		)
 		if err != nil {
-			return nil, err
+			return nil, 0, err
 		}
 		products = append(products, &product)
 	}
 
-	return products, rows.Err()
+	return products, total, rows.Err()
+}
+
+// Facets counts the active products matching the filters by category, shop,
+// price range (bounds in the default currency), rating, stock and shop
+// verification. Each facet ignores its own filter, so its counts show what
+// choosing another value would give.
+func (r *ProductRepository) Facets(ctx context.Context, filters map[string]interface{}, priceBounds []model.Money) (*model.ProductFacets, error) {
+	facets := &model.ProductFacets{}
+	var err error
+
+	if facets.Categories, err = r.facetCounts(ctx, filters, "category", `
+		SELECT c.id::text, c.name, COUNT(*)
+		FROM products
+		INNER JOIN categories c ON c.id = products.category_id
+		%s
+		GROUP BY c.id, c.name
+		ORDER BY COUNT(*) DESC, c.name
+	`); err != nil {
+		return nil, err
+	}
+
+	if facets.Shops, err = r.facetCounts(ctx, filters, "shop", `
+		SELECT s.id::text, s.shop_name, COUNT(*)
+		FROM products
+		INNER JOIN shops s ON s.id = products.shop_id
+		%s
+		GROUP BY s.id, s.shop_name
+		ORDER BY COUNT(*) DESC, s.shop_name
+	`); err != nil {
+		return nil, err
+	}
+
+	if facets.PriceRanges, err = r.priceFacets(ctx, filters, priceBounds); err != nil {
+		return nil, err
+	}
+
+	// Ratings, as "n stars & up"
+	conditions, args := productFilterConditions(filters, "min_rating")
+	query := `
+		SELECT
+			COUNT(*) FILTER (WHERE rating >= 4),
+			COUNT(*) FILTER (WHERE rating >= 3),
+			COUNT(*) FILTER (WHERE rating >= 2),
+			COUNT(*) FILTER (WHERE rating >= 1)
+		FROM (SELECT ` + productAverageRating + ` AS rating FROM products WHERE ` + strings.Join(conditions, " AND ") + `) rated
+	`
+	ratingCounts := make([]int, 4)
+	if err := r.db.Conn(ctx).QueryRow(ctx, query, args...).Scan(&ratingCounts[0], &ratingCounts[1], &ratingCounts[2], &ratingCounts[3]); err != nil {
+		return nil, err
+	}
+	for i, count := range ratingCounts {
+		facets.Ratings = append(facets.Ratings, model.RatingFacet{MinRating: 4 - i, Count: count})
+	}
+
+	// In stock
+	conditions, args = productFilterConditions(filters, "in_stock")
+	query = `SELECT COUNT(*) FILTER (WHERE ` + productInStock + `) FROM products WHERE ` + strings.Join(conditions, " AND ")
+	if err := r.db.Conn(ctx).QueryRow(ctx, query, args...).Scan(&facets.InStock); err != nil {
+		return nil, err
+	}
+
+	// From verified shops
+	conditions, args = productFilterConditions(filters, "verified")
+	query = `SELECT COUNT(*) FILTER (WHERE ` + productShopVerified + `) FROM products WHERE ` + strings.Join(conditions, " AND ")
+	if err := r.db.Conn(ctx).QueryRow(ctx, query, args...).Scan(&facets.VerifiedShops); err != nil {
+		return nil, err
+	}
+
+	return facets, nil
+}
+
+// facetCounts runs a facet query returning value, label and count rows.
+// query has a %s placeholder for the WHERE clause.
+func (r *ProductRepository) facetCounts(ctx context.Context, filters map[string]interface{}, facet, query string) ([]model.FacetCount, error) {
+	conditions, args := productFilterConditions(filters, facet)
+	rows, err := r.db.Conn(ctx).Query(ctx, fmt.Sprintf(query, "WHERE "+strings.Join(conditions, " AND ")), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := []model.FacetCount{}
+	for rows.Next() {
+		var count model.FacetCount
+		if err := rows.Scan(&count.Value, &count.Label, &count.Count); err != nil {
+			return nil, err
+		}
+		counts = append(counts, count)
+	}
+
+	return counts, rows.Err()
+}
+
+// priceFacets counts products in the ranges between consecutive bounds, with
+// the last range open-ended
+func (r *ProductRepository) priceFacets(ctx context.Context, filters map[string]interface{}, bounds []model.Money) ([]model.PriceRangeFacet, error) {
+	conditions, args := productFilterConditions(filters, "price")
+
+	columns := make([]string, len(bounds))
+	for i := range bounds {
+		args = append(args, bounds[i])
+		column := fmt.Sprintf("COUNT(*) FILTER (WHERE base_price >= $%d", len(args))
+		if i+1 < len(bounds) {
+			column += fmt.Sprintf(" AND base_price < $%d", len(args)+1)
+		}
+		columns[i] = column + ")"
+	}
+
+	query := `
+		SELECT ` + strings.Join(columns, ", ") + `
+		FROM (SELECT ` + productBasePrice + ` AS base_price FROM products WHERE ` + strings.Join(conditions, " AND ") + `) priced
+	`
+	counts := make([]int, len(bounds))
+	dest := make([]interface{}, len(bounds))
+	for i := range counts {
+		dest[i] = &counts[i]
+	}
+	if err := r.db.Conn(ctx).QueryRow(ctx, query, args...).Scan(dest...); err != nil {
+		return nil, err
+	}
+
+	ranges := make([]model.PriceRangeFacet, len(bounds))
+	for i, bound := range bounds {
+		ranges[i] = model.PriceRangeFacet{Min: bound, Count: counts[i]}
+		if i+1 < len(bounds) {
+			max := bounds[i+1]
+			ranges[i].Max = &max
+		}
+	}
+
+	return ranges, nil
+}
+
+// productFilterConditions builds the WHERE conditions and arguments for a
+// product listing, leaving out the facet named skip
+func productFilterConditions(filters map[string]interface{}, skip string) ([]string, []interface{}) {
+	conditions := []string{"is_active = true"}
+	args := []interface{}{}
+
+	// Search filter (full-text, every word matched as a prefix)
+	if search, ok := filters["search"].(string); ok {
+		if tsQuery := prefixTSQuery(search); tsQuery != "" {
+			args = append(args, tsQuery)
+			conditions = append(conditions, fmt.Sprintf("search_vector @@ to_tsquery('simple', $%d)", len(args)))
+		}
+	}
+
+	// Category filter (matches the category and all of its active descendants, by ID or slug)
+	if category, ok := filters["category"].(string); ok && category != "" && skip != "category" {
+		args = append(args, category)
+		conditions = append(conditions, fmt.Sprintf(`category_id IN (
+			WITH RECURSIVE category_tree AS (
+				SELECT id FROM categories WHERE (id::text = $%d OR slug = $%d) AND is_active = true
+				UNION
+				SELECT c.id FROM categories c
+				INNER JOIN category_tree ct ON c.parent_id = ct.id
+				WHERE c.is_active = true
+			)
+			SELECT id FROM category_tree
+		)`, len(args), len(args)))
+	}
+
+	// Shop filter
+	if shopID, ok := filters["shop_id"].(uuid.UUID); ok && skip != "shop" {
+		args = append(args, shopID)
+		conditions = append(conditions, fmt.Sprintf("shop_id = $%d", len(args)))
+	}
+
+	if skip != "price" {
+		// Min price filter, in the default currency
+		if minPrice, ok := filters["min_price"].(model.Money); ok {
+			args = append(args, minPrice)
+			conditions = append(conditions, fmt.Sprintf(productBasePrice+" >= $%d", len(args)))
+		}
+
+		// Max price filter, in the default currency
+		if maxPrice, ok := filters["max_price"].(model.Money); ok {
+			args = append(args, maxPrice)
+			conditions = append(conditions, fmt.Sprintf(productBasePrice+" <= $%d", len(args)))
+		}
+	}
+
+	// Minimum average rating filter
+	if minRating, ok := filters["min_rating"].(int); ok && skip != "min_rating" {
+		args = append(args, minRating)
+		conditions = append(conditions, fmt.Sprintf(productAverageRating+" >= $%d", len(args)))
+	}
+
+	// In-stock filter
+	if inStock, ok := filters["in_stock"].(bool); ok && inStock && skip != "in_stock" {
+		conditions = append(conditions, productInStock)
+	}
+
+	// Verified shop filter
+	if verified, ok := filters["verified"].(bool); ok && verified && skip != "verified" {
+		conditions = append(conditions, productShopVerified)
+	}
+
+	return conditions, args
+}
+
+// Suggest retrieves the active products best matching a partly typed search,
+// for autocomplete
+func (r *ProductRepository) Suggest(ctx context.Context, search string, limit int) ([]model.ProductSuggestion, error) {
+	suggestions := []model.ProductSuggestion{}
+	tsQuery := prefixTSQuery(search)
+	if tsQuery == "" {
+		return suggestions, nil
+	}
+
+	query := `
+		SELECT id, name, slug, image_url
+		FROM products
+		WHERE is_active = true AND search_vector @@ to_tsquery('simple', $1)
+		ORDER BY ts_rank(search_vector, to_tsquery('simple', $1)) DESC, name ASC
+		LIMIT $2
+	`
+	rows, err := r.db.Conn(ctx).Query(ctx, query, tsQuery, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var suggestion model.ProductSuggestion
+		if err := rows.Scan(&suggestion.ID, &suggestion.Name, &suggestion.Slug, &suggestion.ImageURL); err != nil {
+			return nil, err
+		}
+		suggestions = append(suggestions, suggestion)
+	}
+
+	return suggestions, rows.Err()
+}
+
+// prefixTSQuery turns free text into a tsquery matching documents containing
+// every word, each as a prefix ("red sh" becomes "red:* & sh:*"). Punctuation
+// is dropped so user input can't break the query syntax.
+func prefixTSQuery(search string) string {
+	words := strings.FieldsFunc(strings.ToLower(search), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r) && !unicode.IsMark(r)
+	})
+
+	terms := make([]string, len(words))
+	for i, word := range words {
+		terms[i] = word + ":*"
+	}
+	return strings.Join(terms, " & ")
 }
 
 // GetByShopID retrieves all products for a specific shop
 func (r *ProductRepository) GetByShopID(ctx context.Context, shopID uuid.UUID) ([]*model.Product, error) {
 	var products []*model.Product
 	query := `
-		SELECT id, shop_id, name, description, price, stock_quantity, category_id, image_url, is_active, created_at, updated_at
+		SELECT id, shop_id, category_id, name, slug, sku, description, price, ` + productCurrency + `, stock_quantity, weight, COALESCE(weight_unit, 'kg'), image_url, is_active, created_at, updated_at
 		FROM products
 		WHERE shop_id = $1
 		ORDER BY created_at DESC
 	`
-	rows, err := r.db.Pool.Query(ctx, query, shopID)
+	rows, err := r.db.Conn(ctx).Query(ctx, query, shopID)
 	if err != nil {
 		return nil, err
 	}
@@ -201,9 +486,14 @@ func (r *ProductRepository) GetByShopID(ctx context.Context, shopID uuid.UUID) (
 			&product.ShopID,
 			&product.CategoryID,
 			&product.Name,
+			&product.Slug,
+			&product.SKU,
 			&product.Description,
 			&product.Price,
+			&product.Price.Currency,
 			&product.StockQuantity,
+			&product.Weight,
+			&product.WeightUnit,
 			&product.ImageURL,
 			&product.IsActive,
 			&product.CreatedAt,
@@ -222,38 +512,109 @@ func (r *ProductRepository) GetByShopID(ctx context.Context, shopID uuid.UUID) (
 func (r *ProductRepository) Update(ctx context.Context, product *model.Product) error {
 	query := `
 		UPDATE products
-		SET name = $1, description = $2, price = $3, stock_quantity = $4, category_id = $5, image_url = $6, is_active = $7, updated_at = $8
-		WHERE id = $9
+		SET name = $1, description = $2, price = $3, stock_quantity = $4, category_id = $5, weight = $6, weight_unit = $7,
+		    image_url = $8, is_active = $9, updated_at = $10, slug = $11, sku = $12
+		WHERE id = $13
 	`
-	_, err := r.db.Pool.Exec(ctx, query,
+	_, err := r.db.Conn(ctx).Exec(ctx, query,
 		product.Name,
 		product.Description,
 		product.Price,
 		product.StockQuantity,
 		product.CategoryID,
+		product.Weight,
+		product.WeightUnit,
 		product.ImageURL,
 		product.IsActive,
 		product.UpdatedAt,
+		product.Slug,
+		product.SKU,
 		product.ID,
 	)
 	return err
 }
 
+// GetBySlug retrieves a product by its current slug, or by a slug it used to
+// have
+func (r *ProductRepository) GetBySlug(ctx context.Context, slug string) (*model.Product, error) {
+	query := `
+		SELECT COALESCE(
+			(SELECT id FROM products WHERE slug = $1),
+			(SELECT product_id FROM product_slug_redirects WHERE old_slug = $1)
+		)
+	`
+	var id *uuid.UUID
+	if err := r.db.Conn(ctx).QueryRow(ctx, query, slug).Scan(&id); err != nil {
+		return nil, err
+	}
+	if id == nil {
+		return nil, pgx.ErrNoRows
+	}
+	return r.GetByID(ctx, *id)
+}
+
+// SlugExists checks whether a product other than excludeID has the slug
+func (r *ProductRepository) SlugExists(ctx context.Context, slug string, excludeID *uuid.UUID) (bool, error) {
+	query := `SELECT EXISTS(SELECT 1 FROM products WHERE slug = $1 AND ($2::uuid IS NULL OR id != $2))`
+	var exists bool
+	err := r.db.Conn(ctx).QueryRow(ctx, query, slug, excludeID).Scan(&exists)
+	return exists, err
+}
+
+// SKUExists checks whether a product or variant other than excludeID has the
+// SKU. Products and variants share one SKU namespace.
+func (r *ProductRepository) SKUExists(ctx context.Context, sku string, excludeID *uuid.UUID) (bool, error) {
+	query := `
+		SELECT EXISTS(SELECT 1 FROM products WHERE sku = $1 AND ($2::uuid IS NULL OR id != $2))
+		    OR EXISTS(SELECT 1 FROM product_variants WHERE sku = $1 AND ($2::uuid IS NULL OR id != $2))
+	`
+	var exists bool
+	err := r.db.Conn(ctx).QueryRow(ctx, query, sku, excludeID).Scan(&exists)
+	return exists, err
+}
+
+// AddSlugRedirect makes a product's old slug lead to it. A redirect from the
+// product's new slug is dropped, since that slug is now current.
+func (r *ProductRepository) AddSlugRedirect(ctx context.Context, productID uuid.UUID, oldSlug, newSlug string) error {
+	if _, err := r.db.Conn(ctx).Exec(ctx, `DELETE FROM product_slug_redirects WHERE old_slug = $1`, newSlug); err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO product_slug_redirects (old_slug, product_id, created_at)
+		VALUES ($1, $2, NOW())
+		ON CONFLICT (old_slug) DO UPDATE SET product_id = EXCLUDED.product_id, created_at = NOW()
+	`
+	_, err := r.db.Conn(ctx).Exec(ctx, query, oldSlug, productID)
+	return err
+}
+
 // Delete deletes a product
 func (r *ProductRepository) Delete(ctx context.Context, id uuid.UUID) error {
 	query := `DELETE FROM products WHERE id = $1`
-	_, err := r.db.Pool.Exec(ctx, query, id)
+	_, err := r.db.Conn(ctx).Exec(ctx, query, id)
 	return err
 }
 
-// ReduceStock reduces product stock quantity
-func (r *ProductRepository) ReduceStock(ctx context.Context, productID uuid.UUID, quantity int) error {
+// ReduceStock reduces the stock of a product, or of one of its variants when
+// variantID is set
+func (r *ProductRepository) ReduceStock(ctx context.Context, productID uuid.UUID, variantID *uuid.UUID, quantity int) error {
 	query := `
 		UPDATE products
 		SET stock_quantity = stock_quantity - $1, updated_at = NOW()
 		WHERE id = $2 AND stock_quantity >= $1
 	`
-	result, err := r.db.Pool.Exec(ctx, query, quantity, productID)
+	args := []interface{}{quantity, productID}
+	if variantID != nil {
+		query = `
+			UPDATE product_variants
+			SET stock_quantity = stock_quantity - $1, updated_at = NOW()
+			WHERE product_id = $2 AND id = $3 AND stock_quantity >= $1
+		`
+		args = append(args, *variantID)
+	}
+
+	result, err := r.db.Conn(ctx).Exec(ctx, query, args...)
 	if err != nil {
 		return err
 	}
@@ -265,14 +626,25 @@ func (r *ProductRepository) ReduceStock(ctx context.Context, productID uuid.UUID
 	return nil
 }
 
-// IncreaseStock increases product stock quantity
-func (r *ProductRepository) IncreaseStock(ctx context.Context, productID uuid.UUID, quantity int) error {
+// IncreaseStock increases the stock of a product, or of one of its variants
+// when variantID is set
+func (r *ProductRepository) IncreaseStock(ctx context.Context, productID uuid.UUID, variantID *uuid.UUID, quantity int) error {
 	query := `
 		UPDATE products
 		SET stock_quantity = stock_quantity + $1, updated_at = NOW()
 		WHERE id = $2
 	`
-	result, err := r.db.Pool.Exec(ctx, query, quantity, productID)
+	args := []interface{}{quantity, productID}
+	if variantID != nil {
+		query = `
+			UPDATE product_variants
+			SET stock_quantity = stock_quantity + $1, updated_at = NOW()
+			WHERE product_id = $2 AND id = $3
+		`
+		args = append(args, *variantID)
+	}
+
+	result, err := r.db.Conn(ctx).Exec(ctx, query, args...)
 	if err != nil {
 		return err
 	}
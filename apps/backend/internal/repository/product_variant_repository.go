@@ -0,0 +1,237 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/imbivek08/hamropasal/internal/database"
+	"github.com/imbivek08/hamropasal/internal/model"
+	"github.com/jackc/pgx/v5"
+)
+
+type ProductVariantRepository struct {
+	db *database.Database
+}
+
+func NewProductVariantRepository(db *database.Database) *ProductVariantRepository {
+	return &ProductVariantRepository{db: db}
+}
+
+const productVariantColumns = `id, product_id, sku, options, price, stock_quantity, image_url, is_active, created_at, updated_at`
+
+// CreateOption adds an option type after the product's existing ones
+func (r *ProductVariantRepository) CreateOption(ctx context.Context, option *model.ProductOption) error {
+	query := `
+		INSERT INTO product_options (id, product_id, name, option_values, display_order, created_at, updated_at)
+		VALUES ($1, $2, $3, $4,
+			(SELECT COALESCE(MAX(display_order) + 1, 0) FROM product_options WHERE product_id = $2),
+			$5, $6)
+		RETURNING display_order
+	`
+	return r.db.Conn(ctx).QueryRow(ctx, query,
+		option.ID,
+		option.ProductID,
+		option.Name,
+		option.Values,
+		option.CreatedAt,
+		option.UpdatedAt,
+	).Scan(&option.DisplayOrder)
+}
+
+// ListOptions retrieves a product's option types in display order
+func (r *ProductVariantRepository) ListOptions(ctx context.Context, productID uuid.UUID) ([]model.ProductOption, error) {
+	query := `
+		SELECT id, product_id, name, option_values, display_order, created_at, updated_at
+		FROM product_options
+		WHERE product_id = $1
+		ORDER BY display_order, name
+	`
+
+	rows, err := r.db.Conn(ctx).Query(ctx, query, productID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	options := []model.ProductOption{}
+	for rows.Next() {
+		var option model.ProductOption
+		err := rows.Scan(
+			&option.ID,
+			&option.ProductID,
+			&option.Name,
+			&option.Values,
+			&option.DisplayOrder,
+			&option.CreatedAt,
+			&option.UpdatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		options = append(options, option)
+	}
+
+	return options, rows.Err()
+}
+
+// DeleteOption removes one of a product's option types
+func (r *ProductVariantRepository) DeleteOption(ctx context.Context, productID, optionID uuid.UUID) error {
+	query := `DELETE FROM product_options WHERE id = $1 AND product_id = $2`
+	result, err := r.db.Conn(ctx).Exec(ctx, query, optionID, productID)
+	if err != nil {
+		return err
+	}
+
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("option not found")
+	}
+
+	return nil
+}
+
+// CreateVariant inserts a new variant
+func (r *ProductVariantRepository) CreateVariant(ctx context.Context, variant *model.ProductVariant) error {
+	query := `
+		INSERT INTO product_variants (` + productVariantColumns + `)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+	`
+	_, err := r.db.Conn(ctx).Exec(ctx, query,
+		variant.ID,
+		variant.ProductID,
+		variant.SKU,
+		variant.Options,
+		variant.Price,
+		variant.StockQuantity,
+		variant.ImageURL,
+		variant.IsActive,
+		variant.CreatedAt,
+		variant.UpdatedAt,
+	)
+	return err
+}
+
+// GetVariantByID retrieves a variant by ID
+func (r *ProductVariantRepository) GetVariantByID(ctx context.Context, id uuid.UUID) (*model.ProductVariant, error) {
+	query := `SELECT ` + productVariantColumns + ` FROM product_variants WHERE id = $1`
+	return scanProductVariant(r.db.Conn(ctx).QueryRow(ctx, query, id))
+}
+
+// ListVariants retrieves a product's variants, oldest first
+func (r *ProductVariantRepository) ListVariants(ctx context.Context, productID uuid.UUID) ([]model.ProductVariant, error) {
+	query := `
+		SELECT ` + productVariantColumns + `
+		FROM product_variants
+		WHERE product_id = $1
+		ORDER BY created_at, sku
+	`
+
+	rows, err := r.db.Conn(ctx).Query(ctx, query, productID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	variants := []model.ProductVariant{}
+	for rows.Next() {
+		variant, err := scanProductVariant(rows)
+		if err != nil {
+			return nil, err
+		}
+		variants = append(variants, *variant)
+	}
+
+	return variants, rows.Err()
+}
+
+// HasVariants reports whether a product is sold by variant
+func (r *ProductVariantRepository) HasVariants(ctx context.Context, productID uuid.UUID) (bool, error) {
+	var exists bool
+	query := `SELECT EXISTS(SELECT 1 FROM product_variants WHERE product_id = $1)`
+	err := r.db.Conn(ctx).QueryRow(ctx, query, productID).Scan(&exists)
+	return exists, err
+}
+
+// UpdateVariant saves a variant's editable fields
+func (r *ProductVariantRepository) UpdateVariant(ctx context.Context, variant *model.ProductVariant) error {
+	query := `
+		UPDATE product_variants
+		SET sku = $1, price = $2, stock_quantity = $3, image_url = $4, is_active = $5, updated_at = NOW()
+		WHERE id = $6
+	`
+	result, err := r.db.Conn(ctx).Exec(ctx, query,
+		variant.SKU,
+		variant.Price,
+		variant.StockQuantity,
+		variant.ImageURL,
+		variant.IsActive,
+		variant.ID,
+	)
+	if err != nil {
+		return err
+	}
+
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("variant not found")
+	}
+
+	return nil
+}
+
+// DeleteVariant removes one of a product's variants
+func (r *ProductVariantRepository) DeleteVariant(ctx context.Context, productID, variantID uuid.UUID) error {
+	query := `DELETE FROM product_variants WHERE id = $1 AND product_id = $2`
+	result, err := r.db.Conn(ctx).Exec(ctx, query, variantID, productID)
+	if err != nil {
+		return err
+	}
+
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("variant not found")
+	}
+
+	return nil
+}
+
+// IsVariantOrdered reports whether any order line names a variant
+func (r *ProductVariantRepository) IsVariantOrdered(ctx context.Context, variantID uuid.UUID) (bool, error) {
+	var exists bool
+	query := `SELECT EXISTS(SELECT 1 FROM order_items WHERE variant_id = $1)`
+	err := r.db.Conn(ctx).QueryRow(ctx, query, variantID).Scan(&exists)
+	return exists, err
+}
+
+// DeactivateVariant takes one of a product's variants off sale
+func (r *ProductVariantRepository) DeactivateVariant(ctx context.Context, productID, variantID uuid.UUID) error {
+	query := `UPDATE product_variants SET is_active = FALSE, updated_at = NOW() WHERE id = $1 AND product_id = $2`
+	result, err := r.db.Conn(ctx).Exec(ctx, query, variantID, productID)
+	if err != nil {
+		return err
+	}
+
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("variant not found")
+	}
+
+	return nil
+}
+
+func scanProductVariant(row pgx.Row) (*model.ProductVariant, error) {
+	var variant model.ProductVariant
+	err := row.Scan(
+		&variant.ID,
+		&variant.ProductID,
+		&variant.SKU,
+		&variant.Options,
+		&variant.Price,
+		&variant.StockQuantity,
+		&variant.ImageURL,
+		&variant.IsActive,
+		&variant.CreatedAt,
+		&variant.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &variant, nil
+}
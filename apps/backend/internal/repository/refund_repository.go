@@ -0,0 +1,224 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/imbivek08/hamropasal/internal/database"
+	"github.com/imbivek08/hamropasal/internal/model"
+	"github.com/jackc/pgx/v5"
+)
+
+const refundColumns = `id, order_id, provider_refund_id, amount, reason, status, failure_reason, restock, created_by, created_at, updated_at`
+
+type RefundRepository struct {
+	db *database.Database
+}
+
+func NewRefundRepository(db *database.Database) *RefundRepository {
+	return &RefundRepository{db: db}
+}
+
+// Create inserts a refund together with its items
+func (r *RefundRepository) Create(ctx context.Context, refund *model.Refund) error {
+	return r.db.WithTx(ctx, func(ctx context.Context) error {
+		query := `
+			INSERT INTO refunds (id, order_id, provider_refund_id, amount, reason, status, restock, created_by, created_at, updated_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		`
+		_, err := r.db.Conn(ctx).Exec(ctx, query,
+			refund.ID,
+			refund.OrderID,
+			refund.ProviderRefundID,
+			refund.Amount,
+			refund.Reason,
+			refund.Status,
+			refund.Restock,
+			refund.CreatedBy,
+			refund.CreatedAt,
+			refund.UpdatedAt,
+		)
+		if err != nil {
+			return err
+		}
+
+		query = `
+			INSERT INTO refund_items (id, refund_id, order_item_id, quantity, amount)
+			VALUES ($1, $2, $3, $4, $5)
+		`
+		for _, item := range refund.Items {
+			_, err := r.db.Conn(ctx).Exec(ctx, query, item.ID, item.RefundID, item.OrderItemID, item.Quantity, item.Amount)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// GetByID retrieves a refund with its items
+func (r *RefundRepository) GetByID(ctx context.Context, id uuid.UUID) (*model.Refund, error) {
+	query := `SELECT ` + refundColumns + ` FROM refunds WHERE id = $1`
+	refund, err := scanRefund(r.db.Conn(ctx).QueryRow(ctx, query, id))
+	if err != nil {
+		return nil, err
+	}
+	return refund, r.attachItems(ctx, refund)
+}
+
+// GetByProviderRefundID retrieves a refund with its items by its gateway refund ID
+func (r *RefundRepository) GetByProviderRefundID(ctx context.Context, providerRefundID string) (*model.Refund, error) {
+	query := `SELECT ` + refundColumns + ` FROM refunds WHERE provider_refund_id = $1`
+	refund, err := scanRefund(r.db.Conn(ctx).QueryRow(ctx, query, providerRefundID))
+	if err != nil {
+		return nil, err
+	}
+	return refund, r.attachItems(ctx, refund)
+}
+
+// GetByOrderID retrieves an order's refunds, newest first
+func (r *RefundRepository) GetByOrderID(ctx context.Context, orderID uuid.UUID) ([]*model.Refund, error) {
+	query := `SELECT ` + refundColumns + ` FROM refunds WHERE order_id = $1 ORDER BY created_at DESC`
+	rows, err := r.db.Conn(ctx).Query(ctx, query, orderID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	refunds := []*model.Refund{}
+	for rows.Next() {
+		refund, err := scanRefund(rows)
+		if err != nil {
+			return nil, err
+		}
+		refunds = append(refunds, refund)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for _, refund := range refunds {
+		if err := r.attachItems(ctx, refund); err != nil {
+			return nil, err
+		}
+	}
+
+	return refunds, nil
+}
+
+// GetRefundedQuantities returns, per order item, the quantity covered by
+// refunds that have not failed
+func (r *RefundRepository) GetRefundedQuantities(ctx context.Context, orderID uuid.UUID) (map[uuid.UUID]int, error) {
+	query := `
+		SELECT ri.order_item_id, SUM(ri.quantity)
+		FROM refund_items ri
+		INNER JOIN refunds rf ON ri.refund_id = rf.id
+		WHERE rf.order_id = $1 AND rf.status != 'failed'
+		GROUP BY ri.order_item_id
+	`
+	rows, err := r.db.Conn(ctx).Query(ctx, query, orderID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	quantities := make(map[uuid.UUID]int)
+	for rows.Next() {
+		var orderItemID uuid.UUID
+		var quantity int
+		if err := rows.Scan(&orderItemID, &quantity); err != nil {
+			return nil, err
+		}
+		quantities[orderItemID] = quantity
+	}
+
+	return quantities, rows.Err()
+}
+
+// GetRefundedAmount returns the sum of an order's refunds in the given statuses
+func (r *RefundRepository) GetRefundedAmount(ctx context.Context, orderID uuid.UUID, statuses ...model.RefundStatus) (model.Money, error) {
+	var amount model.Money
+	query := `SELECT COALESCE(SUM(amount), 0) FROM refunds WHERE order_id = $1 AND status = ANY($2)`
+	err := r.db.Conn(ctx).QueryRow(ctx, query, orderID, statuses).Scan(&amount)
+	return amount, err
+}
+
+// SetProviderRefundID links a refund to the refund created at the payment gateway
+func (r *RefundRepository) SetProviderRefundID(ctx context.Context, id uuid.UUID, providerRefundID string) error {
+	query := `UPDATE refunds SET provider_refund_id = $1 WHERE id = $2`
+	result, err := r.db.Conn(ctx).Exec(ctx, query, providerRefundID, id)
+	if err != nil {
+		return err
+	}
+
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("refund not found")
+	}
+
+	return nil
+}
+
+// MarkSucceeded moves a pending refund to succeeded. It reports false when
+// the refund was already settled, so side effects are applied only once.
+func (r *RefundRepository) MarkSucceeded(ctx context.Context, id uuid.UUID) (bool, error) {
+	query := `UPDATE refunds SET status = 'succeeded' WHERE id = $1 AND status = 'pending'`
+	result, err := r.db.Conn(ctx).Exec(ctx, query, id)
+	if err != nil {
+		return false, err
+	}
+	return result.RowsAffected() == 1, nil
+}
+
+// MarkFailed moves a pending refund to failed with the reason
+func (r *RefundRepository) MarkFailed(ctx context.Context, id uuid.UUID, reason string) error {
+	query := `UPDATE refunds SET status = 'failed', failure_reason = $1 WHERE id = $2 AND status = 'pending'`
+	_, err := r.db.Conn(ctx).Exec(ctx, query, reason, id)
+	return err
+}
+
+func (r *RefundRepository) attachItems(ctx context.Context, refund *model.Refund) error {
+	query := `
+		SELECT id, refund_id, order_item_id, quantity, amount
+		FROM refund_items
+		WHERE refund_id = $1
+	`
+	rows, err := r.db.Conn(ctx).Query(ctx, query, refund.ID)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	refund.Items = []model.RefundItem{}
+	for rows.Next() {
+		var item model.RefundItem
+		if err := rows.Scan(&item.ID, &item.RefundID, &item.OrderItemID, &item.Quantity, &item.Amount); err != nil {
+			return err
+		}
+		refund.Items = append(refund.Items, item)
+	}
+
+	return rows.Err()
+}
+
+func scanRefund(row pgx.Row) (*model.Refund, error) {
+	var refund model.Refund
+	err := row.Scan(
+		&refund.ID,
+		&refund.OrderID,
+		&refund.ProviderRefundID,
+		&refund.Amount,
+		&refund.Reason,
+		&refund.Status,
+		&refund.FailureReason,
+		&refund.Restock,
+		&refund.CreatedBy,
+		&refund.CreatedAt,
+		&refund.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &refund, nil
+}
@@ -23,7 +23,7 @@ func (r *ReviewRepository) CreateReview(ctx context.Context, review *model.Revie
 		INSERT INTO reviews (id, product_id, user_id, order_id, rating, title, comment, is_verified_purchase, is_approved, helpful_count, created_at, updated_at)
 		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
 	`
-	_, err := r.db.Pool.Exec(ctx, query,
+	_, err := r.db.Conn(ctx).Exec(ctx, query,
 		review.ID,
 		review.ProductID,
 		review.UserID,
@@ -51,7 +51,7 @@ func (r *ReviewRepository) GetByID(ctx context.Context, id uuid.UUID) (*model.Re
 		LEFT JOIN users u ON r.user_id = u.id
 		WHERE r.id = $1
 	`
-	err := r.db.Pool.QueryRow(ctx, query, id).Scan(
+	err := r.db.Conn(ctx).QueryRow(ctx, query, id).Scan(
 		&review.ID,
 		&review.ProductID,
 		&review.UserID,
@@ -82,7 +82,7 @@ func (r *ReviewRepository) GetByProductID(ctx context.Context, productID uuid.UU
 		ORDER BY r.created_at DESC
 		LIMIT $2 OFFSET $3
 	`
-	rows, err := r.db.Pool.Query(ctx, query, productID, limit, offset)
+	rows, err := r.db.Conn(ctx).Query(ctx, query, productID, limit, offset)
 	if err != nil {
 		return nil, err
 	}
@@ -119,7 +119,7 @@ func (r *ReviewRepository) GetByProductID(ctx context.Context, productID uuid.UU
 func (r *ReviewRepository) CountByProductID(ctx context.Context, productID uuid.UUID) (int, error) {
 	var count int
 	query := `SELECT COUNT(*) FROM reviews WHERE product_id = $1 AND is_approved = true`
-	err := r.db.Pool.QueryRow(ctx, query, productID).Scan(&count)
+	err := r.db.Conn(ctx).QueryRow(ctx, query, productID).Scan(&count)
 	return count, err
 }
 
@@ -140,7 +140,7 @@ func (r *ReviewRepository) GetProductRatingStats(ctx context.Context, productID
 		FROM reviews
 		WHERE product_id = $1 AND is_approved = true
 	`
-	err := r.db.Pool.QueryRow(ctx, query, productID).Scan(
+	err := r.db.Conn(ctx).QueryRow(ctx, query, productID).Scan(
 		&stats.TotalReviews,
 		&stats.AverageRating,
 		&stats.FiveStarCount,
@@ -162,21 +162,21 @@ func (r *ReviewRepository) UpdateReview(ctx context.Context, id uuid.UUID, req *
 		    updated_at = NOW()
 		WHERE id = $1
 	`
-	_, err := r.db.Pool.Exec(ctx, query, id, req.Rating, req.Title, req.Comment)
+	_, err := r.db.Conn(ctx).Exec(ctx, query, id, req.Rating, req.Title, req.Comment)
 	return err
 }
 
 // DeleteReview deletes a review
 func (r *ReviewRepository) DeleteReview(ctx context.Context, id uuid.UUID) error {
 	query := `DELETE FROM reviews WHERE id = $1`
-	_, err := r.db.Pool.Exec(ctx, query, id)
+	_, err := r.db.Conn(ctx).Exec(ctx, query, id)
 	return err
 }
 
 // IncrementHelpfulCount increments the helpful count for a review
 func (r *ReviewRepository) IncrementHelpfulCount(ctx context.Context, id uuid.UUID) error {
 	query := `UPDATE reviews SET helpful_count = helpful_count + 1 WHERE id = $1`
-	_, err := r.db.Pool.Exec(ctx, query, id)
+	_, err := r.db.Conn(ctx).Exec(ctx, query, id)
 	return err
 }
 
@@ -184,6 +184,6 @@ func (r *ReviewRepository) IncrementHelpfulCount(ctx context.Context, id uuid.UU
 func (r *ReviewRepository) HasUserReviewedProduct(ctx context.Context, userID, productID uuid.UUID) (bool, error) {
 	var exists bool
 	query := `SELECT EXISTS(SELECT 1 FROM reviews WHERE user_id = $1 AND product_id = $2)`
-	err := r.db.Pool.QueryRow(ctx, query, userID, productID).Scan(&exists)
+	err := r.db.Conn(ctx).QueryRow(ctx, query, userID, productID).Scan(&exists)
 	return exists, err
 }
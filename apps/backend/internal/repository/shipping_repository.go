@@ -0,0 +1,303 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/imbivek08/hamropasal/internal/database"
+	"github.com/imbivek08/hamropasal/internal/model"
+	"github.com/jackc/pgx/v5"
+)
+
+type ShippingRepository struct {
+	db *database.Database
+}
+
+func NewShippingRepository(db *database.Database) *ShippingRepository {
+	return &ShippingRepository{db: db}
+}
+
+const shippingZoneColumns = `id, name, country, states, cities, is_active, created_at, updated_at`
+
+const shippingMethodColumns = `id, shop_id, zone_id, name, rate_type, base_rate, per_kg_rate, free_above,
+	min_days, max_days, is_active, created_at, updated_at`
+
+// CreateZone inserts a new shipping zone
+func (r *ShippingRepository) CreateZone(ctx context.Context, zone *model.ShippingZone) error {
+	query := `
+		INSERT INTO shipping_zones (` + shippingZoneColumns + `)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+	`
+
+	_, err := r.db.Conn(ctx).Exec(ctx, query,
+		zone.ID,
+		zone.Name,
+		zone.Country,
+		zone.States,
+		zone.Cities,
+		zone.IsActive,
+		zone.CreatedAt,
+		zone.UpdatedAt,
+	)
+	return err
+}
+
+// GetZoneByID retrieves a shipping zone by ID
+func (r *ShippingRepository) GetZoneByID(ctx context.Context, id uuid.UUID) (*model.ShippingZone, error) {
+	query := `SELECT ` + shippingZoneColumns + ` FROM shipping_zones WHERE id = $1`
+	return scanShippingZone(r.db.Conn(ctx).QueryRow(ctx, query, id))
+}
+
+// ListZones retrieves shipping zones by name, optionally only the active ones
+func (r *ShippingRepository) ListZones(ctx context.Context, activeOnly bool) ([]*model.ShippingZone, error) {
+	query := `
+		SELECT ` + shippingZoneColumns + `
+		FROM shipping_zones
+		WHERE NOT $1 OR is_active = true
+		ORDER BY country, name
+	`
+
+	rows, err := r.db.Conn(ctx).Query(ctx, query, activeOnly)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	zones := []*model.ShippingZone{}
+	for rows.Next() {
+		zone, err := scanShippingZone(rows)
+		if err != nil {
+			return nil, err
+		}
+		zones = append(zones, zone)
+	}
+
+	return zones, rows.Err()
+}
+
+// UpdateZone saves a shipping zone's editable fields
+func (r *ShippingRepository) UpdateZone(ctx context.Context, zone *model.ShippingZone) error {
+	query := `
+		UPDATE shipping_zones
+		SET name = $1, country = $2, states = $3, cities = $4, is_active = $5, updated_at = NOW()
+		WHERE id = $6
+	`
+
+	result, err := r.db.Conn(ctx).Exec(ctx, query,
+		zone.Name,
+		zone.Country,
+		zone.States,
+		zone.Cities,
+		zone.IsActive,
+		zone.ID,
+	)
+	if err != nil {
+		return err
+	}
+
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("shipping zone not found")
+	}
+
+	return nil
+}
+
+// DeleteZone deletes a shipping zone together with the shops' methods for it
+func (r *ShippingRepository) DeleteZone(ctx context.Context, id uuid.UUID) error {
+	result, err := r.db.Conn(ctx).Exec(ctx, `DELETE FROM shipping_zones WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("shipping zone not found")
+	}
+
+	return nil
+}
+
+// CreateMethod inserts a new shipping method
+func (r *ShippingRepository) CreateMethod(ctx context.Context, method *model.ShippingMethod) error {
+	query := `
+		INSERT INTO shipping_methods (` + shippingMethodColumns + `)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+	`
+
+	_, err := r.db.Conn(ctx).Exec(ctx, query,
+		method.ID,
+		method.ShopID,
+		method.ZoneID,
+		method.Name,
+		method.RateType,
+		method.BaseRate,
+		method.PerKgRate,
+		method.FreeAbove,
+		method.MinDays,
+		method.MaxDays,
+		method.IsActive,
+		method.CreatedAt,
+		method.UpdatedAt,
+	)
+	return err
+}
+
+// GetMethodByID retrieves a shipping method by ID
+func (r *ShippingRepository) GetMethodByID(ctx context.Context, id uuid.UUID) (*model.ShippingMethod, error) {
+	query := `SELECT ` + shippingMethodColumns + ` FROM shipping_methods WHERE id = $1`
+	return scanShippingMethod(r.db.Conn(ctx).QueryRow(ctx, query, id))
+}
+
+// ListMethods retrieves shipping methods by name. A nil shopID lists every
+// shop's methods.
+func (r *ShippingRepository) ListMethods(ctx context.Context, shopID *uuid.UUID) ([]*model.ShippingMethod, error) {
+	query := `
+		SELECT ` + shippingMethodColumns + `
+		FROM shipping_methods
+		WHERE $1::uuid IS NULL OR shop_id = $1
+		ORDER BY shop_id, name
+	`
+	return r.queryMethods(ctx, query, shopID)
+}
+
+// GetActiveMethodsForShops retrieves the active methods of the given shops.
+// Methods of inactive zones are left out.
+func (r *ShippingRepository) GetActiveMethodsForShops(ctx context.Context, shopIDs []uuid.UUID) ([]*model.ShippingMethod, error) {
+	query := `
+		SELECT m.id, m.shop_id, m.zone_id, m.name, m.rate_type, m.base_rate, m.per_kg_rate, m.free_above,
+		       m.min_days, m.max_days, m.is_active, m.created_at, m.updated_at
+		FROM shipping_methods m
+		INNER JOIN shipping_zones z ON z.id = m.zone_id
+		WHERE m.shop_id = ANY($1) AND m.is_active = true AND z.is_active = true
+		ORDER BY m.shop_id, m.name
+	`
+	return r.queryMethods(ctx, query, shopIDs)
+}
+
+// CountMethodsForShops returns how many shipping methods, active or not, each
+// of the given shops has set up
+func (r *ShippingRepository) CountMethodsForShops(ctx context.Context, shopIDs []uuid.UUID) (map[uuid.UUID]int, error) {
+	query := `SELECT shop_id, COUNT(*) FROM shipping_methods WHERE shop_id = ANY($1) GROUP BY shop_id`
+
+	rows, err := r.db.Conn(ctx).Query(ctx, query, shopIDs)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := make(map[uuid.UUID]int)
+	for rows.Next() {
+		var shopID uuid.UUID
+		var count int
+		if err := rows.Scan(&shopID, &count); err != nil {
+			return nil, err
+		}
+		counts[shopID] = count
+	}
+
+	return counts, rows.Err()
+}
+
+// UpdateMethod saves a shipping method's editable fields
+func (r *ShippingRepository) UpdateMethod(ctx context.Context, method *model.ShippingMethod) error {
+	query := `
+		UPDATE shipping_methods
+		SET name = $1, base_rate = $2, per_kg_rate = $3, free_above = $4, min_days = $5, max_days = $6,
+		    is_active = $7, updated_at = NOW()
+		WHERE id = $8
+	`
+
+	result, err := r.db.Conn(ctx).Exec(ctx, query,
+		method.Name,
+		method.BaseRate,
+		method.PerKgRate,
+		method.FreeAbove,
+		method.MinDays,
+		method.MaxDays,
+		method.IsActive,
+		method.ID,
+	)
+	if err != nil {
+		return err
+	}
+
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("shipping method not found")
+	}
+
+	return nil
+}
+
+// DeleteMethod deletes a shipping method. Orders shipped with it keep its name and cost.
+func (r *ShippingRepository) DeleteMethod(ctx context.Context, id uuid.UUID) error {
+	result, err := r.db.Conn(ctx).Exec(ctx, `DELETE FROM shipping_methods WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("shipping method not found")
+	}
+
+	return nil
+}
+
+func (r *ShippingRepository) queryMethods(ctx context.Context, query string, args ...interface{}) ([]*model.ShippingMethod, error) {
+	rows, err := r.db.Conn(ctx).Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	methods := []*model.ShippingMethod{}
+	for rows.Next() {
+		method, err := scanShippingMethod(rows)
+		if err != nil {
+			return nil, err
+		}
+		methods = append(methods, method)
+	}
+
+	return methods, rows.Err()
+}
+
+func scanShippingZone(row pgx.Row) (*model.ShippingZone, error) {
+	var zone model.ShippingZone
+	err := row.Scan(
+		&zone.ID,
+		&zone.Name,
+		&zone.Country,
+		&zone.States,
+		&zone.Cities,
+		&zone.IsActive,
+		&zone.CreatedAt,
+		&zone.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &zone, nil
+}
+
+func scanShippingMethod(row pgx.Row) (*model.ShippingMethod, error) {
+	var method model.ShippingMethod
+	err := row.Scan(
+		&method.ID,
+		&method.ShopID,
+		&method.ZoneID,
+		&method.Name,
+		&method.RateType,
+		&method.BaseRate,
+		&method.PerKgRate,
+		&method.FreeAbove,
+		&method.MinDays,
+		&method.MaxDays,
+		&method.IsActive,
+		&method.CreatedAt,
+		&method.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &method, nil
+}
@@ -24,8 +24,8 @@ func (r *ShopRepository) Create(ctx context.Context, shop *model.Shop) error {
 	query := `
 		INSERT INTO shops (id, vendor_id, shop_name, slug, description, logo_url, banner_url, 
 		                   address, city, state, country, postal_code, contact_phone, contact_email, 
-		                   is_active, is_verified)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16)
+		                   currency, is_active, is_verified)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17)
 		RETURNING created_at, updated_at
 	`
 
@@ -44,6 +44,7 @@ func (r *ShopRepository) Create(ctx context.Context, shop *model.Shop) error {
 		shop.PostalCode,
 		shop.Phone,
 		shop.Email,
+		shop.Currency,
 		shop.IsActive,
 		shop.IsVerified,
 	).Scan(&shop.CreatedAt, &shop.UpdatedAt)
@@ -56,7 +57,7 @@ func (r *ShopRepository) GetByID(ctx context.Context, id uuid.UUID) (*model.Shop
 	query := `
 		SELECT id, vendor_id, shop_name, slug, description, logo_url, banner_url,
 		       address, city, state, country, postal_code, contact_phone, contact_email,
-		       is_active, is_verified, created_at, updated_at
+		       currency, is_active, is_verified, created_at, updated_at
 		FROM shops
 		WHERE id = $1
 	`
@@ -77,6 +78,7 @@ func (r *ShopRepository) GetByID(ctx context.Context, id uuid.UUID) (*model.Shop
 		&shop.PostalCode,
 		&shop.Phone,
 		&shop.Email,
+		&shop.Currency,
 		&shop.IsActive,
 		&shop.IsVerified,
 		&shop.CreatedAt,
@@ -95,7 +97,7 @@ func (r *ShopRepository) GetBySlug(ctx context.Context, slug string) (*model.Sho
 	query := `
 		SELECT id, vendor_id, shop_name, slug, description, logo_url, banner_url,
 		       address, city, state, country, postal_code, contact_phone, contact_email,
-		       is_active, is_verified, created_at, updated_at
+		       currency, is_active, is_verified, created_at, updated_at
 		FROM shops
 		WHERE slug = $1
 	`
@@ -116,6 +118,7 @@ func (r *ShopRepository) GetBySlug(ctx context.Context, slug string) (*model.Sho
 		&shop.PostalCode,
 		&shop.Phone,
 		&shop.Email,
+		&shop.Currency,
 		&shop.IsActive,
 		&shop.IsVerified,
 		&shop.CreatedAt,
@@ -134,7 +137,7 @@ func (r *ShopRepository) GetByVendorID(ctx context.Context, vendorID uuid.UUID)
 	query := `
 		SELECT id, vendor_id, shop_name, slug, description, logo_url, banner_url,
 		       address, city, state, country, postal_code, contact_phone, contact_email,
-		       is_active, is_verified, created_at, updated_at
+		       currency, is_active, is_verified, created_at, updated_at
 		FROM shops
 		WHERE vendor_id = $1
 	`
@@ -155,6 +158,7 @@ func (r *ShopRepository) GetByVendorID(ctx context.Context, vendorID uuid.UUID)
 		&shop.PostalCode,
 		&shop.Phone,
 		&shop.Email,
+		&shop.Currency,
 		&shop.IsActive,
 		&shop.IsVerified,
 		&shop.CreatedAt,
@@ -207,7 +211,7 @@ func (r *ShopRepository) List(ctx context.Context, page, pageSize int, search st
 	query := fmt.Sprintf(`
 		SELECT id, vendor_id, shop_name, slug, description, logo_url, banner_url,
 		       address, city, state, country, postal_code, contact_phone, contact_email,
-		       is_active, is_verified, created_at, updated_at
+		       currency, is_active, is_verified, created_at, updated_at
 		FROM shops
 		%s
 		ORDER BY created_at DESC
@@ -238,6 +242,7 @@ func (r *ShopRepository) List(ctx context.Context, page, pageSize int, search st
 			&shop.PostalCode,
 			&shop.Phone,
 			&shop.Email,
+			&shop.Currency,
 			&shop.IsActive,
 			&shop.IsVerified,
 			&shop.CreatedAt,
@@ -256,10 +261,10 @@ func (r *ShopRepository) List(ctx context.Context, page, pageSize int, search st
 func (r *ShopRepository) Update(ctx context.Context, shop *model.Shop) error {
 	query := `
 		UPDATE shops
-		SET name = $1, description = $2, logo_url = $3, banner_url = $4,
+		SET shop_name = $1, description = $2, logo_url = $3, banner_url = $4,
 		    address = $5, city = $6, state = $7, country = $8, postal_code = $9,
-		    phone = $10, email = $11, updated_at = NOW()
-		WHERE id = $12
+		    contact_phone = $10, contact_email = $11, currency = $12, updated_at = NOW()
+		WHERE id = $13
 		RETURNING updated_at
 	`
 
@@ -275,6 +280,7 @@ func (r *ShopRepository) Update(ctx context.Context, shop *model.Shop) error {
 		shop.PostalCode,
 		shop.Phone,
 		shop.Email,
+		shop.Currency,
 		shop.ID,
 	).Scan(&shop.UpdatedAt)
 
@@ -0,0 +1,151 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/imbivek08/hamropasal/internal/database"
+	"github.com/imbivek08/hamropasal/internal/model"
+)
+
+type StockReservationRepository struct {
+	db *database.Database
+}
+
+func NewStockReservationRepository(db *database.Database) *StockReservationRepository {
+	return &StockReservationRepository{db: db}
+}
+
+// Create inserts reservations for an order
+func (r *StockReservationRepository) Create(ctx context.Context, reservations []model.StockReservation) error {
+	query := `
+		INSERT INTO stock_reservations (id, order_id, product_id, variant_id, quantity, status, expires_at, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+	`
+
+	for _, reservation := range reservations {
+		_, err := r.db.Conn(ctx).Exec(ctx, query,
+			reservation.ID,
+			reservation.OrderID,
+			reservation.ProductID,
+			reservation.VariantID,
+			reservation.Quantity,
+			reservation.Status,
+			reservation.ExpiresAt,
+			reservation.CreatedAt,
+			reservation.UpdatedAt,
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// SetExpiryForOrder moves the expiry of an order's active reservations
+func (r *StockReservationRepository) SetExpiryForOrder(ctx context.Context, orderID uuid.UUID, expiresAt time.Time) error {
+	query := `
+		UPDATE stock_reservations
+		SET expires_at = $1
+		WHERE order_id = $2 AND status = 'active'
+	`
+	_, err := r.db.Conn(ctx).Exec(ctx, query, expiresAt, orderID)
+	return err
+}
+
+// GetActiveExpiryForOrder returns when the first of an order's active
+// reservations expires, or nil when it holds none
+func (r *StockReservationRepository) GetActiveExpiryForOrder(ctx context.Context, orderID uuid.UUID) (*time.Time, error) {
+	query := `
+		SELECT MIN(expires_at)
+		FROM stock_reservations
+		WHERE order_id = $1 AND status = 'active'
+	`
+	var expiresAt *time.Time
+	err := r.db.Conn(ctx).QueryRow(ctx, query, orderID).Scan(&expiresAt)
+	return expiresAt, err
+}
+
+// CommitForOrder marks an order's active reservations as committed and
+// returns how many were committed
+func (r *StockReservationRepository) CommitForOrder(ctx context.Context, orderID uuid.UUID) (int64, error) {
+	query := `
+		UPDATE stock_reservations
+		SET status = 'committed'
+		WHERE order_id = $1 AND status = 'active'
+	`
+	result, err := r.db.Conn(ctx).Exec(ctx, query, orderID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+// ReleaseForOrder marks an order's active reservations as released and returns
+// them, so the caller can put the stock back. Already settled reservations are
+// left alone, which makes releasing idempotent.
+func (r *StockReservationRepository) ReleaseForOrder(ctx context.Context, orderID uuid.UUID) ([]model.StockReservation, error) {
+	query := `
+		UPDATE stock_reservations
+		SET status = 'released'
+		WHERE order_id = $1 AND status = 'active'
+		RETURNING id, order_id, product_id, variant_id, quantity, status, expires_at, created_at, updated_at
+	`
+	rows, err := r.db.Conn(ctx).Query(ctx, query, orderID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var reservations []model.StockReservation
+	for rows.Next() {
+		var reservation model.StockReservation
+		err := rows.Scan(
+			&reservation.ID,
+			&reservation.OrderID,
+			&reservation.ProductID,
+			&reservation.VariantID,
+			&reservation.Quantity,
+			&reservation.Status,
+			&reservation.ExpiresAt,
+			&reservation.CreatedAt,
+			&reservation.UpdatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		reservations = append(reservations, reservation)
+	}
+
+	return reservations, rows.Err()
+}
+
+// GetExpiredOrderIDs returns orders that still hold reservations past their expiry
+func (r *StockReservationRepository) GetExpiredOrderIDs(ctx context.Context, limit int) ([]uuid.UUID, error) {
+	query := `
+		SELECT order_id
+		FROM stock_reservations
+		WHERE status = 'active' AND expires_at <= NOW()
+		GROUP BY order_id
+		ORDER BY MIN(expires_at) ASC
+		LIMIT $1
+	`
+	rows, err := r.db.Conn(ctx).Query(ctx, query, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var orderIDs []uuid.UUID
+	for rows.Next() {
+		var orderID uuid.UUID
+		if err := rows.Scan(&orderID); err != nil {
+			return nil, err
+		}
+		orderIDs = append(orderIDs, orderID)
+	}
+
+	return orderIDs, rows.Err()
+}
@@ -0,0 +1,50 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/imbivek08/hamropasal/internal/database"
+)
+
+// SvixMessageRepository persists the IDs of received Svix webhooks. It
+// implements svix.ReplayStore.
+type SvixMessageRepository struct {
+	db *database.Database
+}
+
+func NewSvixMessageRepository(db *database.Database) *SvixMessageRepository {
+	return &SvixMessageRepository{db: db}
+}
+
+// Remember records a message ID and reports false if it was already recorded
+func (r *SvixMessageRepository) Remember(ctx context.Context, msgID string) (bool, error) {
+	query := `
+		INSERT INTO svix_messages (message_id, received_at)
+		VALUES ($1, NOW())
+		ON CONFLICT (message_id) DO NOTHING
+	`
+	result, err := r.db.Conn(ctx).Exec(ctx, query, msgID)
+	if err != nil {
+		return false, err
+	}
+	return result.RowsAffected() == 1, nil
+}
+
+// Forget deletes a message ID so the message can be delivered again
+func (r *SvixMessageRepository) Forget(ctx context.Context, msgID string) error {
+	query := `DELETE FROM svix_messages WHERE message_id = $1`
+	_, err := r.db.Conn(ctx).Exec(ctx, query, msgID)
+	return err
+}
+
+// DeleteReceivedBefore prunes message IDs received before a time and returns
+// how many were deleted
+func (r *SvixMessageRepository) DeleteReceivedBefore(ctx context.Context, before time.Time) (int64, error) {
+	query := `DELETE FROM svix_messages WHERE received_at < $1`
+	result, err := r.db.Conn(ctx).Exec(ctx, query, before)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
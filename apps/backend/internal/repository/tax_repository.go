@@ -0,0 +1,130 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/imbivek08/hamropasal/internal/database"
+	"github.com/imbivek08/hamropasal/internal/model"
+	"github.com/jackc/pgx/v5"
+)
+
+type TaxRepository struct {
+	db *database.Database
+}
+
+func NewTaxRepository(db *database.Database) *TaxRepository {
+	return &TaxRepository{db: db}
+}
+
+const taxRateColumns = `id, name, country, state, category_id, rate, inclusive, is_active, created_at, updated_at`
+
+// Create inserts a new tax rate
+func (r *TaxRepository) Create(ctx context.Context, rate *model.TaxRate) error {
+	query := `
+		INSERT INTO tax_rates (` + taxRateColumns + `)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+	`
+
+	_, err := r.db.Conn(ctx).Exec(ctx, query,
+		rate.ID,
+		rate.Name,
+		rate.Country,
+		rate.State,
+		rate.CategoryID,
+		rate.Rate,
+		rate.Inclusive,
+		rate.IsActive,
+		rate.CreatedAt,
+		rate.UpdatedAt,
+	)
+	return err
+}
+
+// GetByID retrieves a tax rate by ID
+func (r *TaxRepository) GetByID(ctx context.Context, id uuid.UUID) (*model.TaxRate, error) {
+	query := `SELECT ` + taxRateColumns + ` FROM tax_rates WHERE id = $1`
+	return scanTaxRate(r.db.Conn(ctx).QueryRow(ctx, query, id))
+}
+
+// List retrieves tax rates by region, optionally only the active ones
+func (r *TaxRepository) List(ctx context.Context, activeOnly bool) ([]*model.TaxRate, error) {
+	query := `
+		SELECT ` + taxRateColumns + `
+		FROM tax_rates
+		WHERE NOT $1 OR is_active = true
+		ORDER BY country, state NULLS FIRST, name
+	`
+
+	rows, err := r.db.Conn(ctx).Query(ctx, query, activeOnly)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	rates := []*model.TaxRate{}
+	for rows.Next() {
+		rate, err := scanTaxRate(rows)
+		if err != nil {
+			return nil, err
+		}
+		rates = append(rates, rate)
+	}
+
+	return rates, rows.Err()
+}
+
+// Update saves a tax rate's editable fields
+func (r *TaxRepository) Update(ctx context.Context, rate *model.TaxRate) error {
+	query := `
+		UPDATE tax_rates
+		SET name = $1, rate = $2, inclusive = $3, is_active = $4, updated_at = NOW()
+		WHERE id = $5
+	`
+
+	result, err := r.db.Conn(ctx).Exec(ctx, query, rate.Name, rate.Rate, rate.Inclusive, rate.IsActive, rate.ID)
+	if err != nil {
+		return err
+	}
+
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("tax rate not found")
+	}
+
+	return nil
+}
+
+// Delete deletes a tax rate. Orders keep the tax they were charged.
+func (r *TaxRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	result, err := r.db.Conn(ctx).Exec(ctx, `DELETE FROM tax_rates WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("tax rate not found")
+	}
+
+	return nil
+}
+
+func scanTaxRate(row pgx.Row) (*model.TaxRate, error) {
+	var rate model.TaxRate
+	err := row.Scan(
+		&rate.ID,
+		&rate.Name,
+		&rate.Country,
+		&rate.State,
+		&rate.CategoryID,
+		&rate.Rate,
+		&rate.Inclusive,
+		&rate.IsActive,
+		&rate.CreatedAt,
+		&rate.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &rate, nil
+}
@@ -47,7 +47,7 @@ func (r *UserRepository) Create(ctx context.Context, req *model.CreateUserReques
 		RETURNING id, clerk_id, email, username, first_name, last_name, phone, avatar_url, is_active, role, created_at, updated_at, last_login_at
 	`
 
-	err := r.db.Pool.QueryRow(ctx, query,
+	err := r.db.Conn(ctx).QueryRow(ctx, query,
 		user.ID, user.ClerkID, user.Email, user.Username, user.FirstName,
 		user.LastName, user.Phone, user.AvatarURL, user.IsActive, user.Role,
 		user.CreatedAt, user.UpdatedAt,
@@ -74,7 +74,7 @@ func (r *UserRepository) GetByClerkID(ctx context.Context, clerkID string) (*mod
 		WHERE clerk_id = $1
 	`
 
-	err := r.db.Pool.QueryRow(ctx, query, clerkID).Scan(
+	err := r.db.Conn(ctx).QueryRow(ctx, query, clerkID).Scan(
 		&user.ID, &user.ClerkID, &user.Email, &user.Username, &user.FirstName,
 		&user.LastName, &user.Phone, &user.AvatarURL, &user.IsActive, &user.Role,
 		&user.CreatedAt, &user.UpdatedAt, &user.LastLoginAt,
@@ -100,7 +100,7 @@ func (r *UserRepository) GetByID(ctx context.Context, id uuid.UUID) (*model.User
 		WHERE id = $1
 	`
 
-	err := r.db.Pool.QueryRow(ctx, query, id).Scan(
+	err := r.db.Conn(ctx).QueryRow(ctx, query, id).Scan(
 		&user.ID, &user.ClerkID, &user.Email, &user.Username, &user.FirstName,
 		&user.LastName, &user.Phone, &user.AvatarURL, &user.IsActive, &user.Role,
 		&user.CreatedAt, &user.UpdatedAt, &user.LastLoginAt,
@@ -132,7 +132,7 @@ func (r *UserRepository) Update(ctx context.Context, clerkID string, req *model.
 	`
 
 	user := &model.User{}
-	err := r.db.Pool.QueryRow(ctx, query,
+	err := r.db.Conn(ctx).QueryRow(ctx, query,
 		clerkID, req.Username, req.FirstName, req.LastName, req.Phone, req.AvatarURL, req.Role, time.Now(),
 	).Scan(
 		&user.ID, &user.ClerkID, &user.Email, &user.Username, &user.FirstName,
@@ -158,7 +158,7 @@ func (r *UserRepository) UpdateLastLogin(ctx context.Context, clerkID string) er
 		WHERE clerk_id = $1
 	`
 
-	_, err := r.db.Pool.Exec(ctx, query, clerkID, time.Now())
+	_, err := r.db.Conn(ctx).Exec(ctx, query, clerkID, time.Now())
 	if err != nil {
 		return fmt.Errorf("failed to update last login: %w", err)
 	}
@@ -174,7 +174,7 @@ func (r *UserRepository) Delete(ctx context.Context, clerkID string) error {
 		WHERE clerk_id = $1
 	`
 
-	result, err := r.db.Pool.Exec(ctx, query, clerkID, time.Now())
+	result, err := r.db.Conn(ctx).Exec(ctx, query, clerkID, time.Now())
 	if err != nil {
 		return fmt.Errorf("failed to delete user: %w", err)
 	}
@@ -196,7 +196,7 @@ func (r *UserRepository) GetByEmail(ctx context.Context, email string) (*model.U
 		WHERE email = $1
 	`
 
-	err := r.db.Pool.QueryRow(ctx, query, email).Scan(
+	err := r.db.Conn(ctx).QueryRow(ctx, query, email).Scan(
 		&user.ID, &user.ClerkID, &user.Email, &user.Username, &user.FirstName,
 		&user.LastName, &user.Phone, &user.AvatarURL, &user.IsActive, &user.Role,
 		&user.CreatedAt, &user.UpdatedAt, &user.LastLoginAt,
@@ -223,7 +223,7 @@ func (r *UserRepository) GetShopIDByVendorID(ctx context.Context, vendorID uuid.
 		LIMIT 1
 	`
 
-	err := r.db.Pool.QueryRow(ctx, query, vendorID).Scan(&shopID)
+	err := r.db.Conn(ctx).QueryRow(ctx, query, vendorID).Scan(&shopID)
 	if err != nil {
 		if err == pgx.ErrNoRows {
 			return uuid.Nil, fmt.Errorf("no shop found for vendor")
@@ -0,0 +1,170 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/imbivek08/hamropasal/internal/database"
+	"github.com/imbivek08/hamropasal/internal/model"
+	"github.com/jackc/pgx/v5"
+)
+
+const webhookEventColumns = `id, provider, event_id, event_type, payload, status, attempts, last_error, received_at, processed_at, updated_at`
+
+type WebhookEventRepository struct {
+	db *database.Database
+}
+
+func NewWebhookEventRepository(db *database.Database) *WebhookEventRepository {
+	return &WebhookEventRepository{db: db}
+}
+
+// Record stores a newly received event in the processing state. It reports
+// false, without changing anything, when the event was received before.
+func (r *WebhookEventRepository) Record(ctx context.Context, event *model.WebhookEvent) (bool, error) {
+	query := `
+		INSERT INTO webhook_events (id, provider, event_id, event_type, payload, status, attempts, received_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, 'processing', 1, NOW(), NOW())
+		ON CONFLICT (provider, event_id) DO NOTHING
+		RETURNING status, attempts, received_at, updated_at
+	`
+	err := r.db.Conn(ctx).QueryRow(ctx, query,
+		event.ID,
+		event.Provider,
+		event.EventID,
+		event.EventType,
+		event.Payload,
+	).Scan(&event.Status, &event.Attempts, &event.ReceivedAt, &event.UpdatedAt)
+	if err == pgx.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// webhookEventStaleAfter is how long an event may sit in processing before it
+// is assumed abandoned (e.g. the process died) and may be claimed again
+const webhookEventStaleAfter = "10 minutes"
+
+// ClaimForRetry moves a failed (or abandoned) event back to processing for
+// another attempt. It reports false when the event is processed or currently
+// being processed, so concurrent retries and replays cannot both run.
+func (r *WebhookEventRepository) ClaimForRetry(ctx context.Context, provider, eventID string) (*model.WebhookEvent, bool, error) {
+	query := `
+		UPDATE webhook_events
+		SET status = 'processing', attempts = attempts + 1
+		WHERE provider = $1 AND event_id = $2
+		  AND (status = 'failed' OR (status = 'processing' AND updated_at < NOW() - $3::interval))
+		RETURNING ` + webhookEventColumns
+	event, err := scanWebhookEvent(r.db.Conn(ctx).QueryRow(ctx, query, provider, eventID, webhookEventStaleAfter))
+	if err == pgx.ErrNoRows {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return event, true, nil
+}
+
+// GetByID retrieves a webhook event by ID
+func (r *WebhookEventRepository) GetByID(ctx context.Context, id uuid.UUID) (*model.WebhookEvent, error) {
+	query := `SELECT ` + webhookEventColumns + ` FROM webhook_events WHERE id = $1`
+	return scanWebhookEvent(r.db.Conn(ctx).QueryRow(ctx, query, id))
+}
+
+// List retrieves events, newest first, optionally filtered by status
+func (r *WebhookEventRepository) List(ctx context.Context, status string, page, pageSize int) ([]*model.WebhookEvent, int, error) {
+	offset := (page - 1) * pageSize
+
+	var total int
+	countQuery := `SELECT COUNT(*) FROM webhook_events WHERE ($1 = '' OR status = $1)`
+	if err := r.db.Conn(ctx).QueryRow(ctx, countQuery, status).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	query := `
+		SELECT ` + webhookEventColumns + `
+		FROM webhook_events
+		WHERE ($1 = '' OR status = $1)
+		ORDER BY received_at DESC
+		LIMIT $2 OFFSET $3
+	`
+	rows, err := r.db.Conn(ctx).Query(ctx, query, status, pageSize, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	events := []*model.WebhookEvent{}
+	for rows.Next() {
+		event, err := scanWebhookEvent(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		events = append(events, event)
+	}
+
+	return events, total, rows.Err()
+}
+
+// MarkProcessed records a successful processing attempt
+func (r *WebhookEventRepository) MarkProcessed(ctx context.Context, id uuid.UUID) error {
+	query := `
+		UPDATE webhook_events
+		SET status = 'processed', last_error = NULL, processed_at = NOW()
+		WHERE id = $1
+	`
+	result, err := r.db.Conn(ctx).Exec(ctx, query, id)
+	if err != nil {
+		return err
+	}
+
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("webhook event not found")
+	}
+
+	return nil
+}
+
+// MarkFailed records a failed processing attempt and its error
+func (r *WebhookEventRepository) MarkFailed(ctx context.Context, id uuid.UUID, processErr error) error {
+	query := `
+		UPDATE webhook_events
+		SET status = 'failed', last_error = $1
+		WHERE id = $2
+	`
+	result, err := r.db.Conn(ctx).Exec(ctx, query, processErr.Error(), id)
+	if err != nil {
+		return err
+	}
+
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("webhook event not found")
+	}
+
+	return nil
+}
+
+func scanWebhookEvent(row pgx.Row) (*model.WebhookEvent, error) {
+	var event model.WebhookEvent
+	err := row.Scan(
+		&event.ID,
+		&event.Provider,
+		&event.EventID,
+		&event.EventType,
+		&event.Payload,
+		&event.Status,
+		&event.Attempts,
+		&event.LastError,
+		&event.ReceivedAt,
+		&event.ProcessedAt,
+		&event.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &event, nil
+}
@@ -0,0 +1,118 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/imbivek08/hamropasal/internal/database"
+	"github.com/imbivek08/hamropasal/internal/model"
+)
+
+type WishlistRepository struct {
+	db *database.Database
+}
+
+func NewWishlistRepository(db *database.Database) *WishlistRepository {
+	return &WishlistRepository{db: db}
+}
+
+// AddItem adds a product to the user's wishlist. Adding an existing product is a no-op.
+func (r *WishlistRepository) AddItem(ctx context.Context, userID, productID uuid.UUID) (*model.WishlistItem, error) {
+	var item model.WishlistItem
+
+	query := `
+		INSERT INTO wishlists (id, user_id, product_id, created_at)
+		VALUES ($1, $2, $3, NOW())
+		ON CONFLICT (user_id, product_id)
+		DO UPDATE SET user_id = EXCLUDED.user_id
+		RETURNING id, user_id, product_id, created_at
+	`
+
+	err := r.db.Conn(ctx).QueryRow(ctx, query, uuid.New(), userID, productID).Scan(
+		&item.ID,
+		&item.UserID,
+		&item.ProductID,
+		&item.CreatedAt,
+	)
+
+	return &item, err
+}
+
+// RemoveItem removes a product from the user's wishlist
+func (r *WishlistRepository) RemoveItem(ctx context.Context, userID, productID uuid.UUID) error {
+	query := `DELETE FROM wishlists WHERE user_id = $1 AND product_id = $2`
+
+	result, err := r.db.Conn(ctx).Exec(ctx, query, userID, productID)
+	if err != nil {
+		return err
+	}
+
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("wishlist item not found")
+	}
+
+	return nil
+}
+
+// GetByUserID retrieves the user's wishlist with product details
+func (r *WishlistRepository) GetByUserID(ctx context.Context, userID uuid.UUID) ([]model.WishlistItemWithProduct, error) {
+	query := `
+		SELECT 
+			w.id,
+			w.product_id,
+			p.name as product_name,
+			p.price as product_price,
+			s.currency,
+			p.image_url as product_image_url,
+			p.stock_quantity,
+			p.is_active,
+			p.shop_id,
+			s.shop_name,
+			w.created_at
+		FROM wishlists w
+		INNER JOIN products p ON w.product_id = p.id
+		INNER JOIN shops s ON p.shop_id = s.id
+		WHERE w.user_id = $1
+		ORDER BY w.created_at DESC
+	`
+
+	rows, err := r.db.Conn(ctx).Query(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := []model.WishlistItemWithProduct{}
+	for rows.Next() {
+		var item model.WishlistItemWithProduct
+		err := rows.Scan(
+			&item.ID,
+			&item.ProductID,
+			&item.ProductName,
+			&item.ProductPrice,
+			&item.Currency,
+			&item.ProductImageURL,
+			&item.StockQuantity,
+			&item.IsActive,
+			&item.ShopID,
+			&item.ShopName,
+			&item.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		item.ProductPrice.Currency = item.Currency
+		items = append(items, item)
+	}
+
+	return items, rows.Err()
+}
+
+// Exists checks if a product is in the user's wishlist
+func (r *WishlistRepository) Exists(ctx context.Context, userID, productID uuid.UUID) (bool, error) {
+	var exists bool
+	query := `SELECT EXISTS(SELECT 1 FROM wishlists WHERE user_id = $1 AND product_id = $2)`
+	err := r.db.Conn(ctx).QueryRow(ctx, query, userID, productID).Scan(&exists)
+	return exists, err
+}
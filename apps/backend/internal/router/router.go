@@ -2,20 +2,31 @@ package router
 
 import (
 	"bytes"
+	"context"
+	"fmt"
 	"io"
+	"log"
 	"net/http"
+	"time"
 
+	"github.com/google/uuid"
 	"github.com/labstack/echo/v4"
 
 	"github.com/imbivek08/hamropasal/internal/config"
 	"github.com/imbivek08/hamropasal/internal/database"
 	"github.com/imbivek08/hamropasal/internal/handler"
+	"github.com/imbivek08/hamropasal/internal/jobs"
 	"github.com/imbivek08/hamropasal/internal/middleware"
+	"github.com/imbivek08/hamropasal/internal/model"
+	"github.com/imbivek08/hamropasal/internal/payment"
 	"github.com/imbivek08/hamropasal/internal/repository"
 	"github.com/imbivek08/hamropasal/internal/service"
+	"github.com/imbivek08/hamropasal/internal/svix"
 )
 
-func SetupRoutes(e *echo.Echo, db *database.Database, cfg *config.Config) {
+// SetupRoutes wires every dependency and registers the routes, and the
+// background jobs with scheduler
+func SetupRoutes(e *echo.Echo, db *database.Database, cfg *config.Config, scheduler *jobs.Scheduler) {
 	// Health check endpoint
 	e.GET("/health", healthCheck)
 
@@ -25,51 +36,128 @@ func SetupRoutes(e *echo.Echo, db *database.Database, cfg *config.Config) {
 	// Initialize repositories
 	userRepo := repository.NewUserRepository(db)
 	productRepo := repository.NewProductRepository(db)
+	productImageRepo := repository.NewProductImageRepository(db)
+	productVariantRepo := repository.NewProductVariantRepository(db)
 	shopRepo := repository.NewShopRepository(db.Pool)
 	cartRepo := repository.NewCartRepository(db)
 	orderRepo := repository.NewOrderRepository(db)
+	fulfilmentRepo := repository.NewFulfilmentRepository(db)
 	reviewRepo := repository.NewReviewRepository(db)
 	addressRepo := repository.NewAddressRepository(db)
+	categoryRepo := repository.NewCategoryRepository(db)
+	wishlistRepo := repository.NewWishlistRepository(db)
+	reservationRepo := repository.NewStockReservationRepository(db)
+	svixMessageRepo := repository.NewSvixMessageRepository(db)
+	webhookEventRepo := repository.NewWebhookEventRepository(db)
+	refundRepo := repository.NewRefundRepository(db)
+	couponRepo := repository.NewCouponRepository(db)
+	shippingRepo := repository.NewShippingRepository(db)
+	taxRepo := repository.NewTaxRepository(db)
+	exchangeRateRepo := repository.NewExchangeRateRepository(db)
+	ledgerRepo := repository.NewLedgerRepository(db)
+	commissionRepo := repository.NewCommissionRepository(db)
+	jobRepo := repository.NewJobRepository(db)
 
 	// Initialize services
+	policy := service.NewPolicy(orderRepo)
 	userService := service.NewUserService(userRepo)
-	productService := service.NewProductService(productRepo)
-	shopService := service.NewShopService(shopRepo, userRepo)
-	cartService := service.NewCartService(cartRepo, productRepo)
-	orderService := service.NewOrderService(orderRepo, cartRepo, productRepo, addressRepo)
-	reviewService := service.NewReviewService(reviewRepo, orderRepo, productRepo)
+	currencyService := service.NewCurrencyService(db, exchangeRateRepo)
+	productService := service.NewProductService(db, productRepo, productImageRepo, productVariantRepo, currencyService, policy)
+	shopService := service.NewShopService(shopRepo, userRepo, currencyService, policy)
+	couponService := service.NewCouponService(couponRepo, currencyService, policy)
+	shippingService := service.NewShippingService(shippingRepo, addressRepo, currencyService, policy)
+	taxService := service.NewTaxService(taxRepo, categoryRepo)
+	cartService := service.NewCartService(cartRepo, productRepo, productVariantRepo, couponService, shippingService, currencyService, cfg.CartTokenSecret)
+	reservationService := service.NewStockReservationService(db, reservationRepo, orderRepo, fulfilmentRepo, productRepo, couponService)
+	payments := newPaymentRegistry(cfg)
+	queue := jobs.NewQueue(jobRepo)
+	ledgerService := service.NewLedgerService(db, ledgerRepo, commissionRepo, orderRepo, fulfilmentRepo, categoryRepo, shopRepo, time.Duration(cfg.PayoutHoldingDays)*24*time.Hour)
+	orderService := service.NewOrderService(db, payments, orderRepo, fulfilmentRepo, cartRepo, productRepo, addressRepo, reservationService, couponService, shippingService, taxService, currencyService, ledgerService, queue, policy)
+	reviewService := service.NewReviewService(reviewRepo, orderRepo, productRepo, policy)
 	addressService := service.NewAddressService(addressRepo)
-	stripeService := service.NewStripeService(
-		cfg.StripeSecretKey,
+	categoryService := service.NewCategoryService(categoryRepo)
+	wishlistService := service.NewWishlistService(wishlistRepo, productRepo, cartService)
+	refundService := service.NewRefundService(db, payments, refundRepo, orderRepo, fulfilmentRepo, productRepo, ledgerService)
+	paymentService := service.NewPaymentService(
+		payments,
+		cfg.APIURL,
 		cfg.FrontendURL,
+		db,
+		reservationService,
+		refundService,
+		ledgerService,
+		webhookEventRepo,
 		orderRepo,
-		cartRepo,
-		productRepo,
-		addressRepo,
+		fulfilmentRepo,
 	)
 
+	// Return stock held by unpaid orders whose payment session expired
+	scheduler.Add("release expired stock reservations", time.Minute, reservationService.ReleaseExpired)
+
+	// Cancel orders left unpaid past the payment window
+	scheduler.Add("cancel unpaid orders", 5*time.Minute, func(ctx context.Context) error {
+		return orderService.CancelUnpaidOrders(ctx, cfg.UnpaidOrderWindow)
+	})
+
+	// Forget Clerk webhook IDs once a replay would fail the timestamp check
+	// anyway. A message may be stamped up to the tolerance before or after it
+	// arrives, so IDs are kept for twice that, plus a margin for clock drift.
+	scheduler.Add("prune svix messages", time.Hour, func(ctx context.Context) error {
+		pruned, err := svixMessageRepo.DeleteReceivedBefore(ctx, time.Now().Add(-(2*svix.DefaultTolerance + time.Hour)))
+		if err != nil {
+			return fmt.Errorf("failed to prune svix messages: %w", err)
+		}
+		if pruned > 0 {
+			log.Printf("[Webhooks] pruned %d svix message id(s)", pruned)
+		}
+		return nil
+	})
+
+	// Queued job types, run by the job workers
+	jobs.Handle(queue, service.JobClosePayment, orderService.ClosePayment)
+
+	// Each worker runs due queued jobs one at a time
+	for i := 1; i <= cfg.JobWorkers; i++ {
+		scheduler.Add(fmt.Sprintf("job worker %d", i), time.Second, queue.Work)
+	}
+
 	// Initialize handlers
 	userHandler := handler.NewUserHandler(userService)
-	productHandler := handler.NewProductHandler(productService, userService)
-	shopHandler := handler.NewShopHandler(shopService)
+	productHandler := handler.NewProductHandler(productService, userService, wishlistService)
+	shopHandler := handler.NewShopHandler(shopService, userService)
 	roleHandler := handler.NewRoleHandler(userService)
-	webhookHandler := handler.NewWebhookHandler(userService)
+	webhookHandler := handler.NewWebhookHandler(userService, newClerkWebhookVerifier(cfg, svixMessageRepo))
 	cartHandler := handler.NewCartHandler(cartService, userService)
 	orderHandler := handler.NewOrderHandler(orderService, userService, shopService)
 	reviewHandler := handler.NewReviewHandler(reviewService, userService)
 	addressHandler := handler.NewAddressHandler(addressService, userService)
-	stripeHandler := handler.NewStripeHandler(stripeService, orderService, userService, cfg.StripeWebhookSecret)
+	categoryHandler := handler.NewCategoryHandler(categoryService)
+	wishlistHandler := handler.NewWishlistHandler(wishlistService, userService)
+	refundHandler := handler.NewRefundHandler(refundService, userService)
+	couponHandler := handler.NewCouponHandler(couponService, userService)
+	shippingHandler := handler.NewShippingHandler(shippingService, userService)
+	taxHandler := handler.NewTaxHandler(taxService)
+	currencyHandler := handler.NewCurrencyHandler(currencyService)
+	paymentHandler := handler.NewPaymentHandler(paymentService, orderService, userService)
+	ledgerHandler := handler.NewLedgerHandler(ledgerService, userService)
+	jobHandler := handler.NewJobHandler(queue)
 
 	// API v1 group
 	v1 := e.Group("/api/v1")
 
 	// Webhook routes (no auth required)
 	webhooks := v1.Group("/webhooks")
-	webhooks.POST("/clerk", webhookHandler.HandleClerkWebhook)
-	webhooks.POST("/stripe", stripeHandler.HandleStripeWebhook, saveRawBody())
+	webhooks.POST("/clerk", webhookHandler.HandleClerkWebhook, saveRawBody())
+	webhooks.POST("/stripe", paymentHandler.HandleStripeWebhook, saveRawBody())
+
+	// Payment routes (no auth required: gateways send customers back here)
+	v1.GET("/payment-methods", paymentHandler.ListPaymentMethods)          // List accepted payment methods
+	v1.GET("/payments/:method/return", paymentHandler.HandlePaymentReturn) // Gateway return, redirects to the frontend
 
 	// Auth middleware for protected routes
 	authMiddleware := middleware.ClerkAuthMiddleware(cfg)
+	optionalAuthMiddleware := middleware.OptionalClerkAuthMiddleware(cfg)
+	guestOrAuthMiddleware := middleware.GuestOrClerkAuthMiddleware(cfg)
 	loadUserMiddleware := middleware.LoadUserMiddleware(userService)
 
 	// User routes (protected)
@@ -82,22 +170,46 @@ func SetupRoutes(e *echo.Echo, db *database.Database, cfg *config.Config) {
 	users.GET("/my-role", roleHandler.GetMyRole)
 
 	// Product routes
-	setupProductRoutes(v1, productHandler, authMiddleware, loadUserMiddleware)
+	setupProductRoutes(v1, productHandler, authMiddleware, optionalAuthMiddleware, loadUserMiddleware)
+
+	// Category routes
+	setupCategoryRoutes(v1, categoryHandler, authMiddleware, loadUserMiddleware)
 
 	// Shop routes
 	setupShopRoutes(v1, shopHandler, authMiddleware, loadUserMiddleware)
 
+	// Wishlist routes
+	setupWishlistRoutes(v1, wishlistHandler, authMiddleware, loadUserMiddleware)
+
 	// Cart routes
-	setupCartRoutes(v1, cartHandler, authMiddleware, loadUserMiddleware)
+	setupCartRoutes(v1, cartHandler, authMiddleware, guestOrAuthMiddleware, loadUserMiddleware)
 
 	// Order routes
-	setupOrderRoutes(v1, orderHandler, stripeHandler, authMiddleware, loadUserMiddleware)
+	setupOrderRoutes(v1, orderHandler, paymentHandler, refundHandler, authMiddleware, loadUserMiddleware)
+
+	// Coupon routes
+	setupCouponRoutes(v1, couponHandler, authMiddleware, loadUserMiddleware)
+
+	// Shipping routes
+	setupShippingRoutes(v1, shippingHandler, authMiddleware, loadUserMiddleware)
+
+	// Tax routes
+	setupTaxRoutes(v1, taxHandler, authMiddleware, loadUserMiddleware)
+
+	// Currency routes
+	setupCurrencyRoutes(v1, currencyHandler, authMiddleware, loadUserMiddleware)
 
 	// Review routes
 	setupReviewRoutes(v1, reviewHandler, authMiddleware, loadUserMiddleware)
 
 	// Address routes
 	setupAddressRoutes(v1, addressHandler, authMiddleware, loadUserMiddleware)
+
+	// Vendor balance, commission and payout routes
+	setupLedgerRoutes(v1, ledgerHandler, authMiddleware, loadUserMiddleware)
+
+	// Admin routes
+	setupAdminRoutes(v1, paymentHandler, orderHandler, shopHandler, jobHandler, authMiddleware, loadUserMiddleware)
 }
 
 func healthCheck(c echo.Context) error {
@@ -112,23 +224,56 @@ func setupUserRoutes(g *echo.Group, db *database.Database) {
 	// Moved to main SetupRoutes function
 }
 
-func setupProductRoutes(g *echo.Group, productHandler *handler.ProductHandler, authMiddleware, loadUserMiddleware echo.MiddlewareFunc) {
+func setupProductRoutes(g *echo.Group, productHandler *handler.ProductHandler, authMiddleware, optionalAuthMiddleware, loadUserMiddleware echo.MiddlewareFunc) {
 	products := g.Group("/products")
 
 	// Public routes
-	products.GET("", productHandler.GetProducts)        // Get all products
-	products.GET("/:id", productHandler.GetProductByID) // Get single product
+	products.GET("", productHandler.GetProducts)                                         // Get all products
+	products.GET("/suggest", productHandler.SuggestProducts)                             // Autocomplete product search
+	products.GET("/slug/:slug", productHandler.GetProductBySlug, optionalAuthMiddleware) // Get product by slug
+	products.GET("/:id", productHandler.GetProductByID, optionalAuthMiddleware)          // Get single product
 
 	// Protected routes (vendor only)
 	products.POST("", productHandler.CreateProduct, authMiddleware, loadUserMiddleware)       // Create product
 	products.PUT("/:id", productHandler.UpdateProduct, authMiddleware, loadUserMiddleware)    // Update product
 	products.DELETE("/:id", productHandler.DeleteProduct, authMiddleware, loadUserMiddleware) // Delete product
 
+	// Product image gallery
+	products.GET("/:id/images", productHandler.GetProductImages)                                                              // Get product gallery
+	products.POST("/:id/images", productHandler.AddProductImage, authMiddleware, loadUserMiddleware)                          // Add image
+	products.PUT("/:id/images/order", productHandler.ReorderProductImages, authMiddleware, loadUserMiddleware)                // Reorder images
+	products.PATCH("/:id/images/:imageId/primary", productHandler.SetPrimaryProductImage, authMiddleware, loadUserMiddleware) // Set primary image
+	products.DELETE("/:id/images/:imageId", productHandler.DeleteProductImage, authMiddleware, loadUserMiddleware)            // Delete image
+
+	// Product options and variants
+	products.GET("/:id/variants", productHandler.GetProductVariants)                                                     // Get options and variants
+	products.POST("/:id/options", productHandler.AddProductOption, authMiddleware, loadUserMiddleware)                   // Add option type
+	products.DELETE("/:id/options/:optionId", productHandler.DeleteProductOption, authMiddleware, loadUserMiddleware)    // Delete option type
+	products.POST("/:id/variants", productHandler.CreateProductVariant, authMiddleware, loadUserMiddleware)              // Add variant
+	products.PUT("/:id/variants/:variantId", productHandler.UpdateProductVariant, authMiddleware, loadUserMiddleware)    // Update variant
+	products.DELETE("/:id/variants/:variantId", productHandler.DeleteProductVariant, authMiddleware, loadUserMiddleware) // Delete variant
+
 	// Vendor-specific routes
 	vendor := g.Group("/vendor", authMiddleware, loadUserMiddleware)
 	vendor.GET("/products", productHandler.GetVendorProducts) // Get my products
 }
 
+func setupCategoryRoutes(g *echo.Group, categoryHandler *handler.CategoryHandler, authMiddleware, loadUserMiddleware echo.MiddlewareFunc) {
+	categories := g.Group("/categories")
+
+	// Public routes
+	categories.GET("", categoryHandler.ListCategories)               // List all categories
+	categories.GET("/tree", categoryHandler.GetCategoryTree)         // Get nested category tree
+	categories.GET("/slug/:slug", categoryHandler.GetCategoryBySlug) // Get category by slug
+	categories.GET("/:id", categoryHandler.GetCategoryByID)          // Get category by ID
+
+	// Admin routes (protected, admin role required)
+	adminGroup := categories.Group("", authMiddleware, loadUserMiddleware, middleware.RequireAdmin())
+	adminGroup.POST("", categoryHandler.CreateCategory)       // Create category
+	adminGroup.PUT("/:id", categoryHandler.UpdateCategory)    // Update category
+	adminGroup.DELETE("/:id", categoryHandler.DeleteCategory) // Delete category
+}
+
 func setupShopRoutes(g *echo.Group, shopHandler *handler.ShopHandler, authMiddleware, loadUserMiddleware echo.MiddlewareFunc) {
 	shops := g.Group("/shops")
 
@@ -151,19 +296,35 @@ func setupShopRoutes(g *echo.Group, shopHandler *handler.ShopHandler, authMiddle
 	adminGroup.PATCH("/shops/:id/verify", shopHandler.VerifyShop) // Verify shop
 }
 
-func setupCartRoutes(g *echo.Group, cartHandler *handler.CartHandler, authMiddleware, loadUserMiddleware echo.MiddlewareFunc) {
-	cart := g.Group("/cart", authMiddleware, loadUserMiddleware)
+func setupCartRoutes(g *echo.Group, cartHandler *handler.CartHandler, authMiddleware, guestOrAuthMiddleware, loadUserMiddleware echo.MiddlewareFunc) {
+	// Cart routes work for signed-in users and for guests holding an
+	// X-Cart-Token; a session token that fails verification is rejected
+	cart := g.Group("/cart", guestOrAuthMiddleware)
+
+	cart.GET("", cartHandler.GetCart)                       // Get user's or guest's cart
+	cart.GET("/count", cartHandler.GetCartItemCount)        // Get cart item count
+	cart.POST("/items", cartHandler.AddToCart)              // Add item to cart (issues a guest token if needed)
+	cart.PUT("/items/:id", cartHandler.UpdateCartItem)      // Update cart item quantity
+	cart.DELETE("/items/:id", cartHandler.RemoveCartItem)   // Remove item from cart
+	cart.DELETE("", cartHandler.ClearCart)                  // Clear entire cart
+	cart.POST("/coupon", cartHandler.ApplyCoupon)           // Apply coupon (previews the discount)
+	cart.DELETE("/coupon", cartHandler.RemoveCoupon)        // Remove coupon
+	cart.POST("/shipping-quote", cartHandler.QuoteShipping) // Quote shipping options per shop
+
+	// Merge the guest cart into the user's cart after sign-in
+	cart.POST("/merge", cartHandler.MergeGuestCart, authMiddleware, loadUserMiddleware)
+}
+
+func setupWishlistRoutes(g *echo.Group, wishlistHandler *handler.WishlistHandler, authMiddleware, loadUserMiddleware echo.MiddlewareFunc) {
+	wishlist := g.Group("/wishlist", authMiddleware, loadUserMiddleware)
 
-	// Cart routes (all protected, customer role)
-	cart.GET("", cartHandler.GetCart)                     // Get user's cart
-	cart.GET("/count", cartHandler.GetCartItemCount)      // Get cart item count
-	cart.POST("/items", cartHandler.AddToCart)            // Add item to cart
-	cart.PUT("/items/:id", cartHandler.UpdateCartItem)    // Update cart item quantity
-	cart.DELETE("/items/:id", cartHandler.RemoveCartItem) // Remove item from cart
-	cart.DELETE("", cartHandler.ClearCart)                // Clear entire cart
+	wishlist.GET("", wishlistHandler.GetWishlist)                         // Get user's wishlist
+	wishlist.POST("", wishlistHandler.AddToWishlist)                      // Add product to wishlist
+	wishlist.DELETE("/:productId", wishlistHandler.RemoveFromWishlist)    // Remove product from wishlist
+	wishlist.POST("/:productId/move-to-cart", wishlistHandler.MoveToCart) // Move product to cart
 }
 
-func setupOrderRoutes(g *echo.Group, orderHandler *handler.OrderHandler, stripeHandler *handler.StripeHandler, authMiddleware, loadUserMiddleware echo.MiddlewareFunc) {
+func setupOrderRoutes(g *echo.Group, orderHandler *handler.OrderHandler, paymentHandler *handler.PaymentHandler, refundHandler *handler.RefundHandler, authMiddleware, loadUserMiddleware echo.MiddlewareFunc) {
 	orders := g.Group("/orders", authMiddleware, loadUserMiddleware)
 
 	// Customer order routes
@@ -172,15 +333,71 @@ func setupOrderRoutes(g *echo.Group, orderHandler *handler.OrderHandler, stripeH
 	orders.GET("/:id", orderHandler.GetOrderByID)        // Get order details
 	orders.POST("/:id/cancel", orderHandler.CancelOrder) // Cancel order
 
-	// Stripe checkout routes
-	orders.POST("/checkout/stripe", stripeHandler.CreateCheckoutSession) // Create Stripe checkout
-	orders.GET("/checkout/verify", stripeHandler.VerifySession)          // Verify Stripe session
+	// Payment checkout routes
+	orders.POST("/checkout", paymentHandler.CreateCheckout)               // Start paying with the order's method
+	orders.POST("/checkout/stripe", paymentHandler.CreateCheckoutSession) // Create Stripe checkout
+	orders.GET("/checkout/verify", paymentHandler.VerifySession)          // Verify a payment session
+	orders.POST("/:id/pay", paymentHandler.PayOrder)                      // Resume or retry an unpaid order's payment
+
+	// Refund routes (admins refund any order, vendors their own shop's lines)
+	refundAccess := middleware.RequireRole(model.RoleAdmin, model.RoleVendor)
+	orders.POST("/:id/refunds", refundHandler.CreateRefund, refundAccess)   // Refund order
+	orders.GET("/:id/refunds", refundHandler.GetOrderRefunds, refundAccess) // List order refunds
 
 	// Vendor order routes
 	vendor := g.Group("/vendor", authMiddleware, loadUserMiddleware, middleware.RequireVendor())
 	vendor.GET("/orders", orderHandler.GetVendorOrders)                // Get shop orders
 	vendor.PATCH("/orders/:id/status", orderHandler.UpdateOrderStatus) // Update order status
 }
+
+func setupCouponRoutes(g *echo.Group, couponHandler *handler.CouponHandler, authMiddleware, loadUserMiddleware echo.MiddlewareFunc) {
+	// Vendors manage their shop's coupons, admins every coupon
+	coupons := g.Group("/coupons", authMiddleware, loadUserMiddleware, middleware.RequireRole(model.RoleAdmin, model.RoleVendor))
+
+	coupons.POST("", couponHandler.CreateCoupon)           // Create coupon
+	coupons.GET("", couponHandler.ListCoupons)             // List coupons
+	coupons.PUT("/:id", couponHandler.UpdateCoupon)        // Update coupon
+	coupons.DELETE("/:id", couponHandler.DeactivateCoupon) // Deactivate coupon
+}
+
+func setupShippingRoutes(g *echo.Group, shippingHandler *handler.ShippingHandler, authMiddleware, loadUserMiddleware echo.MiddlewareFunc) {
+	// Admins define zones; vendors price shipping to them for their shop
+	shipping := g.Group("/shipping", authMiddleware, loadUserMiddleware, middleware.RequireRole(model.RoleAdmin, model.RoleVendor))
+	adminOnly := middleware.RequireRole(model.RoleAdmin)
+
+	shipping.GET("/zones", shippingHandler.ListZones)                    // List shipping zones
+	shipping.POST("/zones", shippingHandler.CreateZone, adminOnly)       // Create shipping zone
+	shipping.PUT("/zones/:id", shippingHandler.UpdateZone, adminOnly)    // Update shipping zone
+	shipping.DELETE("/zones/:id", shippingHandler.DeleteZone, adminOnly) // Delete shipping zone
+	shipping.POST("/methods", shippingHandler.CreateMethod)              // Create shipping method
+	shipping.GET("/methods", shippingHandler.ListMethods)                // List shipping methods
+	shipping.PUT("/methods/:id", shippingHandler.UpdateMethod)           // Update shipping method
+	shipping.DELETE("/methods/:id", shippingHandler.DeleteMethod)        // Delete shipping method
+}
+
+func setupTaxRoutes(g *echo.Group, taxHandler *handler.TaxHandler, authMiddleware, loadUserMiddleware echo.MiddlewareFunc) {
+	// Tax rules apply to every shop, so only admins manage them
+	taxRates := g.Group("/tax-rates", authMiddleware, loadUserMiddleware, middleware.RequireAdmin())
+
+	taxRates.GET("", taxHandler.ListTaxRates)         // List tax rates
+	taxRates.POST("", taxHandler.CreateTaxRate)       // Create tax rate
+	taxRates.PUT("/:id", taxHandler.UpdateTaxRate)    // Update tax rate
+	taxRates.DELETE("/:id", taxHandler.DeleteTaxRate) // Delete tax rate
+}
+
+func setupCurrencyRoutes(g *echo.Group, currencyHandler *handler.CurrencyHandler, authMiddleware, loadUserMiddleware echo.MiddlewareFunc) {
+	exchangeRates := g.Group("/exchange-rates")
+
+	// Public routes
+	exchangeRates.GET("", currencyHandler.ListRates) // List supported currencies and rates
+
+	// Admin routes (protected, admin role required)
+	adminGroup := exchangeRates.Group("", authMiddleware, loadUserMiddleware, middleware.RequireAdmin())
+	adminGroup.POST("/import", currencyHandler.ImportRates)     // Import rates from a CSV file
+	adminGroup.PUT("/:currency", currencyHandler.SetRate)       // Set a currency's rate
+	adminGroup.DELETE("/:currency", currencyHandler.DeleteRate) // Delete a currency's rate
+}
+
 func setupReviewRoutes(g *echo.Group, reviewHandler *handler.ReviewHandler, authMiddleware, loadUserMiddleware echo.MiddlewareFunc) {
 	reviews := g.Group("/reviews")
 
@@ -210,9 +427,86 @@ func setupAddressRoutes(g *echo.Group, addressHandler *handler.AddressHandler, a
 	addresses.PATCH("/:id/default", addressHandler.SetDefaultAddress) // Set default
 }
 
+func setupLedgerRoutes(g *echo.Group, ledgerHandler *handler.LedgerHandler, authMiddleware, loadUserMiddleware echo.MiddlewareFunc) {
+	// Vendor routes
+	vendor := g.Group("/vendor", authMiddleware, loadUserMiddleware, middleware.RequireVendor())
+	vendor.GET("/balance", ledgerHandler.GetVendorBalance) // What the platform owes the shop
+	vendor.GET("/ledger", ledgerHandler.ListVendorLedger)  // The shop's ledger entries
+
+	// Admin routes
+	admin := g.Group("/admin", authMiddleware, loadUserMiddleware, middleware.RequireAdmin())
+	admin.GET("/commission-rates", ledgerHandler.ListCommissionRates)                        // List commission rates
+	admin.PUT("/commission-rates/default", ledgerHandler.SetDefaultCommission)               // Set the platform default
+	admin.PUT("/commission-rates/categories/:id", ledgerHandler.SetCategoryCommission)       // Set a category's rate
+	admin.DELETE("/commission-rates/categories/:id", ledgerHandler.DeleteCategoryCommission) // Remove a category's rate
+	admin.PUT("/commission-rates/shops/:id", ledgerHandler.SetShopCommission)                // Override a shop's rate
+	admin.DELETE("/commission-rates/shops/:id", ledgerHandler.DeleteShopCommission)          // Remove a shop's override
+	admin.GET("/shops/:id/balance", ledgerHandler.GetShopBalance)                            // Any shop's balance
+	admin.GET("/shops/:id/ledger", ledgerHandler.ListShopLedger)                             // Any shop's ledger entries
+	admin.POST("/payout-batches", ledgerHandler.CreatePayoutBatch)                           // Pay out available balances
+	admin.GET("/payout-batches", ledgerHandler.ListPayoutBatches)                            // List payout batches
+	admin.GET("/payout-batches/:id", ledgerHandler.GetPayoutBatch)                           // Get a payout batch
+	admin.PATCH("/payouts/:id/status", ledgerHandler.UpdatePayoutStatus)                     // Mark a payout paid or failed
+}
+
+func setupAdminRoutes(g *echo.Group, paymentHandler *handler.PaymentHandler, orderHandler *handler.OrderHandler, shopHandler *handler.ShopHandler, jobHandler *handler.JobHandler, authMiddleware, loadUserMiddleware echo.MiddlewareFunc) {
+	admin := g.Group("/admin", authMiddleware, loadUserMiddleware, middleware.RequireAdmin())
+
+	// Ownership overrides: same handlers as the vendor routes, any order or shop
+	admin.PATCH("/orders/:id/status", orderHandler.UpdateOrderStatus) // Update any order's fulfilments
+	admin.PUT("/shops/:id", shopHandler.UpdateShop)                   // Update any shop
+	admin.PATCH("/shops/:id/status", shopHandler.ToggleShopStatus)    // Toggle any shop's status
+
+	admin.GET("/webhook-events", paymentHandler.ListWebhookEvents)              // List webhook events (failed by default)
+	admin.POST("/webhook-events/:id/replay", paymentHandler.ReplayWebhookEvent) // Replay a failed webhook event
+
+	admin.GET("/jobs", jobHandler.ListJobs)            // List background jobs
+	admin.GET("/jobs/:id", jobHandler.GetJob)          // Get a background job
+	admin.POST("/jobs/:id/retry", jobHandler.RetryJob) // Run a dead or pending job again now
+}
+
+// newClerkWebhookVerifier builds the Svix verifier for Clerk webhooks. Without
+// a valid CLERK_WEBHOOK_SECRET it returns nil and the webhook rejects everything.
+func newClerkWebhookVerifier(cfg *config.Config, store svix.ReplayStore) *svix.Verifier {
+	verifier, err := svix.NewVerifier(cfg.ClerkWebhookSecret, store)
+	if err != nil {
+		log.Printf("Clerk webhooks disabled: %v", err)
+		return nil
+	}
+	return verifier
+}
+
+// newPaymentRegistry registers the payment methods checkout accepts. eSewa and
+// Khalti are offered only once their keys are configured; methods listed in
+// FAKE_PAYMENT_METHODS are served by an in-memory fake gateway instead.
+func newPaymentRegistry(cfg *config.Config) *payment.Registry {
+	payments := payment.NewRegistry(
+		payment.NewStripe(cfg.StripeSecretKey, cfg.StripeWebhookSecret),
+		payment.NewCOD(),
+	)
+
+	if cfg.EsewaSecretKey != "" {
+		payments.Register(payment.NewEsewa(cfg.EsewaProductCode, cfg.EsewaSecretKey, cfg.EsewaFormURL, cfg.EsewaStatusURL))
+	} else {
+		log.Printf("eSewa payments disabled: ESEWA_SECRET_KEY is not set")
+	}
+	if cfg.KhaltiSecretKey != "" {
+		payments.Register(payment.NewKhalti(cfg.KhaltiSecretKey, cfg.KhaltiBaseURL))
+	} else {
+		log.Printf("Khalti payments disabled: KHALTI_SECRET_KEY is not set")
+	}
+
+	for _, method := range cfg.FakePaymentMethods {
+		log.Printf("Payment method %s uses a fake gateway", method)
+		payments.Register(payment.NewFake(method, uuid.New().String()))
+	}
+
+	return payments
+}
+
 // saveRawBody is a middleware that reads the request body and stores it in the
 // echo context so that the handler can access the raw bytes for signature
-// verification (required by Stripe webhooks). It replaces the body so
+// verification (required by Stripe and Clerk webhooks). It replaces the body so
 // downstream code can still read it if needed.
 func saveRawBody() echo.MiddlewareFunc {
 	return func(next echo.HandlerFunc) echo.HandlerFunc {
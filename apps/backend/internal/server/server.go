@@ -11,22 +11,25 @@ import (
 
 	"github.com/imbivek08/hamropasal/internal/config"
 	"github.com/imbivek08/hamropasal/internal/database"
+	"github.com/imbivek08/hamropasal/internal/jobs"
 	"github.com/imbivek08/hamropasal/internal/router"
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
 )
 
 type Server struct {
-	echo   *echo.Echo
-	db     *database.Database
-	config *config.Config
+	echo      *echo.Echo
+	db        *database.Database
+	config    *config.Config
+	scheduler *jobs.Scheduler
 }
 
 func New(cfg *config.Config, db *database.Database) *Server {
 	return &Server{
-		echo:   echo.New(),
-		db:     db,
-		config: cfg,
+		echo:      echo.New(),
+		db:        db,
+		config:    cfg,
+		scheduler: jobs.NewScheduler(),
 	}
 }
 
@@ -41,8 +44,11 @@ func (s *Server) Start() error {
 	// Apply global middleware
 	s.setupMiddleware()
 
-	// Setup routes
-	router.SetupRoutes(s.echo, s.db, s.config)
+	// Setup routes and the background jobs they rely on
+	router.SetupRoutes(s.echo, s.db, s.config, s.scheduler)
+
+	// Background jobs run until the server shuts down
+	s.scheduler.Start()
 
 	// Start server with graceful shutdown
 	return s.startWithGracefulShutdown()
@@ -59,9 +65,10 @@ func (s *Server) setupMiddleware() {
 
 	// CORS middleware
 	s.echo.Use(middleware.CORSWithConfig(middleware.CORSConfig{
-		AllowOrigins: []string{"*"},
-		AllowMethods: []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodDelete, http.MethodPatch},
-		AllowHeaders: []string{echo.HeaderOrigin, echo.HeaderContentType, echo.HeaderAccept, echo.HeaderAuthorization},
+		AllowOrigins:  []string{"*"},
+		AllowMethods:  []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodDelete, http.MethodPatch},
+		AllowHeaders:  []string{echo.HeaderOrigin, echo.HeaderContentType, echo.HeaderAccept, echo.HeaderAuthorization, "X-Cart-Token"},
+		ExposeHeaders: []string{"X-Cart-Token"},
 	}))
 
 	// Request ID middleware
@@ -91,6 +98,7 @@ func (s *Server) startWithGracefulShutdown() error {
 	// Block until we receive a signal or an error
 	select {
 	case err := <-serverErrors:
+		s.stopJobs(context.Background())
 		return fmt.Errorf("server error: %w", err)
 
 	case sig := <-shutdown:
@@ -103,14 +111,25 @@ func (s *Server) startWithGracefulShutdown() error {
 		// Attempt graceful shutdown
 		if err := s.echo.Shutdown(ctx); err != nil {
 			s.echo.Logger.Error(fmt.Sprintf("Graceful shutdown failed: %v", err))
+			s.stopJobs(ctx)
 			return s.echo.Close()
 		}
 
+		// Let running jobs finish before the database closes
+		s.stopJobs(ctx)
+
 		s.echo.Logger.Info("Server stopped gracefully")
 		return nil
 	}
 }
 
+// stopJobs stops the background jobs, waiting for running ones until ctx ends
+func (s *Server) stopJobs(ctx context.Context) {
+	if err := s.scheduler.Stop(ctx); err != nil {
+		s.echo.Logger.Error(fmt.Sprintf("Background jobs did not stop: %v", err))
+	}
+}
+
 func (s *Server) Close() error {
 	s.db.Close()
 	return s.echo.Close()
@@ -1,7 +1,10 @@
 package server
 
 import (
+	"reflect"
+
 	"github.com/go-playground/validator/v10"
+	"github.com/imbivek08/hamropasal/internal/model"
 	"github.com/labstack/echo/v4"
 )
 
@@ -20,7 +23,17 @@ func (cv *CustomValidator) Validate(i interface{}) error {
 
 // NewValidator creates a new validator instance
 func NewValidator() echo.Validator {
+	v := validator.New()
+
+	// Validate amounts of money by their minor units, so tags like gt=0 work
+	v.RegisterCustomTypeFunc(func(field reflect.Value) interface{} {
+		if money, ok := field.Interface().(model.Money); ok {
+			return money.Amount
+		}
+		return nil
+	}, model.Money{})
+
 	return &CustomValidator{
-		validator: validator.New(),
+		validator: v,
 	}
 }
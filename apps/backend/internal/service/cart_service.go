@@ -2,32 +2,87 @@ package service
 
 import (
 	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
 	"fmt"
+	"strings"
 
 	"github.com/google/uuid"
 	"github.com/imbivek08/hamropasal/internal/model"
 	"github.com/imbivek08/hamropasal/internal/repository"
+	"github.com/jackc/pgx/v5"
 )
 
+// ErrInvalidCartToken is wrapped by every guest cart token that fails
+// verification
+var ErrInvalidCartToken = errors.New("invalid cart token")
+
 type CartService struct {
-	cartRepo    *repository.CartRepository
-	productRepo *repository.ProductRepository
+	cartRepo        *repository.CartRepository
+	productRepo     *repository.ProductRepository
+	variantRepo     *repository.ProductVariantRepository
+	couponService   *CouponService
+	shippingService *ShippingService
+	currencyService *CurrencyService
+	cartTokenSecret []byte
 }
 
-func NewCartService(cartRepo *repository.CartRepository, productRepo *repository.ProductRepository) *CartService {
+func NewCartService(
+	cartRepo *repository.CartRepository,
+	productRepo *repository.ProductRepository,
+	variantRepo *repository.ProductVariantRepository,
+	couponService *CouponService,
+	shippingService *ShippingService,
+	currencyService *CurrencyService,
+	cartTokenSecret string,
+) *CartService {
 	return &CartService{
-		cartRepo:    cartRepo,
-		productRepo: productRepo,
+		cartRepo:        cartRepo,
+		productRepo:     productRepo,
+		variantRepo:     variantRepo,
+		couponService:   couponService,
+		shippingService: shippingService,
+		currencyService: currencyService,
+		cartTokenSecret: []byte(cartTokenSecret),
+	}
+}
+
+// NewGuestCartToken starts a new guest session and returns its ID together
+// with the signed token the client sends back in the X-Cart-Token header
+func (s *CartService) NewGuestCartToken() (sessionID string, token string) {
+	sessionID = uuid.NewString()
+	return sessionID, sessionID + "." + s.signSessionID(sessionID)
+}
+
+// VerifyGuestCartToken checks a guest cart token's signature and returns its session ID
+func (s *CartService) VerifyGuestCartToken(token string) (string, error) {
+	sessionID, signature, ok := strings.Cut(token, ".")
+	if !ok || sessionID == "" {
+		return "", fmt.Errorf("%w: malformed token", ErrInvalidCartToken)
 	}
+
+	if !hmac.Equal([]byte(signature), []byte(s.signSessionID(sessionID))) {
+		return "", fmt.Errorf("%w: signature mismatch", ErrInvalidCartToken)
+	}
+
+	return sessionID, nil
+}
+
+func (s *CartService) signSessionID(sessionID string) string {
+	mac := hmac.New(sha256.New, s.cartTokenSecret)
+	mac.Write([]byte(sessionID))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
 }
 
-// GetOrCreateUserCart gets or creates cart for user
-func (s *CartService) GetOrCreateUserCart(ctx context.Context, userID uuid.UUID) (*model.Cart, error) {
-	return s.cartRepo.GetOrCreateCart(ctx, userID)
+// GetOrCreateCart gets or creates cart for a user or guest session
+func (s *CartService) GetOrCreateCart(ctx context.Context, owner model.CartOwner) (*model.Cart, error) {
+	return s.cartRepo.GetOrCreateCart(ctx, owner)
 }
 
-// AddToCart adds a product to user's cart
-func (s *CartService) AddToCart(ctx context.Context, userID uuid.UUID, req *model.AddToCartRequest) (*model.CartItemResponse, error) {
+// AddToCart adds a product to the owner's cart
+func (s *CartService) AddToCart(ctx context.Context, owner model.CartOwner, req *model.AddToCartRequest) (*model.CartItemResponse, error) {
 	// Validate product exists and is available
 	product, err := s.productRepo.GetByID(ctx, req.ProductID)
 	if err != nil {
@@ -38,18 +93,32 @@ func (s *CartService) AddToCart(ctx context.Context, userID uuid.UUID, req *mode
 		return nil, fmt.Errorf("product is not available")
 	}
 
-	if product.StockQuantity < req.Quantity {
-		return nil, fmt.Errorf("insufficient stock: only %d available", product.StockQuantity)
+	// Products sold by variant are added one variant at a time
+	variant, err := s.cartVariant(ctx, product, req.VariantID)
+	if err != nil {
+		return nil, err
+	}
+
+	price, imageURL, stock := product.Price, product.ImageURL, product.StockQuantity
+	if variant != nil {
+		price, stock = variant.PriceOf(product.Price), variant.StockQuantity
+		if variant.ImageURL != nil {
+			imageURL = variant.ImageURL
+		}
+	}
+
+	if stock < req.Quantity {
+		return nil, fmt.Errorf("insufficient stock: only %d available", stock)
 	}
 
 	// Get or create cart
-	cart, err := s.cartRepo.GetOrCreateCart(ctx, userID)
+	cart, err := s.cartRepo.GetOrCreateCart(ctx, owner)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get cart: %w", err)
 	}
 
 	// Add item to cart
-	item, err := s.cartRepo.AddItem(ctx, cart.ID, req.ProductID, req.Quantity)
+	item, err := s.cartRepo.AddItem(ctx, cart.ID, req.ProductID, req.VariantID, req.Quantity)
 	if err != nil {
 		return nil, fmt.Errorf("failed to add item to cart: %w", err)
 	}
@@ -64,34 +133,40 @@ func (s *CartService) AddToCart(ctx context.Context, userID uuid.UUID, req *mode
 	response := &model.CartItemResponse{
 		ID:              item.ID,
 		ProductID:       product.ID,
+		VariantID:       item.VariantID,
 		ProductName:     product.Name,
-		ProductPrice:    product.Price,
-		ProductImageURL: product.ImageURL,
-		StockQuantity:   product.StockQuantity,
+		ProductPrice:    price,
+		ProductImageURL: imageURL,
+		StockQuantity:   stock,
 		IsActive:        product.IsActive,
 		ShopID:          product.ShopID,
 		ShopName:        shop.Name,
 		Quantity:        item.Quantity,
-		Subtotal:        product.Price * float64(item.Quantity),
+		Subtotal:        price.Mul(int64(item.Quantity)),
 		CreatedAt:       item.CreatedAt,
 		UpdatedAt:       item.UpdatedAt,
 	}
+	if variant != nil {
+		response.VariantSKU = &variant.SKU
+		response.VariantOptions = variant.Options
+	}
 
 	return response, nil
 }
 
-// GetCart retrieves user's cart with all items
-func (s *CartService) GetCart(ctx context.Context, userID uuid.UUID) (*model.CartResponse, error) {
+// GetCart retrieves the owner's cart with all items, priced in the requested
+// currency or the default currency when none is requested
+func (s *CartService) GetCart(ctx context.Context, owner model.CartOwner, currency string) (*model.CartResponse, error) {
 	// Get or create cart
-	cart, err := s.cartRepo.GetOrCreateCart(ctx, userID)
+	cart, err := s.cartRepo.GetOrCreateCart(ctx, owner)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get cart: %w", err)
 	}
 
 	// Get cart items with product details
-	items, err := s.cartRepo.GetCartWithItems(ctx, cart.ID)
+	items, currency, err := s.cartItems(ctx, cart.ID, currency)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get cart items: %w", err)
+		return nil, err
 	}
 
 	// Build response
@@ -99,6 +174,7 @@ func (s *CartService) GetCart(ctx context.Context, userID uuid.UUID) (*model.Car
 		ID:        cart.ID,
 		UserID:    cart.UserID,
 		Items:     items,
+		Currency:  currency,
 		CreatedAt: cart.CreatedAt,
 		UpdatedAt: cart.UpdatedAt,
 	}
@@ -106,11 +182,136 @@ func (s *CartService) GetCart(ctx context.Context, userID uuid.UUID) (*model.Car
 	// Calculate totals
 	response.CalculateTotals()
 
+	// Preview the applied coupon against the current items
+	if cart.CouponCode != nil {
+		response.CouponCode = cart.CouponCode
+		discount, err := s.couponService.PreviewDiscount(ctx, *cart.CouponCode, owner.UserID, items)
+		if err != nil {
+			message := err.Error()
+			response.CouponError = &message
+		} else {
+			response.Discount = discount.Amount
+			response.FreeShipping = discount.FreeShipping
+			response.Total = response.Subtotal.Sub(discount.Amount)
+		}
+	}
+
 	return response, nil
 }
 
+// ApplyCoupon applies a promotion code to the owner's cart after checking it
+// is valid for the cart's current items
+func (s *CartService) ApplyCoupon(ctx context.Context, owner model.CartOwner, code string) (*model.CartResponse, error) {
+	cart, err := s.cartRepo.GetOrCreateCart(ctx, owner)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get cart: %w", err)
+	}
+
+	items, _, err := s.cartItems(ctx, cart.ID, "")
+	if err != nil {
+		return nil, err
+	}
+
+	discount, err := s.couponService.PreviewDiscount(ctx, code, owner.UserID, items)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.cartRepo.SetCouponCode(ctx, cart.ID, &discount.Code); err != nil {
+		return nil, fmt.Errorf("failed to apply coupon: %w", err)
+	}
+
+	return s.GetCart(ctx, owner, "")
+}
+
+// RemoveCoupon removes the promotion code from the owner's cart
+func (s *CartService) RemoveCoupon(ctx context.Context, owner model.CartOwner) (*model.CartResponse, error) {
+	cart, err := s.cartRepo.GetOrCreateCart(ctx, owner)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get cart: %w", err)
+	}
+
+	if err := s.cartRepo.SetCouponCode(ctx, cart.ID, nil); err != nil {
+		return nil, fmt.Errorf("failed to remove coupon: %w", err)
+	}
+
+	return s.GetCart(ctx, owner, "")
+}
+
+// QuoteShipping lists each shop's shipping options for the owner's cart to the
+// requested destination
+func (s *CartService) QuoteShipping(ctx context.Context, owner model.CartOwner, req *model.ShippingQuoteRequest) (*model.ShippingQuote, error) {
+	dest, err := s.shippingService.ResolveDestination(ctx, owner.UserID, req)
+	if err != nil {
+		return nil, err
+	}
+
+	cart, err := s.cartRepo.GetOrCreateCart(ctx, owner)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get cart: %w", err)
+	}
+
+	items, currency, err := s.cartItems(ctx, cart.ID, req.Currency)
+	if err != nil {
+		return nil, err
+	}
+
+	quote, err := s.shippingService.QuoteCart(ctx, items, dest)
+	if err != nil {
+		return nil, err
+	}
+
+	quote.Currency = currency
+	return quote, nil
+}
+
+// cartVariant checks the variant chosen for a product can be added to a cart.
+// It returns nil for products that are not sold by variant.
+func (s *CartService) cartVariant(ctx context.Context, product *model.Product, variantID *uuid.UUID) (*model.ProductVariant, error) {
+	if variantID == nil {
+		hasVariants, err := s.variantRepo.HasVariants(ctx, product.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get product variants: %w", err)
+		}
+		if hasVariants {
+			return nil, fmt.Errorf("choose a variant of %s", product.Name)
+		}
+		return nil, nil
+	}
+
+	variant, err := s.variantRepo.GetVariantByID(ctx, *variantID)
+	if err != nil || variant.ProductID != product.ID {
+		return nil, fmt.Errorf("variant not found")
+	}
+	if !variant.IsActive {
+		return nil, fmt.Errorf("variant is not available")
+	}
+
+	return variant, nil
+}
+
+// cartItems gets a cart's items with their prices converted to the requested
+// currency, and returns the currency they ended up in
+func (s *CartService) cartItems(ctx context.Context, cartID uuid.UUID, currency string) ([]model.CartItemWithProduct, string, error) {
+	currency, rates, err := s.currencyService.Resolve(ctx, currency)
+	if err != nil {
+		return nil, "", err
+	}
+
+	items, err := s.cartRepo.GetCartWithItems(ctx, cartID)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to get cart items: %w", err)
+	}
+
+	if err := convertCartItems(rates, items, currency); err != nil {
+		return nil, "", err
+	}
+
+	return items, currency, nil
+}
+
 // UpdateCartItemQuantity updates quantity of cart item
-func (s *CartService) UpdateCartItemQuantity(ctx context.Context, userID, itemID uuid.UUID, quantity int) error {
+func (s *CartService) UpdateCartItemQuantity(ctx context.Context, owner model.CartOwner, itemID uuid.UUID, quantity int) error {
 	// Get cart item
 	item, err := s.cartRepo.GetCartItemByID(ctx, itemID)
 	if err != nil {
@@ -118,19 +319,28 @@ func (s *CartService) UpdateCartItemQuantity(ctx context.Context, userID, itemID
 	}
 
 	// Verify ownership
-	owned, err := s.cartRepo.VerifyCartOwnership(ctx, item.CartID, userID)
+	owned, err := s.cartRepo.VerifyCartOwnership(ctx, item.CartID, owner)
 	if err != nil || !owned {
 		return fmt.Errorf("unauthorized access to cart item")
 	}
 
-	// Validate product stock
+	// Validate product (or variant) stock
 	product, err := s.productRepo.GetByID(ctx, item.ProductID)
 	if err != nil {
 		return fmt.Errorf("product not found")
 	}
 
-	if product.StockQuantity < quantity {
-		return fmt.Errorf("insufficient stock: only %d available", product.StockQuantity)
+	stock := product.StockQuantity
+	if item.VariantID != nil {
+		variant, err := s.variantRepo.GetVariantByID(ctx, *item.VariantID)
+		if err != nil {
+			return fmt.Errorf("variant not found")
+		}
+		stock = variant.StockQuantity
+	}
+
+	if stock < quantity {
+		return fmt.Errorf("insufficient stock: only %d available", stock)
 	}
 
 	// Update quantity
@@ -142,7 +352,7 @@ func (s *CartService) UpdateCartItemQuantity(ctx context.Context, userID, itemID
 }
 
 // RemoveCartItem removes item from cart
-func (s *CartService) RemoveCartItem(ctx context.Context, userID, itemID uuid.UUID) error {
+func (s *CartService) RemoveCartItem(ctx context.Context, owner model.CartOwner, itemID uuid.UUID) error {
 	// Get cart item
 	item, err := s.cartRepo.GetCartItemByID(ctx, itemID)
 	if err != nil {
@@ -150,7 +360,7 @@ func (s *CartService) RemoveCartItem(ctx context.Context, userID, itemID uuid.UU
 	}
 
 	// Verify ownership
-	owned, err := s.cartRepo.VerifyCartOwnership(ctx, item.CartID, userID)
+	owned, err := s.cartRepo.VerifyCartOwnership(ctx, item.CartID, owner)
 	if err != nil || !owned {
 		return fmt.Errorf("unauthorized access to cart item")
 	}
@@ -163,10 +373,10 @@ func (s *CartService) RemoveCartItem(ctx context.Context, userID, itemID uuid.UU
 	return nil
 }
 
-// ClearCart removes all items from user's cart
-func (s *CartService) ClearCart(ctx context.Context, userID uuid.UUID) error {
+// ClearCart removes all items from the owner's cart
+func (s *CartService) ClearCart(ctx context.Context, owner model.CartOwner) error {
 	// Get cart
-	cart, err := s.cartRepo.GetCartByUserID(ctx, userID)
+	cart, err := s.cartRepo.GetCart(ctx, owner)
 	if err != nil {
 		return fmt.Errorf("cart not found")
 	}
@@ -179,10 +389,10 @@ func (s *CartService) ClearCart(ctx context.Context, userID uuid.UUID) error {
 	return nil
 }
 
-// GetCartItemCount returns total items in user's cart
-func (s *CartService) GetCartItemCount(ctx context.Context, userID uuid.UUID) (int, error) {
+// GetCartItemCount returns total items in the owner's cart
+func (s *CartService) GetCartItemCount(ctx context.Context, owner model.CartOwner) (int, error) {
 	// Get cart
-	cart, err := s.cartRepo.GetCartByUserID(ctx, userID)
+	cart, err := s.cartRepo.GetCart(ctx, owner)
 	if err != nil {
 		// Return 0 if cart doesn't exist yet
 		return 0, nil
@@ -190,3 +400,28 @@ func (s *CartService) GetCartItemCount(ctx context.Context, userID uuid.UUID) (i
 
 	return s.cartRepo.GetCartItemCount(ctx, cart.ID)
 }
+
+// MergeGuestCart folds a guest cart into the user's cart after sign-in and
+// returns the merged cart. Merging an unknown or expired guest cart is a no-op.
+func (s *CartService) MergeGuestCart(ctx context.Context, userID uuid.UUID, sessionID string) (*model.CartResponse, error) {
+	owner := model.UserCartOwner(userID)
+
+	guestCart, err := s.cartRepo.GetCartBySessionID(ctx, sessionID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return s.GetCart(ctx, owner, "")
+		}
+		return nil, fmt.Errorf("failed to get guest cart: %w", err)
+	}
+
+	userCart, err := s.cartRepo.GetOrCreateCart(ctx, owner)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get cart: %w", err)
+	}
+
+	if err := s.cartRepo.MergeCarts(ctx, guestCart.ID, userCart.ID); err != nil {
+		return nil, fmt.Errorf("failed to merge carts: %w", err)
+	}
+
+	return s.GetCart(ctx, owner, "")
+}
@@ -0,0 +1,223 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+
+	"github.com/imbivek08/hamropasal/internal/model"
+	"github.com/imbivek08/hamropasal/internal/repository"
+)
+
+type CategoryService struct {
+	categoryRepo *repository.CategoryRepository
+}
+
+func NewCategoryService(categoryRepo *repository.CategoryRepository) *CategoryService {
+	return &CategoryService{
+		categoryRepo: categoryRepo,
+	}
+}
+
+// CreateCategory creates a new category (admin only)
+func (s *CategoryService) CreateCategory(ctx context.Context, req *model.CreateCategoryRequest) (*model.Category, error) {
+	// Verify the parent exists
+	if req.ParentID != nil {
+		if _, err := s.getCategory(ctx, *req.ParentID); err != nil {
+			return nil, errors.New("parent category not found")
+		}
+	}
+
+	slug, err := s.uniqueSlug(ctx, req.Name, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	category := &model.Category{
+		ID:           uuid.New(),
+		Name:         req.Name,
+		Slug:         slug,
+		Description:  req.Description,
+		ParentID:     req.ParentID,
+		ImageURL:     req.ImageURL,
+		IsActive:     true,
+		DisplayOrder: req.DisplayOrder,
+		CreatedAt:    now,
+		UpdatedAt:    now,
+	}
+
+	if err := s.categoryRepo.Create(ctx, category); err != nil {
+		return nil, fmt.Errorf("failed to create category: %w", err)
+	}
+
+	return category, nil
+}
+
+// GetCategoryByID retrieves a category by ID
+func (s *CategoryService) GetCategoryByID(ctx context.Context, id uuid.UUID) (*model.Category, error) {
+	return s.getCategory(ctx, id)
+}
+
+// GetCategoryBySlug retrieves a category by slug
+func (s *CategoryService) GetCategoryBySlug(ctx context.Context, slug string) (*model.Category, error) {
+	category, err := s.categoryRepo.GetBySlug(ctx, slug)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, errors.New("category not found")
+		}
+		return nil, fmt.Errorf("failed to get category: %w", err)
+	}
+	return category, nil
+}
+
+// ListCategories retrieves all categories as a flat list
+func (s *CategoryService) ListCategories(ctx context.Context, activeOnly bool) ([]*model.Category, error) {
+	categories, err := s.categoryRepo.GetAll(ctx, activeOnly)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list categories: %w", err)
+	}
+	return categories, nil
+}
+
+// GetCategoryTree returns active categories nested under their parents.
+// Categories whose parent is inactive are hidden along with that parent.
+func (s *CategoryService) GetCategoryTree(ctx context.Context) ([]*model.CategoryResponse, error) {
+	categories, err := s.categoryRepo.GetAll(ctx, true)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list categories: %w", err)
+	}
+
+	// Categories come back ordered by display_order, so appending keeps siblings ordered
+	nodes := make(map[uuid.UUID]*model.CategoryResponse, len(categories))
+	for _, category := range categories {
+		nodes[category.ID] = category.ToResponse()
+	}
+
+	roots := []*model.CategoryResponse{}
+	for _, category := range categories {
+		node := nodes[category.ID]
+		if category.ParentID == nil {
+			roots = append(roots, node)
+			continue
+		}
+		if parent, ok := nodes[*category.ParentID]; ok {
+			parent.Children = append(parent.Children, node)
+		}
+	}
+
+	return roots, nil
+}
+
+// UpdateCategory updates a category (admin only)
+func (s *CategoryService) UpdateCategory(ctx context.Context, id uuid.UUID, req *model.UpdateCategoryRequest) (*model.Category, error) {
+	category, err := s.getCategory(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Name != nil && *req.Name != category.Name {
+		slug, err := s.uniqueSlug(ctx, *req.Name, &category.ID)
+		if err != nil {
+			return nil, err
+		}
+		category.Name = *req.Name
+		category.Slug = slug
+	}
+	if req.Description != nil {
+		category.Description = req.Description
+	}
+	if req.RemoveParent {
+		category.ParentID = nil
+	} else if req.ParentID != nil {
+		if err := s.validateParent(ctx, category.ID, *req.ParentID); err != nil {
+			return nil, err
+		}
+		category.ParentID = req.ParentID
+	}
+	if req.ImageURL != nil {
+		category.ImageURL = req.ImageURL
+	}
+	if req.DisplayOrder != nil {
+		category.DisplayOrder = *req.DisplayOrder
+	}
+	if req.IsActive != nil {
+		category.IsActive = *req.IsActive
+	}
+
+	if err := s.categoryRepo.Update(ctx, category); err != nil {
+		return nil, fmt.Errorf("failed to update category: %w", err)
+	}
+
+	return category, nil
+}
+
+// DeleteCategory deletes a category (admin only). Categories that still have
+// subcategories must be emptied or re-parented first.
+func (s *CategoryService) DeleteCategory(ctx context.Context, id uuid.UUID) error {
+	if _, err := s.getCategory(ctx, id); err != nil {
+		return err
+	}
+
+	hasChildren, err := s.categoryRepo.HasChildren(ctx, id)
+	if err != nil {
+		return fmt.Errorf("failed to check subcategories: %w", err)
+	}
+	if hasChildren {
+		return errors.New("category has subcategories")
+	}
+
+	return s.categoryRepo.Delete(ctx, id)
+}
+
+// validateParent makes sure a category is not moved under itself or one of its descendants
+func (s *CategoryService) validateParent(ctx context.Context, categoryID, parentID uuid.UUID) error {
+	if parentID == categoryID {
+		return errors.New("category cannot be its own parent")
+	}
+
+	if _, err := s.getCategory(ctx, parentID); err != nil {
+		return errors.New("parent category not found")
+	}
+
+	descendants, err := s.categoryRepo.GetDescendantIDs(ctx, categoryID)
+	if err != nil {
+		return fmt.Errorf("failed to check category hierarchy: %w", err)
+	}
+	for _, id := range descendants {
+		if id == parentID {
+			return errors.New("category cannot be moved under its own subcategory")
+		}
+	}
+
+	return nil
+}
+
+func (s *CategoryService) uniqueSlug(ctx context.Context, name string, excludeID *uuid.UUID) (string, error) {
+	slug := generateSlug(name)
+
+	slugExists, err := s.categoryRepo.SlugExists(ctx, slug, excludeID)
+	if err != nil {
+		return "", fmt.Errorf("failed to check slug uniqueness: %w", err)
+	}
+	if slugExists {
+		slug = generateUniqueSlug(slug)
+	}
+
+	return slug, nil
+}
+
+func (s *CategoryService) getCategory(ctx context.Context, id uuid.UUID) (*model.Category, error) {
+	category, err := s.categoryRepo.GetByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, errors.New("category not found")
+		}
+		return nil, fmt.Errorf("failed to get category: %w", err)
+	}
+	return category, nil
+}
@@ -0,0 +1,330 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/imbivek08/hamropasal/internal/model"
+	"github.com/imbivek08/hamropasal/internal/repository"
+	"github.com/jackc/pgx/v5"
+)
+
+// CouponService manages promotion codes and works out what they are worth.
+// Carts only preview a coupon; checkout redeems it under a row lock so usage
+// limits hold when orders race for the last use.
+type CouponService struct {
+	couponRepo      *repository.CouponRepository
+	currencyService *CurrencyService
+	policy          *Policy
+}
+
+func NewCouponService(couponRepo *repository.CouponRepository, currencyService *CurrencyService, policy *Policy) *CouponService {
+	return &CouponService{
+		couponRepo:      couponRepo,
+		currencyService: currencyService,
+		policy:          policy,
+	}
+}
+
+// CreateCoupon creates a coupon. Vendors create coupons for their own shop;
+// admins create platform-wide coupons or coupons for any shop.
+func (s *CouponService) CreateCoupon(ctx context.Context, actor *Actor, req *model.CreateCouponRequest) (*model.Coupon, error) {
+	shopID := req.ShopID
+	if !actor.IsAdmin() {
+		if actor.ShopID == nil {
+			return nil, fmt.Errorf("%w: you don't have a shop", ErrForbidden)
+		}
+		shopID = actor.ShopID
+	}
+
+	code := strings.ToUpper(req.Code)
+	if _, err := s.couponRepo.GetByCode(ctx, code); err == nil {
+		return nil, errors.New("coupon code already exists")
+	} else if !errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("failed to check coupon code: %w", err)
+	}
+
+	now := time.Now()
+	startsAt := now
+	if req.StartsAt != nil {
+		startsAt = *req.StartsAt
+	}
+
+	coupon := &model.Coupon{
+		ID:           uuid.New(),
+		Code:         code,
+		Description:  req.Description,
+		Type:         req.Type,
+		Value:        req.Value,
+		MaxDiscount:  req.MaxDiscount,
+		MinSubtotal:  req.MinSubtotal,
+		ShopID:       shopID,
+		UsageLimit:   req.UsageLimit,
+		PerUserLimit: req.PerUserLimit,
+		StartsAt:     startsAt,
+		EndsAt:       req.EndsAt,
+		IsActive:     true,
+		CreatedBy:    &actor.UserID,
+		CreatedAt:    now,
+		UpdatedAt:    now,
+	}
+
+	if err := validateCoupon(coupon); err != nil {
+		return nil, err
+	}
+
+	if err := s.couponRepo.Create(ctx, coupon); err != nil {
+		return nil, fmt.Errorf("failed to create coupon: %w", err)
+	}
+
+	return coupon, nil
+}
+
+// ListCoupons lists every coupon for admins and the shop's coupons for vendors
+func (s *CouponService) ListCoupons(ctx context.Context, actor *Actor) ([]*model.Coupon, error) {
+	if actor.IsAdmin() {
+		return s.couponRepo.List(ctx, nil)
+	}
+	if actor.ShopID == nil {
+		return []*model.Coupon{}, nil
+	}
+	return s.couponRepo.List(ctx, actor.ShopID)
+}
+
+// UpdateCoupon changes a coupon's terms
+func (s *CouponService) UpdateCoupon(ctx context.Context, actor *Actor, couponID uuid.UUID, req *model.UpdateCouponRequest) (*model.Coupon, error) {
+	coupon, err := s.getOwnedCoupon(ctx, actor, couponID)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Description != nil {
+		coupon.Description = req.Description
+	}
+	if req.Value != nil {
+		coupon.Value = *req.Value
+	}
+	if req.MaxDiscount != nil {
+		coupon.MaxDiscount = req.MaxDiscount
+	}
+	if req.MinSubtotal != nil {
+		coupon.MinSubtotal = *req.MinSubtotal
+	}
+	if req.UsageLimit != nil {
+		coupon.UsageLimit = req.UsageLimit
+	}
+	if req.PerUserLimit != nil {
+		coupon.PerUserLimit = req.PerUserLimit
+	}
+	if req.StartsAt != nil {
+		coupon.StartsAt = *req.StartsAt
+	}
+	if req.EndsAt != nil {
+		coupon.EndsAt = req.EndsAt
+	}
+	if req.IsActive != nil {
+		coupon.IsActive = *req.IsActive
+	}
+
+	if err := validateCoupon(coupon); err != nil {
+		return nil, err
+	}
+
+	if err := s.couponRepo.Update(ctx, coupon); err != nil {
+		return nil, fmt.Errorf("failed to update coupon: %w", err)
+	}
+
+	return coupon, nil
+}
+
+// DeactivateCoupon stops a coupon from being applied. Past redemptions stay.
+func (s *CouponService) DeactivateCoupon(ctx context.Context, actor *Actor, couponID uuid.UUID) error {
+	coupon, err := s.getOwnedCoupon(ctx, actor, couponID)
+	if err != nil {
+		return err
+	}
+
+	coupon.IsActive = false
+	if err := s.couponRepo.Update(ctx, coupon); err != nil {
+		return fmt.Errorf("failed to deactivate coupon: %w", err)
+	}
+
+	return nil
+}
+
+// PreviewDiscount works out what a coupon code is worth for the given items.
+// Per-user limits are only checked for signed-in users (userID set); guests
+// meet them at checkout.
+func (s *CouponService) PreviewDiscount(ctx context.Context, code string, userID *uuid.UUID, items []model.CartItemWithProduct) (*model.CouponDiscount, error) {
+	coupon, err := s.couponRepo.GetByCode(ctx, code)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, errors.New("coupon not found")
+		}
+		return nil, fmt.Errorf("failed to get coupon: %w", err)
+	}
+
+	return s.evaluate(ctx, coupon, userID, cartShopSubtotals(items))
+}
+
+// ApplyAtCheckout locks the coupon and re-checks it against the cart being
+// ordered. It must run inside the checkout transaction, which then creates the
+// order and calls RedeemForOrder while the lock is still held.
+func (s *CouponService) ApplyAtCheckout(ctx context.Context, code string, userID uuid.UUID, items []model.CartItemWithProduct) (*model.CouponDiscount, error) {
+	coupon, err := s.couponRepo.LockByCode(ctx, code)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, errors.New("coupon not found")
+		}
+		return nil, fmt.Errorf("failed to get coupon: %w", err)
+	}
+
+	return s.evaluate(ctx, coupon, &userID, cartShopSubtotals(items))
+}
+
+// RedeemForOrder records that the order used the coupon applied at checkout
+func (s *CouponService) RedeemForOrder(ctx context.Context, discount *model.CouponDiscount, orderID, userID uuid.UUID) error {
+	if err := s.couponRepo.Redeem(ctx, discount.CouponID, orderID, userID, discount.Amount); err != nil {
+		return fmt.Errorf("failed to redeem coupon: %w", err)
+	}
+	return nil
+}
+
+// ReleaseForOrder gives back the coupon use of a cancelled order
+func (s *CouponService) ReleaseForOrder(ctx context.Context, orderID uuid.UUID) error {
+	if err := s.couponRepo.ReleaseForOrder(ctx, orderID); err != nil {
+		return fmt.Errorf("failed to release coupon: %w", err)
+	}
+	return nil
+}
+
+// evaluate checks a coupon's validity window and usage limits and computes the
+// discount over the items it applies to
+func (s *CouponService) evaluate(ctx context.Context, coupon *model.Coupon, userID *uuid.UUID, shopSubtotals map[uuid.UUID]model.Money) (*model.CouponDiscount, error) {
+	now := time.Now()
+	if !coupon.IsActive {
+		return nil, errors.New("coupon is not active")
+	}
+	if now.Before(coupon.StartsAt) {
+		return nil, errors.New("coupon is not valid yet")
+	}
+	if coupon.EndsAt != nil && now.After(*coupon.EndsAt) {
+		return nil, errors.New("coupon has expired")
+	}
+	if coupon.UsageLimit != nil && coupon.UsedCount >= *coupon.UsageLimit {
+		return nil, errors.New("coupon usage limit reached")
+	}
+
+	if userID != nil && coupon.PerUserLimit != nil {
+		used, err := s.couponRepo.CountUserRedemptions(ctx, coupon.ID, *userID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to check coupon usage: %w", err)
+		}
+		if used >= *coupon.PerUserLimit {
+			return nil, errors.New("you have already used this coupon")
+		}
+	}
+
+	// Shop coupons only count that shop's items
+	var eligible model.Money
+	for shopID, subtotal := range shopSubtotals {
+		if coupon.ShopID == nil || *coupon.ShopID == shopID {
+			eligible = eligible.Add(subtotal)
+		}
+	}
+	if eligible.IsZero() {
+		return nil, errors.New("coupon does not apply to items in your cart")
+	}
+
+	// Coupon amounts are in the coupon's currency, the items in the cart's
+	currency := eligible.CurrencyCode()
+	rates := model.ExchangeRates{}
+	if coupon.Currency != currency {
+		var err error
+		if rates, err = s.currencyService.Rates(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	minSubtotal, err := rates.Convert(coupon.MinSubtotal, currency)
+	if err != nil {
+		return nil, err
+	}
+	if eligible.Cmp(minSubtotal) < 0 {
+		return nil, fmt.Errorf("coupon requires a minimum subtotal of %s", minSubtotal)
+	}
+
+	discount := &model.CouponDiscount{
+		CouponID: coupon.ID,
+		Code:     coupon.Code,
+		ShopID:   coupon.ShopID,
+	}
+
+	switch coupon.Type {
+	case model.CouponTypePercentage:
+		discount.Amount = eligible.Percent(coupon.Value)
+		if coupon.MaxDiscount != nil {
+			maxDiscount, err := rates.Convert(*coupon.MaxDiscount, currency)
+			if err != nil {
+				return nil, err
+			}
+			discount.Amount = discount.Amount.Min(maxDiscount)
+		}
+	case model.CouponTypeFixed:
+		amount, err := rates.Convert(coupon.FixedAmount(), currency)
+		if err != nil {
+			return nil, err
+		}
+		discount.Amount = amount.Min(eligible)
+	case model.CouponTypeFreeShipping:
+		discount.FreeShipping = true
+	}
+
+	return discount, nil
+}
+
+func (s *CouponService) getOwnedCoupon(ctx context.Context, actor *Actor, couponID uuid.UUID) (*model.Coupon, error) {
+	coupon, err := s.couponRepo.GetByID(ctx, couponID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, errors.New("coupon not found")
+		}
+		return nil, fmt.Errorf("failed to get coupon: %w", err)
+	}
+
+	if err := s.policy.AuthorizeCoupon(actor, coupon); err != nil {
+		return nil, err
+	}
+
+	return coupon, nil
+}
+
+func validateCoupon(coupon *model.Coupon) error {
+	switch coupon.Type {
+	case model.CouponTypePercentage:
+		if coupon.Value <= 0 || coupon.Value > 100 {
+			return errors.New("percentage coupons need a value between 0 and 100")
+		}
+	case model.CouponTypeFixed:
+		if coupon.Value <= 0 {
+			return errors.New("fixed coupons need a positive value")
+		}
+	}
+	if coupon.EndsAt != nil && !coupon.EndsAt.After(coupon.StartsAt) {
+		return errors.New("ends_at must be after starts_at")
+	}
+	return nil
+}
+
+// cartShopSubtotals sums cart items per shop
+func cartShopSubtotals(items []model.CartItemWithProduct) map[uuid.UUID]model.Money {
+	subtotals := make(map[uuid.UUID]model.Money)
+	for _, item := range items {
+		subtotals[item.ShopID] = subtotals[item.ShopID].Add(item.Subtotal)
+	}
+	return subtotals
+}
@@ -0,0 +1,180 @@
+package service
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/imbivek08/hamropasal/internal/database"
+	"github.com/imbivek08/hamropasal/internal/model"
+	"github.com/imbivek08/hamropasal/internal/repository"
+)
+
+// CurrencyService maintains exchange rates against model.DefaultCurrency and
+// converts amounts between currencies
+type CurrencyService struct {
+	db       *database.Database
+	rateRepo *repository.ExchangeRateRepository
+}
+
+func NewCurrencyService(db *database.Database, rateRepo *repository.ExchangeRateRepository) *CurrencyService {
+	return &CurrencyService{
+		db:       db,
+		rateRepo: rateRepo,
+	}
+}
+
+// ListRates lists every exchange rate
+func (s *CurrencyService) ListRates(ctx context.Context) (*model.ExchangeRateList, error) {
+	rates, err := s.rateRepo.List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get exchange rates: %w", err)
+	}
+	return &model.ExchangeRateList{Base: model.DefaultCurrency, Rates: rates}, nil
+}
+
+// SetRate sets how many units of a currency one unit of the default currency
+// buys (admin only)
+func (s *CurrencyService) SetRate(ctx context.Context, currency string, rate float64) (*model.ExchangeRate, error) {
+	code, err := currencyCode(currency)
+	if err != nil {
+		return nil, err
+	}
+	if code == model.DefaultCurrency {
+		return nil, fmt.Errorf("%s is the base currency", code)
+	}
+
+	exchangeRate := &model.ExchangeRate{Currency: code, Rate: rate}
+	if err := s.rateRepo.Upsert(ctx, exchangeRate); err != nil {
+		return nil, fmt.Errorf("failed to save exchange rate: %w", err)
+	}
+
+	return exchangeRate, nil
+}
+
+// DeleteRate stops taking a currency (admin only). Currencies shops price in
+// cannot be removed.
+func (s *CurrencyService) DeleteRate(ctx context.Context, currency string) error {
+	code, err := currencyCode(currency)
+	if err != nil {
+		return err
+	}
+
+	inUse, err := s.rateRepo.InUse(ctx, code)
+	if err != nil {
+		return fmt.Errorf("failed to check exchange rate usage: %w", err)
+	}
+	if inUse {
+		return fmt.Errorf("%s is used by shops", code)
+	}
+
+	return s.rateRepo.Delete(ctx, code)
+}
+
+// ImportRates sets the rates listed in a CSV file of currency,rate lines,
+// with an optional header line. Either every rate is set or none is.
+func (s *CurrencyService) ImportRates(ctx context.Context, file io.Reader) (*model.ExchangeRateImportResult, error) {
+	reader := csv.NewReader(file)
+	reader.FieldsPerRecord = 2
+	reader.TrimLeadingSpace = true
+
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("invalid exchange rate file: %w", err)
+	}
+	if len(records) > 0 && strings.EqualFold(strings.TrimSpace(records[0][0]), "currency") {
+		records = records[1:]
+	}
+	if len(records) == 0 {
+		return nil, errors.New("exchange rate file has no rates")
+	}
+
+	rates := make([]*model.ExchangeRate, 0, len(records))
+	for i, record := range records {
+		code, err := currencyCode(record[0])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", i+1, err)
+		}
+		if code == model.DefaultCurrency {
+			continue
+		}
+		rate, err := strconv.ParseFloat(strings.TrimSpace(record[1]), 64)
+		if err != nil || rate <= 0 {
+			return nil, fmt.Errorf("line %d: invalid rate %q", i+1, record[1])
+		}
+		rates = append(rates, &model.ExchangeRate{Currency: code, Rate: rate})
+	}
+
+	err = s.db.WithTx(ctx, func(ctx context.Context) error {
+		for _, rate := range rates {
+			if err := s.rateRepo.Upsert(ctx, rate); err != nil {
+				return fmt.Errorf("failed to save exchange rate for %s: %w", rate.Currency, err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &model.ExchangeRateImportResult{Imported: len(rates)}, nil
+}
+
+// Rates returns the current exchange rates for converting amounts
+func (s *CurrencyService) Rates(ctx context.Context) (model.ExchangeRates, error) {
+	list, err := s.rateRepo.List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get exchange rates: %w", err)
+	}
+
+	rates := make(model.ExchangeRates, len(list))
+	for _, rate := range list {
+		rates[rate.Currency] = rate.Rate
+	}
+	return rates, nil
+}
+
+// Resolve checks a currency requested by a customer can be used, defaulting
+// to the default currency when none is requested
+func (s *CurrencyService) Resolve(ctx context.Context, currency string) (string, model.ExchangeRates, error) {
+	rates, err := s.Rates(ctx)
+	if err != nil {
+		return "", nil, err
+	}
+
+	if currency == "" {
+		return model.DefaultCurrency, rates, nil
+	}
+	code := strings.ToUpper(strings.TrimSpace(currency))
+	if !rates.Supports(code) {
+		return "", nil, fmt.Errorf("%w %s", model.ErrUnsupportedCurrency, code)
+	}
+	return code, rates, nil
+}
+
+// convertCartItems converts the prices and subtotals of cart items to one
+// currency, so they can be added up across shops
+func convertCartItems(rates model.ExchangeRates, items []model.CartItemWithProduct, currency string) error {
+	for i := range items {
+		price, err := rates.Convert(items[i].ProductPrice, currency)
+		if err != nil {
+			return err
+		}
+		items[i].ProductPrice = price
+		items[i].CalculateSubtotal()
+	}
+	return nil
+}
+
+// currencyCode normalises an ISO 4217 currency code
+func currencyCode(currency string) (string, error) {
+	code := strings.ToUpper(strings.TrimSpace(currency))
+	if len(code) != 3 || strings.Trim(code, "ABCDEFGHIJKLMNOPQRSTUVWXYZ") != "" {
+		return "", fmt.Errorf("invalid currency %q", currency)
+	}
+	return code, nil
+}
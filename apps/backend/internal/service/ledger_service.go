@@ -0,0 +1,596 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/imbivek08/hamropasal/internal/database"
+	"github.com/imbivek08/hamropasal/internal/model"
+	"github.com/imbivek08/hamropasal/internal/repository"
+	"github.com/jackc/pgx/v5"
+)
+
+// LedgerService keeps the double-entry ledger of what the platform owes each
+// shop. A paid order credits its shops with what the customer paid for their
+// lines and shipping, less the platform's commission on each line; refunds
+// debit them and return the commission; payouts settle the balance that has
+// cleared the holding period.
+type LedgerService struct {
+	db             *database.Database
+	ledgerRepo     *repository.LedgerRepository
+	commissionRepo *repository.CommissionRepository
+	orderRepo      *repository.OrderRepository
+	fulfilmentRepo *repository.FulfilmentRepository
+	categoryRepo   *repository.CategoryRepository
+	shopRepo       *repository.ShopRepository
+	holdingPeriod  time.Duration
+}
+
+func NewLedgerService(
+	db *database.Database,
+	ledgerRepo *repository.LedgerRepository,
+	commissionRepo *repository.CommissionRepository,
+	orderRepo *repository.OrderRepository,
+	fulfilmentRepo *repository.FulfilmentRepository,
+	categoryRepo *repository.CategoryRepository,
+	shopRepo *repository.ShopRepository,
+	holdingPeriod time.Duration,
+) *LedgerService {
+	return &LedgerService{
+		db:             db,
+		ledgerRepo:     ledgerRepo,
+		commissionRepo: commissionRepo,
+		orderRepo:      orderRepo,
+		fulfilmentRepo: fulfilmentRepo,
+		categoryRepo:   categoryRepo,
+		shopRepo:       shopRepo,
+		holdingPeriod:  holdingPeriod,
+	}
+}
+
+// ListCommissionRates lists the default, category and shop rates (admin only)
+func (s *LedgerService) ListCommissionRates(ctx context.Context) ([]*model.CommissionRate, error) {
+	rates, err := s.commissionRepo.List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get commission rates: %w", err)
+	}
+	return rates, nil
+}
+
+// SetDefaultCommission sets the rate of sales no other rate applies to (admin only)
+func (s *LedgerService) SetDefaultCommission(ctx context.Context, rate float64) (*model.CommissionRate, error) {
+	return s.commissionRepo.SetDefault(ctx, rate)
+}
+
+// SetCategoryCommission sets the rate of a category's sales, subcategories
+// included (admin only)
+func (s *LedgerService) SetCategoryCommission(ctx context.Context, categoryID uuid.UUID, rate float64) (*model.CommissionRate, error) {
+	if _, err := s.categoryRepo.GetByID(ctx, categoryID); err != nil {
+		return nil, errors.New("category not found")
+	}
+	return s.commissionRepo.SetForCategory(ctx, categoryID, rate)
+}
+
+// DeleteCategoryCommission removes a category's rate (admin only)
+func (s *LedgerService) DeleteCategoryCommission(ctx context.Context, categoryID uuid.UUID) error {
+	return s.commissionRepo.DeleteForCategory(ctx, categoryID)
+}
+
+// SetShopCommission overrides the rate of every sale of a shop (admin only)
+func (s *LedgerService) SetShopCommission(ctx context.Context, shopID uuid.UUID, rate float64) (*model.CommissionRate, error) {
+	if _, err := s.shopRepo.GetByID(ctx, shopID); err != nil {
+		return nil, errors.New("shop not found")
+	}
+	return s.commissionRepo.SetForShop(ctx, shopID, rate)
+}
+
+// DeleteShopCommission removes a shop's override (admin only)
+func (s *LedgerService) DeleteShopCommission(ctx context.Context, shopID uuid.UUID) error {
+	return s.commissionRepo.DeleteForShop(ctx, shopID)
+}
+
+// RecordSale credits the shops of a newly paid order. The credit, net of
+// commission, becomes available once the holding period has passed. An
+// order's sale is posted once; later calls do nothing.
+func (s *LedgerService) RecordSale(ctx context.Context, orderID uuid.UUID) error {
+	return s.db.WithTx(ctx, func(ctx context.Context) error {
+		posted, err := s.ledgerRepo.HasSale(ctx, orderID)
+		if err != nil {
+			return fmt.Errorf("failed to check ledger: %w", err)
+		}
+		if posted {
+			return nil
+		}
+
+		order, err := s.orderRepo.GetByID(ctx, orderID)
+		if err != nil {
+			return fmt.Errorf("order not found: %w", err)
+		}
+
+		items, err := s.orderRepo.GetOrderItems(ctx, orderID)
+		if err != nil {
+			return fmt.Errorf("failed to get order items: %w", err)
+		}
+
+		fulfilments, err := s.fulfilmentRepo.GetByOrderID(ctx, orderID)
+		if err != nil {
+			return fmt.Errorf("failed to get fulfilments: %w", err)
+		}
+
+		itemCategories, err := s.orderRepo.GetItemCategories(ctx, orderID)
+		if err != nil {
+			return fmt.Errorf("failed to get item categories: %w", err)
+		}
+
+		rates, err := s.commissionRepo.List(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to get commission rates: %w", err)
+		}
+
+		categories, err := s.categoryRepo.GetAll(ctx, false)
+		if err != nil {
+			return fmt.Errorf("failed to get categories: %w", err)
+		}
+		parents := make(map[uuid.UUID]*uuid.UUID, len(categories))
+		for _, category := range categories {
+			parents[category.ID] = category.ParentID
+		}
+
+		now := time.Now()
+		posting := newLedgerTransaction(order.Currency, now, &order.ID)
+		heldUntil := now.Add(s.holdingPeriod)
+
+		sales := saleShares(order, items, fulfilments)
+		var total model.Money
+		for _, shopID := range shopsOf(items) {
+			if sales[shopID].IsPositive() {
+				posting.add(model.LedgerAccountShop, &shopID, model.LedgerEntrySale, sales[shopID], heldUntil)
+				total = total.Add(sales[shopID])
+			}
+		}
+		posting.add(model.LedgerAccountGateway, nil, model.LedgerEntrySale, total.Mul(-1), now)
+
+		// Commission is charged on each line net of discount and tax
+		for _, item := range items {
+			var categoryID *uuid.UUID
+			if id, ok := itemCategories[item.ID]; ok {
+				categoryID = &id
+			}
+			rate := commissionRate(rates, item.ShopID, categoryChain(parents, categoryID))
+			commission := item.TaxableAmount.Percent(rate)
+			if !commission.IsPositive() {
+				continue
+			}
+
+			itemID, shopID := item.ID, item.ShopID
+			posting.add(model.LedgerAccountShop, &shopID, model.LedgerEntryCommission, commission.Mul(-1), heldUntil).OrderItemID = &itemID
+			posting.add(model.LedgerAccountPlatform, nil, model.LedgerEntryCommission, commission, now).OrderItemID = &itemID
+		}
+
+		if err := s.ledgerRepo.CreateEntries(ctx, posting.entries); err != nil {
+			return fmt.Errorf("failed to record sale: %w", err)
+		}
+		return nil
+	})
+}
+
+// RecordRefund debits the shops of a refunded order with their part of a
+// succeeded refund and returns the commission on the refunded lines. Refunds
+// of orders whose sale was never posted are skipped. A refund is posted once;
+// later calls do nothing.
+func (s *LedgerService) RecordRefund(ctx context.Context, refund *model.Refund) error {
+	return s.db.WithTx(ctx, func(ctx context.Context) error {
+		posted, err := s.ledgerRepo.HasRefund(ctx, refund.ID)
+		if err != nil {
+			return fmt.Errorf("failed to check ledger: %w", err)
+		}
+		if posted {
+			return nil
+		}
+
+		sales, err := s.ledgerRepo.GetOrderSales(ctx, refund.OrderID)
+		if err != nil {
+			return fmt.Errorf("failed to get order sales: %w", err)
+		}
+		if len(sales) == 0 {
+			return nil
+		}
+
+		order, err := s.orderRepo.GetByID(ctx, refund.OrderID)
+		if err != nil {
+			return fmt.Errorf("order not found: %w", err)
+		}
+
+		items, err := s.orderRepo.GetOrderItems(ctx, refund.OrderID)
+		if err != nil {
+			return fmt.Errorf("failed to get order items: %w", err)
+		}
+
+		charged, remaining, err := s.ledgerRepo.GetOrderCommissions(ctx, refund.OrderID)
+		if err != nil {
+			return fmt.Errorf("failed to get order commissions: %w", err)
+		}
+
+		shares, returned := refundShares(refund, items, sales, charged)
+
+		now := time.Now()
+		posting := newLedgerTransaction(order.Currency, now, &order.ID)
+		var total model.Money
+		for _, shopID := range shopsOf(items) {
+			if shares[shopID].IsPositive() {
+				posting.add(model.LedgerAccountShop, &shopID, model.LedgerEntryRefund, shares[shopID].Mul(-1), now)
+				total = total.Add(shares[shopID])
+			}
+		}
+		if total.IsPositive() {
+			posting.add(model.LedgerAccountGateway, nil, model.LedgerEntryRefund, total, now)
+		}
+
+		for _, item := range items {
+			commission := returned[item.ID].Min(remaining[item.ID])
+			if !commission.IsPositive() {
+				continue
+			}
+
+			itemID, shopID := item.ID, item.ShopID
+			posting.add(model.LedgerAccountShop, &shopID, model.LedgerEntryCommission, commission, now).OrderItemID = &itemID
+			posting.add(model.LedgerAccountPlatform, nil, model.LedgerEntryCommission, commission.Mul(-1), now).OrderItemID = &itemID
+		}
+
+		if len(posting.entries) == 0 {
+			return nil
+		}
+		for i := range posting.entries {
+			posting.entries[i].RefundID = &refund.ID
+		}
+
+		if err := s.ledgerRepo.CreateEntries(ctx, posting.entries); err != nil {
+			return fmt.Errorf("failed to record refund: %w", err)
+		}
+		return nil
+	})
+}
+
+// GetShopBalance returns what the platform owes a shop, per currency
+func (s *LedgerService) GetShopBalance(ctx context.Context, shopID uuid.UUID) (*model.ShopBalanceResponse, error) {
+	if _, err := s.shopRepo.GetByID(ctx, shopID); err != nil {
+		return nil, errors.New("shop not found")
+	}
+
+	balances, err := s.ledgerRepo.GetBalances(ctx, shopID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get balances: %w", err)
+	}
+
+	return &model.ShopBalanceResponse{
+		ShopID:      shopID,
+		HoldingDays: int(s.holdingPeriod / (24 * time.Hour)),
+		Balances:    balances,
+	}, nil
+}
+
+// ListShopEntries lists a shop's ledger entries, newest first
+func (s *LedgerService) ListShopEntries(ctx context.Context, shopID uuid.UUID, page, pageSize int) (*model.LedgerEntryListResponse, error) {
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	if _, err := s.shopRepo.GetByID(ctx, shopID); err != nil {
+		return nil, errors.New("shop not found")
+	}
+
+	entries, total, err := s.ledgerRepo.ListEntries(ctx, shopID, page, pageSize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list ledger entries: %w", err)
+	}
+
+	return &model.LedgerEntryListResponse{
+		Entries:    entries,
+		Total:      total,
+		Page:       page,
+		PageSize:   pageSize,
+		TotalPages: int(math.Ceil(float64(total) / float64(pageSize))),
+	}, nil
+}
+
+// CreatePayoutBatch pays out the available balance of every shop, or of the
+// requested shops, in each currency they are owed (admin only). The payouts
+// are debited straight away, so the balance cannot be paid out twice.
+func (s *LedgerService) CreatePayoutBatch(ctx context.Context, actorID uuid.UUID, req *model.CreatePayoutBatchRequest) (*model.PayoutBatch, error) {
+	var batch *model.PayoutBatch
+	err := s.db.WithTx(ctx, func(ctx context.Context) error {
+		if err := s.ledgerRepo.LockPayouts(ctx); err != nil {
+			return fmt.Errorf("failed to lock payouts: %w", err)
+		}
+
+		payouts, err := s.ledgerRepo.GetAvailableBalances(ctx, req.ShopIDs)
+		if err != nil {
+			return fmt.Errorf("failed to get available balances: %w", err)
+		}
+		if len(payouts) == 0 {
+			return errors.New("no balance available for payout")
+		}
+
+		now := time.Now()
+		batch = &model.PayoutBatch{
+			ID:        uuid.New(),
+			CreatedBy: &actorID,
+			Payouts:   payouts,
+			CreatedAt: now,
+		}
+
+		var entries []model.LedgerEntry
+		for _, payout := range payouts {
+			payout.ID = uuid.New()
+			payout.BatchID = batch.ID
+			payout.Status = model.PayoutStatusPending
+			payout.CreatedAt = now
+			payout.UpdatedAt = now
+			entries = append(entries, payoutEntries(payout, payout.Amount, now)...)
+		}
+
+		if err := s.ledgerRepo.CreateBatch(ctx, batch); err != nil {
+			return fmt.Errorf("failed to create payout batch: %w", err)
+		}
+		if err := s.ledgerRepo.CreateEntries(ctx, entries); err != nil {
+			return fmt.Errorf("failed to record payouts: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.ledgerRepo.GetBatch(ctx, batch.ID)
+}
+
+// ListPayoutBatches lists payout batches, newest first (admin only)
+func (s *LedgerService) ListPayoutBatches(ctx context.Context, page, pageSize int) (*model.PayoutBatchListResponse, error) {
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	batches, total, err := s.ledgerRepo.ListBatches(ctx, page, pageSize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list payout batches: %w", err)
+	}
+
+	return &model.PayoutBatchListResponse{
+		Batches:    batches,
+		Total:      total,
+		Page:       page,
+		PageSize:   pageSize,
+		TotalPages: int(math.Ceil(float64(total) / float64(pageSize))),
+	}, nil
+}
+
+// GetPayoutBatch retrieves a payout batch with its payouts (admin only)
+func (s *LedgerService) GetPayoutBatch(ctx context.Context, batchID uuid.UUID) (*model.PayoutBatch, error) {
+	batch, err := s.ledgerRepo.GetBatch(ctx, batchID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, errors.New("payout batch not found")
+		}
+		return nil, fmt.Errorf("failed to get payout batch: %w", err)
+	}
+	return batch, nil
+}
+
+// UpdatePayoutStatus records whether a pending payout reached the shop (admin
+// only). A failed payout is credited back to the shop's balance.
+func (s *LedgerService) UpdatePayoutStatus(ctx context.Context, payoutID uuid.UUID, req *model.UpdatePayoutStatusRequest) (*model.Payout, error) {
+	err := s.db.WithTx(ctx, func(ctx context.Context) error {
+		payout, err := s.ledgerRepo.GetPayout(ctx, payoutID)
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return errors.New("payout not found")
+			}
+			return fmt.Errorf("failed to get payout: %w", err)
+		}
+
+		var transitioned bool
+		if req.Status == model.PayoutStatusPaid {
+			transitioned, err = s.ledgerRepo.MarkPayoutPaid(ctx, payoutID)
+		} else {
+			transitioned, err = s.ledgerRepo.MarkPayoutFailed(ctx, payoutID, req.FailureReason)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to update payout: %w", err)
+		}
+		if !transitioned {
+			return fmt.Errorf("payout is already %s", payout.Status)
+		}
+
+		if req.Status == model.PayoutStatusFailed {
+			if err := s.ledgerRepo.CreateEntries(ctx, payoutEntries(payout, payout.Amount.Mul(-1), time.Now())); err != nil {
+				return fmt.Errorf("failed to return failed payout: %w", err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.ledgerRepo.GetPayout(ctx, payoutID)
+}
+
+// ledgerTransaction collects the entries of one balanced transaction
+type ledgerTransaction struct {
+	id       uuid.UUID
+	currency string
+	now      time.Time
+	orderID  *uuid.UUID
+	entries  []model.LedgerEntry
+}
+
+func newLedgerTransaction(currency string, now time.Time, orderID *uuid.UUID) *ledgerTransaction {
+	return &ledgerTransaction{id: uuid.New(), currency: currency, now: now, orderID: orderID}
+}
+
+// add appends an entry and returns it for further references to be set
+func (t *ledgerTransaction) add(account model.LedgerAccount, shopID *uuid.UUID, entryType model.LedgerEntryType, amount model.Money, availableAt time.Time) *model.LedgerEntry {
+	t.entries = append(t.entries, model.LedgerEntry{
+		ID:            uuid.New(),
+		TransactionID: t.id,
+		Account:       account,
+		ShopID:        shopID,
+		Type:          entryType,
+		Amount:        amount,
+		Currency:      t.currency,
+		OrderID:       t.orderID,
+		AvailableAt:   availableAt,
+		CreatedAt:     t.now,
+	})
+	return &t.entries[len(t.entries)-1]
+}
+
+// payoutEntries moves amount from the shop's balance to the money leaving the
+// gateway account; a negative amount returns a failed payout
+func payoutEntries(payout *model.Payout, amount model.Money, now time.Time) []model.LedgerEntry {
+	posting := newLedgerTransaction(payout.Currency, now, nil)
+	shopID, payoutID := payout.ShopID, payout.ID
+	posting.add(model.LedgerAccountShop, &shopID, model.LedgerEntryPayout, amount.Mul(-1), now).PayoutID = &payoutID
+	posting.add(model.LedgerAccountGateway, nil, model.LedgerEntryPayout, amount, now).PayoutID = &payoutID
+	return posting.entries
+}
+
+// saleShares splits what the customer paid for an order between its shops:
+// each shop's lines after discount with their tax, and its shipping less its
+// share of any shipping a coupon waived
+func saleShares(order *model.Order, items []model.OrderItemWithDetails, fulfilments []model.Fulfilment) map[uuid.UUID]model.Money {
+	shares := make(map[uuid.UUID]model.Money)
+	var lines model.Money
+	for _, item := range items {
+		paid := item.TaxableAmount.Add(item.TaxAmount)
+		shares[item.ShopID] = shares[item.ShopID].Add(paid)
+		lines = lines.Add(paid)
+	}
+
+	// Whatever the lines and shipping come to beyond the total was waived shipping
+	waived := lines.Add(order.ShippingCost).Sub(order.Total).Max(model.Money{}).Min(order.ShippingCost)
+
+	weights := make([]model.Money, len(fulfilments))
+	for i, f := range fulfilments {
+		weights[i] = f.ShippingCost
+	}
+	waivedShares := waived.Allocate(weights)
+	for i, f := range fulfilments {
+		shares[f.ShopID] = shares[f.ShopID].Add(f.ShippingCost.Sub(waivedShares[i]))
+	}
+
+	return shares
+}
+
+// refundShares splits a refund between the shops of its order and works out
+// the commission to return per line. Refunded lines go against their shop
+// and return their share of the line's commission. The rest of the refund,
+// such as shipping, goes against the shops in proportion to their sales; when
+// the refund names no lines at all, as for refunds made at the gateway, it
+// returns commission in the same proportion.
+func refundShares(refund *model.Refund, items []model.OrderItemWithDetails, sales, charged map[uuid.UUID]model.Money) (shares, returned map[uuid.UUID]model.Money) {
+	shares = make(map[uuid.UUID]model.Money)
+	returned = make(map[uuid.UUID]model.Money)
+
+	byID := make(map[uuid.UUID]model.OrderItemWithDetails, len(items))
+	for _, item := range items {
+		byID[item.ID] = item
+	}
+
+	var itemised model.Money
+	for _, refundItem := range refund.Items {
+		item, ok := byID[refundItem.OrderItemID]
+		if !ok || item.Quantity == 0 {
+			continue
+		}
+		shares[item.ShopID] = shares[item.ShopID].Add(refundItem.Amount)
+		itemised = itemised.Add(refundItem.Amount)
+		returned[item.ID] = returned[item.ID].Add(charged[item.ID].MulRate(float64(refundItem.Quantity) / float64(item.Quantity)))
+	}
+
+	rest := refund.Amount.Sub(itemised)
+	if !rest.IsPositive() {
+		return shares, returned
+	}
+
+	var shops []uuid.UUID
+	var weights []model.Money
+	for _, shopID := range shopsOf(items) {
+		if sales[shopID].IsPositive() {
+			shops = append(shops, shopID)
+			weights = append(weights, sales[shopID])
+		}
+	}
+
+	restShares := rest.Allocate(weights)
+	for i, shopID := range shops {
+		shares[shopID] = shares[shopID].Add(restShares[i])
+		if len(refund.Items) > 0 {
+			continue
+		}
+		proportion := float64(restShares[i].Amount) / float64(sales[shopID].Amount)
+		for _, item := range items {
+			if item.ShopID == shopID {
+				returned[item.ID] = returned[item.ID].Add(charged[item.ID].MulRate(proportion))
+			}
+		}
+	}
+
+	return shares, returned
+}
+
+// commissionRate picks the rate of a line: the shop's override, else the rate
+// of the nearest category in chain, else the platform default
+func commissionRate(rates []*model.CommissionRate, shopID uuid.UUID, chain []uuid.UUID) float64 {
+	var best *model.CommissionRate
+	bestDepth := 0
+	for _, rate := range rates {
+		var depth int
+		switch {
+		case rate.ShopID != nil:
+			if *rate.ShopID != shopID {
+				continue
+			}
+			depth = -1
+		case rate.CategoryID != nil:
+			depth = indexOfUUID(chain, *rate.CategoryID)
+			if depth < 0 {
+				continue
+			}
+		default:
+			depth = len(chain)
+		}
+
+		if best == nil || depth < bestDepth {
+			best, bestDepth = rate, depth
+		}
+	}
+
+	if best == nil {
+		return 0
+	}
+	return best.Rate
+}
+
+// shopsOf lists the shops of order lines in the order they first appear
+func shopsOf(items []model.OrderItemWithDetails) []uuid.UUID {
+	seen := make(map[uuid.UUID]bool)
+	var shops []uuid.UUID
+	for _, item := range items {
+		if !seen[item.ShopID] {
+			seen[item.ShopID] = true
+			shops = append(shops, item.ShopID)
+		}
+	}
+	return shops
+}
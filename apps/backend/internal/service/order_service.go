@@ -2,43 +2,111 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"log"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/imbivek08/hamropasal/internal/database"
+	"github.com/imbivek08/hamropasal/internal/jobs"
 	"github.com/imbivek08/hamropasal/internal/model"
+	"github.com/imbivek08/hamropasal/internal/payment"
 	"github.com/imbivek08/hamropasal/internal/repository"
+	"github.com/jackc/pgx/v5"
 )
 
+// JobClosePayment is the job type closing a payment through an order's
+// previous payment method
+const JobClosePayment = "payment.close"
+
+// ClosePaymentJob is the payload of a JobClosePayment job
+type ClosePaymentJob struct {
+	OrderID          uuid.UUID `json:"order_id"`
+	PaymentMethod    string    `json:"payment_method"`
+	PaymentReference *string   `json:"payment_reference,omitempty"`
+}
+
+// ErrOrderNotFound is wrapped when an order, or the shop's part of it, does
+// not exist
+var ErrOrderNotFound = errors.New("order not found")
+
 type OrderService struct {
-	orderRepo   *repository.OrderRepository
-	cartRepo    *repository.CartRepository
-	productRepo *repository.ProductRepository
-	addressRepo *repository.AddressRepository
+	db                 *database.Database
+	payments           *payment.Registry
+	orderRepo          *repository.OrderRepository
+	fulfilmentRepo     *repository.FulfilmentRepository
+	cartRepo           *repository.CartRepository
+	productRepo        *repository.ProductRepository
+	addressRepo        *repository.AddressRepository
+	reservationService *StockReservationService
+	couponService      *CouponService
+	shippingService    *ShippingService
+	taxService         *TaxService
+	currencyService    *CurrencyService
+	ledgerService      *LedgerService
+	queue              *jobs.Queue
+	policy             *Policy
 }
 
 func NewOrderService(
+	db *database.Database,
+	payments *payment.Registry,
 	orderRepo *repository.OrderRepository,
+	fulfilmentRepo *repository.FulfilmentRepository,
 	cartRepo *repository.CartRepository,
 	productRepo *repository.ProductRepository,
 	addressRepo *repository.AddressRepository,
+	reservationService *StockReservationService,
+	couponService *CouponService,
+	shippingService *ShippingService,
+	taxService *TaxService,
+	currencyService *CurrencyService,
+	ledgerService *LedgerService,
+	queue *jobs.Queue,
+	policy *Policy,
 ) *OrderService {
 	return &OrderService{
-		orderRepo:   orderRepo,
-		cartRepo:    cartRepo,
-		productRepo: productRepo,
-		addressRepo: addressRepo,
+		db:                 db,
+		payments:           payments,
+		orderRepo:          orderRepo,
+		fulfilmentRepo:     fulfilmentRepo,
+		cartRepo:           cartRepo,
+		productRepo:        productRepo,
+		addressRepo:        addressRepo,
+		reservationService: reservationService,
+		couponService:      couponService,
+		shippingService:    shippingService,
+		taxService:         taxService,
+		currencyService:    currencyService,
+		ledgerService:      ledgerService,
+		queue:              queue,
+		policy:             policy,
 	}
 }
 
 // CreateOrderFromCart creates an order from user's cart
 func (s *OrderService) CreateOrderFromCart(ctx context.Context, userID uuid.UUID, req *model.CreateOrderRequest) (*model.OrderResponse, error) {
+	// The payment method decides whether the order waits for its payment
+	provider, err := s.payments.Get(req.PaymentMethod)
+	if err != nil {
+		return nil, err
+	}
+	paymentMethod := provider.Name()
+
 	// Get user's cart
 	cart, err := s.cartRepo.GetCartByUserID(ctx, userID)
 	if err != nil {
 		return nil, fmt.Errorf("cart not found")
 	}
 
+	// The whole order is paid in one currency
+	currency, rates, err := s.currencyService.Resolve(ctx, req.Currency)
+	if err != nil {
+		return nil, err
+	}
+
 	// Get cart items with product details
 	cartItems, err := s.cartRepo.GetCartWithItems(ctx, cart.ID)
 	if err != nil {
@@ -49,179 +117,261 @@ func (s *OrderService) CreateOrderFromCart(ctx context.Context, userID uuid.UUID
 		return nil, fmt.Errorf("cart is empty")
 	}
 
-	// Validate stock availability for all items
+	// Lock the prices at the current exchange rates
+	if err := convertCartItems(rates, cartItems, currency); err != nil {
+		return nil, err
+	}
+
+	// Validate stock availability for all items. Items carry the current
+	// availability and stock of their product, or of their variant.
 	for _, item := range cartItems {
-		product, err := s.productRepo.GetByID(ctx, item.ProductID)
-		if err != nil {
-			return nil, fmt.Errorf("product %s not found", item.ProductName)
+		if !item.IsActive {
+			return nil, fmt.Errorf("product %s is no longer available", item.DisplayName())
 		}
 
-		if !product.IsActive {
-			return nil, fmt.Errorf("product %s is no longer available", item.ProductName)
+		if item.StockQuantity < item.Quantity {
+			return nil, fmt.Errorf("insufficient stock for %s: only %d available", item.DisplayName(), item.StockQuantity)
 		}
+	}
 
-		if product.StockQuantity < item.Quantity {
-			return nil, fmt.Errorf("insufficient stock for %s: only %d available", item.ProductName, product.StockQuantity)
+	// Addresses, order, items, stock and cart are written as one unit of work so
+	// a failure midway leaves no partial order or half-decremented stock
+	var order *model.Order
+	err = s.db.WithTx(ctx, func(ctx context.Context) error {
+		// Resolve shipping address
+		var shippingAddress *model.Address
+
+		if req.ShippingAddressID != nil {
+			// Use an existing saved address
+			address, err := s.addressRepo.GetByID(ctx, *req.ShippingAddressID)
+			if err != nil {
+				return fmt.Errorf("shipping address not found")
+			}
+			if address.UserID != userID {
+				return fmt.Errorf("shipping address not found")
+			}
+			shippingAddress = address
+		} else if req.ShippingAddress != nil {
+			// If marking as default, unset existing defaults first
+			if req.ShippingAddress.IsDefault {
+				if err := s.addressRepo.UnsetDefaultForUser(ctx, userID); err != nil {
+					return fmt.Errorf("failed to unset default address: %w", err)
+				}
+			}
+
+			// Create a new address from the provided input
+			shippingAddress = &model.Address{
+				ID:           uuid.New(),
+				UserID:       userID,
+				FullName:     req.ShippingAddress.FullName,
+				Phone:        req.ShippingAddress.Phone,
+				AddressLine1: req.ShippingAddress.AddressLine1,
+				AddressLine2: req.ShippingAddress.AddressLine2,
+				City:         req.ShippingAddress.City,
+				State:        req.ShippingAddress.State,
+				PostalCode:   req.ShippingAddress.PostalCode,
+				Country:      req.ShippingAddress.Country,
+				IsDefault:    req.ShippingAddress.IsDefault,
+				AddressType:  "shipping",
+				CreatedAt:    time.Now(),
+				UpdatedAt:    time.Now(),
+			}
+
+			if err := s.orderRepo.CreateAddress(ctx, shippingAddress); err != nil {
+				return fmt.Errorf("failed to create shipping address: %w", err)
+			}
+		} else {
+			return fmt.Errorf("shipping address is required: provide shipping_address_id or shipping_address")
 		}
-	}
 
-	// Resolve shipping address
-	var shippingAddress *model.Address
+		// Create billing address
+		var billingAddressID *uuid.UUID
+		if req.UseSameAddress {
+			billingAddressID = &shippingAddress.ID
+		} else if req.BillingAddress != nil {
+			billingAddress := &model.Address{
+				ID:           uuid.New(),
+				UserID:       userID,
+				FullName:     req.BillingAddress.FullName,
+				Phone:        req.BillingAddress.Phone,
+				AddressLine1: req.BillingAddress.AddressLine1,
+				AddressLine2: req.BillingAddress.AddressLine2,
+				City:         req.BillingAddress.City,
+				State:        req.BillingAddress.State,
+				PostalCode:   req.BillingAddress.PostalCode,
+				Country:      req.BillingAddress.Country,
+				IsDefault:    false,
+				AddressType:  "billing",
+				CreatedAt:    time.Now(),
+				UpdatedAt:    time.Now(),
+			}
 
-	if req.ShippingAddressID != nil {
-		// Use an existing saved address
-		shippingAddress, err = s.addressRepo.GetByID(ctx, *req.ShippingAddressID)
+			if err := s.orderRepo.CreateAddress(ctx, billingAddress); err != nil {
+				return fmt.Errorf("failed to create billing address: %w", err)
+			}
+			billingAddressID = &billingAddress.ID
+		}
+
+		// Calculate totals
+		var subtotal model.Money
+		for _, item := range cartItems {
+			subtotal = subtotal.Add(item.Subtotal)
+		}
+
+		// Each shop ships its items with the chosen method, or its cheapest one
+		destination := model.DestinationOf(shippingAddress)
+		shipping, err := s.shippingService.SelectForCheckout(ctx, cartItems, destination, req.ShippingMethodIDs)
 		if err != nil {
-			return nil, fmt.Errorf("shipping address not found")
-		}
-		if shippingAddress.UserID != userID {
-			return nil, fmt.Errorf("shipping address not found")
-		}
-	} else if req.ShippingAddress != nil {
-		// If marking as default, unset existing defaults first
-		if req.ShippingAddress.IsDefault {
-			if err := s.addressRepo.UnsetDefaultForUser(ctx, userID); err != nil {
-				return nil, fmt.Errorf("failed to unset default address: %w", err)
-			}
-		}
-
-		// Create a new address from the provided input
-		shippingAddress = &model.Address{
-			ID:           uuid.New(),
-			UserID:       userID,
-			FullName:     req.ShippingAddress.FullName,
-			Phone:        req.ShippingAddress.Phone,
-			AddressLine1: req.ShippingAddress.AddressLine1,
-			AddressLine2: req.ShippingAddress.AddressLine2,
-			City:         req.ShippingAddress.City,
-			State:        req.ShippingAddress.State,
-			PostalCode:   req.ShippingAddress.PostalCode,
-			Country:      req.ShippingAddress.Country,
-			IsDefault:    req.ShippingAddress.IsDefault,
-			AddressType:  "shipping",
-			CreatedAt:    time.Now(),
-			UpdatedAt:    time.Now(),
-		}
-
-		if err := s.orderRepo.CreateAddress(ctx, shippingAddress); err != nil {
-			return nil, fmt.Errorf("failed to create shipping address: %w", err)
-		}
-	} else {
-		return nil, fmt.Errorf("shipping address is required: provide shipping_address_id or shipping_address")
-	}
-
-	// Create billing address
-	var billingAddressID *uuid.UUID
-	if req.UseSameAddress {
-		billingAddressID = &shippingAddress.ID
-	} else if req.BillingAddress != nil {
-		billingAddress := &model.Address{
-			ID:           uuid.New(),
-			UserID:       userID,
-			FullName:     req.BillingAddress.FullName,
-			Phone:        req.BillingAddress.Phone,
-			AddressLine1: req.BillingAddress.AddressLine1,
-			AddressLine2: req.BillingAddress.AddressLine2,
-			City:         req.BillingAddress.City,
-			State:        req.BillingAddress.State,
-			PostalCode:   req.BillingAddress.PostalCode,
-			Country:      req.BillingAddress.Country,
-			IsDefault:    false,
-			AddressType:  "billing",
-			CreatedAt:    time.Now(),
-			UpdatedAt:    time.Now(),
-		}
-
-		if err := s.orderRepo.CreateAddress(ctx, billingAddress); err != nil {
-			return nil, fmt.Errorf("failed to create billing address: %w", err)
-		}
-		billingAddressID = &billingAddress.ID
-	}
-
-	// Calculate totals
-	var subtotal float64
-	for _, item := range cartItems {
-		subtotal += item.Subtotal
-	}
+			return err
+		}
 
-	shippingCost := 0.0 // TODO: Calculate based on location/weight
-	tax := 0.0          // TODO: Calculate based on region
-	discount := 0.0
-	total := subtotal + shippingCost + tax - discount
+		var shippingCost model.Money
+		for _, option := range shipping {
+			shippingCost = shippingCost.Add(option.Cost)
+		}
 
-	// Determine order status and payment status based on payment method
-	orderStatus := model.OrderStatusConfirmed
-	paymentStatus := model.PaymentStatusPending
+		var discount model.Money
 
-	// For Stripe payments, order stays pending until payment is confirmed via webhook
-	if req.PaymentMethod == "stripe" {
-		orderStatus = model.OrderStatusPending
-		paymentStatus = model.PaymentStatusPending
-	} else if req.PaymentMethod == "COD" {
-		// COD orders are confirmed immediately, payment will be collected on delivery
-		paymentStatus = model.PaymentStatusPending
-	}
+		// Re-check the cart's coupon under lock; it is redeemed once the order exists
+		var coupon *model.CouponDiscount
+		var itemDiscount model.Money
+		if cart.CouponCode != nil {
+			applied, err := s.couponService.ApplyAtCheckout(ctx, *cart.CouponCode, userID, cartItems)
+			if err != nil {
+				return fmt.Errorf("coupon %s cannot be used: %w", *cart.CouponCode, err)
+			}
+			itemDiscount = applied.Amount
+			if applied.FreeShipping {
+				applied.Amount = applied.Amount.Add(waivedShipping(shipping, applied.ShopID))
+			}
+			coupon = applied
+			discount = applied.Amount
+		}
 
-	// Create order
-	now := time.Now()
-	var confirmedAt *time.Time
-	if orderStatus == model.OrderStatusConfirmed {
-		confirmedAt = &now // Only auto-confirm for COD
-	}
+		// Tax each line on what is paid for it after the coupon. Only exclusive
+		// tax adds to the total; inclusive tax is already part of the prices.
+		taxes, err := s.taxService.TaxLines(ctx, destination, taxableLines(cartItems, coupon, itemDiscount))
+		if err != nil {
+			return err
+		}
 
-	order := &model.Order{
-		ID:                uuid.New(),
-		UserID:            userID,
-		OrderNumber:       model.GenerateOrderNumber(),
-		Status:            orderStatus,
-		ShippingAddressID: &shippingAddress.ID,
-		BillingAddressID:  billingAddressID,
-		Subtotal:          subtotal,
-		ShippingCost:      shippingCost,
-		Tax:               tax,
-		Discount:          discount,
-		Total:             total,
-		PaymentMethod:     &req.PaymentMethod,
-		PaymentStatus:     paymentStatus,
-		Notes:             req.Notes,
-		CreatedAt:         now,
-		UpdatedAt:         now,
-		ConfirmedAt:       confirmedAt,
-	}
+		var tax model.Money
+		for _, lineTax := range taxes {
+			if !lineTax.Inclusive {
+				tax = tax.Add(lineTax.Amount)
+			}
+		}
 
-	if err := s.orderRepo.Create(ctx, order); err != nil {
-		return nil, fmt.Errorf("failed to create order: %w", err)
-	}
+		total := subtotal.Add(shippingCost).Add(tax).Sub(discount)
 
-	// Create order items and reduce stock
-	var orderItems []model.OrderItem
-	for _, cartItem := range cartItems {
-		orderItem := model.OrderItem{
-			ID:          uuid.New(),
-			OrderID:     order.ID,
-			ProductID:   cartItem.ProductID,
-			ShopID:      cartItem.ShopID,
-			ProductName: cartItem.ProductName,
-			Quantity:    cartItem.Quantity,
-			UnitPrice:   cartItem.ProductPrice,
-			Subtotal:    cartItem.Subtotal,
-			CreatedAt:   time.Now(),
+		// Online payments keep the order pending until the gateway confirms them.
+		// Offline orders (COD) are confirmed immediately and paid on delivery.
+		orderStatus := model.OrderStatusConfirmed
+		paymentStatus := model.PaymentStatusPending
+		if provider.Online() {
+			orderStatus = model.OrderStatusPending
 		}
-		orderItems = append(orderItems, orderItem)
 
-		// Reduce product stock
-		if err := s.productRepo.ReduceStock(ctx, cartItem.ProductID, cartItem.Quantity); err != nil {
-			return nil, fmt.Errorf("failed to reduce stock for %s: %w", cartItem.ProductName, err)
+		// Create order
+		now := time.Now()
+		var confirmedAt *time.Time
+		if orderStatus == model.OrderStatusConfirmed {
+			confirmedAt = &now // Only auto-confirm offline payments
 		}
-	}
 
-	if err := s.orderRepo.CreateOrderItems(ctx, orderItems); err != nil {
-		return nil, fmt.Errorf("failed to create order items: %w", err)
-	}
+		order = &model.Order{
+			ID:                uuid.New(),
+			UserID:            userID,
+			OrderNumber:       model.GenerateOrderNumber(),
+			Status:            orderStatus,
+			ShippingAddressID: &shippingAddress.ID,
+			BillingAddressID:  billingAddressID,
+			Subtotal:          subtotal,
+			ShippingCost:      shippingCost,
+			Tax:               tax,
+			Discount:          discount,
+			Total:             total,
+			Currency:          currency,
+			PaymentMethod:     &paymentMethod,
+			PaymentStatus:     paymentStatus,
+			Notes:             req.Notes,
+			CreatedAt:         now,
+			UpdatedAt:         now,
+			ConfirmedAt:       confirmedAt,
+		}
+
+		if err := s.orderRepo.Create(ctx, order); err != nil {
+			return fmt.Errorf("failed to create order: %w", err)
+		}
+
+		// Create order items and reduce stock
+		var orderItems []model.OrderItem
+		for i, cartItem := range cartItems {
+			orderItem := model.OrderItem{
+				ID:            uuid.New(),
+				OrderID:       order.ID,
+				ProductID:     cartItem.ProductID,
+				VariantID:     cartItem.VariantID,
+				ShopID:        cartItem.ShopID,
+				ProductName:   cartItem.ProductName,
+				ProductSKU:    cartItem.VariantSKU,
+				Quantity:      cartItem.Quantity,
+				UnitPrice:     cartItem.ProductPrice,
+				Subtotal:      cartItem.Subtotal,
+				TaxName:       taxes[i].Name,
+				TaxRate:       taxes[i].Rate,
+				TaxableAmount: taxes[i].TaxableAmount,
+				TaxAmount:     taxes[i].Amount,
+				TaxInclusive:  taxes[i].Inclusive,
+				CreatedAt:     time.Now(),
+			}
+			if cartItem.VariantID != nil {
+				variantName := model.VariantName(cartItem.VariantOptions)
+				orderItem.VariantName = &variantName
+			}
+			orderItems = append(orderItems, orderItem)
+
+			// Reduce product (or variant) stock
+			if err := s.productRepo.ReduceStock(ctx, cartItem.ProductID, cartItem.VariantID, cartItem.Quantity); err != nil {
+				return fmt.Errorf("failed to reduce stock for %s: %w", cartItem.DisplayName(), err)
+			}
+		}
+
+		if err := s.orderRepo.CreateOrderItems(ctx, orderItems); err != nil {
+			return fmt.Errorf("failed to create order items: %w", err)
+		}
+
+		// Each shop fulfils its own items
+		if err := s.fulfilmentRepo.Create(ctx, buildFulfilments(order, orderItems, shipping)); err != nil {
+			return fmt.Errorf("failed to create fulfilments: %w", err)
+		}
+
+		if coupon != nil {
+			if err := s.couponService.RedeemForOrder(ctx, coupon, order.ID, userID); err != nil {
+				return err
+			}
+		}
 
-	// Clear cart
-	if err := s.cartRepo.ClearCart(ctx, cart.ID); err != nil {
-		return nil, fmt.Errorf("failed to clear cart: %w", err)
+		// Unpaid online orders only hold their stock until the payment expires
+		if order.Status == model.OrderStatusPending {
+			expiresAt := now.Add(model.StockReservationTTL + model.StockReservationGracePeriod)
+			if err := s.reservationService.ReserveForOrder(ctx, order.ID, orderItems, expiresAt); err != nil {
+				return err
+			}
+		}
+
+		// Clear cart, including the redeemed coupon
+		if err := s.cartRepo.ClearCart(ctx, cart.ID); err != nil {
+			return fmt.Errorf("failed to clear cart: %w", err)
+		}
+		if err := s.cartRepo.SetCouponCode(ctx, cart.ID, nil); err != nil {
+			return fmt.Errorf("failed to clear cart coupon: %w", err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	// Get full order details for response
@@ -248,6 +398,12 @@ func (s *OrderService) GetOrderByID(ctx context.Context, orderID, userID uuid.UU
 		return nil, fmt.Errorf("failed to get order items: %w", err)
 	}
 
+	// Get per-shop fulfilments
+	fulfilments, err := s.fulfilmentRepo.GetByOrderID(ctx, orderID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get fulfilments: %w", err)
+	}
+
 	// Get addresses
 	var shippingAddress *model.Address
 	var billingAddress *model.Address
@@ -266,27 +422,40 @@ func (s *OrderService) GetOrderByID(ctx context.Context, orderID, userID uuid.UU
 		}
 	}
 
+	var taxIncluded model.Money
+	for _, item := range items {
+		if item.TaxInclusive {
+			taxIncluded = taxIncluded.Add(item.TaxAmount)
+		}
+	}
+
 	return &model.OrderResponse{
-		ID:              order.ID,
-		UserID:          order.UserID,
-		OrderNumber:     order.OrderNumber,
-		Status:          order.Status,
-		ShippingAddress: shippingAddress,
-		BillingAddress:  billingAddress,
-		Items:           items,
-		Subtotal:        order.Subtotal,
-		ShippingCost:    order.ShippingCost,
-		Tax:             order.Tax,
-		Discount:        order.Discount,
-		Total:           order.Total,
-		PaymentMethod:   order.PaymentMethod,
-		PaymentStatus:   order.PaymentStatus,
-		Notes:           order.Notes,
-		CreatedAt:       order.CreatedAt,
-		UpdatedAt:       order.UpdatedAt,
-		ConfirmedAt:     order.ConfirmedAt,
-		ShippedAt:       order.ShippedAt,
-		DeliveredAt:     order.DeliveredAt,
+		ID:                 order.ID,
+		UserID:             order.UserID,
+		OrderNumber:        order.OrderNumber,
+		Status:             order.Status,
+		ShippingAddress:    shippingAddress,
+		BillingAddress:     billingAddress,
+		Items:              items,
+		Fulfilments:        fulfilments,
+		Subtotal:           order.Subtotal,
+		ShippingCost:       order.ShippingCost,
+		Tax:                order.Tax,
+		TaxIncluded:        taxIncluded,
+		TaxBreakdown:       model.TaxBreakdownOf(items),
+		Discount:           order.Discount,
+		Total:              order.Total,
+		Currency:           order.Currency,
+		PaymentMethod:      order.PaymentMethod,
+		PaymentStatus:      order.PaymentStatus,
+		Notes:              order.Notes,
+		CreatedAt:          order.CreatedAt,
+		UpdatedAt:          order.UpdatedAt,
+		ConfirmedAt:        order.ConfirmedAt,
+		ShippedAt:          order.ShippedAt,
+		DeliveredAt:        order.DeliveredAt,
+		CancelledAt:        order.CancelledAt,
+		CancellationReason: order.CancellationReason,
 	}, nil
 }
 
@@ -295,28 +464,46 @@ func (s *OrderService) GetUserOrders(ctx context.Context, userID uuid.UUID) ([]*
 	return s.orderRepo.GetByUserID(ctx, userID)
 }
 
-// GetVendorOrders retrieves orders for vendor's shop
-func (s *OrderService) GetVendorOrders(ctx context.Context, shopID uuid.UUID) ([]*model.Order, error) {
-	return s.orderRepo.GetByShopID(ctx, shopID)
+// GetVendorOrders retrieves the shop's fulfilments, each with only the shop's own items
+func (s *OrderService) GetVendorOrders(ctx context.Context, shopID uuid.UUID) ([]*model.VendorOrder, error) {
+	orders, err := s.fulfilmentRepo.GetVendorOrders(ctx, shopID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get fulfilments: %w", err)
+	}
+
+	for _, order := range orders {
+		items, err := s.orderRepo.GetOrderItems(ctx, order.OrderID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get order items: %w", err)
+		}
+		order.Items = []model.OrderItemWithDetails{}
+		for _, item := range items {
+			if item.ShopID == shopID {
+				order.Items = append(order.Items, item)
+			}
+		}
+
+		if order.ShippingAddressID != nil {
+			order.ShippingAddress, err = s.orderRepo.GetAddressByID(ctx, *order.ShippingAddressID)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get shipping address: %w", err)
+			}
+		}
+	}
+
+	return orders, nil
 }
 
-// validStatusTransitions defines the allowed order status flow
-var validStatusTransitions = map[model.OrderStatus][]model.OrderStatus{
-	model.OrderStatusPending:    {model.OrderStatusConfirmed, model.OrderStatusCancelled},
-	model.OrderStatusConfirmed:  {model.OrderStatusProcessing, model.OrderStatusCancelled},
-	model.OrderStatusProcessing: {model.OrderStatusShipped, model.OrderStatusCancelled},
+// validFulfilmentTransitions defines how a vendor may advance their fulfilment.
+// Payment, cancellation and refunds move fulfilments through the order instead.
+var validFulfilmentTransitions = map[model.OrderStatus][]model.OrderStatus{
+	model.OrderStatusConfirmed:  {model.OrderStatusProcessing},
+	model.OrderStatusProcessing: {model.OrderStatusShipped},
 	model.OrderStatusShipped:    {model.OrderStatusDelivered},
-	model.OrderStatusDelivered:  {model.OrderStatusRefunded},
-	model.OrderStatusCancelled:  {},
-	model.OrderStatusRefunded:   {},
 }
 
-func isValidStatusTransition(from, to model.OrderStatus) bool {
-	allowed, ok := validStatusTransitions[from]
-	if !ok {
-		return false
-	}
-	for _, s := range allowed {
+func isValidFulfilmentTransition(from, to model.OrderStatus) bool {
+	for _, s := range validFulfilmentTransitions[from] {
 		if s == to {
 			return true
 		}
@@ -324,66 +511,487 @@ func isValidStatusTransition(from, to model.OrderStatus) bool {
 	return false
 }
 
-// UpdateOrderStatus updates order status with transition validation (vendor/admin only)
-func (s *OrderService) UpdateOrderStatus(ctx context.Context, orderID uuid.UUID, status model.OrderStatus) error {
-	// Get current order to validate transition
+// UpdateFulfilmentStatus advances fulfilments of an order and re-derives the
+// order status from all of them. Vendors move their own shop's fulfilment;
+// admins move the named shop's, or every one that can make the transition.
+func (s *OrderService) UpdateFulfilmentStatus(ctx context.Context, actor *Actor, orderID uuid.UUID, req *model.UpdateOrderStatusRequest) error {
+	if _, err := s.orderRepo.GetByID(ctx, orderID); err != nil {
+		return orderLookupError(err)
+	}
+
+	// Vendors may only touch orders containing their shop's items
+	if err := s.policy.AuthorizeOrder(ctx, actor, orderID); err != nil {
+		return err
+	}
+
+	shopID := req.ShopID
+	if !actor.IsAdmin() {
+		shopID = actor.ShopID
+	}
+
+	return s.db.WithTx(ctx, func(ctx context.Context) error {
+		// Lock the order so concurrent vendors derive its status one at a time
+		if err := s.orderRepo.LockByID(ctx, orderID); err != nil {
+			return orderLookupError(err)
+		}
+
+		var fulfilments []model.Fulfilment
+		if shopID != nil {
+			fulfilment, err := s.fulfilmentRepo.LockByOrderAndShop(ctx, orderID, *shopID)
+			if errors.Is(err, pgx.ErrNoRows) {
+				return fmt.Errorf("%w: no fulfilment for shop %s: %w", ErrOrderNotFound, *shopID, err)
+			}
+			if err != nil {
+				return fmt.Errorf("failed to get fulfilment: %w", err)
+			}
+			fulfilments = append(fulfilments, *fulfilment)
+		} else {
+			all, err := s.fulfilmentRepo.GetByOrderID(ctx, orderID)
+			if err != nil {
+				return fmt.Errorf("failed to get fulfilments: %w", err)
+			}
+			for _, f := range all {
+				if isValidFulfilmentTransition(f.Status, req.Status) {
+					fulfilments = append(fulfilments, f)
+				}
+			}
+			if len(fulfilments) == 0 {
+				return fmt.Errorf("invalid status transition: no fulfilment can move to '%s'", req.Status)
+			}
+		}
+
+		for _, fulfilment := range fulfilments {
+			// Validate the status transition
+			if !isValidFulfilmentTransition(fulfilment.Status, req.Status) {
+				return fmt.Errorf("invalid status transition: cannot move from '%s' to '%s'", fulfilment.Status, req.Status)
+			}
+
+			if err := s.fulfilmentRepo.UpdateStatus(ctx, fulfilment.ID, req.Status); err != nil {
+				return err
+			}
+		}
+
+		return s.syncOrderStatus(ctx, orderID)
+	})
+}
+
+// orderLookupError wraps a failed order lookup, as ErrOrderNotFound when the
+// order does not exist
+func orderLookupError(err error) error {
+	if errors.Is(err, pgx.ErrNoRows) {
+		return fmt.Errorf("%w: %w", ErrOrderNotFound, err)
+	}
+	return fmt.Errorf("failed to get order: %w", err)
+}
+
+// syncOrderStatus sets the order status derived from its fulfilments
+func (s *OrderService) syncOrderStatus(ctx context.Context, orderID uuid.UUID) error {
 	order, err := s.orderRepo.GetByID(ctx, orderID)
 	if err != nil {
-		return fmt.Errorf("order not found")
+		return orderLookupError(err)
 	}
 
-	// Validate the status transition
-	if !isValidStatusTransition(order.Status, status) {
-		return fmt.Errorf("invalid status transition: cannot move from '%s' to '%s'", order.Status, status)
+	fulfilments, err := s.fulfilmentRepo.GetByOrderID(ctx, orderID)
+	if err != nil {
+		return fmt.Errorf("failed to get fulfilments: %w", err)
+	}
+
+	status := deriveOrderStatus(fulfilments)
+	if status == "" || status == order.Status {
+		return nil
 	}
 
-	// Update the order status
 	if err := s.orderRepo.UpdateStatusWithTimestamp(ctx, orderID, status); err != nil {
 		return err
 	}
 
 	// For COD orders, automatically mark payment as paid when delivered
-	if status == model.OrderStatusDelivered && order.PaymentMethod != nil && *order.PaymentMethod == "COD" {
+	if status == model.OrderStatusDelivered && order.PaymentMethod != nil && *order.PaymentMethod == payment.MethodCOD {
 		if err := s.orderRepo.UpdatePaymentStatus(ctx, orderID, model.PaymentStatusPaid); err != nil {
 			return fmt.Errorf("failed to update payment status: %w", err)
 		}
+
+		// The courier hands the cash to the platform, which now owes the shops
+		if err := s.ledgerService.RecordSale(ctx, orderID); err != nil {
+			return err
+		}
 	}
 
 	return nil
 }
 
-// CancelOrder cancels an order and restores stock
-func (s *OrderService) CancelOrder(ctx context.Context, orderID, userID uuid.UUID) error {
+// fulfilmentProgress orders the stages a live fulfilment goes through
+var fulfilmentProgress = map[model.OrderStatus]int{
+	model.OrderStatusPending:    0,
+	model.OrderStatusConfirmed:  1,
+	model.OrderStatusProcessing: 2,
+	model.OrderStatusShipped:    3,
+	model.OrderStatusDelivered:  4,
+}
+
+// deriveOrderStatus returns the least advanced status among the live
+// fulfilments, so an order only counts as shipped once every shop has shipped.
+// Without live fulfilments the order is refunded or cancelled.
+func deriveOrderStatus(fulfilments []model.Fulfilment) model.OrderStatus {
+	var derived model.OrderStatus
+	refunded := false
+	for _, f := range fulfilments {
+		progress, live := fulfilmentProgress[f.Status]
+		if !live {
+			refunded = refunded || f.Status == model.OrderStatusRefunded
+			continue
+		}
+		if derived == "" || progress < fulfilmentProgress[derived] {
+			derived = f.Status
+		}
+	}
+
+	if derived == "" && len(fulfilments) > 0 {
+		if refunded {
+			return model.OrderStatusRefunded
+		}
+		return model.OrderStatusCancelled
+	}
+	return derived
+}
+
+// buildFulfilments groups a new order's items into one fulfilment per shop,
+// each shipped with the option selected for the shop
+func buildFulfilments(order *model.Order, items []model.OrderItem, shipping map[uuid.UUID]model.ShippingOption) []model.Fulfilment {
+	var fulfilments []model.Fulfilment
+	byShop := make(map[uuid.UUID]int)
+	for _, item := range items {
+		i, ok := byShop[item.ShopID]
+		if !ok {
+			i = len(fulfilments)
+			byShop[item.ShopID] = i
+			option := shipping[item.ShopID]
+			fulfilment := model.Fulfilment{
+				ID:               uuid.New(),
+				OrderID:          order.ID,
+				ShopID:           item.ShopID,
+				Status:           order.Status,
+				ShippingMethodID: option.MethodID,
+				ShippingCost:     option.Cost,
+				CreatedAt:        order.CreatedAt,
+				UpdatedAt:        order.CreatedAt,
+				ConfirmedAt:      order.ConfirmedAt,
+			}
+			if option.Name != "" {
+				fulfilment.ShippingMethodName = &option.Name
+			}
+			fulfilments = append(fulfilments, fulfilment)
+		}
+		fulfilments[i].Subtotal = fulfilments[i].Subtotal.Add(item.Subtotal)
+	}
+	return fulfilments
+}
+
+// waivedShipping is the shipping a free-shipping coupon covers: that of the
+// coupon's shop, or all of it for platform-wide coupons
+func waivedShipping(shipping map[uuid.UUID]model.ShippingOption, shopID *uuid.UUID) model.Money {
+	var waived model.Money
+	for id, option := range shipping {
+		if shopID == nil || *shopID == id {
+			waived = waived.Add(option.Cost)
+		}
+	}
+	return waived
+}
+
+// taxableLines spreads the coupon's item discount over the cart lines it
+// applies to, in proportion to their subtotals. The shares add up to the
+// discount exactly.
+func taxableLines(items []model.CartItemWithProduct, coupon *model.CouponDiscount, itemDiscount model.Money) []TaxableLine {
+	weights := make([]model.Money, len(items))
+	for i, item := range items {
+		if coupon != nil && (coupon.ShopID == nil || *coupon.ShopID == item.ShopID) {
+			weights[i] = item.Subtotal
+		}
+	}
+	shares := itemDiscount.Allocate(weights)
+
+	lines := make([]TaxableLine, len(items))
+	for i, item := range items {
+		lines[i] = TaxableLine{CategoryID: item.CategoryID, Amount: item.Subtotal.Sub(shares[i])}
+	}
+	return lines
+}
+
+// PreparePayment readies one of the user's unpaid orders to be paid again,
+// switching it to another payment method when one is given. Only pending
+// orders that still hold their stock can be paid. Switching to an offline
+// method confirms the order at once, as checkout does, and an open payment
+// through the previous method is closed in the background so it cannot be
+// paid twice. A payment completed through it before then is refunded.
+func (s *OrderService) PreparePayment(ctx context.Context, orderID, userID uuid.UUID, method string) (*model.OrderResponse, error) {
 	// Verify ownership
 	owned, err := s.orderRepo.VerifyOrderOwnership(ctx, orderID, userID)
 	if err != nil || !owned {
-		return fmt.Errorf("order not found or unauthorized")
+		return nil, fmt.Errorf("order not found or unauthorized")
 	}
 
-	// Get order
-	order, err := s.orderRepo.GetByID(ctx, orderID)
+	var provider payment.Provider
+	if method != "" {
+		if provider, err = s.payments.Get(method); err != nil {
+			return nil, err
+		}
+	}
+
+	err = s.db.WithTx(ctx, func(ctx context.Context) error {
+		// Lock the order so a concurrent payment or expiry cannot settle it meanwhile
+		if err := s.orderRepo.LockByID(ctx, orderID); err != nil {
+			return fmt.Errorf("order not found: %w", err)
+		}
+
+		order, err := s.orderRepo.GetByID(ctx, orderID)
+		if err != nil {
+			return fmt.Errorf("order not found: %w", err)
+		}
+
+		if order.Status != model.OrderStatusPending || order.PaymentStatus == model.PaymentStatusPaid {
+			return errors.New("order is not awaiting payment")
+		}
+
+		held, err := s.reservationService.IsHeld(ctx, orderID)
+		if err != nil {
+			return err
+		}
+		if !held {
+			return errors.New("order's stock reservation has expired")
+		}
+
+		if provider == nil || (order.PaymentMethod != nil && strings.EqualFold(*order.PaymentMethod, provider.Name())) {
+			return nil
+		}
+
+		if err := s.orderRepo.UpdatePaymentMethod(ctx, orderID, provider.Name()); err != nil {
+			return fmt.Errorf("failed to update payment method: %w", err)
+		}
+		// The previous payment is closed once the switch commits
+		if order.PaymentMethod != nil {
+			_, err := s.queue.Enqueue(ctx, JobClosePayment, ClosePaymentJob{
+				OrderID:          orderID,
+				PaymentMethod:    *order.PaymentMethod,
+				PaymentReference: order.PaymentReference,
+			})
+			if err != nil {
+				return err
+			}
+		}
+
+		if provider.Online() {
+			return nil
+		}
+
+		// Offline orders are confirmed now and keep their stock
+		if err := s.orderRepo.UpdateStatusWithTimestamp(ctx, orderID, model.OrderStatusConfirmed); err != nil {
+			return fmt.Errorf("failed to update order status: %w", err)
+		}
+		if err := s.fulfilmentRepo.TransitionForOrder(ctx, orderID, model.OrderStatusConfirmed, model.OrderStatusPending); err != nil {
+			return fmt.Errorf("failed to confirm fulfilments: %w", err)
+		}
+		return s.reservationService.CommitForOrder(ctx, orderID)
+	})
 	if err != nil {
-		return fmt.Errorf("order not found: %w", err)
+		return nil, err
 	}
 
-	// Check if order can be cancelled (only confirmed orders, before processing)
-	if order.Status != model.OrderStatusConfirmed {
-		return fmt.Errorf("order cannot be cancelled in current status: %s", order.Status)
+	return s.GetOrderByID(ctx, orderID, userID)
+}
+
+// CancelOrder cancels a confirmed order not yet paid, such as one paid cash on
+// delivery, and restores stock. The status change and the stock restore
+// commit together, and the order row stays locked meanwhile so concurrent
+// cancellations cannot restore stock twice.
+func (s *OrderService) CancelOrder(ctx context.Context, orderID, userID uuid.UUID) error {
+	// Verify ownership
+	owned, err := s.orderRepo.VerifyOrderOwnership(ctx, orderID, userID)
+	if err != nil || !owned {
+		return fmt.Errorf("order not found or unauthorized")
 	}
 
-	// Get order items
-	items, err := s.orderRepo.GetOrderItems(ctx, orderID)
+	return s.db.WithTx(ctx, func(ctx context.Context) error {
+		// Lock the order so the status check below cannot race another cancellation
+		if err := s.orderRepo.LockByID(ctx, orderID); err != nil {
+			return fmt.Errorf("order not found: %w", err)
+		}
+
+		// Get order
+		order, err := s.orderRepo.GetByID(ctx, orderID)
+		if err != nil {
+			return fmt.Errorf("order not found: %w", err)
+		}
+
+		// Check if order can be cancelled (only confirmed orders, before processing)
+		if order.Status != model.OrderStatusConfirmed {
+			return fmt.Errorf("order cannot be cancelled in current status: %s", order.Status)
+		}
+
+		// Orders paid online are cancelled by refunding them, which returns
+		// the money and takes the sale off the shops' balances
+		if order.PaymentStatus != model.PaymentStatusPending {
+			return errors.New("paid orders cannot be cancelled, request a refund instead")
+		}
+
+		// Get order items
+		items, err := s.orderRepo.GetOrderItems(ctx, orderID)
+		if err != nil {
+			return fmt.Errorf("failed to get order items: %w", err)
+		}
+
+		// Restore stock for each item
+		for _, item := range items {
+			if err := s.productRepo.IncreaseStock(ctx, item.ProductID, item.VariantID, item.Quantity); err != nil {
+				return fmt.Errorf("failed to restore stock for %s: %w", item.ProductName, err)
+			}
+		}
+
+		if err := s.fulfilmentRepo.TransitionForOrder(ctx, orderID, model.OrderStatusCancelled, model.OrderStatusConfirmed); err != nil {
+			return fmt.Errorf("failed to cancel fulfilments: %w", err)
+		}
+
+		// The coupon use goes back to the customer
+		if err := s.couponService.ReleaseForOrder(ctx, orderID); err != nil {
+			return err
+		}
+
+		// Update order status to cancelled
+		return s.orderRepo.Cancel(ctx, orderID, model.CancelReasonCustomer)
+	})
+}
+
+// CancelUnpaidOrders cancels orders that have waited for their payment longer
+// than window and puts their stock back. It runs as a background job. Each
+// order's open payment is closed first so it cannot be paid once cancelled.
+func (s *OrderService) CancelUnpaidOrders(ctx context.Context, window time.Duration) error {
+	orderIDs, err := s.orderRepo.GetUnpaidBefore(ctx, time.Now().Add(-window), sweepBatchSize)
 	if err != nil {
-		return fmt.Errorf("failed to get order items: %w", err)
+		return fmt.Errorf("failed to list unpaid orders: %w", err)
 	}
 
-	// Restore stock for each item
-	for _, item := range items {
-		if err := s.productRepo.IncreaseStock(ctx, item.ProductID, item.Quantity); err != nil {
-			return fmt.Errorf("failed to restore stock for %s: %w", item.ProductName, err)
+	cancelled := 0
+	defer func() {
+		if cancelled > 0 {
+			log.Printf("[Orders] cancelled %d unpaid order(s)", cancelled)
+		}
+	}()
+
+	// One order failing must not hold up the ones placed after it
+	var errs []error
+	for _, orderID := range orderIDs {
+		order, err := s.orderRepo.GetByID(ctx, orderID)
+		if err != nil {
+			log.Printf("[Orders] failed to get unpaid order %s: %v", orderID, err)
+			errs = append(errs, fmt.Errorf("order %s not found: %w", orderID, err))
+			continue
+		}
+
+		// The customer may be paying right now; the next run tries again
+		if err := s.closePayment(ctx, order); err != nil {
+			log.Printf("[Orders] kept unpaid order %s: %v", order.OrderNumber, err)
+			continue
+		}
+
+		ok, err := s.cancelUnpaidOrder(ctx, orderID)
+		if err != nil {
+			log.Printf("[Orders] failed to cancel unpaid order %s: %v", order.OrderNumber, err)
+			errs = append(errs, fmt.Errorf("failed to cancel order %s: %w", order.OrderNumber, err))
+			continue
+		}
+		if ok {
+			cancelled++
 		}
 	}
 
-	// Update order status to cancelled
-	return s.orderRepo.UpdateStatus(ctx, orderID, model.OrderStatusCancelled)
+	return errors.Join(errs...)
+}
+
+// cancelUnpaidOrder cancels an order still waiting for its payment and
+// reports whether it did
+func (s *OrderService) cancelUnpaidOrder(ctx context.Context, orderID uuid.UUID) (bool, error) {
+	cancelled := false
+	err := s.db.WithTx(ctx, func(ctx context.Context) error {
+		// Lock the order so a payment arriving now cannot confirm it meanwhile
+		if err := s.orderRepo.LockByID(ctx, orderID); err != nil {
+			return fmt.Errorf("order not found: %w", err)
+		}
+
+		order, err := s.orderRepo.GetByID(ctx, orderID)
+		if err != nil {
+			return fmt.Errorf("order not found: %w", err)
+		}
+		if order.Status != model.OrderStatusPending || order.PaymentStatus == model.PaymentStatusPaid {
+			// Paid or cancelled since it was listed
+			return nil
+		}
+
+		// Reserved stock goes back the way an expired payment's does
+		if err := s.reservationService.ReleaseForOrder(ctx, orderID, model.CancelReasonUnpaid); err != nil {
+			return err
+		}
+
+		order, err = s.orderRepo.GetByID(ctx, orderID)
+		if err != nil {
+			return fmt.Errorf("order not found: %w", err)
+		}
+		if order.Status == model.OrderStatusPending {
+			// Orders placed before stock was reserved took it straight off the shelf
+			items, err := s.orderRepo.GetOrderItems(ctx, orderID)
+			if err != nil {
+				return fmt.Errorf("failed to get order items: %w", err)
+			}
+			for _, item := range items {
+				if err := s.productRepo.IncreaseStock(ctx, item.ProductID, item.VariantID, item.Quantity); err != nil {
+					return fmt.Errorf("failed to restore stock for %s: %w", item.ProductName, err)
+				}
+			}
+
+			if err := s.orderRepo.UpdatePaymentStatus(ctx, orderID, model.PaymentStatusFailed); err != nil {
+				return fmt.Errorf("failed to update payment status: %w", err)
+			}
+			if err := s.fulfilmentRepo.TransitionForOrder(ctx, orderID, model.OrderStatusCancelled, model.OrderStatusPending); err != nil {
+				return fmt.Errorf("failed to cancel fulfilments: %w", err)
+			}
+			if err := s.couponService.ReleaseForOrder(ctx, orderID); err != nil {
+				return err
+			}
+			if err := s.orderRepo.Cancel(ctx, orderID, model.CancelReasonUnpaid); err != nil {
+				return fmt.Errorf("failed to cancel order: %w", err)
+			}
+		}
+
+		cancelled = true
+		return nil
+	})
+	return cancelled, err
+}
+
+// ClosePayment closes a payment the customer switched away from. It runs as a
+// JobClosePayment job.
+func (s *OrderService) ClosePayment(ctx context.Context, job ClosePaymentJob) error {
+	return s.closePayment(ctx, &model.Order{
+		ID:               job.OrderID,
+		PaymentMethod:    &job.PaymentMethod,
+		PaymentReference: job.PaymentReference,
+	})
+}
+
+// closePayment closes the order's open payment at its gateway, for gateways
+// that keep payments open
+func (s *OrderService) closePayment(ctx context.Context, order *model.Order) error {
+	if order.PaymentMethod == nil {
+		return nil
+	}
+	provider, err := s.payments.Get(*order.PaymentMethod)
+	if err != nil {
+		// A method no longer accepted has no payment left to close
+		return nil
+	}
+
+	resumer, ok := provider.(payment.Resumer)
+	if !ok {
+		return nil
+	}
+	return resumer.Close(ctx, order)
 }
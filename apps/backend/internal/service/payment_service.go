@@ -0,0 +1,512 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/imbivek08/hamropasal/internal/database"
+	"github.com/imbivek08/hamropasal/internal/model"
+	"github.com/imbivek08/hamropasal/internal/payment"
+	"github.com/imbivek08/hamropasal/internal/repository"
+	"github.com/jackc/pgx/v5"
+)
+
+// PaymentService takes payment for orders through the payment method's
+// provider and applies what the provider's callbacks report
+type PaymentService struct {
+	payments           *payment.Registry
+	apiURL             string
+	frontendURL        string
+	db                 *database.Database
+	reservationService *StockReservationService
+	refundService      *RefundService
+	ledgerService      *LedgerService
+	webhookEventRepo   *repository.WebhookEventRepository
+	orderRepo          *repository.OrderRepository
+	fulfilmentRepo     *repository.FulfilmentRepository
+}
+
+func NewPaymentService(
+	payments *payment.Registry,
+	apiURL string,
+	frontendURL string,
+	db *database.Database,
+	reservationService *StockReservationService,
+	refundService *RefundService,
+	ledgerService *LedgerService,
+	webhookEventRepo *repository.WebhookEventRepository,
+	orderRepo *repository.OrderRepository,
+	fulfilmentRepo *repository.FulfilmentRepository,
+) *PaymentService {
+	return &PaymentService{
+		payments:           payments,
+		apiURL:             apiURL,
+		frontendURL:        frontendURL,
+		db:                 db,
+		reservationService: reservationService,
+		refundService:      refundService,
+		ledgerService:      ledgerService,
+		webhookEventRepo:   webhookEventRepo,
+		orderRepo:          orderRepo,
+		fulfilmentRepo:     fulfilmentRepo,
+	}
+}
+
+// ListMethods lists the payment methods customers can choose at checkout
+func (s *PaymentService) ListMethods() []model.PaymentMethodInfo {
+	methods := []model.PaymentMethodInfo{}
+	for _, name := range s.payments.Methods() {
+		provider, err := s.payments.Get(name)
+		if err != nil {
+			continue
+		}
+		methods = append(methods, model.PaymentMethodInfo{Name: provider.Name(), Online: provider.Online()})
+	}
+	return methods
+}
+
+// StartCheckout starts paying for a new order through its payment method.
+// Offline orders have nothing to pay yet and are returned as they are.
+func (s *PaymentService) StartCheckout(ctx context.Context, order *model.OrderResponse) (*model.CreateCheckoutResponse, error) {
+	if order.PaymentMethod == nil {
+		return nil, errors.New("order has no payment method")
+	}
+	provider, err := s.payments.Get(*order.PaymentMethod)
+	if err != nil {
+		return nil, err
+	}
+	if !provider.Online() {
+		return &model.CreateCheckoutResponse{Order: order}, nil
+	}
+
+	// The payment should not outlive the order's stock reservation
+	expiresAt := time.Now().Add(model.StockReservationTTL)
+
+	session, err := provider.Initiate(ctx, &payment.Checkout{
+		Order: &model.Order{
+			ID:            order.ID,
+			OrderNumber:   order.OrderNumber,
+			Subtotal:      order.Subtotal,
+			ShippingCost:  order.ShippingCost,
+			Tax:           order.Tax,
+			Discount:      order.Discount,
+			Total:         order.Total,
+			Currency:      order.Currency,
+			PaymentMethod: order.PaymentMethod,
+			PaymentStatus: order.PaymentStatus,
+		},
+		Items:      order.Items,
+		ReturnURL:  fmt.Sprintf("%s/api/v1/payments/%s/return", s.apiURL, url.PathEscape(provider.Name())),
+		SuccessURL: s.frontendURL + "/payment/success",
+		CancelURL:  s.frontendURL + "/payment/cancel",
+		WebsiteURL: s.frontendURL,
+		ExpiresAt:  expiresAt,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Save the gateway's reference on the order
+	if err := s.orderRepo.UpdatePaymentReference(ctx, order.ID, session.Reference); err != nil {
+		return nil, fmt.Errorf("failed to save payment reference: %w", err)
+	}
+
+	// Hold the stock a little past the payment's expiry so its outcome settles it first
+	if session.ExpiresAt != nil {
+		if err := s.reservationService.ExtendForOrder(ctx, order.ID, session.ExpiresAt.Add(model.StockReservationGracePeriod)); err != nil {
+			return nil, err
+		}
+	}
+
+	return &model.CreateCheckoutResponse{
+		Order:        order,
+		CheckoutURL:  session.RedirectURL,
+		CheckoutForm: session.FormFields,
+	}, nil
+}
+
+// ResumeCheckout takes the customer back to paying for an unpaid order: to the
+// payment they left while its gateway still has it open, or else to a new one
+func (s *PaymentService) ResumeCheckout(ctx context.Context, order *model.OrderResponse) (*model.CreateCheckoutResponse, error) {
+	if order.PaymentMethod == nil {
+		return nil, errors.New("order has no payment method")
+	}
+	provider, err := s.payments.Get(*order.PaymentMethod)
+	if err != nil {
+		return nil, err
+	}
+
+	if resumer, ok := provider.(payment.Resumer); ok {
+		stored, err := s.orderRepo.GetByID(ctx, order.ID)
+		if err != nil {
+			return nil, fmt.Errorf("order not found: %w", err)
+		}
+
+		session, err := resumer.Resume(ctx, stored)
+		if err != nil {
+			return nil, err
+		}
+		if session != nil {
+			return &model.CreateCheckoutResponse{
+				Order:        order,
+				CheckoutURL:  session.RedirectURL,
+				CheckoutForm: session.FormFields,
+			}, nil
+		}
+	}
+
+	return s.StartCheckout(ctx, order)
+}
+
+// HandleCallback authenticates a callback from a payment method's gateway,
+// records it and processes it once. Redelivered callbacks are skipped unless
+// their previous attempt failed; a returned error means the callback was
+// recorded as failed and the gateway should retry.
+func (s *PaymentService) HandleCallback(ctx context.Context, method string, callback *payment.Callback) (*payment.Event, error) {
+	provider, err := s.payments.Get(method)
+	if err != nil {
+		return nil, err
+	}
+
+	event, err := provider.HandleCallback(ctx, callback)
+	if err != nil {
+		return nil, err
+	}
+
+	record := &model.WebhookEvent{
+		ID:        uuid.New(),
+		Provider:  provider.Name(),
+		EventID:   event.ID,
+		EventType: event.Type,
+		Payload:   event.Payload,
+	}
+
+	isNew, err := s.webhookEventRepo.Record(ctx, record)
+	if err != nil {
+		return nil, fmt.Errorf("failed to record webhook event: %w", err)
+	}
+
+	if !isNew {
+		// Seen before: only retry it if the earlier attempt failed
+		claimed, ok, err := s.webhookEventRepo.ClaimForRetry(ctx, provider.Name(), event.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to claim webhook event: %w", err)
+		}
+		if !ok {
+			return event, nil
+		}
+		record = claimed
+	}
+
+	return event, s.runWebhookEvent(ctx, record, provider, event)
+}
+
+// HandleReturn processes the callback of a customer coming back from a
+// payment page and returns the shop page to send them on to
+func (s *PaymentService) HandleReturn(ctx context.Context, method string, callback *payment.Callback) string {
+	event, err := s.HandleCallback(ctx, method, callback)
+	if err != nil {
+		log.Printf("[Payments] %s return failed: %v", method, err)
+		return s.frontendURL + "/payment/cancel"
+	}
+
+	if event.Payment == nil || event.Payment.Status == payment.StatusFailed {
+		return s.frontendURL + "/payment/cancel"
+	}
+	return s.frontendURL + "/payment/success?session_id=" + url.QueryEscape(event.Payment.Reference)
+}
+
+// VerifyPayment asks the gateway about the payment of one of the user's
+// orders, settling the order if its callback has not arrived yet
+func (s *PaymentService) VerifyPayment(ctx context.Context, reference string, userID uuid.UUID) (*model.PaymentSessionStatus, error) {
+	order, err := s.orderRepo.GetByPaymentReference(ctx, reference)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, errors.New("order not found")
+		}
+		return nil, fmt.Errorf("failed to get order: %w", err)
+	}
+	if order.UserID != userID || order.PaymentMethod == nil {
+		return nil, errors.New("order not found")
+	}
+
+	provider, err := s.payments.Get(*order.PaymentMethod)
+	if err != nil {
+		return nil, err
+	}
+
+	verified, err := provider.Verify(ctx, order)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.applyPayment(ctx, provider, verified); err != nil {
+		return nil, err
+	}
+
+	order, err = s.orderRepo.GetByID(ctx, order.ID)
+	if err != nil {
+		return nil, fmt.Errorf("order not found: %w", err)
+	}
+
+	return &model.PaymentSessionStatus{
+		SessionID:     reference,
+		PaymentMethod: provider.Name(),
+		PaymentStatus: order.PaymentStatus,
+		OrderID:       order.ID,
+		OrderNumber:   order.OrderNumber,
+	}, nil
+}
+
+// ListWebhookEvents lists recorded webhook events, optionally by status (admin only)
+func (s *PaymentService) ListWebhookEvents(ctx context.Context, status string, page, pageSize int) (*model.WebhookEventListResponse, error) {
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	events, total, err := s.webhookEventRepo.List(ctx, status, page, pageSize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list webhook events: %w", err)
+	}
+
+	return &model.WebhookEventListResponse{
+		Events:     events,
+		Total:      total,
+		Page:       page,
+		PageSize:   pageSize,
+		TotalPages: int(math.Ceil(float64(total) / float64(pageSize))),
+	}, nil
+}
+
+// ReplayWebhookEvent processes a failed webhook event again (admin only)
+func (s *PaymentService) ReplayWebhookEvent(ctx context.Context, id uuid.UUID) (*model.WebhookEvent, error) {
+	record, err := s.webhookEventRepo.GetByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, errors.New("webhook event not found")
+		}
+		return nil, fmt.Errorf("failed to get webhook event: %w", err)
+	}
+
+	if record.Status != model.WebhookEventStatusFailed {
+		return nil, errors.New("only failed webhook events can be replayed")
+	}
+
+	claimed, ok, err := s.webhookEventRepo.ClaimForRetry(ctx, record.Provider, record.EventID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim webhook event: %w", err)
+	}
+	if !ok {
+		return nil, errors.New("only failed webhook events can be replayed")
+	}
+
+	// The payload was authenticated when the event arrived
+	var event *payment.Event
+	provider, err := s.payments.Get(claimed.Provider)
+	if err == nil {
+		event, err = provider.HandleCallback(ctx, &payment.Callback{Body: claimed.Payload, Verified: true})
+	}
+	if err != nil {
+		processErr := fmt.Errorf("invalid stored payload: %w", err)
+		if err := s.webhookEventRepo.MarkFailed(ctx, claimed.ID, processErr); err != nil {
+			return nil, fmt.Errorf("failed to record webhook outcome: %w", err)
+		}
+		return nil, processErr
+	}
+
+	// The outcome is stored on the event, which is returned either way
+	_ = s.runWebhookEvent(ctx, claimed, provider, event)
+
+	return s.webhookEventRepo.GetByID(ctx, id)
+}
+
+// runWebhookEvent processes an event and stores the outcome on its record
+func (s *PaymentService) runWebhookEvent(ctx context.Context, record *model.WebhookEvent, provider payment.Provider, event *payment.Event) error {
+	processErr := s.processEvent(ctx, provider, event)
+	if processErr != nil {
+		if err := s.webhookEventRepo.MarkFailed(ctx, record.ID, processErr); err != nil {
+			return fmt.Errorf("failed to record webhook outcome: %w (processing error: %v)", err, processErr)
+		}
+		return processErr
+	}
+
+	if err := s.webhookEventRepo.MarkProcessed(ctx, record.ID); err != nil {
+		return fmt.Errorf("failed to record webhook outcome: %w", err)
+	}
+
+	return nil
+}
+
+// processEvent applies the payment or refunds an event reports
+func (s *PaymentService) processEvent(ctx context.Context, provider payment.Provider, event *payment.Event) error {
+	if event.Payment != nil {
+		if err := s.applyPayment(ctx, provider, event.Payment); err != nil {
+			return err
+		}
+	}
+
+	if len(event.Refunds) > 0 {
+		return s.refundService.ReconcileRefunds(ctx, provider.Name(), event.TransactionID, event.Refunds)
+	}
+
+	return nil
+}
+
+// applyPayment settles the order of a completed or failed payment. Pending
+// payments and payments of orders the shop does not know are ignored, and a
+// payment completing an order already cancelled is refunded.
+func (s *PaymentService) applyPayment(ctx context.Context, provider payment.Provider, p *payment.Payment) error {
+	if p.Status == payment.StatusPending {
+		return nil
+	}
+
+	order, err := s.paymentOrder(ctx, provider, p)
+	if err != nil || order == nil {
+		return err
+	}
+
+	if p.Status == payment.StatusFailed {
+		// A payment the customer has since retried no longer decides the order
+		if order.PaymentReference != nil && p.Reference != "" && *order.PaymentReference != p.Reference {
+			return nil
+		}
+		return s.reservationService.ReleaseForOrder(ctx, order.ID, model.CancelReasonPaymentFailed)
+	}
+
+	if !paidThrough(order, provider) {
+		return s.refundSwitchedPayment(ctx, provider, order, p)
+	}
+
+	if !p.Amount.IsZero() && p.Amount.Amount != order.Total.Amount {
+		return fmt.Errorf("payment of %s does not match order total %s", p.Amount, order.Total)
+	}
+
+	cancelled := false
+	err = s.db.WithTx(ctx, func(ctx context.Context) error {
+		// Lock the order so a concurrent expiry cannot release its stock meanwhile
+		if err := s.orderRepo.LockByID(ctx, order.ID); err != nil {
+			return fmt.Errorf("order not found: %w", err)
+		}
+
+		current, err := s.orderRepo.GetByID(ctx, order.ID)
+		if err != nil {
+			return fmt.Errorf("order not found: %w", err)
+		}
+		if current.PaymentStatus != model.PaymentStatusPending && current.PaymentStatus != model.PaymentStatusFailed {
+			// Already settled by an earlier callback or verification
+			return nil
+		}
+
+		// Update payment status to paid
+		if err := s.orderRepo.UpdatePaymentStatus(ctx, order.ID, model.PaymentStatusPaid); err != nil {
+			return fmt.Errorf("failed to update payment status: %w", err)
+		}
+
+		// Keep the gateway transaction, refunds are issued against it
+		if p.TransactionID != "" {
+			if err := s.orderRepo.UpdatePaymentTransactionID(ctx, order.ID, p.TransactionID); err != nil {
+				return fmt.Errorf("failed to save payment transaction: %w", err)
+			}
+		}
+
+		// Paid too late: the order's stock was released and may be sold on,
+		// so the payment is recorded and refunded rather than confirmed
+		if current.Status == model.OrderStatusCancelled {
+			cancelled = true
+			return nil
+		}
+
+		// Update order status to confirmed, and every shop's fulfilment with it
+		if err := s.orderRepo.UpdateStatusWithTimestamp(ctx, order.ID, model.OrderStatusConfirmed); err != nil {
+			return fmt.Errorf("failed to update order status: %w", err)
+		}
+		if err := s.fulfilmentRepo.TransitionForOrder(ctx, order.ID, model.OrderStatusConfirmed, model.OrderStatusPending); err != nil {
+			return fmt.Errorf("failed to confirm fulfilments: %w", err)
+		}
+
+		// The reserved stock is now sold
+		if err := s.reservationService.CommitForOrder(ctx, order.ID); err != nil {
+			return err
+		}
+
+		// Credit the shops with the sale
+		return s.ledgerService.RecordSale(ctx, order.ID)
+	})
+	if err != nil || !cancelled {
+		return err
+	}
+
+	log.Printf("[Payments] order %s was paid after it was cancelled, refunding", order.OrderNumber)
+	if _, err := s.refundService.RefundCancelledOrder(ctx, order.ID); err != nil {
+		return fmt.Errorf("failed to refund cancelled order %s: %w", order.OrderNumber, err)
+	}
+	return nil
+}
+
+// paymentOrder finds the order a payment is for, by the order ID the gateway
+// echoed back or else by the payment's reference. Failed payments through a
+// method the order no longer uses are ignored.
+func (s *PaymentService) paymentOrder(ctx context.Context, provider payment.Provider, p *payment.Payment) (*model.Order, error) {
+	var order *model.Order
+	var err error
+	if p.OrderID != uuid.Nil {
+		order, err = s.orderRepo.GetByID(ctx, p.OrderID)
+	} else {
+		order, err = s.orderRepo.GetByPaymentReference(ctx, p.Reference)
+	}
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			// Not one of our orders
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to find order: %w", err)
+	}
+
+	// The customer switched away from this method before its payment failed
+	if p.Status == payment.StatusFailed && !paidThrough(order, provider) {
+		return nil, nil
+	}
+
+	return order, nil
+}
+
+// paidThrough reports whether an order is still to be paid through provider
+func paidThrough(order *model.Order, provider payment.Provider) bool {
+	return order.PaymentMethod != nil && strings.EqualFold(*order.PaymentMethod, provider.Name())
+}
+
+// refundSwitchedPayment refunds a payment completed through a method the
+// customer had already switched the order away from. The order is left to
+// its new method; only the gateway is asked for the money back, keyed on the
+// transaction so a redelivered callback cannot refund it twice.
+func (s *PaymentService) refundSwitchedPayment(ctx context.Context, provider payment.Provider, order *model.Order, p *payment.Payment) error {
+	if p.TransactionID == "" {
+		return fmt.Errorf("order %s was paid by %s after switching methods, but the payment has no transaction to refund", order.OrderNumber, provider.Name())
+	}
+
+	amount := p.Amount
+	if amount.IsZero() {
+		amount = order.Total
+	}
+
+	log.Printf("[Payments] order %s was paid by %s after switching methods, refunding %s", order.OrderNumber, provider.Name(), p.TransactionID)
+	_, err := provider.Refund(ctx, &payment.RefundRequest{
+		RefundID:      uuid.NewSHA1(uuid.NameSpaceURL, []byte(provider.Name()+":"+p.TransactionID)),
+		Order:         order,
+		TransactionID: p.TransactionID,
+		Amount:        amount,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to refund %s payment of order %s: %w", provider.Name(), order.OrderNumber, err)
+	}
+	return nil
+}
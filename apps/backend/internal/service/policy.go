@@ -0,0 +1,96 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/imbivek08/hamropasal/internal/model"
+	"github.com/imbivek08/hamropasal/internal/repository"
+)
+
+// ErrForbidden is wrapped by every ownership failure, so handlers can map it
+// to 403 with errors.Is while the message still names the resource
+var ErrForbidden = errors.New("unauthorized")
+
+// Actor is the user a mutation is performed for. ShopID is set for vendors
+// that have an active shop.
+type Actor struct {
+	UserID uuid.UUID
+	Role   model.UserRole
+	ShopID *uuid.UUID
+}
+
+// IsAdmin reports whether the actor may override ownership checks
+func (a *Actor) IsAdmin() bool {
+	return a.Role == model.RoleAdmin
+}
+
+// Policy decides whether an actor may mutate a resource. Admins may act on
+// anything; everyone else only on what they own.
+type Policy struct {
+	orderRepo *repository.OrderRepository
+}
+
+func NewPolicy(orderRepo *repository.OrderRepository) *Policy {
+	return &Policy{orderRepo: orderRepo}
+}
+
+// AuthorizeShop allows the shop's vendor
+func (p *Policy) AuthorizeShop(actor *Actor, shop *model.Shop) error {
+	if actor.IsAdmin() || shop.VendorID == actor.UserID {
+		return nil
+	}
+	return fmt.Errorf("%w: you don't own this shop", ErrForbidden)
+}
+
+// AuthorizeProduct allows the vendor of the shop selling the product
+func (p *Policy) AuthorizeProduct(actor *Actor, product *model.Product) error {
+	if actor.IsAdmin() || (actor.ShopID != nil && product.ShopID == *actor.ShopID) {
+		return nil
+	}
+	return fmt.Errorf("%w: you don't own this product", ErrForbidden)
+}
+
+// AuthorizeReview allows the review's author
+func (p *Policy) AuthorizeReview(actor *Actor, review *model.Review) error {
+	if actor.IsAdmin() || review.UserID == actor.UserID {
+		return nil
+	}
+	return fmt.Errorf("%w: you don't own this review", ErrForbidden)
+}
+
+// AuthorizeCoupon allows the vendor of the coupon's shop. Platform-wide
+// coupons are admin only.
+func (p *Policy) AuthorizeCoupon(actor *Actor, coupon *model.Coupon) error {
+	if actor.IsAdmin() || (coupon.ShopID != nil && actor.ShopID != nil && *coupon.ShopID == *actor.ShopID) {
+		return nil
+	}
+	return fmt.Errorf("%w: you don't own this coupon", ErrForbidden)
+}
+
+// AuthorizeShippingMethod allows the vendor of the method's shop
+func (p *Policy) AuthorizeShippingMethod(actor *Actor, method *model.ShippingMethod) error {
+	if actor.IsAdmin() || (actor.ShopID != nil && method.ShopID == *actor.ShopID) {
+		return nil
+	}
+	return fmt.Errorf("%w: you don't own this shipping method", ErrForbidden)
+}
+
+// AuthorizeOrder allows vendors whose shop has items in the order
+func (p *Policy) AuthorizeOrder(ctx context.Context, actor *Actor, orderID uuid.UUID) error {
+	if actor.IsAdmin() {
+		return nil
+	}
+	if actor.ShopID != nil {
+		hasItems, err := p.orderRepo.HasShopItems(ctx, orderID, *actor.ShopID)
+		if err != nil {
+			return fmt.Errorf("failed to check order ownership: %w", err)
+		}
+		if hasItems {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: order has no items from your shop", ErrForbidden)
+}
@@ -2,33 +2,72 @@ package service
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/imbivek08/hamropasal/internal/database"
 	"github.com/imbivek08/hamropasal/internal/model"
 	"github.com/imbivek08/hamropasal/internal/repository"
+	"github.com/jackc/pgx/v5"
 )
 
+// ErrInvalidVariantOptions is returned for variants whose options don't match
+// the product's option types
+var ErrInvalidVariantOptions = errors.New("invalid variant options")
+
 type ProductService struct {
-	repo *repository.ProductRepository
+	db              *database.Database
+	repo            *repository.ProductRepository
+	imageRepo       *repository.ProductImageRepository
+	variantRepo     *repository.ProductVariantRepository
+	currencyService *CurrencyService
+	policy          *Policy
 }
 
-func NewProductService(productRepo *repository.ProductRepository) *ProductService {
+func NewProductService(db *database.Database, productRepo *repository.ProductRepository, imageRepo *repository.ProductImageRepository, variantRepo *repository.ProductVariantRepository, currencyService *CurrencyService, policy *Policy) *ProductService {
 	return &ProductService{
-		repo: productRepo,
+		db:              db,
+		repo:            productRepo,
+		imageRepo:       imageRepo,
+		variantRepo:     variantRepo,
+		currencyService: currencyService,
+		policy:          policy,
 	}
 }
 
 // CreateProduct creates a new product
 func (s *ProductService) CreateProduct(ctx context.Context, shopID uuid.UUID, req *model.CreateProductRequest) (*model.Product, error) {
+	weightUnit := req.WeightUnit
+	if weightUnit == "" {
+		weightUnit = model.WeightUnitKg
+	}
+
+	slug, err := s.uniqueSlug(ctx, req.Name, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	sku, err := s.productSKU(ctx, req.Name, req.SKU)
+	if err != nil {
+		return nil, err
+	}
+
 	product := &model.Product{
 		ID:            uuid.New(),
 		ShopID:        shopID,
 		CategoryID:    req.CategoryID,
 		Name:          req.Name,
+		Slug:          slug,
+		SKU:           &sku,
 		Description:   req.Description,
 		Price:         req.Price,
 		StockQuantity: req.StockQuantity,
+		Weight:        req.Weight,
+		WeightUnit:    weightUnit,
 		ImageURL:      req.ImageURL,
 		IsActive:      true,
 		CreatedAt:     time.Now(),
@@ -39,46 +78,206 @@ func (s *ProductService) CreateProduct(ctx context.Context, shopID uuid.UUID, re
 		return nil, err
 	}
 
+	// Start the gallery with the provided image
+	if req.ImageURL != nil && *req.ImageURL != "" {
+		if err := s.addPrimaryImage(ctx, product.ID, *req.ImageURL); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := s.attachImages(ctx, product); err != nil {
+		return nil, err
+	}
+
 	return product, nil
 }
 
 // GetProductByID retrieves a product by ID
 func (s *ProductService) GetProductByID(ctx context.Context, id uuid.UUID) (*model.Product, error) {
-	return s.repo.GetByID(ctx, id)
+	product, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.attachImages(ctx, product); err != nil {
+		return nil, err
+	}
+
+	if product.Options, product.Variants, err = s.loadVariants(ctx, product); err != nil {
+		return nil, err
+	}
+
+	return product, nil
+}
+
+// GetProductBySlug retrieves a product by its slug. Slugs the product used to
+// have still find it; callers compare product.Slug to redirect.
+func (s *ProductService) GetProductBySlug(ctx context.Context, slug string) (*model.Product, error) {
+	product, err := s.repo.GetBySlug(ctx, slug)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, errors.New("product not found")
+		}
+		return nil, fmt.Errorf("failed to get product: %w", err)
+	}
+
+	if err := s.attachImages(ctx, product); err != nil {
+		return nil, err
+	}
+
+	if product.Options, product.Variants, err = s.loadVariants(ctx, product); err != nil {
+		return nil, err
+	}
+
+	return product, nil
+}
+
+// priceFacetBounds are the lower bounds of the price range facets, in the
+// default currency
+var priceFacetBounds = []model.Money{
+	model.NewMoney(0, model.DefaultCurrency),
+	model.NewMoney(500_00, model.DefaultCurrency),
+	model.NewMoney(1000_00, model.DefaultCurrency),
+	model.NewMoney(2500_00, model.DefaultCurrency),
+	model.NewMoney(5000_00, model.DefaultCurrency),
+	model.NewMoney(10000_00, model.DefaultCurrency),
+}
+
+// ListProducts retrieves a page of active products with facets. With a
+// display currency, prices, price ranges and the min_price/max_price filters
+// are in that currency; otherwise prices are in each shop's currency and the
+// ranges and filters in the default currency.
+func (s *ProductService) ListProducts(ctx context.Context, filters map[string]interface{}, page, pageSize int, currency string) (*model.ProductListResponse, error) {
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	display, rates, err := s.currencyService.Resolve(ctx, currency)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, key := range []string{"min_price", "max_price"} {
+		if bound, ok := filters[key].(model.Money); ok {
+			bound.Currency = display
+			if filters[key], err = rates.Convert(bound, model.DefaultCurrency); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	products, total, err := s.repo.List(ctx, filters, page, pageSize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list products: %w", err)
+	}
+
+	facets, err := s.repo.Facets(ctx, filters, priceFacetBounds)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count product facets: %w", err)
+	}
+
+	for i := range facets.PriceRanges {
+		priceRange := &facets.PriceRanges[i]
+		if priceRange.Min, err = rates.Convert(priceRange.Min, display); err != nil {
+			return nil, err
+		}
+		if priceRange.Max != nil {
+			max, err := rates.Convert(*priceRange.Max, display)
+			if err != nil {
+				return nil, err
+			}
+			priceRange.Max = &max
+		}
+	}
+
+	if currency != "" {
+		for _, product := range products {
+			if product.Price, err = rates.Convert(product.Price, display); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if len(products) > 0 {
+		if err := s.attachImages(ctx, products...); err != nil {
+			return nil, err
+		}
+	}
+
+	responses := make([]*model.ProductResponse, len(products))
+	for i, product := range products {
+		responses[i] = product.ToResponse()
+	}
+
+	return &model.ProductListResponse{
+		Products:   responses,
+		Total:      total,
+		Page:       page,
+		PageSize:   pageSize,
+		TotalPages: int(math.Ceil(float64(total) / float64(pageSize))),
+		Facets:     facets,
+	}, nil
 }
 
-// GetAllProducts retrieves all products
-func (s *ProductService) GetAllProducts(ctx context.Context, filters map[string]interface{}) ([]*model.Product, error) {
-	return s.repo.GetAll(ctx, filters)
+// SuggestProducts retrieves up to limit active products matching a partly
+// typed search, best match first
+func (s *ProductService) SuggestProducts(ctx context.Context, search string, limit int) ([]model.ProductSuggestion, error) {
+	suggestions, err := s.repo.Suggest(ctx, search, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get product suggestions: %w", err)
+	}
+	return suggestions, nil
 }
 
 // GetShopProducts retrieves all products for a shop
 func (s *ProductService) GetShopProducts(ctx context.Context, shopID uuid.UUID) ([]*model.Product, error) {
-	return s.repo.GetByShopID(ctx, shopID)
+	products, err := s.repo.GetByShopID(ctx, shopID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.attachImages(ctx, products...); err != nil {
+		return nil, err
+	}
+
+	return products, nil
 }
 
 // UpdateProduct updates a product
-func (s *ProductService) UpdateProduct(ctx context.Context, productID uuid.UUID, shopID uuid.UUID, req *model.UpdateProductRequest) (*model.Product, error) {
-	// Get existing product
-	product, err := s.repo.GetByID(ctx, productID)
+func (s *ProductService) UpdateProduct(ctx context.Context, actor *Actor, productID uuid.UUID, req *model.UpdateProductRequest) (*model.Product, error) {
+	// Get existing product, checking the actor may change it
+	product, err := s.getOwnedProduct(ctx, productID, actor)
 	if err != nil {
 		return nil, err
 	}
 
-	// Check if shop owns the product
-	if product.ShopID != shopID {
-		return nil, err // You could create a custom error for unauthorized access
-	}
-
-	// Update fields if provided
-	if req.Name != nil {
+	// Update fields if provided. A new name gives a new slug; the old one
+	// redirects to it.
+	oldSlug := product.Slug
+	if req.Name != nil && *req.Name != product.Name {
+		if product.Slug, err = s.uniqueSlug(ctx, *req.Name, &product.ID); err != nil {
+			return nil, err
+		}
 		product.Name = *req.Name
 	}
+	if req.SKU != nil {
+		sku := strings.TrimSpace(*req.SKU)
+		if sku == "" {
+			return nil, errors.New("sku cannot be empty")
+		}
+		if err := s.checkSKUFree(ctx, sku, &product.ID); err != nil {
+			return nil, err
+		}
+		product.SKU = &sku
+	}
 	if req.Description != nil {
 		product.Description = req.Description
 	}
 	if req.Price != nil {
-		product.Price = *req.Price
+		product.Price.Amount = req.Price.Amount // Still in the shop's currency
 	}
 	if req.StockQuantity != nil {
 		product.StockQuantity = *req.StockQuantity
@@ -86,8 +285,11 @@ func (s *ProductService) UpdateProduct(ctx context.Context, productID uuid.UUID,
 	if req.CategoryID != nil {
 		product.CategoryID = req.CategoryID
 	}
-	if req.ImageURL != nil {
-		product.ImageURL = req.ImageURL
+	if req.Weight != nil {
+		product.Weight = req.Weight
+	}
+	if req.WeightUnit != nil {
+		product.WeightUnit = *req.WeightUnit
 	}
 	if req.IsActive != nil {
 		product.IsActive = *req.IsActive
@@ -95,7 +297,30 @@ func (s *ProductService) UpdateProduct(ctx context.Context, productID uuid.UUID,
 
 	product.UpdatedAt = time.Now()
 
-	if err := s.repo.Update(ctx, product); err != nil {
+	err = s.db.WithTx(ctx, func(ctx context.Context) error {
+		if err := s.repo.Update(ctx, product); err != nil {
+			return err
+		}
+		if product.Slug != oldSlug {
+			if err := s.repo.AddSlugRedirect(ctx, product.ID, oldSlug, product.Slug); err != nil {
+				return fmt.Errorf("failed to redirect old slug: %w", err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// image_url always mirrors the primary gallery image, so route it through the gallery
+	if req.ImageURL != nil && *req.ImageURL != "" {
+		if err := s.addPrimaryImage(ctx, product.ID, *req.ImageURL); err != nil {
+			return nil, err
+		}
+		product.ImageURL = req.ImageURL
+	}
+
+	if err := s.attachImages(ctx, product); err != nil {
 		return nil, err
 	}
 
@@ -103,17 +328,511 @@ func (s *ProductService) UpdateProduct(ctx context.Context, productID uuid.UUID,
 }
 
 // DeleteProduct deletes a product
-func (s *ProductService) DeleteProduct(ctx context.Context, productID uuid.UUID, shopID uuid.UUID) error {
-	// Get existing product
+func (s *ProductService) DeleteProduct(ctx context.Context, actor *Actor, productID uuid.UUID) error {
+	// Get existing product, checking the actor may delete it
+	if _, err := s.getOwnedProduct(ctx, productID, actor); err != nil {
+		return err
+	}
+
+	return s.repo.Delete(ctx, productID)
+}
+
+// GetProductImages retrieves the ordered gallery for a product
+func (s *ProductService) GetProductImages(ctx context.Context, productID uuid.UUID) ([]model.ProductImage, error) {
+	if _, err := s.repo.GetByID(ctx, productID); err != nil {
+		return nil, errors.New("product not found")
+	}
+
+	images, err := s.imageRepo.GetByProductID(ctx, productID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get product images: %w", err)
+	}
+	if images == nil {
+		images = []model.ProductImage{}
+	}
+
+	return images, nil
+}
+
+// AddProductImage appends an image to a product's gallery (own products, or admin)
+func (s *ProductService) AddProductImage(ctx context.Context, actor *Actor, productID uuid.UUID, req *model.AddProductImageRequest) (*model.ProductImage, error) {
+	if _, err := s.getOwnedProduct(ctx, productID, actor); err != nil {
+		return nil, err
+	}
+
+	image := &model.ProductImage{
+		ID:        uuid.New(),
+		ProductID: productID,
+		ImageURL:  req.ImageURL,
+		AltText:   req.AltText,
+		IsPrimary: req.IsPrimary,
+		CreatedAt: time.Now(),
+	}
+
+	if err := s.imageRepo.Create(ctx, image); err != nil {
+		return nil, fmt.Errorf("failed to add product image: %w", err)
+	}
+
+	return image, nil
+}
+
+// ReorderProductImages reorders a product's gallery. imageIDs must contain every image exactly once.
+func (s *ProductService) ReorderProductImages(ctx context.Context, actor *Actor, productID uuid.UUID, imageIDs []uuid.UUID) ([]model.ProductImage, error) {
+	if _, err := s.getOwnedProduct(ctx, productID, actor); err != nil {
+		return nil, err
+	}
+
+	images, err := s.imageRepo.GetByProductID(ctx, productID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get product images: %w", err)
+	}
+
+	if len(imageIDs) != len(images) {
+		return nil, errors.New("image_ids must list every product image exactly once")
+	}
+	existing := make(map[uuid.UUID]bool, len(images))
+	for _, image := range images {
+		existing[image.ID] = true
+	}
+	for _, id := range imageIDs {
+		if !existing[id] {
+			return nil, errors.New("image_ids must list every product image exactly once")
+		}
+		delete(existing, id)
+	}
+
+	if err := s.imageRepo.Reorder(ctx, productID, imageIDs); err != nil {
+		return nil, fmt.Errorf("failed to reorder product images: %w", err)
+	}
+
+	return s.imageRepo.GetByProductID(ctx, productID)
+}
+
+// SetPrimaryProductImage makes an image the product's primary image (own products, or admin)
+func (s *ProductService) SetPrimaryProductImage(ctx context.Context, actor *Actor, productID, imageID uuid.UUID) error {
+	if _, err := s.getOwnedProduct(ctx, productID, actor); err != nil {
+		return err
+	}
+
+	if err := s.imageRepo.SetPrimary(ctx, productID, imageID); err != nil {
+		if err.Error() == "image not found" {
+			return err
+		}
+		return fmt.Errorf("failed to set primary image: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteProductImage removes an image from a product's gallery (own products, or admin)
+func (s *ProductService) DeleteProductImage(ctx context.Context, actor *Actor, productID, imageID uuid.UUID) error {
+	if _, err := s.getOwnedProduct(ctx, productID, actor); err != nil {
+		return err
+	}
+
+	if err := s.imageRepo.Delete(ctx, productID, imageID); err != nil {
+		if err.Error() == "image not found" {
+			return err
+		}
+		return fmt.Errorf("failed to delete product image: %w", err)
+	}
+
+	return nil
+}
+
+// GetProductVariants retrieves a product's option types and variants
+func (s *ProductService) GetProductVariants(ctx context.Context, productID uuid.UUID) (*model.ProductVariantList, error) {
 	product, err := s.repo.GetByID(ctx, productID)
 	if err != nil {
+		return nil, errors.New("product not found")
+	}
+
+	options, variants, err := s.loadVariants(ctx, product)
+	if err != nil {
+		return nil, err
+	}
+
+	return &model.ProductVariantList{Options: options, Variants: variants}, nil
+}
+
+// AddProductOption adds an option type, such as Size, to a product (own
+// products, or admin). Options are fixed once the product has variants.
+func (s *ProductService) AddProductOption(ctx context.Context, actor *Actor, productID uuid.UUID, req *model.CreateProductOptionRequest) (*model.ProductOption, error) {
+	if _, err := s.getOwnedProduct(ctx, productID, actor); err != nil {
+		return nil, err
+	}
+
+	if err := s.checkNoVariants(ctx, productID); err != nil {
+		return nil, err
+	}
+
+	name := strings.TrimSpace(req.Name)
+	options, err := s.variantRepo.ListOptions(ctx, productID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get product options: %w", err)
+	}
+	for _, option := range options {
+		if strings.EqualFold(option.Name, name) {
+			return nil, errors.New("option already exists")
+		}
+	}
+
+	values := make([]string, 0, len(req.Values))
+	seen := make(map[string]bool, len(req.Values))
+	for _, value := range req.Values {
+		value = strings.TrimSpace(value)
+		if value == "" || seen[value] {
+			continue
+		}
+		seen[value] = true
+		values = append(values, value)
+	}
+
+	option := &model.ProductOption{
+		ID:        uuid.New(),
+		ProductID: productID,
+		Name:      name,
+		Values:    values,
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+
+	if err := s.variantRepo.CreateOption(ctx, option); err != nil {
+		return nil, fmt.Errorf("failed to add product option: %w", err)
+	}
+
+	return option, nil
+}
+
+// DeleteProductOption removes an option type from a product (own products, or admin)
+func (s *ProductService) DeleteProductOption(ctx context.Context, actor *Actor, productID, optionID uuid.UUID) error {
+	if _, err := s.getOwnedProduct(ctx, productID, actor); err != nil {
 		return err
 	}
 
-	// Check if shop owns the product
-	if product.ShopID != shopID {
-		return err // You could create a custom error for unauthorized access
+	if err := s.checkNoVariants(ctx, productID); err != nil {
+		return err
 	}
 
-	return s.repo.Delete(ctx, productID)
+	if err := s.variantRepo.DeleteOption(ctx, productID, optionID); err != nil {
+		if err.Error() == "option not found" {
+			return err
+		}
+		return fmt.Errorf("failed to delete product option: %w", err)
+	}
+
+	return nil
+}
+
+// CreateProductVariant adds a variant for one combination of the product's
+// option values (own products, or admin)
+func (s *ProductService) CreateProductVariant(ctx context.Context, actor *Actor, productID uuid.UUID, req *model.CreateProductVariantRequest) (*model.ProductVariant, error) {
+	product, err := s.getOwnedProduct(ctx, productID, actor)
+	if err != nil {
+		return nil, err
+	}
+
+	options, variants, err := s.loadVariants(ctx, product)
+	if err != nil {
+		return nil, err
+	}
+
+	selected, err := matchVariantOptions(options, req.Options)
+	if err != nil {
+		return nil, err
+	}
+	name := model.VariantName(selected)
+	for _, variant := range variants {
+		if variant.Name() == name {
+			return nil, errors.New("a variant with these options already exists")
+		}
+	}
+
+	sku := strings.TrimSpace(req.SKU)
+	if err := s.checkSKUFree(ctx, sku, nil); err != nil {
+		return nil, err
+	}
+
+	variant := &model.ProductVariant{
+		ID:            uuid.New(),
+		ProductID:     productID,
+		SKU:           sku,
+		Options:       selected,
+		Price:         req.Price,
+		StockQuantity: req.StockQuantity,
+		ImageURL:      req.ImageURL,
+		IsActive:      true,
+		CreatedAt:     time.Now(),
+		UpdatedAt:     time.Now(),
+	}
+	if variant.Price != nil {
+		variant.Price.Currency = product.Price.Currency // In the shop's currency
+	}
+
+	if err := s.variantRepo.CreateVariant(ctx, variant); err != nil {
+		return nil, fmt.Errorf("failed to add product variant: %w", err)
+	}
+
+	return variant, nil
+}
+
+// UpdateProductVariant updates a variant's SKU, price, stock, image or
+// availability (own products, or admin)
+func (s *ProductService) UpdateProductVariant(ctx context.Context, actor *Actor, productID, variantID uuid.UUID, req *model.UpdateProductVariantRequest) (*model.ProductVariant, error) {
+	product, err := s.getOwnedProduct(ctx, productID, actor)
+	if err != nil {
+		return nil, err
+	}
+
+	variant, err := s.variantRepo.GetVariantByID(ctx, variantID)
+	if err != nil || variant.ProductID != productID {
+		return nil, errors.New("variant not found")
+	}
+
+	if req.SKU != nil {
+		sku := strings.TrimSpace(*req.SKU)
+		if err := s.checkSKUFree(ctx, sku, &variant.ID); err != nil {
+			return nil, err
+		}
+		variant.SKU = sku
+	}
+	if req.Price != nil {
+		variant.Price = req.Price
+	}
+	if req.StockQuantity != nil {
+		variant.StockQuantity = *req.StockQuantity
+	}
+	if req.ImageURL != nil {
+		variant.ImageURL = req.ImageURL
+	}
+	if req.IsActive != nil {
+		variant.IsActive = *req.IsActive
+	}
+	if variant.Price != nil {
+		variant.Price.Currency = product.Price.Currency
+	}
+
+	if err := s.variantRepo.UpdateVariant(ctx, variant); err != nil {
+		if err.Error() == "variant not found" {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to update product variant: %w", err)
+	}
+
+	variant.UpdatedAt = time.Now()
+	return variant, nil
+}
+
+// DeleteProductVariant removes a variant from a product (own products, or
+// admin). A variant already ordered is deactivated instead, so cancelled and
+// refunded lines can still be restocked to it.
+func (s *ProductService) DeleteProductVariant(ctx context.Context, actor *Actor, productID, variantID uuid.UUID) error {
+	if _, err := s.getOwnedProduct(ctx, productID, actor); err != nil {
+		return err
+	}
+
+	ordered, err := s.variantRepo.IsVariantOrdered(ctx, variantID)
+	if err != nil {
+		return fmt.Errorf("failed to check variant orders: %w", err)
+	}
+
+	remove := s.variantRepo.DeleteVariant
+	if ordered {
+		remove = s.variantRepo.DeactivateVariant
+	}
+	if err := remove(ctx, productID, variantID); err != nil {
+		if err.Error() == "variant not found" {
+			return err
+		}
+		return fmt.Errorf("failed to delete product variant: %w", err)
+	}
+
+	return nil
+}
+
+// loadVariants retrieves a product's option types and variants, with variant
+// prices in the product's currency
+func (s *ProductService) loadVariants(ctx context.Context, product *model.Product) ([]model.ProductOption, []model.ProductVariant, error) {
+	options, err := s.variantRepo.ListOptions(ctx, product.ID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get product options: %w", err)
+	}
+
+	variants, err := s.variantRepo.ListVariants(ctx, product.ID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get product variants: %w", err)
+	}
+	for i := range variants {
+		if variants[i].Price != nil {
+			variants[i].Price.Currency = product.Price.Currency
+		}
+	}
+
+	return options, variants, nil
+}
+
+// checkNoVariants refuses to change a product's option types once variants
+// are built from them
+func (s *ProductService) checkNoVariants(ctx context.Context, productID uuid.UUID) error {
+	hasVariants, err := s.variantRepo.HasVariants(ctx, productID)
+	if err != nil {
+		return fmt.Errorf("failed to check product variants: %w", err)
+	}
+	if hasVariants {
+		return errors.New("delete the product's variants before changing its options")
+	}
+	return nil
+}
+
+// checkSKUFree checks no product or variant other than excludeID uses sku
+func (s *ProductService) checkSKUFree(ctx context.Context, sku string, excludeID *uuid.UUID) error {
+	skuExists, err := s.repo.SKUExists(ctx, sku, excludeID)
+	if err != nil {
+		return fmt.Errorf("failed to check sku uniqueness: %w", err)
+	}
+	if skuExists {
+		return errors.New("sku already exists")
+	}
+	return nil
+}
+
+// productSKU checks a vendor-supplied SKU is free, or generates one from the
+// product name when none is given
+func (s *ProductService) productSKU(ctx context.Context, name string, requested *string) (string, error) {
+	if requested != nil && strings.TrimSpace(*requested) != "" {
+		sku := strings.TrimSpace(*requested)
+		if err := s.checkSKUFree(ctx, sku, nil); err != nil {
+			return "", err
+		}
+		return sku, nil
+	}
+
+	for {
+		sku := generateSKU(name)
+		skuExists, err := s.repo.SKUExists(ctx, sku, nil)
+		if err != nil {
+			return "", fmt.Errorf("failed to check sku uniqueness: %w", err)
+		}
+		if !skuExists {
+			return sku, nil
+		}
+	}
+}
+
+func (s *ProductService) uniqueSlug(ctx context.Context, name string, excludeID *uuid.UUID) (string, error) {
+	slug := generateSlug(name)
+
+	slugExists, err := s.repo.SlugExists(ctx, slug, excludeID)
+	if err != nil {
+		return "", fmt.Errorf("failed to check slug uniqueness: %w", err)
+	}
+	if slugExists {
+		slug = generateUniqueSlug(slug)
+	}
+
+	return slug, nil
+}
+
+// matchVariantOptions checks a variant gives one listed value for each of the
+// product's option types, returning them keyed by the options' own names
+func matchVariantOptions(options []model.ProductOption, requested map[string]string) (map[string]string, error) {
+	if len(options) == 0 {
+		return nil, fmt.Errorf("%w: add options to the product before adding variants", ErrInvalidVariantOptions)
+	}
+
+	names := make([]string, len(options))
+	for i, option := range options {
+		names[i] = option.Name
+	}
+	if len(requested) != len(options) {
+		return nil, fmt.Errorf("%w: give one value for each of %s", ErrInvalidVariantOptions, strings.Join(names, ", "))
+	}
+
+	selected := make(map[string]string, len(options))
+	for _, option := range options {
+		value, ok := "", false
+		for name, v := range requested {
+			if strings.EqualFold(name, option.Name) {
+				value, ok = strings.TrimSpace(v), true
+				break
+			}
+		}
+		if !ok {
+			return nil, fmt.Errorf("%w: give one value for each of %s", ErrInvalidVariantOptions, strings.Join(names, ", "))
+		}
+
+		valid := false
+		for _, allowed := range option.Values {
+			if strings.EqualFold(allowed, value) {
+				value, valid = allowed, true
+				break
+			}
+		}
+		if !valid {
+			return nil, fmt.Errorf("%w: %s must be one of %s", ErrInvalidVariantOptions, option.Name, strings.Join(option.Values, ", "))
+		}
+		selected[option.Name] = value
+	}
+
+	return selected, nil
+}
+
+// getOwnedProduct retrieves a product and checks that the actor may change it
+func (s *ProductService) getOwnedProduct(ctx context.Context, productID uuid.UUID, actor *Actor) (*model.Product, error) {
+	product, err := s.repo.GetByID(ctx, productID)
+	if err != nil {
+		return nil, errors.New("product not found")
+	}
+
+	if err := s.policy.AuthorizeProduct(actor, product); err != nil {
+		return nil, err
+	}
+
+	return product, nil
+}
+
+// addPrimaryImage makes imageURL the primary image, reusing an existing gallery entry when present
+func (s *ProductService) addPrimaryImage(ctx context.Context, productID uuid.UUID, imageURL string) error {
+	images, err := s.imageRepo.GetByProductID(ctx, productID)
+	if err != nil {
+		return fmt.Errorf("failed to get product images: %w", err)
+	}
+
+	for _, image := range images {
+		if image.ImageURL == imageURL {
+			return s.imageRepo.SetPrimary(ctx, productID, image.ID)
+		}
+	}
+
+	image := &model.ProductImage{
+		ID:        uuid.New(),
+		ProductID: productID,
+		ImageURL:  imageURL,
+		IsPrimary: true,
+		CreatedAt: time.Now(),
+	}
+	if err := s.imageRepo.Create(ctx, image); err != nil {
+		return fmt.Errorf("failed to add product image: %w", err)
+	}
+
+	return nil
+}
+
+// attachImages loads the galleries for the given products in a single query
+func (s *ProductService) attachImages(ctx context.Context, products ...*model.Product) error {
+	ids := make([]uuid.UUID, 0, len(products))
+	for _, product := range products {
+		ids = append(ids, product.ID)
+	}
+
+	galleries, err := s.imageRepo.GetByProductIDs(ctx, ids)
+	if err != nil {
+		return fmt.Errorf("failed to get product images: %w", err)
+	}
+
+	for _, product := range products {
+		product.Images = galleries[product.ID]
+	}
+
+	return nil
 }
@@ -0,0 +1,535 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/imbivek08/hamropasal/internal/database"
+	"github.com/imbivek08/hamropasal/internal/model"
+	"github.com/imbivek08/hamropasal/internal/payment"
+	"github.com/imbivek08/hamropasal/internal/repository"
+	"github.com/jackc/pgx/v5"
+)
+
+// RefundService refunds paid orders through their payment method's provider
+// and keeps the order's payment status, order status, stock and the shops'
+// ledger in step with the refunds
+type RefundService struct {
+	db             *database.Database
+	payments       *payment.Registry
+	refundRepo     *repository.RefundRepository
+	orderRepo      *repository.OrderRepository
+	fulfilmentRepo *repository.FulfilmentRepository
+	productRepo    *repository.ProductRepository
+	ledgerService  *LedgerService
+}
+
+func NewRefundService(
+	db *database.Database,
+	payments *payment.Registry,
+	refundRepo *repository.RefundRepository,
+	orderRepo *repository.OrderRepository,
+	fulfilmentRepo *repository.FulfilmentRepository,
+	productRepo *repository.ProductRepository,
+	ledgerService *LedgerService,
+) *RefundService {
+	return &RefundService{
+		db:             db,
+		payments:       payments,
+		refundRepo:     refundRepo,
+		orderRepo:      orderRepo,
+		fulfilmentRepo: fulfilmentRepo,
+		productRepo:    productRepo,
+		ledgerService:  ledgerService,
+	}
+}
+
+// CreateRefund refunds an order (admin) or the caller's shop lines of an order
+// (vendor, shopID set). Without items everything still refundable is refunded.
+func (s *RefundService) CreateRefund(ctx context.Context, orderID uuid.UUID, shopID *uuid.UUID, actorID uuid.UUID, req *model.CreateRefundRequest) (*model.Refund, error) {
+	var pending *model.Refund
+	var order *model.Order
+	var provider payment.Provider
+
+	// Validate and record the refund under the order lock so concurrent
+	// requests cannot refund the same lines twice
+	err := s.db.WithTx(ctx, func(ctx context.Context) error {
+		if err := s.orderRepo.LockByID(ctx, orderID); err != nil {
+			return errors.New("order not found")
+		}
+
+		var err error
+		order, err = s.orderRepo.GetByID(ctx, orderID)
+		if err != nil {
+			return errors.New("order not found")
+		}
+
+		if order.PaymentMethod == nil ||
+			(order.PaymentStatus != model.PaymentStatusPaid && order.PaymentStatus != model.PaymentStatusPartiallyRefunded) {
+			return errors.New("only paid orders can be refunded")
+		}
+
+		provider, err = s.payments.Get(*order.PaymentMethod)
+		if err != nil {
+			return err
+		}
+
+		items, err := s.orderRepo.GetOrderItems(ctx, orderID)
+		if err != nil {
+			return fmt.Errorf("failed to get order items: %w", err)
+		}
+
+		refunded, err := s.refundRepo.GetRefundedQuantities(ctx, orderID)
+		if err != nil {
+			return fmt.Errorf("failed to get refunded quantities: %w", err)
+		}
+
+		refundItems, err := buildRefundItems(items, refunded, shopID, req.Items)
+		if err != nil {
+			return err
+		}
+
+		// Never refund more than was paid and not yet refunded
+		alreadyRefunded, err := s.refundRepo.GetRefundedAmount(ctx, orderID, model.RefundStatusPending, model.RefundStatusSucceeded)
+		if err != nil {
+			return fmt.Errorf("failed to get refunded amount: %w", err)
+		}
+		remaining := order.Total.Sub(alreadyRefunded)
+
+		var amount model.Money
+		for _, item := range refundItems {
+			amount = amount.Add(item.Amount)
+		}
+
+		// An admin refunding everything also returns shipping and tax
+		if shopID == nil && len(req.Items) == 0 {
+			amount = remaining
+		}
+		amount = amount.Min(remaining)
+		if !amount.IsPositive() {
+			return errors.New("nothing left to refund")
+		}
+
+		now := time.Now()
+		pending = &model.Refund{
+			ID:        uuid.New(),
+			OrderID:   orderID,
+			Amount:    amount,
+			Reason:    req.Reason,
+			Status:    model.RefundStatusPending,
+			Restock:   req.Restock,
+			CreatedBy: &actorID,
+			CreatedAt: now,
+			UpdatedAt: now,
+		}
+		for i := range refundItems {
+			refundItems[i].ID = uuid.New()
+			refundItems[i].RefundID = pending.ID
+		}
+		pending.Items = refundItems
+
+		return s.refundRepo.Create(ctx, pending)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.issue(ctx, provider, order, pending)
+}
+
+// RefundCancelledOrder refunds in full a payment that arrived after its order
+// was cancelled. The order stays cancelled; its stock was already released.
+func (s *RefundService) RefundCancelledOrder(ctx context.Context, orderID uuid.UUID) (*model.Refund, error) {
+	var pending *model.Refund
+	var order *model.Order
+	var provider payment.Provider
+
+	err := s.db.WithTx(ctx, func(ctx context.Context) error {
+		if err := s.orderRepo.LockByID(ctx, orderID); err != nil {
+			return errors.New("order not found")
+		}
+
+		var err error
+		order, err = s.orderRepo.GetByID(ctx, orderID)
+		if err != nil {
+			return errors.New("order not found")
+		}
+
+		if order.Status != model.OrderStatusCancelled || order.PaymentMethod == nil || order.PaymentStatus != model.PaymentStatusPaid {
+			return errors.New("only paid cancelled orders can be refunded")
+		}
+
+		provider, err = s.payments.Get(*order.PaymentMethod)
+		if err != nil {
+			return err
+		}
+
+		alreadyRefunded, err := s.refundRepo.GetRefundedAmount(ctx, orderID, model.RefundStatusPending, model.RefundStatusSucceeded)
+		if err != nil {
+			return fmt.Errorf("failed to get refunded amount: %w", err)
+		}
+		amount := order.Total.Sub(alreadyRefunded)
+		if !amount.IsPositive() {
+			return nil
+		}
+
+		reason := "order was cancelled before its payment arrived"
+		now := time.Now()
+		pending = &model.Refund{
+			ID:        uuid.New(),
+			OrderID:   orderID,
+			Amount:    amount,
+			Reason:    &reason,
+			Status:    model.RefundStatusPending,
+			CreatedAt: now,
+			UpdatedAt: now,
+		}
+
+		return s.refundRepo.Create(ctx, pending)
+	})
+	if err != nil || pending == nil {
+		return nil, err
+	}
+
+	return s.issue(ctx, provider, order, pending)
+}
+
+// issue sends a recorded pending refund to the order's gateway and applies
+// the outcome
+func (s *RefundService) issue(ctx context.Context, provider payment.Provider, order *model.Order, pending *model.Refund) (*model.Refund, error) {
+	transactionID, err := s.orderRepo.GetPaymentTransactionID(ctx, order.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get payment transaction: %w", err)
+	}
+
+	// Issue the refund at the gateway; the refund row ID doubles as idempotency key
+	refundReq := &payment.RefundRequest{RefundID: pending.ID, Order: order, Amount: pending.Amount}
+	if transactionID != nil {
+		refundReq.TransactionID = *transactionID
+	}
+	update, err := provider.Refund(ctx, refundReq)
+	if err != nil {
+		if markErr := s.refundRepo.MarkFailed(ctx, pending.ID, err.Error()); markErr != nil {
+			return nil, fmt.Errorf("failed to record refund failure: %w (%s error: %v)", markErr, provider.Name(), err)
+		}
+		return nil, fmt.Errorf("refund failed: %w", err)
+	}
+
+	if update.ID != "" {
+		if err := s.refundRepo.SetProviderRefundID(ctx, pending.ID, update.ID); err != nil {
+			return nil, fmt.Errorf("failed to save provider refund id: %w", err)
+		}
+	}
+
+	if err := s.settle(ctx, pending.ID, update.Status, update.FailureReason); err != nil {
+		return nil, err
+	}
+
+	return s.refundRepo.GetByID(ctx, pending.ID)
+}
+
+// GetOrderRefunds lists an order's refunds. Vendors (shopID set) only see
+// orders that contain their products.
+func (s *RefundService) GetOrderRefunds(ctx context.Context, orderID uuid.UUID, shopID *uuid.UUID) ([]*model.Refund, error) {
+	if _, err := s.orderRepo.GetByID(ctx, orderID); err != nil {
+		return nil, errors.New("order not found")
+	}
+
+	if shopID != nil {
+		items, err := s.orderRepo.GetOrderItems(ctx, orderID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get order items: %w", err)
+		}
+		if !containsShop(items, *shopID) {
+			return nil, errors.New("order not found")
+		}
+	}
+
+	refunds, err := s.refundRepo.GetByOrderID(ctx, orderID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get refunds: %w", err)
+	}
+	return refunds, nil
+}
+
+// ReconcileRefunds applies the refunds a payment method's gateway reports for
+// a transaction. Refunds made directly at the gateway, such as in the Stripe
+// dashboard, are recorded too, without restocking.
+func (s *RefundService) ReconcileRefunds(ctx context.Context, method, transactionID string, updates []payment.RefundUpdate) error {
+	orderID, err := s.orderRepo.GetIDByPaymentTransactionID(ctx, method, transactionID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			// Not one of our orders
+			return nil
+		}
+		return fmt.Errorf("failed to find order: %w", err)
+	}
+
+	for _, update := range updates {
+		local, err := s.findLocalRefund(ctx, update)
+		if err != nil {
+			return err
+		}
+
+		if local == nil {
+			if update.Status != model.RefundStatusSucceeded {
+				continue
+			}
+			if local, err = s.recordExternalRefund(ctx, orderID, method, update); err != nil {
+				return err
+			}
+		}
+
+		if err := s.settle(ctx, local.ID, update.Status, update.FailureReason); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// settle applies a gateway's refund status to a local refund. Success
+// restocks (when asked), debits the shops and updates the order exactly once;
+// pending refunds wait for the gateway to report them.
+func (s *RefundService) settle(ctx context.Context, refundID uuid.UUID, status model.RefundStatus, failureReason string) error {
+	switch status {
+	case model.RefundStatusSucceeded:
+		return s.db.WithTx(ctx, func(ctx context.Context) error {
+			transitioned, err := s.refundRepo.MarkSucceeded(ctx, refundID)
+			if err != nil {
+				return fmt.Errorf("failed to update refund: %w", err)
+			}
+			if !transitioned {
+				return nil
+			}
+
+			refund, err := s.refundRepo.GetByID(ctx, refundID)
+			if err != nil {
+				return fmt.Errorf("failed to get refund: %w", err)
+			}
+
+			if err := s.orderRepo.LockByID(ctx, refund.OrderID); err != nil {
+				return fmt.Errorf("order not found: %w", err)
+			}
+
+			if refund.Restock {
+				if err := s.restock(ctx, refund); err != nil {
+					return err
+				}
+			}
+
+			if err := s.ledgerService.RecordRefund(ctx, refund); err != nil {
+				return err
+			}
+
+			return s.updateOrderPaymentStatus(ctx, refund.OrderID)
+		})
+
+	case model.RefundStatusFailed:
+		if failureReason == "" {
+			failureReason = string(status)
+		}
+		if err := s.refundRepo.MarkFailed(ctx, refundID, failureReason); err != nil {
+			return fmt.Errorf("failed to update refund: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// updateOrderPaymentStatus marks the order partially or fully refunded
+// depending on how much of its total has been refunded
+func (s *RefundService) updateOrderPaymentStatus(ctx context.Context, orderID uuid.UUID) error {
+	order, err := s.orderRepo.GetByID(ctx, orderID)
+	if err != nil {
+		return fmt.Errorf("order not found: %w", err)
+	}
+
+	refunded, err := s.refundRepo.GetRefundedAmount(ctx, orderID, model.RefundStatusSucceeded)
+	if err != nil {
+		return fmt.Errorf("failed to get refunded amount: %w", err)
+	}
+
+	if refunded.Cmp(order.Total) < 0 {
+		return s.orderRepo.UpdatePaymentStatus(ctx, orderID, model.PaymentStatusPartiallyRefunded)
+	}
+
+	if err := s.orderRepo.UpdatePaymentStatus(ctx, orderID, model.PaymentStatusRefunded); err != nil {
+		return fmt.Errorf("failed to update payment status: %w", err)
+	}
+
+	// A fully refunded order is closed, whatever its fulfilment state
+	if order.Status != model.OrderStatusCancelled && order.Status != model.OrderStatusRefunded {
+		if err := s.fulfilmentRepo.TransitionForOrder(ctx, orderID, model.OrderStatusRefunded,
+			model.OrderStatusPending, model.OrderStatusConfirmed, model.OrderStatusProcessing,
+			model.OrderStatusShipped, model.OrderStatusDelivered); err != nil {
+			return fmt.Errorf("failed to update fulfilments: %w", err)
+		}
+		if err := s.orderRepo.UpdateStatus(ctx, orderID, model.OrderStatusRefunded); err != nil {
+			return fmt.Errorf("failed to update order status: %w", err)
+		}
+	}
+
+	return nil
+}
+
+func (s *RefundService) restock(ctx context.Context, refund *model.Refund) error {
+	if len(refund.Items) == 0 {
+		return nil
+	}
+
+	items, err := s.orderRepo.GetOrderItems(ctx, refund.OrderID)
+	if err != nil {
+		return fmt.Errorf("failed to get order items: %w", err)
+	}
+	orderItems := make(map[uuid.UUID]model.OrderItemWithDetails, len(items))
+	for _, item := range items {
+		orderItems[item.ID] = item
+	}
+
+	for _, item := range refund.Items {
+		orderItem := orderItems[item.OrderItemID]
+		if err := s.productRepo.IncreaseStock(ctx, orderItem.ProductID, orderItem.VariantID, item.Quantity); err != nil {
+			return fmt.Errorf("failed to restore stock: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// findLocalRefund matches a gateway refund by its ID, falling back to the
+// shop's refund ID the gateway echoed back for refunds whose gateway ID was
+// never saved
+func (s *RefundService) findLocalRefund(ctx context.Context, update payment.RefundUpdate) (*model.Refund, error) {
+	if update.ID != "" {
+		local, err := s.refundRepo.GetByProviderRefundID(ctx, update.ID)
+		if err == nil {
+			return local, nil
+		}
+		if !errors.Is(err, pgx.ErrNoRows) {
+			return nil, fmt.Errorf("failed to get refund: %w", err)
+		}
+	}
+
+	if update.RefundID == uuid.Nil {
+		return nil, nil
+	}
+
+	local, err := s.refundRepo.GetByID(ctx, update.RefundID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get refund: %w", err)
+	}
+
+	if update.ID != "" {
+		if err := s.refundRepo.SetProviderRefundID(ctx, local.ID, update.ID); err != nil {
+			return nil, fmt.Errorf("failed to save provider refund id: %w", err)
+		}
+	}
+
+	return local, nil
+}
+
+func (s *RefundService) recordExternalRefund(ctx context.Context, orderID uuid.UUID, method string, update payment.RefundUpdate) (*model.Refund, error) {
+	reason := "refunded in " + method
+	now := time.Now()
+	external := &model.Refund{
+		ID:        uuid.New(),
+		OrderID:   orderID,
+		Amount:    update.Amount,
+		Reason:    &reason,
+		Status:    model.RefundStatusPending,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if update.ID != "" {
+		external.ProviderRefundID = &update.ID
+	}
+
+	if err := s.refundRepo.Create(ctx, external); err != nil {
+		return nil, fmt.Errorf("failed to record %s refund: %w", method, err)
+	}
+
+	return external, nil
+}
+
+// buildRefundItems resolves the requested lines against what is still
+// refundable. Vendors may only refund lines of their own shop.
+func buildRefundItems(items []model.OrderItemWithDetails, refunded map[uuid.UUID]int, shopID *uuid.UUID, requested []model.RefundItemInput) ([]model.RefundItem, error) {
+	if shopID != nil && !containsShop(items, *shopID) {
+		return nil, errors.New("order not found")
+	}
+
+	byID := make(map[uuid.UUID]model.OrderItemWithDetails, len(items))
+	for _, item := range items {
+		byID[item.ID] = item
+	}
+
+	// No lines given: everything refundable by the caller
+	if len(requested) == 0 {
+		for _, item := range items {
+			if shopID != nil && item.ShopID != *shopID {
+				continue
+			}
+			if remaining := item.Quantity - refunded[item.ID]; remaining > 0 {
+				requested = append(requested, model.RefundItemInput{OrderItemID: item.ID, Quantity: remaining})
+			}
+		}
+	}
+
+	quantities := make(map[uuid.UUID]int)
+	var refundItems []model.RefundItem
+	for _, input := range requested {
+		item, ok := byID[input.OrderItemID]
+		if !ok || (shopID != nil && item.ShopID != *shopID) {
+			return nil, fmt.Errorf("order item %s not found", input.OrderItemID)
+		}
+
+		quantities[item.ID] += input.Quantity
+		if quantities[item.ID] > item.Quantity-refunded[item.ID] {
+			return nil, fmt.Errorf("cannot refund more than %d of %s", item.Quantity-refunded[item.ID], item.ProductName)
+		}
+
+		// Units already refunded, in earlier refunds or earlier in this one
+		from := refunded[item.ID] + quantities[item.ID] - input.Quantity
+
+		refundItems = append(refundItems, model.RefundItem{
+			OrderItemID: item.ID,
+			Quantity:    input.Quantity,
+			Amount:      unitsPaid(item, from, input.Quantity),
+		})
+	}
+
+	return refundItems, nil
+}
+
+// unitsPaid is what the customer paid for count units of a line, starting
+// after the first from: the line's price after discount with its tax, split
+// evenly over its units so that refunding every unit returns it exactly
+func unitsPaid(item model.OrderItemWithDetails, from, count int) model.Money {
+	weights := make([]model.Money, item.Quantity)
+	for i := range weights {
+		weights[i] = model.NewMoney(1, item.TaxableAmount.Currency)
+	}
+	units := item.TaxableAmount.Add(item.TaxAmount).Allocate(weights)
+
+	var amount model.Money
+	for _, unit := range units[from : from+count] {
+		amount = amount.Add(unit)
+	}
+	return amount
+}
+
+func containsShop(items []model.OrderItemWithDetails, shopID uuid.UUID) bool {
+	for _, item := range items {
+		if item.ShopID == shopID {
+			return true
+		}
+	}
+	return false
+}
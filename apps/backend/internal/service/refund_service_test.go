@@ -0,0 +1,101 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/imbivek08/hamropasal/internal/model"
+)
+
+func npr(amount int64) model.Money {
+	return model.NewMoney(amount, "NPR")
+}
+
+// discountedOrder is a two-shop order with a 10% platform coupon: shop A's
+// line carries 13% exclusive tax, shop B's 13% inclusive tax
+func discountedOrder() (shopA, shopB uuid.UUID, items []model.OrderItemWithDetails) {
+	shopA, shopB = uuid.New(), uuid.New()
+	items = []model.OrderItemWithDetails{
+		{
+			ID:            uuid.New(),
+			ShopID:        shopA,
+			ProductName:   "Dhaka topi",
+			Quantity:      3,
+			UnitPrice:     npr(1000),
+			Subtotal:      npr(3000),
+			TaxableAmount: npr(2700),
+			TaxAmount:     npr(351),
+		},
+		{
+			ID:            uuid.New(),
+			ShopID:        shopB,
+			ProductName:   "Pashmina",
+			Quantity:      1,
+			UnitPrice:     npr(2000),
+			Subtotal:      npr(2000),
+			TaxableAmount: npr(1593),
+			TaxAmount:     npr(207),
+			TaxInclusive:  true,
+		},
+	}
+	return shopA, shopB, items
+}
+
+func refundTotal(items []model.RefundItem) model.Money {
+	total := npr(0)
+	for _, item := range items {
+		total = total.Add(item.Amount)
+	}
+	return total
+}
+
+func TestBuildRefundItemsRefundsWhatWasPaid(t *testing.T) {
+	shopA, shopB, items := discountedOrder()
+
+	// Everything of shop A: the discounted price with its tax, not the list price
+	refundItems, err := buildRefundItems(items, map[uuid.UUID]int{}, &shopA, nil)
+	if err != nil {
+		t.Fatalf("buildRefundItems: %v", err)
+	}
+	if got := refundTotal(refundItems); got.Amount != 3051 {
+		t.Errorf("shop A refund = %d, want 3051", got.Amount)
+	}
+
+	// Inclusive tax is part of what was paid
+	refundItems, err = buildRefundItems(items, map[uuid.UUID]int{}, &shopB, nil)
+	if err != nil {
+		t.Fatalf("buildRefundItems: %v", err)
+	}
+	if got := refundTotal(refundItems); got.Amount != 1800 {
+		t.Errorf("shop B refund = %d, want 1800", got.Amount)
+	}
+
+	// A vendor cannot refund another shop's line
+	_, err = buildRefundItems(items, map[uuid.UUID]int{}, &shopA, []model.RefundItemInput{{OrderItemID: items[1].ID, Quantity: 1}})
+	if err == nil {
+		t.Error("shop A refunded shop B's line")
+	}
+}
+
+func TestBuildRefundItemsSplitsLinesByUnit(t *testing.T) {
+	shopA, _, items := discountedOrder()
+	line := items[0].ID
+
+	// One unit, then the other two: the parts add up to the line exactly
+	first, err := buildRefundItems(items, map[uuid.UUID]int{}, &shopA, []model.RefundItemInput{{OrderItemID: line, Quantity: 1}})
+	if err != nil {
+		t.Fatalf("buildRefundItems: %v", err)
+	}
+	rest, err := buildRefundItems(items, map[uuid.UUID]int{line: 1}, &shopA, nil)
+	if err != nil {
+		t.Fatalf("buildRefundItems: %v", err)
+	}
+
+	if first[0].Amount.Amount != 1017 || rest[0].Amount.Amount != 2034 {
+		t.Errorf("refunds = %d + %d, want 1017 + 2034", first[0].Amount.Amount, rest[0].Amount.Amount)
+	}
+
+	if _, err := buildRefundItems(items, map[uuid.UUID]int{line: 3}, &shopA, []model.RefundItemInput{{OrderItemID: line, Quantity: 1}}); err == nil {
+		t.Error("refunded more units than were bought")
+	}
+}
@@ -14,17 +14,20 @@ type ReivewService struct {
 	reviewRepo  *repository.ReviewRepository
 	orderRepo   *repository.OrderRepository
 	productRepo *repository.ProductRepository
+	policy      *Policy
 }
 
 func NewReviewService(
 	reviewRepo *repository.ReviewRepository,
 	orderRepo *repository.OrderRepository,
 	productRepo *repository.ProductRepository,
+	policy *Policy,
 ) *ReivewService {
 	return &ReivewService{
 		reviewRepo:  reviewRepo,
 		orderRepo:   orderRepo,
 		productRepo: productRepo,
+		policy:      policy,
 	}
 }
 
@@ -142,30 +145,30 @@ func (s *ReivewService) GetProductRatingStats(ctx context.Context, productID uui
 }
 
 // UpdateReview updates an existing review
-func (s *ReivewService) UpdateReview(ctx context.Context, userID, reviewID uuid.UUID, req *model.UpdateReviewRequest) error {
+func (s *ReivewService) UpdateReview(ctx context.Context, actor *Actor, reviewID uuid.UUID, req *model.UpdateReviewRequest) error {
 	// Get the review to verify ownership
 	review, err := s.reviewRepo.GetByID(ctx, reviewID)
 	if err != nil {
 		return errors.New("review not found")
 	}
 
-	if review.UserID != userID {
-		return errors.New("unauthorized: you can only update your own reviews")
+	if err := s.policy.AuthorizeReview(actor, &review.Review); err != nil {
+		return err
 	}
 
 	return s.reviewRepo.UpdateReview(ctx, reviewID, req)
 }
 
 // DeleteReview deletes a review
-func (s *ReivewService) DeleteReview(ctx context.Context, userID, reviewID uuid.UUID) error {
+func (s *ReivewService) DeleteReview(ctx context.Context, actor *Actor, reviewID uuid.UUID) error {
 	// Get the review to verify ownership
 	review, err := s.reviewRepo.GetByID(ctx, reviewID)
 	if err != nil {
 		return errors.New("review not found")
 	}
 
-	if review.UserID != userID {
-		return errors.New("unauthorized: you can only delete your own reviews")
+	if err := s.policy.AuthorizeReview(actor, &review.Review); err != nil {
+		return err
 	}
 
 	return s.reviewRepo.DeleteReview(ctx, reviewID)
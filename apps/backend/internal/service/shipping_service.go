@@ -0,0 +1,465 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/imbivek08/hamropasal/internal/model"
+	"github.com/imbivek08/hamropasal/internal/repository"
+	"github.com/jackc/pgx/v5"
+)
+
+// ShippingService prices shipping. Admins define the zones destinations fall
+// in; each shop sets up its own methods per zone. A cart ships per shop, each
+// with one of the methods of the most specific zone the destination matches.
+type ShippingService struct {
+	shippingRepo    *repository.ShippingRepository
+	addressRepo     *repository.AddressRepository
+	currencyService *CurrencyService
+	policy          *Policy
+}
+
+func NewShippingService(shippingRepo *repository.ShippingRepository, addressRepo *repository.AddressRepository, currencyService *CurrencyService, policy *Policy) *ShippingService {
+	return &ShippingService{
+		shippingRepo:    shippingRepo,
+		addressRepo:     addressRepo,
+		currencyService: currencyService,
+		policy:          policy,
+	}
+}
+
+// CreateZone creates a shipping zone (admin only)
+func (s *ShippingService) CreateZone(ctx context.Context, req *model.CreateShippingZoneRequest) (*model.ShippingZone, error) {
+	now := time.Now()
+	zone := &model.ShippingZone{
+		ID:        uuid.New(),
+		Name:      strings.TrimSpace(req.Name),
+		Country:   strings.TrimSpace(req.Country),
+		States:    cleanPlaceNames(req.States),
+		Cities:    cleanPlaceNames(req.Cities),
+		IsActive:  true,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+
+	if err := s.shippingRepo.CreateZone(ctx, zone); err != nil {
+		return nil, fmt.Errorf("failed to create shipping zone: %w", err)
+	}
+
+	return zone, nil
+}
+
+// ListZones lists every shipping zone
+func (s *ShippingService) ListZones(ctx context.Context) ([]*model.ShippingZone, error) {
+	return s.shippingRepo.ListZones(ctx, false)
+}
+
+// UpdateZone changes a shipping zone (admin only). States and cities replace
+// the current lists when given.
+func (s *ShippingService) UpdateZone(ctx context.Context, zoneID uuid.UUID, req *model.UpdateShippingZoneRequest) (*model.ShippingZone, error) {
+	zone, err := s.getZone(ctx, zoneID)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Name != nil {
+		zone.Name = strings.TrimSpace(*req.Name)
+	}
+	if req.Country != nil {
+		zone.Country = strings.TrimSpace(*req.Country)
+	}
+	if req.States != nil {
+		zone.States = cleanPlaceNames(req.States)
+	}
+	if req.Cities != nil {
+		zone.Cities = cleanPlaceNames(req.Cities)
+	}
+	if req.IsActive != nil {
+		zone.IsActive = *req.IsActive
+	}
+
+	if err := s.shippingRepo.UpdateZone(ctx, zone); err != nil {
+		return nil, fmt.Errorf("failed to update shipping zone: %w", err)
+	}
+
+	return zone, nil
+}
+
+// DeleteZone deletes a shipping zone and every shop's methods for it (admin only)
+func (s *ShippingService) DeleteZone(ctx context.Context, zoneID uuid.UUID) error {
+	return s.shippingRepo.DeleteZone(ctx, zoneID)
+}
+
+// CreateMethod creates a shipping method. Vendors create methods for their
+// own shop; admins for the shop named in the request.
+func (s *ShippingService) CreateMethod(ctx context.Context, actor *Actor, req *model.CreateShippingMethodRequest) (*model.ShippingMethod, error) {
+	shopID := req.ShopID
+	if !actor.IsAdmin() {
+		if actor.ShopID == nil {
+			return nil, fmt.Errorf("%w: you don't have a shop", ErrForbidden)
+		}
+		shopID = actor.ShopID
+	}
+	if shopID == nil {
+		return nil, errors.New("shop_id is required")
+	}
+
+	if _, err := s.getZone(ctx, req.ZoneID); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	method := &model.ShippingMethod{
+		ID:        uuid.New(),
+		ShopID:    *shopID,
+		ZoneID:    req.ZoneID,
+		Name:      strings.TrimSpace(req.Name),
+		RateType:  req.RateType,
+		BaseRate:  req.BaseRate,
+		PerKgRate: req.PerKgRate,
+		FreeAbove: req.FreeAbove,
+		MinDays:   req.MinDays,
+		MaxDays:   req.MaxDays,
+		IsActive:  true,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+
+	if err := validateShippingMethod(method); err != nil {
+		return nil, err
+	}
+
+	if err := s.shippingRepo.CreateMethod(ctx, method); err != nil {
+		return nil, fmt.Errorf("failed to create shipping method: %w", err)
+	}
+
+	return method, nil
+}
+
+// ListMethods lists every shipping method for admins and the shop's methods for vendors
+func (s *ShippingService) ListMethods(ctx context.Context, actor *Actor) ([]*model.ShippingMethod, error) {
+	if actor.IsAdmin() {
+		return s.shippingRepo.ListMethods(ctx, nil)
+	}
+	if actor.ShopID == nil {
+		return []*model.ShippingMethod{}, nil
+	}
+	return s.shippingRepo.ListMethods(ctx, actor.ShopID)
+}
+
+// UpdateMethod changes a shipping method's rates
+func (s *ShippingService) UpdateMethod(ctx context.Context, actor *Actor, methodID uuid.UUID, req *model.UpdateShippingMethodRequest) (*model.ShippingMethod, error) {
+	method, err := s.getOwnedMethod(ctx, actor, methodID)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Name != nil {
+		method.Name = strings.TrimSpace(*req.Name)
+	}
+	if req.BaseRate != nil {
+		method.BaseRate = *req.BaseRate
+	}
+	if req.PerKgRate != nil {
+		method.PerKgRate = *req.PerKgRate
+	}
+	if req.FreeAbove != nil {
+		method.FreeAbove = req.FreeAbove
+	}
+	if req.MinDays != nil {
+		method.MinDays = req.MinDays
+	}
+	if req.MaxDays != nil {
+		method.MaxDays = req.MaxDays
+	}
+	if req.IsActive != nil {
+		method.IsActive = *req.IsActive
+	}
+
+	if err := validateShippingMethod(method); err != nil {
+		return nil, err
+	}
+
+	if err := s.shippingRepo.UpdateMethod(ctx, method); err != nil {
+		return nil, fmt.Errorf("failed to update shipping method: %w", err)
+	}
+
+	return method, nil
+}
+
+// DeleteMethod deletes a shipping method
+func (s *ShippingService) DeleteMethod(ctx context.Context, actor *Actor, methodID uuid.UUID) error {
+	if _, err := s.getOwnedMethod(ctx, actor, methodID); err != nil {
+		return err
+	}
+
+	return s.shippingRepo.DeleteMethod(ctx, methodID)
+}
+
+// ResolveDestination turns a quote request into a destination. Saved
+// addresses are only available to their signed-in owner (userID set).
+func (s *ShippingService) ResolveDestination(ctx context.Context, userID *uuid.UUID, req *model.ShippingQuoteRequest) (model.ShippingDestination, error) {
+	if req.ShippingAddressID != nil {
+		if userID == nil {
+			return model.ShippingDestination{}, errors.New("shipping address not found")
+		}
+		address, err := s.addressRepo.GetByID(ctx, *req.ShippingAddressID)
+		if err != nil || address.UserID != *userID {
+			return model.ShippingDestination{}, errors.New("shipping address not found")
+		}
+		return model.DestinationOf(address), nil
+	}
+
+	if strings.TrimSpace(req.City) == "" || strings.TrimSpace(req.Country) == "" {
+		return model.ShippingDestination{}, errors.New("provide shipping_address_id or city and country")
+	}
+	return model.ShippingDestination{City: req.City, State: req.State, Country: req.Country}, nil
+}
+
+// QuoteCart lists each shop's shipping options for the cart items to the
+// destination. Shops that have not set up any shipping method ship for free.
+// Costs are in the currency of the items' prices.
+func (s *ShippingService) QuoteCart(ctx context.Context, items []model.CartItemWithProduct, dest model.ShippingDestination) (*model.ShippingQuote, error) {
+	quote := &model.ShippingQuote{
+		Destination: dest,
+		Shops:       []model.ShopShippingQuote{},
+		Deliverable: true,
+	}
+
+	var shopIDs []uuid.UUID
+	byShop := make(map[uuid.UUID]int)
+	shopCurrencies := make(map[uuid.UUID]string)
+	for _, item := range items {
+		i, ok := byShop[item.ShopID]
+		if !ok {
+			i = len(quote.Shops)
+			byShop[item.ShopID] = i
+			shopIDs = append(shopIDs, item.ShopID)
+			shopCurrencies[item.ShopID] = item.ShopCurrency
+			quote.Shops = append(quote.Shops, model.ShopShippingQuote{
+				ShopID:   item.ShopID,
+				ShopName: item.ShopName,
+				Options:  []model.ShippingOption{},
+			})
+		}
+		quote.Shops[i].Subtotal = quote.Shops[i].Subtotal.Add(item.Subtotal)
+		if item.Weight != nil {
+			quote.Shops[i].WeightKg += model.WeightInKg(*item.Weight, item.WeightUnit) * float64(item.Quantity)
+		}
+	}
+	if len(shopIDs) == 0 {
+		return quote, nil
+	}
+
+	methods, err := s.shippingRepo.GetActiveMethodsForShops(ctx, shopIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get shipping methods: %w", err)
+	}
+
+	// Shops set their rates in their own currency
+	rates, err := s.currencyService.Rates(ctx)
+	if err != nil {
+		return nil, err
+	}
+	currency := items[0].ProductPrice.CurrencyCode()
+	for _, method := range methods {
+		if err := convertMethodRates(rates, method, shopCurrencies[method.ShopID], currency); err != nil {
+			return nil, err
+		}
+	}
+
+	configured, err := s.shippingRepo.CountMethodsForShops(ctx, shopIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get shipping methods: %w", err)
+	}
+
+	zones, err := s.shippingRepo.ListZones(ctx, true)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get shipping zones: %w", err)
+	}
+	zonesByID := make(map[uuid.UUID]*model.ShippingZone, len(zones))
+	for _, zone := range zones {
+		zonesByID[zone.ID] = zone
+	}
+
+	for i := range quote.Shops {
+		shop := &quote.Shops[i]
+		shop.WeightKg = math.Round(shop.WeightKg*1000) / 1000
+
+		if configured[shop.ShopID] == 0 {
+			shop.Options = append(shop.Options, model.ShippingOption{Name: "Free shipping"})
+		} else {
+			shop.Options = shippingOptions(shop, methods, zonesByID, dest)
+		}
+
+		if len(shop.Options) == 0 {
+			quote.Deliverable = false
+			continue
+		}
+		quote.ShippingCost = quote.ShippingCost.Add(shop.Options[0].Cost)
+	}
+
+	return quote, nil
+}
+
+// SelectForCheckout picks each shop's shipping for the cart: the chosen
+// method where the customer chose one, otherwise the shop's cheapest option.
+// It fails when a shop does not ship to the destination.
+func (s *ShippingService) SelectForCheckout(ctx context.Context, items []model.CartItemWithProduct, dest model.ShippingDestination, chosen []uuid.UUID) (map[uuid.UUID]model.ShippingOption, error) {
+	quote, err := s.QuoteCart(ctx, items, dest)
+	if err != nil {
+		return nil, err
+	}
+
+	selected := make(map[uuid.UUID]model.ShippingOption, len(quote.Shops))
+	used := make(map[uuid.UUID]bool, len(chosen))
+	for _, shop := range quote.Shops {
+		if len(shop.Options) == 0 {
+			return nil, fmt.Errorf("%s does not ship to %s, %s", shop.ShopName, dest.City, dest.Country)
+		}
+
+		option := shop.Options[0]
+		for _, candidate := range shop.Options {
+			if candidate.MethodID != nil && indexOfUUID(chosen, *candidate.MethodID) >= 0 {
+				option = candidate
+				used[*candidate.MethodID] = true
+				break
+			}
+		}
+		selected[shop.ShopID] = option
+	}
+
+	for _, methodID := range chosen {
+		if !used[methodID] {
+			return nil, fmt.Errorf("shipping method %s is not available for this address", methodID)
+		}
+	}
+
+	return selected, nil
+}
+
+// shippingOptions prices the shop's methods for the most specific zone the
+// destination matches, cheapest first
+func shippingOptions(shop *model.ShopShippingQuote, methods []*model.ShippingMethod, zones map[uuid.UUID]*model.ShippingZone, dest model.ShippingDestination) []model.ShippingOption {
+	best := -1
+	var matching []*model.ShippingMethod
+	for _, method := range methods {
+		zone, ok := zones[method.ZoneID]
+		if method.ShopID != shop.ShopID || !ok || !zone.Matches(dest) {
+			continue
+		}
+		switch specificity := zone.Specificity(); {
+		case specificity > best:
+			best = specificity
+			matching = []*model.ShippingMethod{method}
+		case specificity == best:
+			matching = append(matching, method)
+		}
+	}
+
+	options := []model.ShippingOption{}
+	for _, method := range matching {
+		methodID := method.ID
+		zoneName := zones[method.ZoneID].Name
+		options = append(options, model.ShippingOption{
+			MethodID: &methodID,
+			Name:     method.Name,
+			ZoneName: &zoneName,
+			Cost:     shippingCost(method, shop.Subtotal, shop.WeightKg),
+			MinDays:  method.MinDays,
+			MaxDays:  method.MaxDays,
+		})
+	}
+
+	sort.SliceStable(options, func(i, j int) bool {
+		return options[i].Cost.Cmp(options[j].Cost) < 0
+	})
+	return options
+}
+
+// shippingCost is what a method charges for a shipment. Weight rates are
+// charged for every started kilogram.
+func shippingCost(method *model.ShippingMethod, subtotal model.Money, weightKg float64) model.Money {
+	if method.FreeAbove != nil && subtotal.Cmp(*method.FreeAbove) >= 0 {
+		return model.Money{}
+	}
+
+	cost := method.BaseRate
+	if method.RateType == model.ShippingRateWeight {
+		cost = cost.Add(method.PerKgRate.Mul(int64(math.Ceil(weightKg))))
+	}
+	return cost
+}
+
+// convertMethodRates converts a shipping method's rates from its shop's
+// currency to another currency
+func convertMethodRates(rates model.ExchangeRates, method *model.ShippingMethod, from, to string) error {
+	amounts := []*model.Money{&method.BaseRate, &method.PerKgRate}
+	if method.FreeAbove != nil {
+		amounts = append(amounts, method.FreeAbove)
+	}
+
+	for _, amount := range amounts {
+		amount.Currency = from
+		converted, err := rates.Convert(*amount, to)
+		if err != nil {
+			return err
+		}
+		*amount = converted
+	}
+	return nil
+}
+
+func (s *ShippingService) getZone(ctx context.Context, zoneID uuid.UUID) (*model.ShippingZone, error) {
+	zone, err := s.shippingRepo.GetZoneByID(ctx, zoneID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, errors.New("shipping zone not found")
+		}
+		return nil, fmt.Errorf("failed to get shipping zone: %w", err)
+	}
+	return zone, nil
+}
+
+func (s *ShippingService) getOwnedMethod(ctx context.Context, actor *Actor, methodID uuid.UUID) (*model.ShippingMethod, error) {
+	method, err := s.shippingRepo.GetMethodByID(ctx, methodID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, errors.New("shipping method not found")
+		}
+		return nil, fmt.Errorf("failed to get shipping method: %w", err)
+	}
+
+	if err := s.policy.AuthorizeShippingMethod(actor, method); err != nil {
+		return nil, err
+	}
+
+	return method, nil
+}
+
+func validateShippingMethod(method *model.ShippingMethod) error {
+	if method.RateType == model.ShippingRateWeight && method.BaseRate.IsZero() && method.PerKgRate.IsZero() {
+		return errors.New("weight methods need a base_rate or per_kg_rate")
+	}
+	if method.MinDays != nil && method.MaxDays != nil && *method.MinDays > *method.MaxDays {
+		return errors.New("min_days cannot exceed max_days")
+	}
+	return nil
+}
+
+// cleanPlaceNames trims place names and drops empty ones
+func cleanPlaceNames(names []string) []string {
+	cleaned := []string{}
+	for _, name := range names {
+		if name = strings.TrimSpace(name); name != "" {
+			cleaned = append(cleaned, name)
+		}
+	}
+	return cleaned
+}
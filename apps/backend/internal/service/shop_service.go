@@ -5,8 +5,6 @@ import (
 	"errors"
 	"fmt"
 	"math"
-	"regexp"
-	"strings"
 
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
@@ -16,14 +14,18 @@ import (
 )
 
 type ShopService struct {
-	shopRepo *repository.ShopRepository
-	userRepo *repository.UserRepository
+	shopRepo        *repository.ShopRepository
+	userRepo        *repository.UserRepository
+	currencyService *CurrencyService
+	policy          *Policy
 }
 
-func NewShopService(shopRepo *repository.ShopRepository, userRepo *repository.UserRepository) *ShopService {
+func NewShopService(shopRepo *repository.ShopRepository, userRepo *repository.UserRepository, currencyService *CurrencyService, policy *Policy) *ShopService {
 	return &ShopService{
-		shopRepo: shopRepo,
-		userRepo: userRepo,
+		shopRepo:        shopRepo,
+		userRepo:        userRepo,
+		currencyService: currencyService,
+		policy:          policy,
 	}
 }
 
@@ -64,8 +66,14 @@ func (s *ShopService) CreateShop(ctx context.Context, vendorID uuid.UUID, req *m
 		return nil, errors.New("vendor already has a shop")
 	}
 
+	// Shops price in a currency the store can convert
+	currency, _, err := s.currencyService.Resolve(ctx, req.Currency)
+	if err != nil {
+		return nil, err
+	}
+
 	// Generate slug from shop name
-	slug := s.generateSlug(req.Name)
+	slug := generateSlug(req.Name)
 
 	// Ensure slug is unique
 	slugExists, err := s.shopRepo.SlugExists(ctx, slug, nil)
@@ -73,7 +81,7 @@ func (s *ShopService) CreateShop(ctx context.Context, vendorID uuid.UUID, req *m
 		return nil, fmt.Errorf("failed to check slug uniqueness: %w", err)
 	}
 	if slugExists {
-		slug = s.generateUniqueSlug(slug)
+		slug = generateUniqueSlug(slug)
 	}
 
 	shop := &model.Shop{
@@ -89,6 +97,7 @@ func (s *ShopService) CreateShop(ctx context.Context, vendorID uuid.UUID, req *m
 		PostalCode:  req.PostalCode,
 		Phone:       req.Phone,
 		Email:       req.Email,
+		Currency:    currency,
 		IsActive:    true,
 		IsVerified:  false,
 	}
@@ -154,7 +163,7 @@ func (s *ShopService) ListShops(ctx context.Context, page, pageSize int, search
 	}, nil
 }
 
-func (s *ShopService) UpdateShop(ctx context.Context, shopID, vendorID uuid.UUID, req *model.UpdateShopRequest) (*model.Shop, error) {
+func (s *ShopService) UpdateShop(ctx context.Context, actor *Actor, shopID uuid.UUID, req *model.UpdateShopRequest) (*model.Shop, error) {
 	// Get existing shop
 	shop, err := s.shopRepo.GetByID(ctx, shopID)
 	if err != nil {
@@ -165,8 +174,8 @@ func (s *ShopService) UpdateShop(ctx context.Context, shopID, vendorID uuid.UUID
 	}
 
 	// Verify ownership
-	if shop.VendorID != vendorID {
-		return nil, errors.New("unauthorized: you don't own this shop")
+	if err := s.policy.AuthorizeShop(actor, shop); err != nil {
+		return nil, err
 	}
 
 	// Update fields if provided
@@ -203,6 +212,13 @@ func (s *ShopService) UpdateShop(ctx context.Context, shopID, vendorID uuid.UUID
 	if req.Email != nil {
 		shop.Email = req.Email
 	}
+	if req.Currency != nil {
+		currency, _, err := s.currencyService.Resolve(ctx, *req.Currency)
+		if err != nil {
+			return nil, err
+		}
+		shop.Currency = currency
+	}
 
 	if err := s.shopRepo.Update(ctx, shop); err != nil {
 		return nil, fmt.Errorf("failed to update shop: %w", err)
@@ -212,7 +228,7 @@ func (s *ShopService) UpdateShop(ctx context.Context, shopID, vendorID uuid.UUID
 }
 
 // ToggleShopStatus toggles shop's active status
-func (s *ShopService) ToggleShopStatus(ctx context.Context, shopID, vendorID uuid.UUID) (*model.Shop, error) {
+func (s *ShopService) ToggleShopStatus(ctx context.Context, actor *Actor, shopID uuid.UUID) (*model.Shop, error) {
 	// Get existing shop
 	shop, err := s.shopRepo.GetByID(ctx, shopID)
 	if err != nil {
@@ -223,8 +239,8 @@ func (s *ShopService) ToggleShopStatus(ctx context.Context, shopID, vendorID uui
 	}
 
 	// Verify ownership
-	if shop.VendorID != vendorID {
-		return nil, errors.New("unauthorized: you don't own this shop")
+	if err := s.policy.AuthorizeShop(actor, shop); err != nil {
+		return nil, err
 	}
 
 	// Toggle status
@@ -295,29 +311,3 @@ func (s *ShopService) VerifyShop(ctx context.Context, shopID uuid.UUID, verified
 	shop.IsVerified = verified
 	return shop, nil
 }
-
-// generateSlug converts a string to a URL-friendly slug
-func (s *ShopService) generateSlug(name string) string {
-	// Convert to lowercase
-	slug := strings.ToLower(name)
-
-	// Replace spaces and special characters with hyphens
-	reg := regexp.MustCompile("[^a-z0-9]+")
-	slug = reg.ReplaceAllString(slug, "-")
-
-	// Remove leading and trailing hyphens
-	slug = strings.Trim(slug, "-")
-
-	// Limit length
-	if len(slug) > 100 {
-		slug = slug[:100]
-	}
-
-	return slug
-}
-
-// generateUniqueSlug appends a UUID suffix to make slug unique
-func (s *ShopService) generateUniqueSlug(baseSlug string) string {
-	suffix := uuid.New().String()[:8]
-	return fmt.Sprintf("%s-%s", baseSlug, suffix)
-}
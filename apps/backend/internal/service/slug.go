@@ -0,0 +1,51 @@
+package service
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+var slugInvalidChars = regexp.MustCompile("[^a-z0-9]+")
+
+// generateSlug converts a string to a URL-friendly slug
+func generateSlug(name string) string {
+	// Convert to lowercase
+	slug := strings.ToLower(name)
+
+	// Replace spaces and special characters with hyphens
+	slug = slugInvalidChars.ReplaceAllString(slug, "-")
+
+	// Remove leading and trailing hyphens
+	slug = strings.Trim(slug, "-")
+
+	// Limit length
+	if len(slug) > 100 {
+		slug = slug[:100]
+	}
+
+	return slug
+}
+
+// generateUniqueSlug appends a UUID suffix to make slug unique
+func generateUniqueSlug(baseSlug string) string {
+	suffix := uuid.New().String()[:8]
+	return fmt.Sprintf("%s-%s", baseSlug, suffix)
+}
+
+// generateSKU builds a SKU from a product name and a random suffix, e.g.
+// "RED-T-SHIRT-1A2B3C4D"
+func generateSKU(name string) string {
+	prefix := strings.ToUpper(generateSlug(name))
+	if len(prefix) > 20 {
+		prefix = strings.TrimRight(prefix[:20], "-")
+	}
+	if prefix == "" {
+		prefix = "SKU"
+	}
+
+	suffix := strings.ToUpper(uuid.New().String()[:8])
+	return fmt.Sprintf("%s-%s", prefix, suffix)
+}
@@ -0,0 +1,186 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/imbivek08/hamropasal/internal/database"
+	"github.com/imbivek08/hamropasal/internal/model"
+	"github.com/imbivek08/hamropasal/internal/repository"
+)
+
+// sweepBatchSize caps how many expired orders a single sweep releases
+const sweepBatchSize = 100
+
+// StockReservationService holds stock for unpaid orders. Stock is taken off
+// products.stock_quantity when the order is placed; a reservation is either
+// committed once payment succeeds or released (stock restored, order
+// cancelled) when the payment session expires.
+type StockReservationService struct {
+	db              *database.Database
+	reservationRepo *repository.StockReservationRepository
+	orderRepo       *repository.OrderRepository
+	fulfilmentRepo  *repository.FulfilmentRepository
+	productRepo     *repository.ProductRepository
+	couponService   *CouponService
+}
+
+func NewStockReservationService(
+	db *database.Database,
+	reservationRepo *repository.StockReservationRepository,
+	orderRepo *repository.OrderRepository,
+	fulfilmentRepo *repository.FulfilmentRepository,
+	productRepo *repository.ProductRepository,
+	couponService *CouponService,
+) *StockReservationService {
+	return &StockReservationService{
+		db:              db,
+		reservationRepo: reservationRepo,
+		orderRepo:       orderRepo,
+		fulfilmentRepo:  fulfilmentRepo,
+		productRepo:     productRepo,
+		couponService:   couponService,
+	}
+}
+
+// ReserveForOrder records the stock held by an order's items until expiresAt
+func (s *StockReservationService) ReserveForOrder(ctx context.Context, orderID uuid.UUID, items []model.OrderItem, expiresAt time.Time) error {
+	now := time.Now()
+	reservations := make([]model.StockReservation, 0, len(items))
+	for _, item := range items {
+		reservations = append(reservations, model.StockReservation{
+			ID:        uuid.New(),
+			OrderID:   orderID,
+			ProductID: item.ProductID,
+			VariantID: item.VariantID,
+			Quantity:  item.Quantity,
+			Status:    model.ReservationStatusActive,
+			ExpiresAt: expiresAt,
+			CreatedAt: now,
+			UpdatedAt: now,
+		})
+	}
+
+	if err := s.reservationRepo.Create(ctx, reservations); err != nil {
+		return fmt.Errorf("failed to reserve stock: %w", err)
+	}
+
+	return nil
+}
+
+// ExtendForOrder aligns an order's reservations with its payment session expiry
+func (s *StockReservationService) ExtendForOrder(ctx context.Context, orderID uuid.UUID, expiresAt time.Time) error {
+	if err := s.reservationRepo.SetExpiryForOrder(ctx, orderID, expiresAt); err != nil {
+		return fmt.Errorf("failed to update stock reservation: %w", err)
+	}
+	return nil
+}
+
+// IsHeld reports whether an order still holds its reserved stock
+func (s *StockReservationService) IsHeld(ctx context.Context, orderID uuid.UUID) (bool, error) {
+	expiresAt, err := s.reservationRepo.GetActiveExpiryForOrder(ctx, orderID)
+	if err != nil {
+		return false, fmt.Errorf("failed to get stock reservation: %w", err)
+	}
+	return expiresAt != nil && expiresAt.After(time.Now()), nil
+}
+
+// CommitForOrder turns an order's reservations into a permanent decrement.
+// The stock is already off the shelf, so this only settles the reservations.
+func (s *StockReservationService) CommitForOrder(ctx context.Context, orderID uuid.UUID) error {
+	if _, err := s.reservationRepo.CommitForOrder(ctx, orderID); err != nil {
+		return fmt.Errorf("failed to commit stock reservation: %w", err)
+	}
+	return nil
+}
+
+// ReleaseForOrder puts an unpaid order's reserved stock back and cancels the
+// order for reason. Releasing an order whose reservations are already settled
+// is a no-op.
+func (s *StockReservationService) ReleaseForOrder(ctx context.Context, orderID uuid.UUID, reason string) error {
+	return s.db.WithTx(ctx, func(ctx context.Context) error {
+		// Lock the order so a concurrent payment confirmation cannot interleave
+		if err := s.orderRepo.LockByID(ctx, orderID); err != nil {
+			return fmt.Errorf("order not found: %w", err)
+		}
+
+		order, err := s.orderRepo.GetByID(ctx, orderID)
+		if err != nil {
+			return fmt.Errorf("order not found: %w", err)
+		}
+
+		// Paid orders keep their stock even if a late expiry arrives
+		if order.PaymentStatus == model.PaymentStatusPaid {
+			if _, err := s.reservationRepo.CommitForOrder(ctx, orderID); err != nil {
+				return fmt.Errorf("failed to commit stock reservation: %w", err)
+			}
+			return nil
+		}
+
+		reservations, err := s.reservationRepo.ReleaseForOrder(ctx, orderID)
+		if err != nil {
+			return fmt.Errorf("failed to release stock reservation: %w", err)
+		}
+		if len(reservations) == 0 {
+			return nil
+		}
+
+		for _, reservation := range reservations {
+			if err := s.productRepo.IncreaseStock(ctx, reservation.ProductID, reservation.VariantID, reservation.Quantity); err != nil {
+				return fmt.Errorf("failed to restore stock: %w", err)
+			}
+		}
+
+		if err := s.orderRepo.UpdatePaymentStatus(ctx, orderID, model.PaymentStatusFailed); err != nil {
+			return fmt.Errorf("failed to update payment status: %w", err)
+		}
+
+		if order.Status == model.OrderStatusPending {
+			if err := s.fulfilmentRepo.TransitionForOrder(ctx, orderID, model.OrderStatusCancelled, model.OrderStatusPending); err != nil {
+				return fmt.Errorf("failed to cancel fulfilments: %w", err)
+			}
+			if err := s.orderRepo.Cancel(ctx, orderID, reason); err != nil {
+				return fmt.Errorf("failed to cancel order: %w", err)
+			}
+			if err := s.couponService.ReleaseForOrder(ctx, orderID); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// ReleaseExpired releases every reservation past its expiry. It runs as a
+// background job, backing up the gateways' expiry callbacks, and is the only
+// expiry for gateways that send none.
+func (s *StockReservationService) ReleaseExpired(ctx context.Context) error {
+	orderIDs, err := s.reservationRepo.GetExpiredOrderIDs(ctx, sweepBatchSize)
+	if err != nil {
+		return fmt.Errorf("failed to list expired reservations: %w", err)
+	}
+
+	released := 0
+	defer func() {
+		if released > 0 {
+			log.Printf("[Stock Reservations] released %d expired order(s)", released)
+		}
+	}()
+
+	// One order failing must not hold up the ones expiring after it
+	var errs []error
+	for _, orderID := range orderIDs {
+		if err := s.ReleaseForOrder(ctx, orderID, model.CancelReasonPaymentExpired); err != nil {
+			log.Printf("[Stock Reservations] failed to release order %s: %v", orderID, err)
+			errs = append(errs, fmt.Errorf("failed to release order %s: %w", orderID, err))
+			continue
+		}
+		released++
+	}
+
+	return errors.Join(errs...)
+}
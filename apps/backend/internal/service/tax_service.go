@@ -0,0 +1,210 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/imbivek08/hamropasal/internal/model"
+	"github.com/imbivek08/hamropasal/internal/repository"
+	"github.com/jackc/pgx/v5"
+)
+
+// TaxService manages tax rules and taxes order lines. Each line is taxed by
+// the most specific active rule for the destination: a rule for the line's
+// category (or its nearest parent category) beats a general rule, and a rule
+// for the destination's state beats a country-wide one.
+type TaxService struct {
+	taxRepo      *repository.TaxRepository
+	categoryRepo *repository.CategoryRepository
+}
+
+func NewTaxService(taxRepo *repository.TaxRepository, categoryRepo *repository.CategoryRepository) *TaxService {
+	return &TaxService{
+		taxRepo:      taxRepo,
+		categoryRepo: categoryRepo,
+	}
+}
+
+// TaxableLine is an order line to tax: its product's category and what the
+// customer pays for it after discounts
+type TaxableLine struct {
+	CategoryID *uuid.UUID
+	Amount     model.Money
+}
+
+// CreateRate creates a tax rate (admin only)
+func (s *TaxService) CreateRate(ctx context.Context, req *model.CreateTaxRateRequest) (*model.TaxRate, error) {
+	if req.CategoryID != nil {
+		if _, err := s.categoryRepo.GetByID(ctx, *req.CategoryID); err != nil {
+			return nil, errors.New("category not found")
+		}
+	}
+
+	var state *string
+	if req.State != nil && strings.TrimSpace(*req.State) != "" {
+		trimmed := strings.TrimSpace(*req.State)
+		state = &trimmed
+	}
+
+	now := time.Now()
+	rate := &model.TaxRate{
+		ID:         uuid.New(),
+		Name:       strings.TrimSpace(req.Name),
+		Country:    strings.TrimSpace(req.Country),
+		State:      state,
+		CategoryID: req.CategoryID,
+		Rate:       req.Rate,
+		Inclusive:  req.Inclusive,
+		IsActive:   true,
+		CreatedAt:  now,
+		UpdatedAt:  now,
+	}
+
+	if err := s.taxRepo.Create(ctx, rate); err != nil {
+		return nil, fmt.Errorf("failed to create tax rate: %w", err)
+	}
+
+	return rate, nil
+}
+
+// ListRates lists every tax rate
+func (s *TaxService) ListRates(ctx context.Context) ([]*model.TaxRate, error) {
+	return s.taxRepo.List(ctx, false)
+}
+
+// UpdateRate changes a tax rate (admin only). Placed orders keep their tax.
+func (s *TaxService) UpdateRate(ctx context.Context, rateID uuid.UUID, req *model.UpdateTaxRateRequest) (*model.TaxRate, error) {
+	rate, err := s.taxRepo.GetByID(ctx, rateID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, errors.New("tax rate not found")
+		}
+		return nil, fmt.Errorf("failed to get tax rate: %w", err)
+	}
+
+	if req.Name != nil {
+		rate.Name = strings.TrimSpace(*req.Name)
+	}
+	if req.Rate != nil {
+		rate.Rate = *req.Rate
+	}
+	if req.Inclusive != nil {
+		rate.Inclusive = *req.Inclusive
+	}
+	if req.IsActive != nil {
+		rate.IsActive = *req.IsActive
+	}
+
+	if err := s.taxRepo.Update(ctx, rate); err != nil {
+		return nil, fmt.Errorf("failed to update tax rate: %w", err)
+	}
+
+	return rate, nil
+}
+
+// DeleteRate deletes a tax rate (admin only)
+func (s *TaxService) DeleteRate(ctx context.Context, rateID uuid.UUID) error {
+	return s.taxRepo.Delete(ctx, rateID)
+}
+
+// TaxLines works out the tax of each line shipped to the destination, in the
+// order of the lines. Lines no rule applies to are untaxed.
+func (s *TaxService) TaxLines(ctx context.Context, dest model.ShippingDestination, lines []TaxableLine) ([]model.LineTax, error) {
+	rates, err := s.taxRepo.List(ctx, true)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tax rates: %w", err)
+	}
+
+	categories, err := s.categoryRepo.GetAll(ctx, false)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get categories: %w", err)
+	}
+	parents := make(map[uuid.UUID]*uuid.UUID, len(categories))
+	for _, category := range categories {
+		parents[category.ID] = category.ParentID
+	}
+
+	taxes := make([]model.LineTax, len(lines))
+	for i, line := range lines {
+		rate := matchTaxRate(rates, categoryChain(parents, line.CategoryID), dest)
+		if rate == nil {
+			taxes[i] = model.LineTax{TaxableAmount: line.Amount}
+			continue
+		}
+		taxes[i] = lineTax(rate, line.Amount)
+	}
+
+	return taxes, nil
+}
+
+// matchTaxRate picks the rule for a line whose category and its parents are
+// chain, nearest first
+func matchTaxRate(rates []*model.TaxRate, chain []uuid.UUID, dest model.ShippingDestination) *model.TaxRate {
+	var best *model.TaxRate
+	bestDepth, bestByState := 0, false
+	for _, rate := range rates {
+		if !strings.EqualFold(strings.TrimSpace(rate.Country), strings.TrimSpace(dest.Country)) {
+			continue
+		}
+		byState := rate.State != nil
+		if byState && (dest.State == nil || !strings.EqualFold(strings.TrimSpace(*rate.State), strings.TrimSpace(*dest.State))) {
+			continue
+		}
+
+		// General rules rank after every category in the chain
+		depth := len(chain)
+		if rate.CategoryID != nil {
+			depth = indexOfUUID(chain, *rate.CategoryID)
+			if depth < 0 {
+				continue
+			}
+		}
+
+		if best == nil || depth < bestDepth || (depth == bestDepth && byState && !bestByState) {
+			best, bestDepth, bestByState = rate, depth, byState
+		}
+	}
+	return best
+}
+
+// lineTax applies a rate to a line. Inclusive tax is carved out of the
+// amount; exclusive tax comes on top of it.
+func lineTax(rate *model.TaxRate, amount model.Money) model.LineTax {
+	name := rate.Name
+	tax := model.LineTax{
+		Name:      &name,
+		Rate:      rate.Rate,
+		Inclusive: rate.Inclusive,
+	}
+
+	if rate.Inclusive {
+		tax.TaxableAmount = amount.MulRate(1 / (1 + rate.Rate/100))
+		tax.Amount = amount.Sub(tax.TaxableAmount)
+	} else {
+		tax.TaxableAmount = amount
+		tax.Amount = amount.Percent(rate.Rate)
+	}
+	return tax
+}
+
+// categoryChain lists a category and its parents, nearest first
+func categoryChain(parents map[uuid.UUID]*uuid.UUID, categoryID *uuid.UUID) []uuid.UUID {
+	var chain []uuid.UUID
+	for id := categoryID; id != nil && len(chain) <= len(parents); id = parents[*id] {
+		chain = append(chain, *id)
+	}
+	return chain
+}
+
+func indexOfUUID(ids []uuid.UUID, id uuid.UUID) int {
+	for i, candidate := range ids {
+		if candidate == id {
+			return i
+		}
+	}
+	return -1
+}
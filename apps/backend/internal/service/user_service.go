@@ -121,6 +121,17 @@ func (s *UserService) GetShopIDByVendorID(ctx context.Context, vendorID uuid.UUI
 	return shopID, nil
 }
 
+// ActorFor builds the policy actor for a user, resolving a vendor's shop
+func (s *UserService) ActorFor(ctx context.Context, user *model.User) *Actor {
+	actor := &Actor{UserID: user.ID, Role: user.Role}
+	if user.Role == model.RoleVendor {
+		if shopID, err := s.userRepo.GetShopIDByVendorID(ctx, user.ID); err == nil {
+			actor.ShopID = &shopID
+		}
+	}
+	return actor
+}
+
 // ConvertToVendor converts a customer to vendor with business information
 func (s *UserService) ConvertToVendor(ctx context.Context, userID uuid.UUID, req *model.BecomeVendorRequest) (*model.User, error) {
 	// Get current user
@@ -0,0 +1,98 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/imbivek08/hamropasal/internal/model"
+	"github.com/imbivek08/hamropasal/internal/repository"
+)
+
+type WishlistService struct {
+	wishlistRepo *repository.WishlistRepository
+	productRepo  *repository.ProductRepository
+	cartService  *CartService
+}
+
+func NewWishlistService(wishlistRepo *repository.WishlistRepository, productRepo *repository.ProductRepository, cartService *CartService) *WishlistService {
+	return &WishlistService{
+		wishlistRepo: wishlistRepo,
+		productRepo:  productRepo,
+		cartService:  cartService,
+	}
+}
+
+// AddToWishlist adds a product to user's wishlist
+func (s *WishlistService) AddToWishlist(ctx context.Context, userID uuid.UUID, req *model.AddToWishlistRequest) (*model.WishlistItem, error) {
+	// Validate product exists and is available
+	product, err := s.productRepo.GetByID(ctx, req.ProductID)
+	if err != nil {
+		return nil, fmt.Errorf("product not found")
+	}
+
+	if !product.IsActive {
+		return nil, fmt.Errorf("product is not available")
+	}
+
+	item, err := s.wishlistRepo.AddItem(ctx, userID, req.ProductID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to add item to wishlist: %w", err)
+	}
+
+	return item, nil
+}
+
+// GetWishlist retrieves user's wishlist with product details
+func (s *WishlistService) GetWishlist(ctx context.Context, userID uuid.UUID) (*model.WishlistResponse, error) {
+	items, err := s.wishlistRepo.GetByUserID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get wishlist: %w", err)
+	}
+
+	return &model.WishlistResponse{
+		Items:     items,
+		ItemCount: len(items),
+	}, nil
+}
+
+// RemoveFromWishlist removes a product from user's wishlist
+func (s *WishlistService) RemoveFromWishlist(ctx context.Context, userID, productID uuid.UUID) error {
+	return s.wishlistRepo.RemoveItem(ctx, userID, productID)
+}
+
+// MoveToCart adds a wishlist product to the cart and removes it from the wishlist.
+// Stock and availability checks are the same as adding to cart directly.
+func (s *WishlistService) MoveToCart(ctx context.Context, userID, productID uuid.UUID, variantID *uuid.UUID, quantity int) (*model.CartItemResponse, error) {
+	inWishlist, err := s.wishlistRepo.Exists(ctx, userID, productID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check wishlist: %w", err)
+	}
+	if !inWishlist {
+		return nil, fmt.Errorf("wishlist item not found")
+	}
+
+	if quantity < 1 {
+		quantity = 1
+	}
+
+	item, err := s.cartService.AddToCart(ctx, model.UserCartOwner(userID), &model.AddToCartRequest{
+		ProductID: productID,
+		VariantID: variantID,
+		Quantity:  quantity,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.wishlistRepo.RemoveItem(ctx, userID, productID); err != nil {
+		return nil, fmt.Errorf("failed to remove item from wishlist: %w", err)
+	}
+
+	return item, nil
+}
+
+// IsInWishlist checks if a product is in user's wishlist
+func (s *WishlistService) IsInWishlist(ctx context.Context, userID, productID uuid.UUID) (bool, error) {
+	return s.wishlistRepo.Exists(ctx, userID, productID)
+}
@@ -0,0 +1,199 @@
+package svix
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+)
+
+var testKey = []byte("clerk-test-signing-key")
+
+var testSecret = "whsec_" + base64.StdEncoding.EncodeToString(testKey)
+
+// memoryStore is an in-memory ReplayStore
+type memoryStore struct {
+	seen map[string]bool
+}
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{seen: make(map[string]bool)}
+}
+
+func (s *memoryStore) Remember(ctx context.Context, msgID string) (bool, error) {
+	if s.seen[msgID] {
+		return false, nil
+	}
+	s.seen[msgID] = true
+	return true, nil
+}
+
+func (s *memoryStore) Forget(ctx context.Context, msgID string) error {
+	delete(s.seen, msgID)
+	return nil
+}
+
+func newTestVerifier(t *testing.T, now time.Time) *Verifier {
+	t.Helper()
+
+	verifier, err := NewVerifier(testSecret, newMemoryStore())
+	if err != nil {
+		t.Fatalf("NewVerifier: %v", err)
+	}
+	verifier.now = func() time.Time { return now }
+	return verifier
+}
+
+func signedHeader(msgID string, sentAt time.Time, body []byte) http.Header {
+	timestamp := strconv.FormatInt(sentAt.Unix(), 10)
+
+	header := http.Header{}
+	header.Set("svix-id", msgID)
+	header.Set("svix-timestamp", timestamp)
+	header.Set("svix-signature", "v1,"+sign(testKey, msgID, timestamp, body))
+	return header
+}
+
+func TestVerifyAcceptsValidSignature(t *testing.T) {
+	now := time.Now()
+	body := []byte(`{"type":"user.created","data":{"id":"user_123"}}`)
+	verifier := newTestVerifier(t, now)
+
+	msgID, err := verifier.Verify(context.Background(), signedHeader("msg_1", now, body), body)
+	if err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+	if msgID != "msg_1" {
+		t.Errorf("msgID = %q, want %q", msgID, "msg_1")
+	}
+}
+
+func TestVerifyAcceptsAnyOfSeveralSignatures(t *testing.T) {
+	now := time.Now()
+	body := []byte(`{"type":"user.updated"}`)
+	verifier := newTestVerifier(t, now)
+
+	header := signedHeader("msg_1", now, body)
+	header.Set("svix-signature", "v1,bm90LXRoZS1zaWduYXR1cmU= "+header.Get("svix-signature"))
+
+	if _, err := verifier.Verify(context.Background(), header, body); err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+}
+
+func TestVerifyRejectsTamperedBody(t *testing.T) {
+	now := time.Now()
+	body := []byte(`{"type":"user.deleted","data":{"id":"user_123"}}`)
+	verifier := newTestVerifier(t, now)
+
+	header := signedHeader("msg_1", now, body)
+	tampered := []byte(`{"type":"user.deleted","data":{"id":"user_456"}}`)
+
+	if _, err := verifier.Verify(context.Background(), header, tampered); !errors.Is(err, ErrInvalidSignature) {
+		t.Fatalf("err = %v, want %v", err, ErrInvalidSignature)
+	}
+}
+
+func TestVerifyRejectsWrongSecret(t *testing.T) {
+	now := time.Now()
+	body := []byte(`{"type":"user.created"}`)
+
+	verifier, err := NewVerifier("whsec_"+base64.StdEncoding.EncodeToString([]byte("other-key")), newMemoryStore())
+	if err != nil {
+		t.Fatalf("NewVerifier: %v", err)
+	}
+	verifier.now = func() time.Time { return now }
+
+	if _, err := verifier.Verify(context.Background(), signedHeader("msg_1", now, body), body); !errors.Is(err, ErrInvalidSignature) {
+		t.Fatalf("err = %v, want %v", err, ErrInvalidSignature)
+	}
+}
+
+func TestVerifyRejectsMissingHeaders(t *testing.T) {
+	now := time.Now()
+	body := []byte(`{}`)
+	verifier := newTestVerifier(t, now)
+
+	for _, name := range []string{"svix-id", "svix-timestamp", "svix-signature"} {
+		header := signedHeader("msg_1", now, body)
+		header.Del(name)
+
+		if _, err := verifier.Verify(context.Background(), header, body); !errors.Is(err, ErrMissingHeaders) {
+			t.Errorf("without %s: err = %v, want %v", name, err, ErrMissingHeaders)
+		}
+	}
+}
+
+func TestVerifyChecksTimestampTolerance(t *testing.T) {
+	now := time.Now()
+	body := []byte(`{"type":"user.created"}`)
+
+	tests := []struct {
+		name    string
+		sentAt  time.Time
+		wantErr error
+	}{
+		{"within tolerance", now.Add(-DefaultTolerance + time.Second), nil},
+		{"too old", now.Add(-DefaultTolerance - time.Second), ErrTimestampExpired},
+		{"too far in the future", now.Add(DefaultTolerance + time.Second), ErrTimestampExpired},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			verifier := newTestVerifier(t, now)
+
+			_, err := verifier.Verify(context.Background(), signedHeader("msg_1", tt.sentAt, body), body)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestVerifyRejectsMalformedTimestamp(t *testing.T) {
+	now := time.Now()
+	body := []byte(`{}`)
+	verifier := newTestVerifier(t, now)
+
+	header := signedHeader("msg_1", now, body)
+	header.Set("svix-timestamp", "yesterday")
+
+	if _, err := verifier.Verify(context.Background(), header, body); !errors.Is(err, ErrInvalidTimestamp) {
+		t.Fatalf("err = %v, want %v", err, ErrInvalidTimestamp)
+	}
+}
+
+func TestVerifyRejectsReplayUntilForgotten(t *testing.T) {
+	now := time.Now()
+	body := []byte(`{"type":"user.created"}`)
+	verifier := newTestVerifier(t, now)
+	header := signedHeader("msg_1", now, body)
+	ctx := context.Background()
+
+	if _, err := verifier.Verify(ctx, header, body); err != nil {
+		t.Fatalf("first delivery: %v", err)
+	}
+
+	if _, err := verifier.Verify(ctx, header, body); !errors.Is(err, ErrReplayed) {
+		t.Fatalf("replay: err = %v, want %v", err, ErrReplayed)
+	}
+
+	if err := verifier.Forget(ctx, "msg_1"); err != nil {
+		t.Fatalf("Forget: %v", err)
+	}
+
+	if _, err := verifier.Verify(ctx, header, body); err != nil {
+		t.Fatalf("retry after Forget: %v", err)
+	}
+}
+
+func TestNewVerifierRejectsInvalidSecret(t *testing.T) {
+	for _, secret := range []string{"", "whsec_", "whsec_not base64!"} {
+		if _, err := NewVerifier(secret, newMemoryStore()); err == nil {
+			t.Errorf("NewVerifier(%q) succeeded, want error", secret)
+		}
+	}
+}
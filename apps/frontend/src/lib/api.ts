@@ -36,7 +36,17 @@ export interface ProductSearchParams {
   search?: string;
   min_price?: number;
   max_price?: number;
-  sort_by?: 'price_asc' | 'price_desc' | 'name_asc' | 'newest';
+  sort_by?: 'price_asc' | 'price_desc' | 'name_asc' | 'newest' | 'relevance' | 'rating';
+  page?: number;
+  page_size?: number;
+}
+
+export interface ProductListResponse {
+  products: Product[];
+  total: number;
+  page: number;
+  page_size: number;
+  total_pages: number;
 }
 
 export interface BecomeVendorRequest {
@@ -369,8 +379,11 @@ class ApiClient {
     if (params?.min_price !== undefined) queryParts.push(`min_price=${params.min_price}`);
     if (params?.max_price !== undefined) queryParts.push(`max_price=${params.max_price}`);
     if (params?.sort_by) queryParts.push(`sort_by=${params.sort_by}`);
+    if (params?.page) queryParts.push(`page=${params.page}`);
+    if (params?.page_size) queryParts.push(`page_size=${params.page_size}`);
     const queryString = queryParts.length > 0 ? `?${queryParts.join('&')}` : '';
-    return this.request(`/api/v1/products${queryString}`);
+    const response = await this.request<ProductListResponse>(`/api/v1/products${queryString}`);
+    return { ...response, data: response.data?.products };
   }
 
   async getProductById(id: string): Promise<ApiResponse<Product>> {